@@ -0,0 +1,80 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IPLeaseSpec defines the allocation of an address of an IPPool
+type IPLeaseSpec struct {
+	// Pool is the name of the IPPool
+	// +required
+	Pool string `json:"pool"`
+
+	// Address is the allocated address
+	// +required
+	Address string `json:"address"`
+
+	// Namespace is the namespace of the Wukong the address is allocated to
+	// +required
+	Namespace string `json:"namespace"`
+
+	// Wukong is the name of the Wukong the address is allocated to
+	// +required
+	Wukong string `json:"wukong"`
+
+	// Network is the name of the Wukong network the address is allocated to
+	// +required
+	Network string `json:"network"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Pool",type=string,JSONPath=`.spec.pool`
+// +kubebuilder:printcolumn:name="Address",type=string,JSONPath=`.spec.address`
+// +kubebuilder:printcolumn:name="Namespace",type=string,JSONPath=`.spec.namespace`
+// +kubebuilder:printcolumn:name="Wukong",type=string,JSONPath=`.spec.wukong`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// IPLease is the Schema for the ipleases API
+// It records the allocation of an address of an IPPool to a Wukong network. It is named after the
+// pool and the address, so that an address can never be allocated twice, and is managed by the operator
+type IPLease struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	// spec defines the allocated address
+	// +required
+	Spec IPLeaseSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// IPLeaseList contains a list of IPLease
+type IPLeaseList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitzero"`
+	Items           []IPLease `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&IPLease{}, &IPLeaseList{})
+}
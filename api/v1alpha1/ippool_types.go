@@ -0,0 +1,123 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IPPoolSpec defines the desired state of IPPool
+type IPPoolSpec struct {
+	// CIDR is the subnet addresses are allocated from (IPv4 or IPv6)
+	// Format: "192.168.100.0/24"
+	// +kubebuilder:validation:XValidation:rule="isCIDR(self)",message="cidr must be a valid CIDR"
+	// +required
+	CIDR string `json:"cidr"`
+
+	// Gateway is the default gateway of the subnet, it is never allocated
+	// +optional
+	Gateway string `json:"gateway,omitempty"`
+
+	// DNSServers is a list of DNS server addresses configured in the guests
+	// +optional
+	DNSServers []string `json:"dnsServers,omitempty"`
+
+	// Exclude is a list of addresses or CIDRs that are never allocated
+	// +optional
+	Exclude []string `json:"exclude,omitempty"`
+
+	// Reservations pin addresses to specific Wukong networks
+	// Reserved addresses are only allocated to the Wukong network they are reserved for
+	// +optional
+	Reservations []IPReservation `json:"reservations,omitempty"`
+}
+
+// IPReservation reserves an address of an IPPool for a Wukong network
+type IPReservation struct {
+	// Address is the reserved address
+	// +required
+	Address string `json:"address"`
+
+	// Namespace is the namespace of the Wukong
+	// +required
+	Namespace string `json:"namespace"`
+
+	// Wukong is the name of the Wukong
+	// +required
+	Wukong string `json:"wukong"`
+
+	// Network is the name of the Wukong network
+	// +required
+	Network string `json:"network"`
+}
+
+// IPPoolStatus defines the observed state of IPPool
+type IPPoolStatus struct {
+	// Capacity is the number of allocatable addresses of the CIDR, without the network and
+	// broadcast addresses, the gateway and excluded addresses; reserved addresses are included
+	// +optional
+	Capacity int64 `json:"capacity,omitempty"`
+
+	// Allocated is the number of addresses leased
+	// +optional
+	Allocated int32 `json:"allocated,omitempty"`
+
+	// Conditions represent the current state of the IPPool resource
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="CIDR",type=string,JSONPath=`.spec.cidr`
+// +kubebuilder:printcolumn:name="Allocated",type=integer,JSONPath=`.status.allocated`
+// +kubebuilder:printcolumn:name="Capacity",type=integer,JSONPath=`.status.capacity`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// IPPool is the Schema for the ippools API
+// It is a subnet the operator allocates static addresses from for networks with ipConfig.mode pool
+type IPPool struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	// spec defines the desired state of IPPool
+	// +required
+	Spec IPPoolSpec `json:"spec"`
+
+	// status defines the observed state of IPPool
+	// +optional
+	Status IPPoolStatus `json:"status,omitzero"`
+}
+
+// +kubebuilder:object:root=true
+
+// IPPoolList contains a list of IPPool
+type IPPoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitzero"`
+	Items           []IPPool `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&IPPool{}, &IPPoolList{})
+}
@@ -18,6 +18,7 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -30,6 +31,40 @@ const (
 	PhaseError    = "Error"
 )
 
+// Expansion state constants for VolumeStatus
+const (
+	ExpansionStateResizing                = "Resizing"
+	ExpansionStateFileSystemResizePending = "FileSystemResizePending"
+	ExpansionStateGuestResizePending      = "GuestResizePending"
+	ExpansionStateCompleted               = "Completed"
+	ExpansionStateFailed                  = "Failed"
+)
+
+// Reclaim policy constants for DiskConfig
+const (
+	ReclaimPolicyDelete = "Delete"
+	ReclaimPolicyRetain = "Retain"
+)
+
+// QoS mode constants for DiskQoSSpec
+const (
+	QoSModeHypervisor = "Hypervisor"
+	QoSModeStorage    = "Storage"
+)
+
+// Encryption mode constants for DiskEncryptionSpec
+const (
+	EncryptionModeStorageClass = "StorageClass"
+	EncryptionModeGuest        = "Guest"
+)
+
+// State constants for RemovedVolumeStatus
+const (
+	RemovedVolumeDetaching = "Detaching"
+	RemovedVolumeRetained  = "Retained"
+	RemovedVolumeOrphaned  = "Orphaned"
+)
+
 // WukongSpec defines the desired state of Wukong
 type WukongSpec struct {
 	// CPU is the number of CPU cores for the virtual machine
@@ -44,6 +79,8 @@ type WukongSpec struct {
 	Memory string `json:"memory"`
 
 	// OSImage is the operating system image for Cloud-Init configuration
+	// If it is the name of a WukongImage, the boot disk is created from that image and
+	// the image firmware and default user are applied to the virtual machine
 	// +optional
 	OSImage string `json:"osImage,omitempty"`
 
@@ -55,11 +92,25 @@ type WukongSpec struct {
 	// +optional
 	CloudInitUser *CloudInitUserSpec `json:"cloudInitUser,omitempty"`
 
+	// PodNetwork configures the interface of the virtual machine on the cluster pod network
+	// By default the pod network is attached with the masquerade binding
+	// +optional
+	PodNetwork *PodNetworkSpec `json:"podNetwork,omitempty"`
+
 	// Networks defines the network interfaces for the virtual machine
 	// +optional
 	Networks []NetworkConfig `json:"networks,omitempty"`
 
+	// NetworkPolicy defines what happens when a network cannot be attached because Multus is not installed
+	// strict (default) fails the Wukong with a MultusUnavailable reason; bestEffort starts the
+	// virtual machine without the network and marks it as degraded
+	// +kubebuilder:validation:Enum=strict;bestEffort
+	// +optional
+	NetworkPolicy string `json:"networkPolicy,omitempty"`
+
 	// Disks defines the storage disks for the virtual machine
+	// +listType=map
+	// +listMapKey=name
 	// +optional
 	Disks []DiskConfig `json:"disks,omitempty"`
 
@@ -72,7 +123,32 @@ type WukongSpec struct {
 	StartStrategy *StartStrategySpec `json:"startStrategy,omitempty"`
 }
 
+// PodNetworkSpec defines the interface of the virtual machine on the pod network
+// +kubebuilder:validation:XValidation:rule="!has(self.binding) || !has(self.enabled) || self.enabled",message="binding only applies when the pod network is enabled"
+type PodNetworkSpec struct {
+	// Enabled attaches the virtual machine to the pod network, defaults to true
+	// Disable it for VMs that must only be reachable on their Multus networks; a network
+	// with primary set then provides the default route
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Binding is how the VM interface is connected to the pod network: masquerade, bridge or passt
+	// Defaults to masquerade; bridge prevents live migration, passt uses the network binding plugin
+	// named "passt" registered in the KubeVirt CR
+	// +kubebuilder:validation:Enum=masquerade;bridge;passt
+	// +optional
+	Binding string `json:"binding,omitempty"`
+}
+
 // NetworkConfig defines a network interface configuration
+// +kubebuilder:validation:XValidation:rule="has(self.type) != has(self.networkRef)",message="exactly one of type and networkRef must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.networkRef) || !(has(self.nadName) || has(self.vlanId) || has(self.bridgeName) || has(self.hostInterface) || has(self.mtu) || has(self.macvlanMode) || has(self.sriov) || has(self.trunk))",message="networkRef is mutually exclusive with nadName, vlanId, bridgeName, hostInterface, mtu, macvlanMode, sriov and trunk"
+// +kubebuilder:validation:XValidation:rule="!has(self.type) || self.type != 'macvlan' || has(self.nadName) || has(self.hostInterface)",message="hostInterface is required for macvlan networks"
+// +kubebuilder:validation:XValidation:rule="!has(self.type) || self.type != 'sriov' || has(self.nadName) || has(self.sriov)",message="sriov is required for sriov networks"
+// +kubebuilder:validation:XValidation:rule="!has(self.trunk) || (has(self.type) && self.type == 'ovs')",message="trunk only applies to ovs networks"
+// +kubebuilder:validation:XValidation:rule="(has(self.binding) && self.binding == 'plugin') == has(self.bindingPlugin)",message="bindingPlugin is required for the plugin binding and only applies to it"
+// +kubebuilder:validation:XValidation:rule="!has(self.type) || !has(self.binding) || (self.type == 'sriov') == (self.binding == 'sriov')",message="the sriov binding is required for sriov networks and only applies to them"
+// +kubebuilder:validation:XValidation:rule="!has(self.model) || !((has(self.binding) && self.binding == 'sriov') || (has(self.type) && self.type == 'sriov'))",message="model does not apply to the sriov binding"
 type NetworkConfig struct {
 	// Name is the unique name of the network interface
 	// +kubebuilder:validation:Required
@@ -81,9 +157,15 @@ type NetworkConfig struct {
 	Name string `json:"name"`
 
 	// Type is the network type: bridge, macvlan, sriov, or ovs
+	// Required unless NetworkRef is set
 	// +kubebuilder:validation:Enum=bridge;macvlan;sriov;ovs
-	// +required
-	Type string `json:"type"`
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// NetworkRef is the name of a cluster-scoped WukongNetwork defining the network
+	// All Wukongs referencing it in a namespace share a single NetworkAttachmentDefinition
+	// +optional
+	NetworkRef string `json:"networkRef,omitempty"`
 
 	// NADName is the name of an existing NetworkAttachmentDefinition
 	// If empty, the operator will create a new NAD
@@ -100,33 +182,239 @@ type NetworkConfig struct {
 	// +optional
 	BridgeName string `json:"bridgeName,omitempty"`
 
+	// HostInterface is the host NIC or bond attached to the bridge (e.g., "eth1", "bond0")
+	// If set, the operator provisions the bridge (and, for bridge networks with a VLANID, the VLAN
+	// sub-interface "<hostInterface>.<vlanId>") on the nodes matching the Wukong's node selector
+	// with an NMState NodeNetworkConfigurationPolicy, and waits for it to be Available
+	// If empty, the bridge must already exist on the nodes
+	// For macvlan networks, it is the master interface (required); with a VLANID the master is
+	// the VLAN sub-interface "<hostInterface>.<vlanId>", provisioned with an NMState policy as well
+	// +kubebuilder:validation:MaxLength=15
+	// +optional
+	HostInterface string `json:"hostInterface,omitempty"`
+
+	// MacvlanMode is the macvlan mode (for macvlan type), defaults to bridge
+	// +kubebuilder:validation:Enum=bridge;private;vepa;passthru
+	// +optional
+	MacvlanMode string `json:"macvlanMode,omitempty"`
+
+	// SRIOV defines the SR-IOV virtual function settings (required for sriov type)
+	// +optional
+	SRIOV *SRIOVConfig `json:"sriov,omitempty"`
+
+	// Trunk is the list of VLANs allowed on the port (for ovs type)
+	// +optional
+	Trunk []VLANRange `json:"trunk,omitempty"`
+
+	// MTU is the MTU of the network interface
+	// +kubebuilder:validation:Minimum=576
+	// +kubebuilder:validation:Maximum=9216
+	// +optional
+	MTU *int `json:"mtu,omitempty"`
+
+	// Binding is how the VM interface is connected to the network: bridge, sriov or plugin
+	// Defaults to sriov for sriov networks and bridge otherwise
+	// masquerade and passt only apply to the pod network
+	// +kubebuilder:validation:Enum=bridge;sriov;plugin
+	// +optional
+	Binding string `json:"binding,omitempty"`
+
+	// BindingPlugin is the name of the network binding plugin registered in the KubeVirt CR
+	// (e.g., "passt", "macvtap"), required for the plugin binding
+	// +optional
+	BindingPlugin string `json:"bindingPlugin,omitempty"`
+
+	// Model is the emulated NIC model, defaults to virtio
+	// +kubebuilder:validation:Enum=virtio;e1000e
+	// +optional
+	Model string `json:"model,omitempty"`
+
+	// MACAddress is a fixed MAC address of the VM interface
+	// If empty, the operator allocates a stable MAC address from its MAC prefix
+	// It must not be used by another Wukong
+	// +kubebuilder:validation:Pattern=`^([0-9a-fA-F]{2}:){5}[0-9a-fA-F]{2}$`
+	// +optional
+	MACAddress string `json:"macAddress,omitempty"`
+
+	// PCIAddress is a fixed PCI address of the VM interface (e.g., "0000:81:01.0")
+	// +kubebuilder:validation:Pattern=`^[0-9a-fA-F]{4}:[0-9a-fA-F]{2}:[0-9a-fA-F]{2}\.[0-7]$`
+	// +optional
+	PCIAddress string `json:"pciAddress,omitempty"`
+
+	// BootOrder enables network boot (PXE) from this interface with the given priority (1 is first)
+	// The boot disk is tried after the interfaces with a boot order
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	BootOrder *uint `json:"bootOrder,omitempty"`
+
+	// Primary makes this network the default network of the virtual machine, replacing the
+	// pod network: the pod is attached to it as Multus default network and the guest default
+	// route goes through it. Requires podNetwork.enabled false; at most one network can be primary
+	// +optional
+	Primary bool `json:"primary,omitempty"`
+
 	// IPConfig defines the IP configuration for this network
 	// +optional
 	IPConfig *IPConfigSpec `json:"ipConfig,omitempty"`
 }
 
+// SRIOVConfig defines the SR-IOV virtual function settings of a network
+type SRIOVConfig struct {
+	// ResourceName is the SR-IOV device plugin resource VFs are allocated from
+	// (e.g., "intel.com/sriov_netdevice")
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	ResourceName string `json:"resourceName"`
+
+	// SpoofChk enables MAC spoof checking on the VF
+	// +optional
+	SpoofChk *bool `json:"spoofChk,omitempty"`
+
+	// Trust enables trusted mode on the VF (required for e.g. promiscuous mode in the guest)
+	// +optional
+	Trust *bool `json:"trust,omitempty"`
+}
+
+// VLANRange is a VLAN or a range of VLANs
+// +kubebuilder:validation:XValidation:rule="has(self.id) != (has(self.minId) && has(self.maxId))",message="either id or minId and maxId must be set"
+type VLANRange struct {
+	// ID is a single VLAN ID
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=4094
+	// +optional
+	ID *int `json:"id,omitempty"`
+
+	// MinID is the first VLAN ID of the range
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=4094
+	// +optional
+	MinID *int `json:"minId,omitempty"`
+
+	// MaxID is the last VLAN ID of the range
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=4094
+	// +optional
+	MaxID *int `json:"maxId,omitempty"`
+}
+
+// Network policy constants
+const (
+	NetworkPolicyStrict     = "strict"
+	NetworkPolicyBestEffort = "bestEffort"
+)
+
+// Interface binding constants
+const (
+	InterfaceBindingBridge     = "bridge"
+	InterfaceBindingSRIOV      = "sriov"
+	InterfaceBindingPlugin     = "plugin"
+	InterfaceBindingMasquerade = "masquerade"
+	InterfaceBindingPasst      = "passt"
+)
+
+// IP acquisition mode constants
+const (
+	IPModeStatic = "static"
+	IPModeDHCP   = "dhcp"
+	IPModePool   = "pool"
+)
+
+// IPv6 acquisition mode constants
+const (
+	IPv6ModeStatic = "static"
+	IPv6ModeDHCP   = "dhcp"
+	IPv6ModeSLAAC  = "slaac"
+)
+
 // IPConfigSpec defines IP configuration for a network interface
+// +kubebuilder:validation:XValidation:rule="(self.mode == 'pool') == has(self.pool)",message="pool is required for pool mode and only applies to it"
 type IPConfigSpec struct {
-	// Mode is the IP acquisition mode: static or dhcp
-	// +kubebuilder:validation:Enum=static;dhcp
+	// Mode is the IP acquisition mode: static, dhcp or pool
+	// In pool mode the operator allocates a free address from an IPPool and configures it with cloud-init
+	// +kubebuilder:validation:Enum=static;dhcp;pool
 	// +required
 	Mode string `json:"mode"`
 
+	// Pool is the name of the IPPool the address is allocated from (pool mode)
+	// The gateway and DNS servers of the pool are used unless set here
+	// +optional
+	Pool string `json:"pool,omitempty"`
+
 	// Address is the IP address and subnet mask (required for static mode)
 	// Format: "192.168.1.10/24"
 	// +optional
 	Address *string `json:"address,omitempty"`
 
 	// Gateway is the gateway address (for static mode)
+	// The default route through it is unmetered only on the primary network; on other networks
+	// it (and any DHCP-provided default route) uses metric 2000 plus the network index
 	// +optional
 	Gateway *string `json:"gateway,omitempty"`
 
 	// DNSServers is a list of DNS server addresses
 	// +optional
 	DNSServers []string `json:"dnsServers,omitempty"`
+
+	// SearchDomains is a list of DNS search domains
+	// +optional
+	SearchDomains []string `json:"searchDomains,omitempty"`
+
+	// IPv6 configures IPv6 in addition to the address configured by mode (dual stack)
+	// +optional
+	IPv6 *IPv6ConfigSpec `json:"ipv6,omitempty"`
+
+	// Routes are additional static routes configured in the guest
+	// +optional
+	Routes []RouteSpec `json:"routes,omitempty"`
+}
+
+// IPv6ConfigSpec defines the IPv6 configuration of a dual-stack network interface
+// +kubebuilder:validation:XValidation:rule="self.mode != 'static' || has(self.address)",message="address is required for static mode"
+type IPv6ConfigSpec struct {
+	// Mode is the IPv6 acquisition mode: static, dhcp (DHCPv6) or slaac (router advertisements)
+	// +kubebuilder:validation:Enum=static;dhcp;slaac
+	// +required
+	Mode string `json:"mode"`
+
+	// Address is the IPv6 address and prefix length (required for static mode)
+	// Format: "fd00:100::10/64"
+	// +optional
+	Address *string `json:"address,omitempty"`
+
+	// Gateway is the IPv6 gateway address (for static mode)
+	// The default route through it is unmetered only on the primary network; on other networks
+	// it (and any DHCP-provided default route) uses metric 2000 plus the network index
+	// +optional
+	Gateway *string `json:"gateway,omitempty"`
+}
+
+// RouteSpec defines a static route configured in the guest
+type RouteSpec struct {
+	// To is the destination network in CIDR notation (e.g., "10.20.0.0/16")
+	// +required
+	To string `json:"to"`
+
+	// Via is the next hop address
+	// +required
+	Via string `json:"via"`
+
+	// Metric is the route metric
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Metric *int32 `json:"metric,omitempty"`
 }
 
 // DiskConfig defines a storage disk configuration
+// +kubebuilder:validation:XValidation:rule="!(has(self.image) && has(self.source))",message="image and source are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!has(self.existingClaim) || !(has(self.image) || has(self.source))",message="existingClaim is mutually exclusive with image and source"
+// +kubebuilder:validation:XValidation:rule="[has(self.image) || has(self.source), has(self.existingClaim), has(self.containerDisk), has(self.emptyDisk)].filter(x, x).size() <= 1",message="image/source, existingClaim, containerDisk and emptyDisk are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!has(self.emptyDisk) || has(self.size)",message="size is required for emptyDisk"
+// +kubebuilder:validation:XValidation:rule="!(has(self.ephemeral) && self.ephemeral) || !(has(self.containerDisk) || has(self.emptyDisk))",message="ephemeral only applies to PVC-backed disks"
+// +kubebuilder:validation:XValidation:rule="!has(self.pvcName) || !(has(self.existingClaim) || has(self.containerDisk) || has(self.emptyDisk))",message="pvcName only applies to PVC-backed disks created by the Wukong"
+// +kubebuilder:validation:XValidation:rule="!(has(self.shareable) && self.shareable) || has(self.existingClaim)",message="shareable only applies to existingClaim disks"
+// +kubebuilder:validation:XValidation:rule="!has(self.encryption) || !(has(self.existingClaim) || has(self.containerDisk) || has(self.emptyDisk))",message="encryption only applies to PVC-backed disks created by the Wukong"
+// +kubebuilder:validation:XValidation:rule="!has(self.encryption) || self.encryption.mode != 'Guest' || !((has(self.boot) && self.boot) || has(self.image) || has(self.source))",message="guest encryption requires a blank data disk"
+// +kubebuilder:validation:XValidation:rule="has(oldSelf.pvcName) == has(self.pvcName)",message="pvcName cannot be added to or removed from an existing disk"
 type DiskConfig struct {
 	// Name is the unique name of the disk
 	// +kubebuilder:validation:Required
@@ -135,22 +423,371 @@ type DiskConfig struct {
 	Name string `json:"name"`
 
 	// Size is the disk size (e.g., "80Gi", "500G")
+	// Required unless Image references a WukongImage, in which case the image default size is used
 	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?(Ki|Mi|Gi|Ti|Pi|Ei|K|M|G|T|P|E)?$`
-	// +required
-	Size string `json:"size"`
+	// +optional
+	Size string `json:"size,omitempty"`
 
 	// StorageClassName is the name of the StorageClass to use
-	// +required
-	StorageClassName string `json:"storageClassName"`
+	// If empty, the StorageClass of the namespace WukongDefaults, the namespace annotation
+	// wukong.novasphere.dev/default-storage-class or the cluster default StorageClass is used
+	// The resolved StorageClass is reported in VolumeStatus.StorageClassName
+	// +optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+
+	// PVCName overrides the name of the PVC (and DataVolume) created for the disk
+	// By default the name is "<wukong>-<disk>", with a hash suffix when either name contains a dash
+	// or the name would be too long; PVCs are also found by the labels
+	// wukong.novasphere.dev/name and wukong.novasphere.dev/disk
+	// An existing unlabeled PVC is only adopted under the "<wukong>-<disk>" name; use ExistingClaim
+	// to attach any other PVC. The field is immutable and can only be set when the disk is added
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="pvcName is immutable"
+	// +optional
+	PVCName string `json:"pvcName,omitempty"`
 
 	// Boot indicates whether this is the boot disk
+	// If Image and Source are empty and WukongSpec.OSImage references a WukongImage,
+	// the boot disk is created from that image
 	// +optional
 	Boot bool `json:"boot,omitempty"`
 
 	// Image is the container image URL to create the disk from (uses DataVolume)
 	// If specified, a DataVolume will be created to import the image
+	// The source type is guessed from the URL prefix; use Source for explicit control
+	// Image may also be the name of a WukongImage, in which case the disk is cloned from the
+	// golden image of its StorageClass; a name without "/" or ":" must reference an existing WukongImage
 	// +optional
 	Image string `json:"image,omitempty"`
+
+	// Source explicitly defines where the disk content is imported from (uses DataVolume)
+	// Mutually exclusive with Image
+	// +optional
+	Source *DiskSourceSpec `json:"source,omitempty"`
+
+	// ImportRetry defines how failed imports are retried
+	// An import fails when the DataVolume fails, or when the CDI populating pod has restarted
+	// 3 times and is not running. Only applies to disks populated by a DataVolume (image or source)
+	// +optional
+	ImportRetry *ImportRetryPolicy `json:"importRetry,omitempty"`
+
+	// AccessModes are the access modes of the PVC (e.g., ReadWriteOnce, ReadWriteMany)
+	// ReadWriteMany is required for live migration
+	// Default: ReadWriteOnce, or picked from the StorageProfile when VolumeMode is Auto
+	// +kubebuilder:validation:items:Enum=ReadWriteOnce;ReadOnlyMany;ReadWriteMany;ReadWriteOncePod
+	// +optional
+	AccessModes []string `json:"accessModes,omitempty"`
+
+	// VolumeMode is the volume mode of the PVC: Filesystem, Block or Auto
+	// Auto picks ReadWriteMany Block (then ReadWriteMany Filesystem) when the CDI StorageProfile
+	// of the StorageClass supports it, and falls back to Filesystem otherwise
+	// Default: Filesystem
+	// +kubebuilder:validation:Enum=Filesystem;Block;Auto
+	// +optional
+	VolumeMode string `json:"volumeMode,omitempty"`
+
+	// ExistingClaim is the name of an existing PVC in the same namespace to attach as this disk
+	// The PVC is not created, resized or deleted by the Wukong; Size, StorageClassName,
+	// AccessModes, VolumeMode and ReclaimPolicy are ignored
+	// +optional
+	ExistingClaim string `json:"existingClaim,omitempty"`
+
+	// ContainerDisk boots the disk directly from an OCI image containing a disk image (no PVC is created)
+	// Changes are lost when the VM stops
+	// +optional
+	ContainerDisk *ContainerDiskSource `json:"containerDisk,omitempty"`
+
+	// EmptyDisk creates a sparse scratch disk of Size on the node (no PVC is created)
+	// Its content is lost when the VM stops
+	// +optional
+	EmptyDisk *EmptyDiskSource `json:"emptyDisk,omitempty"`
+
+	// Ephemeral attaches the PVC through a copy-on-write overlay
+	// The PVC is only read; writes go to the overlay and are discarded when the VM stops
+	// +optional
+	Ephemeral bool `json:"ephemeral,omitempty"`
+
+	// Shareable allows the disk to be attached to several VMs at the same time
+	// (e.g., clustered file systems, Windows failover clusters)
+	// Only applies to ExistingClaim disks, since the PVC is shared with other VMs and must not be owned by one Wukong
+	// The PVC must be ReadWriteMany with Block volume mode; the disk cache is set to none
+	// +optional
+	Shareable bool `json:"shareable,omitempty"`
+
+	// ReclaimPolicy defines what happens to the PVC when the disk is removed from the spec
+	// or the Wukong is deleted: Delete or Retain
+	// Retain keeps the PVC (and its data) for the user to delete; Delete removes the PVC and DataVolume
+	// once the disk is detached from the VM
+	// Default: Retain
+	// +kubebuilder:validation:Enum=Delete;Retain
+	// +kubebuilder:default=Retain
+	// +optional
+	ReclaimPolicy string `json:"reclaimPolicy,omitempty"`
+
+	// Encryption encrypts the disk, either by the CSI driver of an encrypted StorageClass
+	// or with LUKS inside the guest
+	// +optional
+	Encryption *DiskEncryptionSpec `json:"encryption,omitempty"`
+
+	// QoS limits the IO of the disk
+	// If not set, the DiskQoS of the namespace WukongDefaults applies to PVC-backed disks
+	// +optional
+	QoS *DiskQoSSpec `json:"qos,omitempty"`
+}
+
+// DiskQoSSpec defines the IO limits of a disk
+// +kubebuilder:validation:XValidation:rule="!has(self.burst) || !has(self.mode) || self.mode == 'Hypervisor'",message="burst is only supported in Hypervisor mode"
+type DiskQoSSpec struct {
+	// Mode defines how the limits are enforced: Hypervisor or Storage
+	// Hypervisor: libvirt block IO tuning applied by a KubeVirt hook sidecar (requires the Sidecar feature gate,
+	// reported by the HypervisorQoSApplied condition); changes take effect when the VM restarts
+	// Storage: a VolumeAttributesClass of the CSI driver is assigned to the PVC (Ceph RBD only);
+	// changes are applied online by the CSI driver
+	// Default: Hypervisor
+	// +kubebuilder:validation:Enum=Hypervisor;Storage
+	// +kubebuilder:default=Hypervisor
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
+	// ReadIOPS is the maximum number of read operations per second
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ReadIOPS *int64 `json:"readIOPS,omitempty"`
+
+	// WriteIOPS is the maximum number of write operations per second
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	WriteIOPS *int64 `json:"writeIOPS,omitempty"`
+
+	// ReadBytesPerSecond is the maximum read bandwidth (e.g., "100Mi")
+	// +optional
+	ReadBytesPerSecond *resource.Quantity `json:"readBytesPerSecond,omitempty"`
+
+	// WriteBytesPerSecond is the maximum write bandwidth (e.g., "50Mi")
+	// +optional
+	WriteBytesPerSecond *resource.Quantity `json:"writeBytesPerSecond,omitempty"`
+
+	// Burst allows short bursts above the limits
+	// +optional
+	Burst *DiskQoSBurst `json:"burst,omitempty"`
+}
+
+// DiskQoSBurst defines the burst limits of a disk
+type DiskQoSBurst struct {
+	// ReadIOPS is the maximum number of read operations per second during a burst
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ReadIOPS *int64 `json:"readIOPS,omitempty"`
+
+	// WriteIOPS is the maximum number of write operations per second during a burst
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	WriteIOPS *int64 `json:"writeIOPS,omitempty"`
+
+	// ReadBytesPerSecond is the maximum read bandwidth during a burst
+	// +optional
+	ReadBytesPerSecond *resource.Quantity `json:"readBytesPerSecond,omitempty"`
+
+	// WriteBytesPerSecond is the maximum write bandwidth during a burst
+	// +optional
+	WriteBytesPerSecond *resource.Quantity `json:"writeBytesPerSecond,omitempty"`
+
+	// DurationSeconds is how long a burst may last
+	// Default: 1
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	DurationSeconds *int64 `json:"durationSeconds,omitempty"`
+}
+
+// ContainerDiskSource defines an OCI image containing a disk image
+type ContainerDiskSource struct {
+	// Image is the OCI image reference (e.g., "quay.io/containerdisks/fedora:latest")
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Image string `json:"image"`
+
+	// ImagePullPolicy is the pull policy of the image: Always, IfNotPresent or Never
+	// +kubebuilder:validation:Enum=Always;IfNotPresent;Never
+	// +optional
+	ImagePullPolicy string `json:"imagePullPolicy,omitempty"`
+
+	// ImagePullSecret is the name of the Secret used to pull the image
+	// +optional
+	ImagePullSecret string `json:"imagePullSecret,omitempty"`
+}
+
+// EmptyDiskSource defines a scratch disk sharing the lifecycle of the VM
+// The capacity of the disk is DiskConfig.Size
+type EmptyDiskSource struct{}
+
+// DiskEncryptionSpec defines how a disk is encrypted
+type DiskEncryptionSpec struct {
+	// Mode is the encryption mode: StorageClass or Guest
+	// StorageClass: the PVC is encrypted by the CSI driver (e.g., Ceph RBD, Longhorn); the StorageClass
+	// must have the parameter encrypted: "true" and read the per-PVC key Secret "<pvc>-key"
+	// (e.g., csi.storage.k8s.io/node-stage-secret-name: ${pvc.name}-key)
+	// If StorageClassName is empty, WukongDefaults.EncryptedStorageClassName is used
+	// Guest: the disk is formatted with LUKS and unlocked at boot by Cloud-Init as /dev/mapper/<disk name>;
+	// only blank data disks are supported
+	// +kubebuilder:validation:Enum=StorageClass;Guest
+	// +required
+	Mode string `json:"mode"`
+
+	// SecretName is the name of a Secret in the same namespace holding the passphrase under the key "passphrase"
+	// If empty, the operator generates a unique passphrase for the disk and stores it in the Secret "<pvc>-key"
+	// Encrypted disks whose key Secret is missing are not attached
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// ImportRetryPolicy defines the retry and backoff policy for failed DataVolume imports
+type ImportRetryPolicy struct {
+	// MaxRetries is the maximum number of times a failed import is recreated
+	// Set to 0 to disable retries
+	// Default: 3
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=20
+	// +optional
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+
+	// BackoffSeconds is the initial delay before a failed import is retried
+	// The delay doubles after each retry, up to 10 minutes
+	// Default: 30
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	BackoffSeconds *int32 `json:"backoffSeconds,omitempty"`
+}
+
+// DiskSourceSpec defines the source of a disk populated by a CDI DataVolume
+// Exactly one source must be specified
+// +kubebuilder:validation:XValidation:rule="[has(self.http), has(self.registry), has(self.s3), has(self.upload), has(self.blank), has(self.pvc), has(self.dataSource), has(self.dataImportCron)].filter(x, x).size() == 1",message="exactly one disk source must be specified"
+type DiskSourceSpec struct {
+	// HTTP imports the disk from an http(s) endpoint
+	// +optional
+	HTTP *HTTPDiskSource `json:"http,omitempty"`
+
+	// Registry imports the disk from a container image registry
+	// +optional
+	Registry *RegistryDiskSource `json:"registry,omitempty"`
+
+	// S3 imports the disk from an S3 compatible object store
+	// +optional
+	S3 *S3DiskSource `json:"s3,omitempty"`
+
+	// Upload creates an empty disk waiting for a client upload through the CDI upload proxy
+	// +optional
+	Upload *UploadDiskSource `json:"upload,omitempty"`
+
+	// Blank creates an empty raw disk
+	// +optional
+	Blank *BlankDiskSource `json:"blank,omitempty"`
+
+	// PVC clones the disk from an existing PersistentVolumeClaim
+	// +optional
+	PVC *PVCDiskSource `json:"pvc,omitempty"`
+
+	// DataSource clones the disk from a CDI DataSource
+	// +optional
+	DataSource *DataSourceReference `json:"dataSource,omitempty"`
+
+	// DataImportCron clones the disk from the DataSource managed by a CDI DataImportCron
+	// +optional
+	DataImportCron *DataSourceReference `json:"dataImportCron,omitempty"`
+}
+
+// HTTPDiskSource defines an http(s) import source
+type HTTPDiskSource struct {
+	// URL is the http(s) URL of the disk image
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +required
+	URL string `json:"url"`
+
+	// CertConfigMap is the name of a ConfigMap containing additional CA certificates
+	// +optional
+	CertConfigMap string `json:"certConfigMap,omitempty"`
+
+	// SecretRef is the name of a Secret containing accessKeyId (username) and secretKey (password)
+	// +optional
+	SecretRef string `json:"secretRef,omitempty"`
+
+	// ExtraHeaders are extra headers sent with the http requests
+	// +optional
+	ExtraHeaders []string `json:"extraHeaders,omitempty"`
+
+	// Checksum is the expected checksum of the downloaded image (e.g., "sha256:<hex>")
+	// Verification is done by CDI and requires a CDI version that supports http checksums
+	// +kubebuilder:validation:Pattern=`^(md5|sha1|sha256|sha512):[a-fA-F0-9]+$`
+	// +optional
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// RegistryDiskSource defines a container registry import source
+type RegistryDiskSource struct {
+	// URL is the image URL, starting with docker:// or oci-archive://
+	// +kubebuilder:validation:Pattern=`^(docker|oci-archive)://`
+	// +required
+	URL string `json:"url"`
+
+	// PullMethod is the pull method: pod (importer pod pulls the image) or node (node container runtime pulls the image)
+	// Default: pod
+	// +kubebuilder:validation:Enum=pod;node
+	// +optional
+	PullMethod string `json:"pullMethod,omitempty"`
+
+	// PullSecret is the name of a Secret containing the registry credentials
+	// +optional
+	PullSecret string `json:"pullSecret,omitempty"`
+
+	// CertConfigMap is the name of a ConfigMap containing the registry CA certificates
+	// +optional
+	CertConfigMap string `json:"certConfigMap,omitempty"`
+}
+
+// S3DiskSource defines an S3 import source
+type S3DiskSource struct {
+	// URL is the S3 URL of the disk image
+	// +required
+	URL string `json:"url"`
+
+	// SecretRef is the name of a Secret containing accessKeyId and secretKey
+	// +optional
+	SecretRef string `json:"secretRef,omitempty"`
+
+	// CertConfigMap is the name of a ConfigMap containing additional CA certificates
+	// +optional
+	CertConfigMap string `json:"certConfigMap,omitempty"`
+}
+
+// UploadDiskSource defines an upload source
+// The upload endpoint is reported in VolumeStatus.UploadEndpoint
+type UploadDiskSource struct{}
+
+// BlankDiskSource defines a blank disk source
+type BlankDiskSource struct{}
+
+// PVCDiskSource defines an existing PVC to clone from
+type PVCDiskSource struct {
+	// Namespace is the namespace of the source PVC
+	// Default: the namespace of the Wukong
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the name of the source PVC
+	// +required
+	Name string `json:"name"`
+}
+
+// DataSourceReference references a CDI object by name
+type DataSourceReference struct {
+	// Namespace is the namespace of the referenced object
+	// Default: the namespace of the Wukong
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the name of the referenced object
+	// +required
+	Name string `json:"name"`
 }
 
 // HighAvailabilitySpec defines high availability configuration
@@ -239,13 +876,22 @@ type WukongStatus struct {
 	// +optional
 	NodeName string `json:"nodeName,omitempty"`
 
+	// PodNetworkMACAddress is the stable MAC address allocated by the operator to the pod network interface
+	// The cloud-init network config matches the interface by this address
+	// +optional
+	PodNetworkMACAddress string `json:"podNetworkMACAddress,omitempty"`
+
 	// Conditions represent the current state of the Wukong resource
 	// Each condition has a unique type and reflects the status of a specific aspect of the resource
 	//
 	// Standard condition types include:
 	// - "Ready": the VM is ready and running
 	// - "NetworksConfigured": all networks are configured
+	// - "HostNetworkReady": the host bridges provisioned by NMState are available
 	// - "VolumesBound": all volumes are bound
+	// - "DiskImportFailed": a disk import has failed
+	// - "LiveMigratable": all volumes are ReadWriteMany and allow live migration
+	// - "RestartRequired": a network config changed and the running VM has to be restarted
 	//
 	// The status of each condition is one of True, False, or Unknown
 	// +listType=map
@@ -260,6 +906,40 @@ type WukongStatus struct {
 	// Volumes represents the status of storage volumes
 	// +optional
 	Volumes []VolumeStatus `json:"volumes,omitempty"`
+
+	// RemovedVolumes represents volumes whose disk was removed from the spec
+	// and that are still attached to the VM, retained, or could not be deleted
+	// +optional
+	RemovedVolumes []RemovedVolumeStatus `json:"removedVolumes,omitempty"`
+}
+
+// RemovedVolumeStatus represents the status of a volume whose disk was removed from the spec
+type RemovedVolumeStatus struct {
+	// Name is the name of the removed disk
+	// +required
+	Name string `json:"name"`
+
+	// PVCName is the name of the PersistentVolumeClaim
+	// +optional
+	PVCName string `json:"pvcName,omitempty"`
+
+	// ReclaimPolicy is the reclaim policy of the disk when it was removed
+	// +optional
+	ReclaimPolicy string `json:"reclaimPolicy,omitempty"`
+
+	// State is the state of the removed volume
+	// Valid values: Detaching, Retained, Orphaned
+	// +kubebuilder:validation:Enum=Detaching;Retained;Orphaned
+	// +optional
+	State string `json:"state,omitempty"`
+
+	// RemovedTime is the time the disk removal was observed
+	// +optional
+	RemovedTime *metav1.Time `json:"removedTime,omitempty"`
+
+	// Message describes the state of the removed volume
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // NetworkStatus represents the status of a network interface
@@ -277,12 +957,41 @@ type NetworkStatus struct {
 	IPAddress string `json:"ipAddress,omitempty"`
 
 	// MACAddress is the MAC address of this interface
+	// It is the configured MAC address, or a stable address allocated by the operator for Multus networks
 	// +optional
 	MACAddress string `json:"macAddress,omitempty"`
 
 	// NADName is the name of the NetworkAttachmentDefinition used
 	// +optional
 	NADName string `json:"nadName,omitempty"`
+
+	// HostNetworkPolicy is the NodeNetworkConfigurationPolicy provisioning the host bridge of this network
+	// +optional
+	HostNetworkPolicy string `json:"hostNetworkPolicy,omitempty"`
+
+	// HostNetworkReady indicates whether the host bridge is provisioned on the selected nodes
+	// Always true for networks without HostNetworkPolicy
+	// +optional
+	HostNetworkReady bool `json:"hostNetworkReady,omitempty"`
+
+	// Degraded indicates that the network is not attached to the virtual machine
+	// (e.g., Multus is not installed and networkPolicy is bestEffort)
+	// +optional
+	Degraded bool `json:"degraded,omitempty"`
+
+	// LastConfigUpdateTime is the time the operator last updated the CNI config of the
+	// NetworkAttachmentDefinition after it drifted from the Wukong or WukongNetwork spec
+	// +optional
+	LastConfigUpdateTime *metav1.Time `json:"lastConfigUpdateTime,omitempty"`
+
+	// RestartRequired indicates that the running virtual machine was started before the last
+	// config update and has to be restarted to use the new CNI config
+	// +optional
+	RestartRequired bool `json:"restartRequired,omitempty"`
+
+	// Message describes why the network is not ready
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // VolumeStatus represents the status of a storage volume
@@ -300,8 +1009,109 @@ type VolumeStatus struct {
 	Bound bool `json:"bound,omitempty"`
 
 	// Size is the actual size of the volume
+	// It is the PVC capacity once bound, and the requested size before that
 	// +optional
 	Size string `json:"size,omitempty"`
+
+	// RequestedSize is the storage size requested in the PVC spec
+	// +optional
+	RequestedSize string `json:"requestedSize,omitempty"`
+
+	// Capacity is the storage capacity reported in the PVC status
+	// +optional
+	Capacity string `json:"capacity,omitempty"`
+
+	// ExpansionState is the state of the latest volume expansion
+	// Valid values: Resizing, FileSystemResizePending, GuestResizePending, Completed, Failed
+	// +kubebuilder:validation:Enum=Resizing;FileSystemResizePending;GuestResizePending;Completed;Failed
+	// +optional
+	ExpansionState string `json:"expansionState,omitempty"`
+
+	// ImportPhase is the phase of the DataVolume populating this volume
+	// (e.g., ImportScheduled, ImportInProgress, Succeeded, Failed)
+	// +optional
+	ImportPhase string `json:"importPhase,omitempty"`
+
+	// ImportProgress is the import progress reported by CDI (e.g., "45.20%")
+	// +optional
+	ImportProgress string `json:"importProgress,omitempty"`
+
+	// RestartCount is the number of times the CDI importer pod has restarted
+	// +optional
+	RestartCount int32 `json:"restartCount,omitempty"`
+
+	// Message is the message of the latest DataVolume condition
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// ImportRetries is the number of times the operator has recreated a failed import
+	// +optional
+	ImportRetries int32 `json:"importRetries,omitempty"`
+
+	// LastImportFailureTime is the time the current import failure was first observed
+	// +optional
+	LastImportFailureTime *metav1.Time `json:"lastImportFailureTime,omitempty"`
+
+	// UploadEndpoint is the CDI upload proxy URL for disks with an upload source
+	// An upload token for the PVC must be requested through an UploadTokenRequest
+	// +optional
+	UploadEndpoint string `json:"uploadEndpoint,omitempty"`
+
+	// PVName is the name of the PersistentVolume bound to the PVC
+	// +optional
+	PVName string `json:"pvName,omitempty"`
+
+	// StorageClassName is the StorageClass of the PVC
+	// +optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+
+	// AccessModes are the access modes of the PVC
+	// +optional
+	AccessModes []string `json:"accessModes,omitempty"`
+
+	// VolumeMode is the volume mode of the PVC (Filesystem or Block)
+	// +optional
+	VolumeMode string `json:"volumeMode,omitempty"`
+
+	// FileSystemUsedBytes is the space used by guest file systems on the disk
+	// Only reported while the VM is running with the QEMU guest agent; file systems spanning several disks are not counted
+	// +optional
+	FileSystemUsedBytes *int64 `json:"fileSystemUsedBytes,omitempty"`
+
+	// FileSystemFreeBytes is the space available in guest file systems on the disk
+	// Only reported while the VM is running with the QEMU guest agent
+	// +optional
+	FileSystemFreeBytes *int64 `json:"fileSystemFreeBytes,omitempty"`
+
+	// ExistingClaim indicates the PVC was not created by the Wukong and is never deleted or resized by it
+	// +optional
+	ExistingClaim bool `json:"existingClaim,omitempty"`
+
+	// ReclaimPolicy is the reclaim policy of the disk (Delete or Retain)
+	// It is kept in the status so that the policy still applies after the disk is removed from the spec
+	// +optional
+	ReclaimPolicy string `json:"reclaimPolicy,omitempty"`
+
+	// SourcePVC is the golden PVC (namespace/name) the volume was cloned from
+	// Golden PVCs referenced here are not garbage-collected by image refresh
+	// +optional
+	SourcePVC string `json:"sourcePVC,omitempty"`
+
+	// EncryptionMode is the encryption mode of the disk (StorageClass or Guest), empty if not encrypted
+	// +optional
+	EncryptionMode string `json:"encryptionMode,omitempty"`
+
+	// EncryptionKeySecret is the Secret holding the passphrase of the encrypted disk
+	// +optional
+	EncryptionKeySecret string `json:"encryptionKeySecret,omitempty"`
+
+	// QoS is the IO limits applied to the disk, from the disk or the namespace WukongDefaults
+	// +optional
+	QoS *DiskQoSSpec `json:"qos,omitempty"`
+
+	// VolumeAttributesClassName is the VolumeAttributesClass assigned to the PVC for Storage QoS
+	// +optional
+	VolumeAttributesClassName string `json:"volumeAttributesClassName,omitempty"`
 }
 
 // +kubebuilder:object:root=true
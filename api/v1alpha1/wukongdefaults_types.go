@@ -0,0 +1,69 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WukongDefaultsSpec defines the defaults applied to Wukongs in a namespace
+type WukongDefaultsSpec struct {
+	// StorageClassName is the StorageClass used by PVC-backed disks that do not specify one
+	// It takes precedence over the namespace annotation and the cluster default StorageClass
+	// +optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+
+	// EncryptedStorageClassName is the StorageClass used by disks with StorageClass encryption that do not specify one
+	// +optional
+	EncryptedStorageClassName string `json:"encryptedStorageClassName,omitempty"`
+
+	// DiskQoS is the IO limits applied to PVC-backed disks that do not specify QoS
+	// +optional
+	DiskQoS *DiskQoSSpec `json:"diskQoS,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=wukongdefaults
+// +kubebuilder:printcolumn:name="Storage Class",type=string,JSONPath=`.spec.storageClassName`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// WukongDefaults is the Schema for the wukongdefaults API
+// It defines per-namespace defaults for Wukongs; a namespace should contain at most one
+type WukongDefaults struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	// spec defines the defaults applied to Wukongs in the namespace
+	// +required
+	Spec WukongDefaultsSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// WukongDefaultsList contains a list of WukongDefaults
+type WukongDefaultsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitzero"`
+	Items           []WukongDefaults `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WukongDefaults{}, &WukongDefaultsList{})
+}
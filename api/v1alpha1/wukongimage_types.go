@@ -0,0 +1,200 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Phase constants for WukongImage
+const (
+	ImagePhasePending   = "Pending"
+	ImagePhaseImporting = "Importing"
+	ImagePhaseReady     = "Ready"
+	ImagePhaseError     = "Error"
+)
+
+// WukongImageSpec defines the desired state of WukongImage
+// +kubebuilder:validation:XValidation:rule="!has(self.refresh) || has(self.source.registry)",message="refresh is only supported for registry sources"
+type WukongImageSpec struct {
+	// DisplayName is a human readable name of the image (e.g., "Ubuntu 24.04 LTS")
+	// +optional
+	DisplayName string `json:"displayName,omitempty"`
+
+	// Source defines where the image is imported from
+	// +required
+	Source DiskSourceSpec `json:"source"`
+
+	// DefaultSize is the disk size used when a disk referencing this image does not specify a size
+	// It is also the size of the golden PVCs
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?(Ki|Mi|Gi|Ti|Pi|Ei|K|M|G|T|P|E)?$`
+	// +required
+	DefaultSize string `json:"defaultSize"`
+
+	// Firmware defines the firmware required to boot the image
+	// +optional
+	Firmware *FirmwareSpec `json:"firmware,omitempty"`
+
+	// DefaultUser is the default Cloud-Init user of the image (e.g., "ubuntu", "fedora")
+	// SSH keys are injected for this user when no CloudInitUser is specified
+	// +optional
+	DefaultUser string `json:"defaultUser,omitempty"`
+
+	// StorageClasses is the list of StorageClasses the image is pre-imported into
+	// Disks using one of these StorageClasses are cloned from the golden PVC instead of downloading the image,
+	// unless the WukongStorageProfile of the StorageClass reports that it cannot clone efficiently
+	// (clone strategy copy); such disks are imported from the image source
+	// +optional
+	StorageClasses []string `json:"storageClasses,omitempty"`
+
+	// Refresh periodically re-imports the image from its registry tag using a CDI DataImportCron
+	// New builds become new golden PVCs; disks created afterwards are cloned from the latest one
+	// Only supported for registry sources
+	// +optional
+	Refresh *ImageRefreshSpec `json:"refresh,omitempty"`
+}
+
+// ImageRefreshSpec defines how a catalog image tracks new builds of its registry tag
+type ImageRefreshSpec struct {
+	// Schedule is the cron schedule used to poll the registry for new builds (e.g., "0 */12 * * *")
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Schedule string `json:"schedule"`
+
+	// ImportsToKeep is the number of most recent golden PVCs kept per StorageClass
+	// Older golden PVCs are deleted unless they are still referenced by a Wukong
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=3
+	// +optional
+	ImportsToKeep *int32 `json:"importsToKeep,omitempty"`
+}
+
+// FirmwareSpec defines the firmware of a virtual machine
+type FirmwareSpec struct {
+	// Bootloader is the bootloader type: BIOS or EFI
+	// Default: BIOS
+	// +kubebuilder:validation:Enum=BIOS;EFI
+	// +optional
+	Bootloader string `json:"bootloader,omitempty"`
+
+	// SecureBoot enables EFI secure boot (only valid with EFI bootloader)
+	// +optional
+	SecureBoot bool `json:"secureBoot,omitempty"`
+}
+
+// WukongImageStatus defines the observed state of WukongImage
+type WukongImageStatus struct {
+	// Phase represents the current phase of the image
+	// Valid values: Pending, Importing, Ready, Error
+	// +kubebuilder:validation:Enum=Pending;Importing;Ready;Error
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// GoldenImages represents the status of the golden PVC per StorageClass
+	// +optional
+	GoldenImages []GoldenImageStatus `json:"goldenImages,omitempty"`
+
+	// Conditions represent the current state of the WukongImage resource
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// GoldenImageStatus represents the status of a golden image in a StorageClass
+type GoldenImageStatus struct {
+	// StorageClassName is the StorageClass of the golden PVC
+	// +required
+	StorageClassName string `json:"storageClassName"`
+
+	// Namespace is the namespace of the golden PVC and DataSource
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// PVCName is the name of the golden PVC
+	// When spec.refresh is specified, this is the most recently imported PVC
+	// +optional
+	PVCName string `json:"pvcName,omitempty"`
+
+	// DataSourceName is the name of the DataSource pointing to the golden PVC
+	// +optional
+	DataSourceName string `json:"dataSourceName,omitempty"`
+
+	// DataImportCronName is the name of the DataImportCron refreshing the golden PVC
+	// Only set when spec.refresh is specified
+	// +optional
+	DataImportCronName string `json:"dataImportCronName,omitempty"`
+
+	// LastImportTime is the time the latest build of the image was imported
+	// Only set when spec.refresh is specified
+	// +optional
+	LastImportTime *metav1.Time `json:"lastImportTime,omitempty"`
+
+	// ImportPhase is the phase of the DataVolume importing the golden PVC
+	// +optional
+	ImportPhase string `json:"importPhase,omitempty"`
+
+	// ImportProgress is the import progress reported by CDI
+	// +optional
+	ImportProgress string `json:"importProgress,omitempty"`
+
+	// Ready indicates whether the golden PVC can be cloned
+	// +optional
+	Ready bool `json:"ready,omitempty"`
+
+	// Message is the message of the latest DataVolume condition
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Display Name",type=string,JSONPath=`.spec.displayName`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// WukongImage is the Schema for the wukongimages API
+// It defines a named operating system image in the golden image catalog
+type WukongImage struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	// spec defines the desired state of WukongImage
+	// +required
+	Spec WukongImageSpec `json:"spec"`
+
+	// status defines the observed state of WukongImage
+	// +optional
+	Status WukongImageStatus `json:"status,omitzero"`
+}
+
+// +kubebuilder:object:root=true
+
+// WukongImageList contains a list of WukongImage
+type WukongImageList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitzero"`
+	Items           []WukongImage `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WukongImage{}, &WukongImageList{})
+}
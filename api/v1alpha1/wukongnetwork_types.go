@@ -0,0 +1,160 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IPAM type constants for WukongNetwork
+const (
+	NetworkIPAMDHCP        = "dhcp"
+	NetworkIPAMWhereabouts = "whereabouts"
+)
+
+// WukongNetworkSpec defines the desired state of WukongNetwork
+// +kubebuilder:validation:XValidation:rule="self.type != 'macvlan' || has(self.hostInterface)",message="hostInterface is required for macvlan networks"
+// +kubebuilder:validation:XValidation:rule="self.type != 'sriov' || has(self.sriov)",message="sriov is required for sriov networks"
+// +kubebuilder:validation:XValidation:rule="!has(self.trunk) || self.type == 'ovs'",message="trunk only applies to ovs networks"
+type WukongNetworkSpec struct {
+	// Type is the network type: bridge, macvlan, sriov, or ovs
+	// +kubebuilder:validation:Enum=bridge;macvlan;sriov;ovs
+	// +required
+	Type string `json:"type"`
+
+	// VLANID is the VLAN ID (1-4094)
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=4094
+	// +optional
+	VLANID *int `json:"vlanId,omitempty"`
+
+	// BridgeName is the bridge name (for bridge and ovs types)
+	// Defaults to "br-<name>"
+	// +optional
+	BridgeName string `json:"bridgeName,omitempty"`
+
+	// HostInterface is the host NIC or bond attached to the bridge, or the macvlan master
+	// For bridge and ovs networks, the operator provisions the bridge with NMState, see NetworkConfig.HostInterface
+	// +kubebuilder:validation:MaxLength=15
+	// +optional
+	HostInterface string `json:"hostInterface,omitempty"`
+
+	// MacvlanMode is the macvlan mode (for macvlan type), defaults to bridge
+	// +kubebuilder:validation:Enum=bridge;private;vepa;passthru
+	// +optional
+	MacvlanMode string `json:"macvlanMode,omitempty"`
+
+	// SRIOV defines the SR-IOV virtual function settings (required for sriov type)
+	// +optional
+	SRIOV *SRIOVConfig `json:"sriov,omitempty"`
+
+	// Trunk is the list of VLANs allowed on the port (for ovs type)
+	// +optional
+	Trunk []VLANRange `json:"trunk,omitempty"`
+
+	// MTU is the MTU of the network interfaces
+	// +kubebuilder:validation:Minimum=576
+	// +kubebuilder:validation:Maximum=9216
+	// +optional
+	MTU *int `json:"mtu,omitempty"`
+
+	// IPAM defines how the CNI plugin assigns addresses to the pod side of the attachment
+	// If empty, no CNI IPAM is configured and the guest acquires its address itself
+	// (DHCP on the network or the IPConfig of the Wukong network)
+	// +optional
+	IPAM *NetworkIPAMSpec `json:"ipam,omitempty"`
+}
+
+// NetworkIPAMSpec defines the CNI IPAM of a shared network
+// +kubebuilder:validation:XValidation:rule="self.type != 'whereabouts' || has(self.range)",message="range is required for whereabouts IPAM"
+type NetworkIPAMSpec struct {
+	// Type is the IPAM plugin: dhcp or whereabouts
+	// whereabouts allocates addresses cluster-wide, so it can be shared by all namespaces
+	// +kubebuilder:validation:Enum=dhcp;whereabouts
+	// +required
+	Type string `json:"type"`
+
+	// Range is the CIDR addresses are allocated from (whereabouts only)
+	// Format: "192.168.100.0/24"
+	// +optional
+	Range string `json:"range,omitempty"`
+
+	// Gateway is the default gateway (whereabouts only)
+	// +optional
+	Gateway string `json:"gateway,omitempty"`
+
+	// Exclude is a list of CIDRs never allocated (whereabouts only)
+	// +optional
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// WukongNetworkStatus defines the observed state of WukongNetwork
+type WukongNetworkStatus struct {
+	// References is the number of Wukongs attached to the network
+	// +optional
+	References int32 `json:"references,omitempty"`
+
+	// Namespaces lists the namespaces where a shared NetworkAttachmentDefinition exists for the network
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Conditions represent the current state of the WukongNetwork resource
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+// +kubebuilder:printcolumn:name="VLAN",type=integer,JSONPath=`.spec.vlanId`
+// +kubebuilder:printcolumn:name="References",type=integer,JSONPath=`.status.references`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// WukongNetwork is the Schema for the wukongnetworks API
+// It defines a network once; Wukong networks reference it with networkRef and share a single
+// NetworkAttachmentDefinition per namespace
+type WukongNetwork struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	// spec defines the desired state of WukongNetwork
+	// +required
+	Spec WukongNetworkSpec `json:"spec"`
+
+	// status defines the observed state of WukongNetwork
+	// +optional
+	Status WukongNetworkStatus `json:"status,omitzero"`
+}
+
+// +kubebuilder:object:root=true
+
+// WukongNetworkList contains a list of WukongNetwork
+type WukongNetworkList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitzero"`
+	Items           []WukongNetwork `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WukongNetwork{}, &WukongNetworkList{})
+}
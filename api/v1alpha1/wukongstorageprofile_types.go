@@ -0,0 +1,155 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Storage backend constants for WukongStorageProfile
+const (
+	StorageBackendCephRBD   = "ceph-rbd"
+	StorageBackendCephFS    = "cephfs"
+	StorageBackendLonghorn  = "longhorn"
+	StorageBackendLocalPath = "local-path"
+	StorageBackendNFS       = "nfs"
+	StorageBackendGeneric   = "generic"
+)
+
+// WukongStorageProfileSpec defines the desired state of WukongStorageProfile
+type WukongStorageProfileSpec struct {
+	// StorageClassName is the StorageClass described by this profile
+	// +required
+	StorageClassName string `json:"storageClassName"`
+
+	// DefaultAccessModes overrides the access modes picked for disks with volumeMode Auto
+	// +kubebuilder:validation:items:Enum=ReadWriteOnce;ReadOnlyMany;ReadWriteMany;ReadWriteOncePod
+	// +optional
+	DefaultAccessModes []string `json:"defaultAccessModes,omitempty"`
+
+	// DefaultVolumeMode overrides the volume mode picked for disks with volumeMode Auto
+	// +kubebuilder:validation:Enum=Filesystem;Block
+	// +optional
+	DefaultVolumeMode string `json:"defaultVolumeMode,omitempty"`
+}
+
+// StorageCapabilities describes what a StorageClass supports
+type StorageCapabilities struct {
+	// Expansion indicates whether PVCs of the StorageClass can be expanded
+	// Disk expansion is refused when false
+	// +optional
+	Expansion bool `json:"expansion,omitempty"`
+
+	// Snapshot indicates whether a VolumeSnapshotClass exists for the CSI driver
+	// The snapshot clone strategy is used when no other strategy is known
+	// +optional
+	Snapshot bool `json:"snapshot,omitempty"`
+
+	// Clone indicates whether PVCs can be cloned efficiently (CSI clone or snapshot)
+	// Disks are only cloned from golden images when true
+	// +optional
+	Clone bool `json:"clone,omitempty"`
+
+	// CloneStrategy is the clone strategy used by CDI (copy, snapshot or csi-clone)
+	// +optional
+	CloneStrategy string `json:"cloneStrategy,omitempty"`
+
+	// RWXBlock indicates whether ReadWriteMany Block PVCs are supported (live migration without file system)
+	// +optional
+	RWXBlock bool `json:"rwxBlock,omitempty"`
+
+	// RWXFilesystem indicates whether ReadWriteMany Filesystem PVCs are supported
+	// +optional
+	RWXFilesystem bool `json:"rwxFilesystem,omitempty"`
+}
+
+// WukongStorageProfileStatus defines the observed state of WukongStorageProfile
+type WukongStorageProfileStatus struct {
+	// Provisioner is the provisioner of the StorageClass
+	// +optional
+	Provisioner string `json:"provisioner,omitempty"`
+
+	// Backend is the storage backend detected from the provisioner
+	// Valid values: ceph-rbd, cephfs, longhorn, local-path, nfs, generic
+	// +optional
+	Backend string `json:"backend,omitempty"`
+
+	// CSI indicates whether the provisioner is a CSI driver
+	// +optional
+	CSI bool `json:"csi,omitempty"`
+
+	// VolumeBindingMode is the volume binding mode of the StorageClass
+	// +optional
+	VolumeBindingMode string `json:"volumeBindingMode,omitempty"`
+
+	// Capabilities describes what the StorageClass supports
+	// +optional
+	Capabilities StorageCapabilities `json:"capabilities,omitempty"`
+
+	// DefaultAccessModes are the access modes used for disks with volumeMode Auto
+	// +optional
+	DefaultAccessModes []string `json:"defaultAccessModes,omitempty"`
+
+	// DefaultVolumeMode is the volume mode used for disks with volumeMode Auto
+	// +optional
+	DefaultVolumeMode string `json:"defaultVolumeMode,omitempty"`
+
+	// Conditions represent the current state of the WukongStorageProfile resource
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Backend",type=string,JSONPath=`.status.backend`
+// +kubebuilder:printcolumn:name="Expansion",type=boolean,JSONPath=`.status.capabilities.expansion`
+// +kubebuilder:printcolumn:name="RWX Block",type=boolean,JSONPath=`.status.capabilities.rwxBlock`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// WukongStorageProfile is the Schema for the wukongstorageprofiles API
+// It describes the capabilities of a StorageClass and is maintained by the operator
+type WukongStorageProfile struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	// spec defines the desired state of WukongStorageProfile
+	// +required
+	Spec WukongStorageProfileSpec `json:"spec"`
+
+	// status defines the observed state of WukongStorageProfile
+	// +optional
+	Status WukongStorageProfileStatus `json:"status,omitzero"`
+}
+
+// +kubebuilder:object:root=true
+
+// WukongStorageProfileList contains a list of WukongStorageProfile
+type WukongStorageProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitzero"`
+	Items           []WukongStorageProfile `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WukongStorageProfile{}, &WukongStorageProfileList{})
+}
@@ -21,14 +21,114 @@ limitations under the License.
 package v1alpha1
 
 import (
-	"k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlankDiskSource) DeepCopyInto(out *BlankDiskSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlankDiskSource.
+func (in *BlankDiskSource) DeepCopy() *BlankDiskSource {
+	if in == nil {
+		return nil
+	}
+	out := new(BlankDiskSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloudInitUserSpec) DeepCopyInto(out *CloudInitUserSpec) {
+	*out = *in
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudInitUserSpec.
+func (in *CloudInitUserSpec) DeepCopy() *CloudInitUserSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CloudInitUserSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerDiskSource) DeepCopyInto(out *ContainerDiskSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerDiskSource.
+func (in *ContainerDiskSource) DeepCopy() *ContainerDiskSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerDiskSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataSourceReference) DeepCopyInto(out *DataSourceReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataSourceReference.
+func (in *DataSourceReference) DeepCopy() *DataSourceReference {
+	if in == nil {
+		return nil
+	}
+	out := new(DataSourceReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DiskConfig) DeepCopyInto(out *DiskConfig) {
 	*out = *in
+	if in.Source != nil {
+		in, out := &in.Source, &out.Source
+		*out = new(DiskSourceSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImportRetry != nil {
+		in, out := &in.ImportRetry, &out.ImportRetry
+		*out = new(ImportRetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AccessModes != nil {
+		in, out := &in.AccessModes, &out.AccessModes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ContainerDisk != nil {
+		in, out := &in.ContainerDisk, &out.ContainerDisk
+		*out = new(ContainerDiskSource)
+		**out = **in
+	}
+	if in.EmptyDisk != nil {
+		in, out := &in.EmptyDisk, &out.EmptyDisk
+		*out = new(EmptyDiskSource)
+		**out = **in
+	}
+	if in.Encryption != nil {
+		in, out := &in.Encryption, &out.Encryption
+		*out = new(DiskEncryptionSpec)
+		**out = **in
+	}
+	if in.QoS != nil {
+		in, out := &in.QoS, &out.QoS
+		*out = new(DiskQoSSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskConfig.
@@ -41,6 +141,225 @@ func (in *DiskConfig) DeepCopy() *DiskConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskEncryptionSpec) DeepCopyInto(out *DiskEncryptionSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskEncryptionSpec.
+func (in *DiskEncryptionSpec) DeepCopy() *DiskEncryptionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskEncryptionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskQoSBurst) DeepCopyInto(out *DiskQoSBurst) {
+	*out = *in
+	if in.ReadIOPS != nil {
+		in, out := &in.ReadIOPS, &out.ReadIOPS
+		*out = new(int64)
+		**out = **in
+	}
+	if in.WriteIOPS != nil {
+		in, out := &in.WriteIOPS, &out.WriteIOPS
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ReadBytesPerSecond != nil {
+		in, out := &in.ReadBytesPerSecond, &out.ReadBytesPerSecond
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.WriteBytesPerSecond != nil {
+		in, out := &in.WriteBytesPerSecond, &out.WriteBytesPerSecond
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.DurationSeconds != nil {
+		in, out := &in.DurationSeconds, &out.DurationSeconds
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskQoSBurst.
+func (in *DiskQoSBurst) DeepCopy() *DiskQoSBurst {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskQoSBurst)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskQoSSpec) DeepCopyInto(out *DiskQoSSpec) {
+	*out = *in
+	if in.ReadIOPS != nil {
+		in, out := &in.ReadIOPS, &out.ReadIOPS
+		*out = new(int64)
+		**out = **in
+	}
+	if in.WriteIOPS != nil {
+		in, out := &in.WriteIOPS, &out.WriteIOPS
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ReadBytesPerSecond != nil {
+		in, out := &in.ReadBytesPerSecond, &out.ReadBytesPerSecond
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.WriteBytesPerSecond != nil {
+		in, out := &in.WriteBytesPerSecond, &out.WriteBytesPerSecond
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Burst != nil {
+		in, out := &in.Burst, &out.Burst
+		*out = new(DiskQoSBurst)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskQoSSpec.
+func (in *DiskQoSSpec) DeepCopy() *DiskQoSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskQoSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskSourceSpec) DeepCopyInto(out *DiskSourceSpec) {
+	*out = *in
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(HTTPDiskSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Registry != nil {
+		in, out := &in.Registry, &out.Registry
+		*out = new(RegistryDiskSource)
+		**out = **in
+	}
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3DiskSource)
+		**out = **in
+	}
+	if in.Upload != nil {
+		in, out := &in.Upload, &out.Upload
+		*out = new(UploadDiskSource)
+		**out = **in
+	}
+	if in.Blank != nil {
+		in, out := &in.Blank, &out.Blank
+		*out = new(BlankDiskSource)
+		**out = **in
+	}
+	if in.PVC != nil {
+		in, out := &in.PVC, &out.PVC
+		*out = new(PVCDiskSource)
+		**out = **in
+	}
+	if in.DataSource != nil {
+		in, out := &in.DataSource, &out.DataSource
+		*out = new(DataSourceReference)
+		**out = **in
+	}
+	if in.DataImportCron != nil {
+		in, out := &in.DataImportCron, &out.DataImportCron
+		*out = new(DataSourceReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskSourceSpec.
+func (in *DiskSourceSpec) DeepCopy() *DiskSourceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskSourceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EmptyDiskSource) DeepCopyInto(out *EmptyDiskSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EmptyDiskSource.
+func (in *EmptyDiskSource) DeepCopy() *EmptyDiskSource {
+	if in == nil {
+		return nil
+	}
+	out := new(EmptyDiskSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FirmwareSpec) DeepCopyInto(out *FirmwareSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FirmwareSpec.
+func (in *FirmwareSpec) DeepCopy() *FirmwareSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FirmwareSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GoldenImageStatus) DeepCopyInto(out *GoldenImageStatus) {
+	*out = *in
+	if in.LastImportTime != nil {
+		in, out := &in.LastImportTime, &out.LastImportTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GoldenImageStatus.
+func (in *GoldenImageStatus) DeepCopy() *GoldenImageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GoldenImageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPDiskSource) DeepCopyInto(out *HTTPDiskSource) {
+	*out = *in
+	if in.ExtraHeaders != nil {
+		in, out := &in.ExtraHeaders, &out.ExtraHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPDiskSource.
+func (in *HTTPDiskSource) DeepCopy() *HTTPDiskSource {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPDiskSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HighAvailabilitySpec) DeepCopyInto(out *HighAvailabilitySpec) {
 	*out = *in
@@ -53,125 +372,926 @@ func (in *HighAvailabilitySpec) DeepCopyInto(out *HighAvailabilitySpec) {
 	}
 	if in.Tolerations != nil {
 		in, out := &in.Tolerations, &out.Tolerations
-		*out = make([]v1.Toleration, len(*in))
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HighAvailabilitySpec.
+func (in *HighAvailabilitySpec) DeepCopy() *HighAvailabilitySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HighAvailabilitySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPConfigSpec) DeepCopyInto(out *IPConfigSpec) {
+	*out = *in
+	if in.Address != nil {
+		in, out := &in.Address, &out.Address
+		*out = new(string)
+		**out = **in
+	}
+	if in.Gateway != nil {
+		in, out := &in.Gateway, &out.Gateway
+		*out = new(string)
+		**out = **in
+	}
+	if in.DNSServers != nil {
+		in, out := &in.DNSServers, &out.DNSServers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SearchDomains != nil {
+		in, out := &in.SearchDomains, &out.SearchDomains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IPv6 != nil {
+		in, out := &in.IPv6, &out.IPv6
+		*out = new(IPv6ConfigSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]RouteSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPConfigSpec.
+func (in *IPConfigSpec) DeepCopy() *IPConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IPConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPLease) DeepCopyInto(out *IPLease) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPLease.
+func (in *IPLease) DeepCopy() *IPLease {
+	if in == nil {
+		return nil
+	}
+	out := new(IPLease)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IPLease) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPLeaseList) DeepCopyInto(out *IPLeaseList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]IPLease, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPLeaseList.
+func (in *IPLeaseList) DeepCopy() *IPLeaseList {
+	if in == nil {
+		return nil
+	}
+	out := new(IPLeaseList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IPLeaseList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPLeaseSpec) DeepCopyInto(out *IPLeaseSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPLeaseSpec.
+func (in *IPLeaseSpec) DeepCopy() *IPLeaseSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IPLeaseSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPPool) DeepCopyInto(out *IPPool) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPPool.
+func (in *IPPool) DeepCopy() *IPPool {
+	if in == nil {
+		return nil
+	}
+	out := new(IPPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IPPool) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPPoolList) DeepCopyInto(out *IPPoolList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]IPPool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPPoolList.
+func (in *IPPoolList) DeepCopy() *IPPoolList {
+	if in == nil {
+		return nil
+	}
+	out := new(IPPoolList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IPPoolList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPPoolSpec) DeepCopyInto(out *IPPoolSpec) {
+	*out = *in
+	if in.DNSServers != nil {
+		in, out := &in.DNSServers, &out.DNSServers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Reservations != nil {
+		in, out := &in.Reservations, &out.Reservations
+		*out = make([]IPReservation, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPPoolSpec.
+func (in *IPPoolSpec) DeepCopy() *IPPoolSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IPPoolSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPPoolStatus) DeepCopyInto(out *IPPoolStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPPoolStatus.
+func (in *IPPoolStatus) DeepCopy() *IPPoolStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IPPoolStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPReservation) DeepCopyInto(out *IPReservation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPReservation.
+func (in *IPReservation) DeepCopy() *IPReservation {
+	if in == nil {
+		return nil
+	}
+	out := new(IPReservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPv6ConfigSpec) DeepCopyInto(out *IPv6ConfigSpec) {
+	*out = *in
+	if in.Address != nil {
+		in, out := &in.Address, &out.Address
+		*out = new(string)
+		**out = **in
+	}
+	if in.Gateway != nil {
+		in, out := &in.Gateway, &out.Gateway
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPv6ConfigSpec.
+func (in *IPv6ConfigSpec) DeepCopy() *IPv6ConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IPv6ConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageRefreshSpec) DeepCopyInto(out *ImageRefreshSpec) {
+	*out = *in
+	if in.ImportsToKeep != nil {
+		in, out := &in.ImportsToKeep, &out.ImportsToKeep
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageRefreshSpec.
+func (in *ImageRefreshSpec) DeepCopy() *ImageRefreshSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageRefreshSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImportRetryPolicy) DeepCopyInto(out *ImportRetryPolicy) {
+	*out = *in
+	if in.MaxRetries != nil {
+		in, out := &in.MaxRetries, &out.MaxRetries
+		*out = new(int32)
+		**out = **in
+	}
+	if in.BackoffSeconds != nil {
+		in, out := &in.BackoffSeconds, &out.BackoffSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImportRetryPolicy.
+func (in *ImportRetryPolicy) DeepCopy() *ImportRetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ImportRetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkConfig) DeepCopyInto(out *NetworkConfig) {
+	*out = *in
+	if in.VLANID != nil {
+		in, out := &in.VLANID, &out.VLANID
+		*out = new(int)
+		**out = **in
+	}
+	if in.SRIOV != nil {
+		in, out := &in.SRIOV, &out.SRIOV
+		*out = new(SRIOVConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Trunk != nil {
+		in, out := &in.Trunk, &out.Trunk
+		*out = make([]VLANRange, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MTU != nil {
+		in, out := &in.MTU, &out.MTU
+		*out = new(int)
+		**out = **in
+	}
+	if in.BootOrder != nil {
+		in, out := &in.BootOrder, &out.BootOrder
+		*out = new(uint)
+		**out = **in
+	}
+	if in.IPConfig != nil {
+		in, out := &in.IPConfig, &out.IPConfig
+		*out = new(IPConfigSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkConfig.
+func (in *NetworkConfig) DeepCopy() *NetworkConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkIPAMSpec) DeepCopyInto(out *NetworkIPAMSpec) {
+	*out = *in
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkIPAMSpec.
+func (in *NetworkIPAMSpec) DeepCopy() *NetworkIPAMSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkIPAMSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkStatus) DeepCopyInto(out *NetworkStatus) {
+	*out = *in
+	if in.LastConfigUpdateTime != nil {
+		in, out := &in.LastConfigUpdateTime, &out.LastConfigUpdateTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkStatus.
+func (in *NetworkStatus) DeepCopy() *NetworkStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PVCDiskSource) DeepCopyInto(out *PVCDiskSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PVCDiskSource.
+func (in *PVCDiskSource) DeepCopy() *PVCDiskSource {
+	if in == nil {
+		return nil
+	}
+	out := new(PVCDiskSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodNetworkSpec) DeepCopyInto(out *PodNetworkSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodNetworkSpec.
+func (in *PodNetworkSpec) DeepCopy() *PodNetworkSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PodNetworkSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryDiskSource) DeepCopyInto(out *RegistryDiskSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryDiskSource.
+func (in *RegistryDiskSource) DeepCopy() *RegistryDiskSource {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryDiskSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemovedVolumeStatus) DeepCopyInto(out *RemovedVolumeStatus) {
+	*out = *in
+	if in.RemovedTime != nil {
+		in, out := &in.RemovedTime, &out.RemovedTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemovedVolumeStatus.
+func (in *RemovedVolumeStatus) DeepCopy() *RemovedVolumeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RemovedVolumeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouteSpec) DeepCopyInto(out *RouteSpec) {
+	*out = *in
+	if in.Metric != nil {
+		in, out := &in.Metric, &out.Metric
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteSpec.
+func (in *RouteSpec) DeepCopy() *RouteSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RouteSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3DiskSource) DeepCopyInto(out *S3DiskSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3DiskSource.
+func (in *S3DiskSource) DeepCopy() *S3DiskSource {
+	if in == nil {
+		return nil
+	}
+	out := new(S3DiskSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SRIOVConfig) DeepCopyInto(out *SRIOVConfig) {
+	*out = *in
+	if in.SpoofChk != nil {
+		in, out := &in.SpoofChk, &out.SpoofChk
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Trust != nil {
+		in, out := &in.Trust, &out.Trust
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SRIOVConfig.
+func (in *SRIOVConfig) DeepCopy() *SRIOVConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(SRIOVConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StartStrategySpec) DeepCopyInto(out *StartStrategySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StartStrategySpec.
+func (in *StartStrategySpec) DeepCopy() *StartStrategySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StartStrategySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageCapabilities) DeepCopyInto(out *StorageCapabilities) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageCapabilities.
+func (in *StorageCapabilities) DeepCopy() *StorageCapabilities {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageCapabilities)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UploadDiskSource) DeepCopyInto(out *UploadDiskSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UploadDiskSource.
+func (in *UploadDiskSource) DeepCopy() *UploadDiskSource {
+	if in == nil {
+		return nil
+	}
+	out := new(UploadDiskSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VLANRange) DeepCopyInto(out *VLANRange) {
+	*out = *in
+	if in.ID != nil {
+		in, out := &in.ID, &out.ID
+		*out = new(int)
+		**out = **in
+	}
+	if in.MinID != nil {
+		in, out := &in.MinID, &out.MinID
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxID != nil {
+		in, out := &in.MaxID, &out.MaxID
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VLANRange.
+func (in *VLANRange) DeepCopy() *VLANRange {
+	if in == nil {
+		return nil
+	}
+	out := new(VLANRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeStatus) DeepCopyInto(out *VolumeStatus) {
+	*out = *in
+	if in.LastImportFailureTime != nil {
+		in, out := &in.LastImportFailureTime, &out.LastImportFailureTime
+		*out = (*in).DeepCopy()
+	}
+	if in.AccessModes != nil {
+		in, out := &in.AccessModes, &out.AccessModes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FileSystemUsedBytes != nil {
+		in, out := &in.FileSystemUsedBytes, &out.FileSystemUsedBytes
+		*out = new(int64)
+		**out = **in
+	}
+	if in.FileSystemFreeBytes != nil {
+		in, out := &in.FileSystemFreeBytes, &out.FileSystemFreeBytes
+		*out = new(int64)
+		**out = **in
+	}
+	if in.QoS != nil {
+		in, out := &in.QoS, &out.QoS
+		*out = new(DiskQoSSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeStatus.
+func (in *VolumeStatus) DeepCopy() *VolumeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Wukong) DeepCopyInto(out *Wukong) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Wukong.
+func (in *Wukong) DeepCopy() *Wukong {
+	if in == nil {
+		return nil
+	}
+	out := new(Wukong)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Wukong) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WukongDefaults) DeepCopyInto(out *WukongDefaults) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WukongDefaults.
+func (in *WukongDefaults) DeepCopy() *WukongDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(WukongDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WukongDefaults) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WukongDefaultsList) DeepCopyInto(out *WukongDefaultsList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]WukongDefaults, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WukongDefaultsList.
+func (in *WukongDefaultsList) DeepCopy() *WukongDefaultsList {
+	if in == nil {
+		return nil
+	}
+	out := new(WukongDefaultsList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WukongDefaultsList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WukongDefaultsSpec) DeepCopyInto(out *WukongDefaultsSpec) {
+	*out = *in
+	if in.DiskQoS != nil {
+		in, out := &in.DiskQoS, &out.DiskQoS
+		*out = new(DiskQoSSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WukongDefaultsSpec.
+func (in *WukongDefaultsSpec) DeepCopy() *WukongDefaultsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WukongDefaultsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WukongImage) DeepCopyInto(out *WukongImage) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WukongImage.
+func (in *WukongImage) DeepCopy() *WukongImage {
+	if in == nil {
+		return nil
+	}
+	out := new(WukongImage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WukongImage) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WukongImageList) DeepCopyInto(out *WukongImageList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]WukongImage, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HighAvailabilitySpec.
-func (in *HighAvailabilitySpec) DeepCopy() *HighAvailabilitySpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WukongImageList.
+func (in *WukongImageList) DeepCopy() *WukongImageList {
 	if in == nil {
 		return nil
 	}
-	out := new(HighAvailabilitySpec)
+	out := new(WukongImageList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WukongImageList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *IPConfigSpec) DeepCopyInto(out *IPConfigSpec) {
+func (in *WukongImageSpec) DeepCopyInto(out *WukongImageSpec) {
 	*out = *in
-	if in.Address != nil {
-		in, out := &in.Address, &out.Address
-		*out = new(string)
-		**out = **in
-	}
-	if in.Gateway != nil {
-		in, out := &in.Gateway, &out.Gateway
-		*out = new(string)
+	in.Source.DeepCopyInto(&out.Source)
+	if in.Firmware != nil {
+		in, out := &in.Firmware, &out.Firmware
+		*out = new(FirmwareSpec)
 		**out = **in
 	}
-	if in.DNSServers != nil {
-		in, out := &in.DNSServers, &out.DNSServers
+	if in.StorageClasses != nil {
+		in, out := &in.StorageClasses, &out.StorageClasses
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Refresh != nil {
+		in, out := &in.Refresh, &out.Refresh
+		*out = new(ImageRefreshSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPConfigSpec.
-func (in *IPConfigSpec) DeepCopy() *IPConfigSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WukongImageSpec.
+func (in *WukongImageSpec) DeepCopy() *WukongImageSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(IPConfigSpec)
+	out := new(WukongImageSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NetworkConfig) DeepCopyInto(out *NetworkConfig) {
+func (in *WukongImageStatus) DeepCopyInto(out *WukongImageStatus) {
 	*out = *in
-	if in.VLANID != nil {
-		in, out := &in.VLANID, &out.VLANID
-		*out = new(int)
-		**out = **in
+	if in.GoldenImages != nil {
+		in, out := &in.GoldenImages, &out.GoldenImages
+		*out = make([]GoldenImageStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
-	if in.IPConfig != nil {
-		in, out := &in.IPConfig, &out.IPConfig
-		*out = new(IPConfigSpec)
-		(*in).DeepCopyInto(*out)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkConfig.
-func (in *NetworkConfig) DeepCopy() *NetworkConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WukongImageStatus.
+func (in *WukongImageStatus) DeepCopy() *WukongImageStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(NetworkConfig)
+	out := new(WukongImageStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NetworkStatus) DeepCopyInto(out *NetworkStatus) {
+func (in *WukongList) DeepCopyInto(out *WukongList) {
 	*out = *in
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkStatus.
-func (in *NetworkStatus) DeepCopy() *NetworkStatus {
-	if in == nil {
-		return nil
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Wukong, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
-	out := new(NetworkStatus)
-	in.DeepCopyInto(out)
-	return out
-}
-
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *StartStrategySpec) DeepCopyInto(out *StartStrategySpec) {
-	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StartStrategySpec.
-func (in *StartStrategySpec) DeepCopy() *StartStrategySpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WukongList.
+func (in *WukongList) DeepCopy() *WukongList {
 	if in == nil {
 		return nil
 	}
-	out := new(StartStrategySpec)
+	out := new(WukongList)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VolumeStatus) DeepCopyInto(out *VolumeStatus) {
-	*out = *in
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeStatus.
-func (in *VolumeStatus) DeepCopy() *VolumeStatus {
-	if in == nil {
-		return nil
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WukongList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
 	}
-	out := new(VolumeStatus)
-	in.DeepCopyInto(out)
-	return out
+	return nil
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Wukong) DeepCopyInto(out *Wukong) {
+func (in *WukongNetwork) DeepCopyInto(out *WukongNetwork) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
@@ -179,18 +1299,18 @@ func (in *Wukong) DeepCopyInto(out *Wukong) {
 	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Wukong.
-func (in *Wukong) DeepCopy() *Wukong {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WukongNetwork.
+func (in *WukongNetwork) DeepCopy() *WukongNetwork {
 	if in == nil {
 		return nil
 	}
-	out := new(Wukong)
+	out := new(WukongNetwork)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Wukong) DeepCopyObject() runtime.Object {
+func (in *WukongNetwork) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -198,40 +1318,119 @@ func (in *Wukong) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *WukongList) DeepCopyInto(out *WukongList) {
+func (in *WukongNetworkList) DeepCopyInto(out *WukongNetworkList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Wukong, len(*in))
+		*out = make([]WukongNetwork, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WukongList.
-func (in *WukongList) DeepCopy() *WukongList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WukongNetworkList.
+func (in *WukongNetworkList) DeepCopy() *WukongNetworkList {
 	if in == nil {
 		return nil
 	}
-	out := new(WukongList)
+	out := new(WukongNetworkList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *WukongList) DeepCopyObject() runtime.Object {
+func (in *WukongNetworkList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WukongNetworkSpec) DeepCopyInto(out *WukongNetworkSpec) {
+	*out = *in
+	if in.VLANID != nil {
+		in, out := &in.VLANID, &out.VLANID
+		*out = new(int)
+		**out = **in
+	}
+	if in.SRIOV != nil {
+		in, out := &in.SRIOV, &out.SRIOV
+		*out = new(SRIOVConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Trunk != nil {
+		in, out := &in.Trunk, &out.Trunk
+		*out = make([]VLANRange, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MTU != nil {
+		in, out := &in.MTU, &out.MTU
+		*out = new(int)
+		**out = **in
+	}
+	if in.IPAM != nil {
+		in, out := &in.IPAM, &out.IPAM
+		*out = new(NetworkIPAMSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WukongNetworkSpec.
+func (in *WukongNetworkSpec) DeepCopy() *WukongNetworkSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WukongNetworkSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WukongNetworkStatus) DeepCopyInto(out *WukongNetworkStatus) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WukongNetworkStatus.
+func (in *WukongNetworkStatus) DeepCopy() *WukongNetworkStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(WukongNetworkStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WukongSpec) DeepCopyInto(out *WukongSpec) {
 	*out = *in
+	if in.CloudInitUser != nil {
+		in, out := &in.CloudInitUser, &out.CloudInitUser
+		*out = new(CloudInitUserSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodNetwork != nil {
+		in, out := &in.PodNetwork, &out.PodNetwork
+		*out = new(PodNetworkSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Networks != nil {
 		in, out := &in.Networks, &out.Networks
 		*out = make([]NetworkConfig, len(*in))
@@ -242,7 +1441,9 @@ func (in *WukongSpec) DeepCopyInto(out *WukongSpec) {
 	if in.Disks != nil {
 		in, out := &in.Disks, &out.Disks
 		*out = make([]DiskConfig, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.HighAvailability != nil {
 		in, out := &in.HighAvailability, &out.HighAvailability
@@ -271,7 +1472,7 @@ func (in *WukongStatus) DeepCopyInto(out *WukongStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]metav1.Condition, len(*in))
+		*out = make([]v1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -279,12 +1480,23 @@ func (in *WukongStatus) DeepCopyInto(out *WukongStatus) {
 	if in.Networks != nil {
 		in, out := &in.Networks, &out.Networks
 		*out = make([]NetworkStatus, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.Volumes != nil {
 		in, out := &in.Volumes, &out.Volumes
 		*out = make([]VolumeStatus, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RemovedVolumes != nil {
+		in, out := &in.RemovedVolumes, &out.RemovedVolumes
+		*out = make([]RemovedVolumeStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
@@ -297,3 +1509,110 @@ func (in *WukongStatus) DeepCopy() *WukongStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WukongStorageProfile) DeepCopyInto(out *WukongStorageProfile) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WukongStorageProfile.
+func (in *WukongStorageProfile) DeepCopy() *WukongStorageProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(WukongStorageProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WukongStorageProfile) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WukongStorageProfileList) DeepCopyInto(out *WukongStorageProfileList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]WukongStorageProfile, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WukongStorageProfileList.
+func (in *WukongStorageProfileList) DeepCopy() *WukongStorageProfileList {
+	if in == nil {
+		return nil
+	}
+	out := new(WukongStorageProfileList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WukongStorageProfileList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WukongStorageProfileSpec) DeepCopyInto(out *WukongStorageProfileSpec) {
+	*out = *in
+	if in.DefaultAccessModes != nil {
+		in, out := &in.DefaultAccessModes, &out.DefaultAccessModes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WukongStorageProfileSpec.
+func (in *WukongStorageProfileSpec) DeepCopy() *WukongStorageProfileSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WukongStorageProfileSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WukongStorageProfileStatus) DeepCopyInto(out *WukongStorageProfileStatus) {
+	*out = *in
+	out.Capabilities = in.Capabilities
+	if in.DefaultAccessModes != nil {
+		in, out := &in.DefaultAccessModes, &out.DefaultAccessModes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WukongStorageProfileStatus.
+func (in *WukongStorageProfileStatus) DeepCopy() *WukongStorageProfileStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(WukongStorageProfileStatus)
+	in.DeepCopyInto(out)
+	return out
+}
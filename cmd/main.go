@@ -39,6 +39,9 @@ import (
 
 	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
 	"github.com/kuihuar/novasphere/internal/controller"
+	"github.com/kuihuar/novasphere/pkg/catalog"
+	"github.com/kuihuar/novasphere/pkg/kubevirt"
+	"github.com/kuihuar/novasphere/pkg/network"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -68,6 +71,9 @@ func main() {
 	var secureMetrics bool
 	var enableHTTP2 bool
 	var tlsOpts []func(*tls.Config)
+	var imageNamespace string
+	var macPrefix string
+	var sidecarShimImage string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -85,6 +91,12 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&imageNamespace, "image-namespace", catalog.DefaultImageNamespace,
+		"The namespace holding the golden PVCs and DataSources of the WukongImage catalog.")
+	flag.StringVar(&macPrefix, "mac-prefix", network.DefaultMACPrefix,
+		"The unicast prefix (1 to 5 bytes) of the MAC addresses allocated to the Multus networks of Wukongs.")
+	flag.StringVar(&sidecarShimImage, "sidecar-shim-image", kubevirt.DefaultSidecarShimImage,
+		"The KubeVirt hook sidecar image applying Hypervisor disk QoS; it should match the KubeVirt version of the cluster.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -93,6 +105,11 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if _, err := network.ParseMACPrefix(macPrefix); err != nil {
+		setupLog.Error(err, "invalid --mac-prefix")
+		os.Exit(1)
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -184,11 +201,50 @@ func main() {
 		os.Exit(1)
 	}
 
+	subresourceClient, err := kubevirt.NewSubresourceClient(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create KubeVirt subresource client")
+		os.Exit(1)
+	}
 	if err := (&controller.WukongReconciler{
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		GuestAgent:       subresourceClient,
+		VolumeHotplug:    subresourceClient,
+		MACPrefix:        macPrefix,
+		SidecarShimImage: sidecarShimImage,
+		Recorder:         mgr.GetEventRecorderFor("wukong-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Wukong")
+		os.Exit(1)
+	}
+	if err := (&controller.WukongImageReconciler{
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		ImageNamespace: imageNamespace,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "WukongImage")
+		os.Exit(1)
+	}
+	if err := (&controller.WukongStorageProfileReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Wukong")
+		setupLog.Error(err, "unable to create controller", "controller", "WukongStorageProfile")
+		os.Exit(1)
+	}
+	if err := (&controller.WukongNetworkReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "WukongNetwork")
+		os.Exit(1)
+	}
+	if err := (&controller.IPPoolReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "IPPool")
 		os.Exit(1)
 	}
 	// +kubebuilder:scaffold:builder
@@ -0,0 +1,149 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+	"github.com/kuihuar/novasphere/pkg/network"
+)
+
+const (
+	// ipPoolFinalizer 防止仍有地址租约的 IPPool 被删除
+	ipPoolFinalizer = "wukong.novasphere.dev/pool-in-use"
+)
+
+// IPPoolReconciler reconciles a IPPool object
+type IPPoolReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=vm.novasphere.dev,resources=ippools,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=vm.novasphere.dev,resources=ippools/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=vm.novasphere.dev,resources=ippools/finalizers,verbs=update
+// +kubebuilder:rbac:groups=vm.novasphere.dev,resources=ipleases,verbs=get;list;watch
+
+// Reconcile records the capacity and the number of leased addresses of an IPPool in its status.
+// An IPPool cannot be deleted while addresses are leased from it.
+func (r *IPPoolReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.V(1).Info("Reconciling IPPool", "name", req.Name)
+
+	// 1. 获取 IPPool
+	var pool vmv1alpha1.IPPool
+	if err := r.Get(ctx, req.NamespacedName, &pool); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "unable to fetch IPPool")
+		return ctrl.Result{}, err
+	}
+
+	// 2. 统计地址租约
+	var leases vmv1alpha1.IPLeaseList
+	if err := r.List(ctx, &leases, client.MatchingLabels{network.LabelPool: pool.Name}); err != nil {
+		logger.Error(err, "failed to list IPLeases")
+		return ctrl.Result{}, err
+	}
+	allocated := int32(len(leases.Items))
+
+	// 3. 处理删除：仍有租约时保留 finalizer
+	if !pool.DeletionTimestamp.IsZero() {
+		if allocated > 0 {
+			logger.Info("IPPool still has leases, delaying deletion", "name", pool.Name, "allocated", allocated)
+			pool.Status.Allocated = allocated
+			meta.SetStatusCondition(&pool.Status.Conditions, metav1.Condition{
+				Type:    "Ready",
+				Status:  metav1.ConditionFalse,
+				Reason:  "InUse",
+				Message: fmt.Sprintf("Pool is deleted but %d addresses are still leased", allocated),
+			})
+			r.Status().Update(ctx, &pool)
+			return ctrl.Result{RequeueAfter: time.Minute}, nil
+		}
+		if containsString(pool.Finalizers, ipPoolFinalizer) {
+			pool.Finalizers = removeString(pool.Finalizers, ipPoolFinalizer)
+			if err := r.Update(ctx, &pool); err != nil {
+				logger.Error(err, "unable to remove finalizer")
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// 4. 添加 finalizer
+	if !containsString(pool.Finalizers, ipPoolFinalizer) {
+		pool.Finalizers = append(pool.Finalizers, ipPoolFinalizer)
+		if err := r.Update(ctx, &pool); err != nil {
+			logger.Error(err, "unable to add finalizer")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// 5. 更新状态
+	pool.Status.Capacity = network.PoolCapacity(&pool)
+	pool.Status.Allocated = allocated
+	readyCondition := metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionTrue,
+		Reason:  "AddressesAvailable",
+		Message: fmt.Sprintf("%d of %d addresses leased", allocated, pool.Status.Capacity),
+	}
+	if network.PoolExhausted(&pool, leases.Items) {
+		readyCondition.Status = metav1.ConditionFalse
+		readyCondition.Reason = "PoolExhausted"
+	}
+	meta.SetStatusCondition(&pool.Status.Conditions, readyCondition)
+	if err := r.Status().Update(ctx, &pool); err != nil {
+		logger.Error(err, "unable to update IPPool status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// leaseToPool 将 IPLease 的变化映射到其所属的 IPPool
+func (r *IPPoolReconciler) leaseToPool(ctx context.Context, obj client.Object) []reconcile.Request {
+	pool := obj.GetLabels()[network.LabelPool]
+	if pool == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Name: pool}}}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IPPoolReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&vmv1alpha1.IPPool{}).
+		Watches(&vmv1alpha1.IPLease{}, handler.EnqueueRequestsFromMapFunc(r.leaseToPool)).
+		Named("ippool").
+		Complete(r)
+}
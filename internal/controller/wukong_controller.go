@@ -18,15 +18,23 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/netip"
+	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
 	"github.com/kuihuar/novasphere/pkg/kubevirt"
@@ -43,6 +51,24 @@ const (
 type WukongReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// GuestAgent queries guest file system usage; usage is not reported if nil
+	GuestAgent kubevirt.GuestAgent
+
+	// VolumeHotplug hot-unplugs removed disks from running VMs; removed disks are detached on restart if nil
+	VolumeHotplug kubevirt.VolumeHotplug
+
+	// Recorder records events on Wukongs; no events are recorded if nil
+	Recorder record.EventRecorder
+
+	// MACPrefix is the prefix of the MAC addresses allocated to Multus networks; network.DefaultMACPrefix if empty
+	MACPrefix string
+
+	// SidecarShimImage is the KubeVirt hook sidecar image applying Hypervisor QoS; kubevirt.DefaultSidecarShimImage if empty
+	SidecarShimImage string
+
+	// multusCRD 缓存 Multus CRD 是否已安装
+	multusCRD network.MultusCRDCache
 }
 
 // +kubebuilder:rbac:groups=vm.novasphere.dev,resources=wukongs,verbs=get;list;watch;create;update;patch;delete
@@ -50,11 +76,27 @@ type WukongReconciler struct {
 // +kubebuilder:rbac:groups=vm.novasphere.dev,resources=wukongs/finalizers,verbs=update
 // +kubebuilder:rbac:groups=kubevirt.io,resources=virtualmachines,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=kubevirt.io,resources=virtualmachineinstances,verbs=get;list;watch
+// +kubebuilder:rbac:groups=kubevirt.io,resources=kubevirts,verbs=get;list;watch
+// +kubebuilder:rbac:groups=subresources.kubevirt.io,resources=virtualmachineinstances/filesystemlist,verbs=get
+// +kubebuilder:rbac:groups=subresources.kubevirt.io,resources=virtualmachines/removevolume,verbs=update
 // +kubebuilder:rbac:groups=k8s.cni.cncf.io,resources=networkattachmentdefinitions,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=nmstate.io,resources=nodenetworkconfigurationpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=vm.novasphere.dev,resources=wukongnetworks,verbs=get;list;watch
+// +kubebuilder:rbac:groups=vm.novasphere.dev,resources=ippools,verbs=get;list;watch
+// +kubebuilder:rbac:groups=vm.novasphere.dev,resources=ipleases,verbs=get;list;watch;create;delete
 // +kubebuilder:rbac:groups=cdi.kubevirt.io,resources=datavolumes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cdi.kubevirt.io,resources=datavolumes/source,verbs=create
+// +kubebuilder:rbac:groups=cdi.kubevirt.io,resources=datasources;dataimportcrons,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cdi.kubevirt.io,resources=cdiconfigs;storageprofiles,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=core,resources=persistentvolumes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=volumeattributesclasses,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=vm.novasphere.dev,resources=wukongdefaults;wukongstorageprofiles,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -118,6 +160,18 @@ func (r *WukongReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 
 	// 7. 处理网络配置
 	networksStatus, err := r.reconcileNetworks(ctx, &vmp)
+	if errors.Is(err, network.ErrMultusUnavailable) {
+		// strict 模式下 Multus 未安装：不创建缺少网卡的 VM，等待安装 Multus
+		logger.Info("Multus CNI not installed, waiting", "reason", err.Error())
+		r.setNetworksNotConfigured(ctx, &vmp, "MultusUnavailable", err.Error()+"; install Multus or set networkPolicy to bestEffort")
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+	if errors.Is(err, network.ErrNADNotFound) {
+		// 用户指定的 NAD 不存在：等待用户创建
+		logger.Info("NetworkAttachmentDefinition not found, waiting", "reason", err.Error())
+		r.setNetworksNotConfigured(ctx, &vmp, "NADNotFound", err.Error())
+		return ctrl.Result{RequeueAfter: time.Second * 30}, nil
+	}
 	if err != nil {
 		logger.Error(err, "failed to reconcile networks")
 		vmp.Status.Phase = vmv1alpha1.PhaseError
@@ -125,6 +179,12 @@ func (r *WukongReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{RequeueAfter: time.Second * 30}, err
 	}
 
+	for _, net := range networksStatus {
+		if net.Degraded {
+			r.recordEvent(&vmp, corev1.EventTypeWarning, "NetworkDegraded", fmt.Sprintf("Network %s: %s", net.Name, net.Message))
+		}
+	}
+
 	// 8. 处理存储配置
 	volumesStatus, err := r.reconcileDisks(ctx, &vmp)
 	if err != nil {
@@ -139,6 +199,9 @@ func (r *WukongReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{RequeueAfter: time.Second * 30}, err
 	}
 
+	// 记录已从 spec 中移除的磁盘，VM 更新后再按 reclaimPolicy 处理
+	storage.TrackRemovedVolumes(&vmp)
+
 	// 8.1. 处理磁盘扩展（如果磁盘大小发生变化）
 	if err := storage.ReconcileDiskExpansion(ctx, r.Client, &vmp, volumesStatus); err != nil {
 		logger.V(1).Info("Disk expansion in progress or failed", "error", err)
@@ -159,12 +222,32 @@ func (r *WukongReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		logger.Info("Not all volumes are bound yet, requeuing", "volumes", len(volumesStatus))
 		vmp.Status.Volumes = volumesStatus
 		r.updateConditions(&vmp, networksStatus, volumesStatus, "")
+		// 导入失败且没有剩余重试次数时，标记为 Error，等待用户修改 spec
+		if failed := failedImports(&vmp, volumesStatus); len(failed) > 0 {
+			logger.Info("Disk import failed permanently", "disks", failed)
+			vmp.Status.Phase = vmv1alpha1.PhaseError
+			r.Status().Update(ctx, &vmp)
+			return ctrl.Result{RequeueAfter: time.Minute}, nil
+		}
 		r.Status().Update(ctx, &vmp)
 		return ctrl.Result{RequeueAfter: time.Second * 10}, nil
 	}
 
+	// 检查主机网络是否已就绪，未就绪时 VM 无法连接到网桥
+	for _, net := range networksStatus {
+		if !net.HostNetworkReady {
+			logger.Info("Host network not ready yet, requeuing", "network", net.Name, "policy", net.HostNetworkPolicy, "reason", net.Message)
+			vmp.Status.Networks = networksStatus
+			vmp.Status.Volumes = volumesStatus
+			r.updateConditions(&vmp, networksStatus, volumesStatus, "")
+			r.Status().Update(ctx, &vmp)
+			return ctrl.Result{RequeueAfter: time.Second * 15}, nil
+		}
+	}
+
 	// 9. 创建/更新 VirtualMachine (KubeVirt)
-	vmName, err := r.reconcileVirtualMachine(ctx, &vmp, networksStatus, volumesStatus)
+	vmOptions := r.vmOptions(ctx, volumesStatus)
+	vmName, err := r.reconcileVirtualMachine(ctx, &vmp, networksStatus, volumesStatus, vmOptions)
 	if err != nil {
 		logger.Error(err, "failed to reconcile VirtualMachine")
 		vmp.Status.Phase = vmv1alpha1.PhaseError
@@ -186,6 +269,30 @@ func (r *WukongReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		}
 	}
 
+	// 11.1. 检查运行中的 VM 是否需要重启以使用更新后的 NAD 配置
+	if vmPhase == "Running" && vmName != "" {
+		if err := r.markRestartRequired(ctx, &vmp, vmName, networksStatus); err != nil {
+			logger.V(1).Info("failed to check network restart requirement", "error", err)
+		}
+	}
+
+	// 11.2. 完成 guest 侧的磁盘扩容
+	if err := r.completeGuestResize(ctx, &vmp, vmName, vmPhase, volumesStatus); err != nil {
+		logger.V(1).Info("failed to check guest disk resize", "error", err)
+	}
+
+	// 11.3. 从 guest agent 同步文件系统使用情况
+	if vmPhase == "Running" && r.GuestAgent != nil {
+		if err := r.syncGuestFileSystems(ctx, &vmp, vmName, volumesStatus); err != nil {
+			logger.V(1).Info("failed to get guest file systems", "error", err)
+		}
+	}
+
+	// 11.4. 拔出并回收已从 spec 中移除的磁盘
+	if err := r.reconcileRemovedVolumes(ctx, &vmp, vmName); err != nil {
+		logger.Error(err, "failed to reconcile removed volumes")
+	}
+
 	// 12. 更新 Wukong 状态
 	vmp.Status.VMName = vmName
 	vmp.Status.Networks = networksStatus
@@ -219,12 +326,37 @@ func (r *WukongReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 
 	// 13. 更新条件
 	r.updateConditions(&vmp, networksStatus, volumesStatus, vmPhase)
+	meta.SetStatusCondition(&vmp.Status.Conditions, hypervisorQoSCondition(volumesStatus, vmOptions))
 
 	if err := r.Status().Update(ctx, &vmp); err != nil {
 		logger.Error(err, "unable to update VirtualMachineProfile status")
 		return ctrl.Result{}, err
 	}
 
+	// 磁盘扩容未完成时继续跟踪
+	guestResizePending := false
+	for _, vol := range volumesStatus {
+		// 停止的 VM 在下次启动前不会完成 guest 扩容，降低检查频率
+		if vol.ExpansionState == vmv1alpha1.ExpansionStateGuestResizePending && vmPhase != "Running" {
+			guestResizePending = true
+			continue
+		}
+		if storage.ExpansionInProgress(vol) {
+			logger.V(1).Info("Volume expansion in progress, will requeue", "volume", vol.Name, "state", vol.ExpansionState)
+			return ctrl.Result{RequeueAfter: time.Second * 30}, nil
+		}
+	}
+	for _, vol := range vmp.Status.RemovedVolumes {
+		if vol.State != vmv1alpha1.RemovedVolumeRetained {
+			logger.V(1).Info("Removed volume not reclaimed yet, will requeue", "volume", vol.Name, "state", vol.State)
+			return ctrl.Result{RequeueAfter: time.Minute}, nil
+		}
+	}
+	if guestResizePending {
+		logger.V(1).Info("Guest resize pending until the VM starts, will requeue")
+		return ctrl.Result{RequeueAfter: time.Minute * 5}, nil
+	}
+
 	logger.Info("Successfully reconciled Wukong", "name", req.Name, "phase", vmp.Status.Phase)
 	return ctrl.Result{}, nil
 }
@@ -235,13 +367,27 @@ func (r *WukongReconciler) reconcileNetworks(ctx context.Context, vmp *vmv1alpha
 	logger.Info("Reconciling networks (Multus + NMState)", "count", len(vmp.Spec.Networks))
 
 	// 1. 使用 Multus 管理 NetworkAttachmentDefinition
-	netStatuses, err := network.ReconcileNetworks(ctx, r.Client, vmp)
+	netStatuses, err := network.ReconcileNetworks(ctx, r.Client, vmp, &r.multusCRD)
 	if err != nil {
 		return nil, err
 	}
 
-	// 2. 使用 NMState 预留接口（当前为 no-op，占位）
-	if err := network.ReconcileNMState(ctx, r.Client, vmp); err != nil {
+	// 2. 为 Multus 网络分配稳定的 MAC 地址
+	macPrefix := r.MACPrefix
+	if macPrefix == "" {
+		macPrefix = network.DefaultMACPrefix
+	}
+	if err := network.ReconcileMACAddresses(ctx, r.Client, vmp, netStatuses, macPrefix); err != nil {
+		return nil, err
+	}
+
+	// 3. 为 pool 模式的网络从 IPPool 分配地址
+	if err := network.ReconcileIPAM(ctx, r.Client, vmp, netStatuses); err != nil {
+		return nil, err
+	}
+
+	// 4. 使用 NMState 在节点上创建网桥和 VLAN 子接口
+	if err := network.ReconcileNMState(ctx, r.Client, vmp, netStatuses); err != nil {
 		return nil, err
 	}
 
@@ -269,12 +415,12 @@ func (r *WukongReconciler) reconcileDisks(ctx context.Context, vmp *vmv1alpha1.W
 }
 
 // reconcileVirtualMachine 创建/更新 KubeVirt VirtualMachine
-func (r *WukongReconciler) reconcileVirtualMachine(ctx context.Context, vmp *vmv1alpha1.Wukong, networks []vmv1alpha1.NetworkStatus, volumes []vmv1alpha1.VolumeStatus) (string, error) {
+func (r *WukongReconciler) reconcileVirtualMachine(ctx context.Context, vmp *vmv1alpha1.Wukong, networks []vmv1alpha1.NetworkStatus, volumes []vmv1alpha1.VolumeStatus, opts kubevirt.VMOptions) (string, error) {
 	logger := log.FromContext(ctx)
 	logger.Info("Reconciling VirtualMachine (KubeVirt)")
 
 	// 使用 KubeVirt 模块创建/更新 VM
-	vmName, err := kubevirt.ReconcileVirtualMachine(ctx, r.Client, vmp, networks, volumes)
+	vmName, err := kubevirt.ReconcileVirtualMachine(ctx, r.Client, vmp, networks, volumes, opts)
 	if err != nil {
 		logger.Error(err, "failed to reconcile VirtualMachine")
 		return "", err
@@ -283,6 +429,43 @@ func (r *WukongReconciler) reconcileVirtualMachine(ctx context.Context, vmp *vmv
 	return vmName, nil
 }
 
+// vmOptions 返回构建 VM 的选项；只有存在 Hypervisor QoS 时才检查 KubeVirt 的 Sidecar feature gate
+func (r *WukongReconciler) vmOptions(ctx context.Context, volumes []vmv1alpha1.VolumeStatus) kubevirt.VMOptions {
+	opts := kubevirt.VMOptions{SidecarShimImage: r.SidecarShimImage}
+	if !kubevirt.HypervisorQoSRequested(volumes) {
+		return opts
+	}
+	enabled, err := kubevirt.SidecarFeatureGateEnabled(ctx, r.Client)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to check the KubeVirt Sidecar feature gate")
+	}
+	opts.HookSidecars = enabled
+	return opts
+}
+
+// hypervisorQoSCondition 返回 HypervisorQoSApplied 条件：Hypervisor QoS 需要 KubeVirt 的 Sidecar feature gate
+func hypervisorQoSCondition(volumes []vmv1alpha1.VolumeStatus, opts kubevirt.VMOptions) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               "HypervisorQoSApplied",
+		Status:             metav1.ConditionTrue,
+		Reason:             "NoHypervisorQoS",
+		Message:            "No disk uses Hypervisor QoS",
+		LastTransitionTime: metav1.Now(),
+	}
+	if !kubevirt.HypervisorQoSRequested(volumes) {
+		return condition
+	}
+	if !opts.HookSidecars {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "SidecarFeatureGateDisabled"
+		condition.Message = fmt.Sprintf("The KubeVirt %s feature gate is disabled; Hypervisor QoS is not applied", kubevirt.SidecarFeatureGate)
+		return condition
+	}
+	condition.Reason = "IOTuneHookConfigured"
+	condition.Message = "Hypervisor QoS is applied by the IO tuning hook sidecar when the VM starts"
+	return condition
+}
+
 // reconcileDelete 处理资源删除时的清理逻辑
 func (r *WukongReconciler) reconcileDelete(ctx context.Context, vmp *vmv1alpha1.Wukong) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
@@ -297,16 +480,42 @@ func (r *WukongReconciler) reconcileDelete(ctx context.Context, vmp *vmv1alpha1.
 		// 目前依赖 OwnerReference 的级联删除机制
 	}
 
-	// 2. 删除 PVC/DataVolume（通过 OwnerReference 自动删除，但为了确保，可以显式删除）
+	// 2. 根据 reclaimPolicy 删除或保留 PVC/DataVolume（PVC 没有 OwnerReference，需要显式删除）
 	for _, vol := range vmp.Status.Volumes {
-		if vol.PVCName != "" {
-			logger.V(1).Info("PVC will be deleted", "name", vol.PVCName)
-			// PVC 和 DataVolume 会通过 OwnerReference 自动删除
+		if err := r.reclaimVolume(ctx, vmp, vol.Name, vol.PVCName, vol.ReclaimPolicy); err != nil {
+			return ctrl.Result{RequeueAfter: time.Second * 10}, err
+		}
+	}
+	for _, vol := range vmp.Status.RemovedVolumes {
+		if err := r.reclaimVolume(ctx, vmp, vol.Name, vol.PVCName, vol.ReclaimPolicy); err != nil {
+			return ctrl.Result{RequeueAfter: time.Second * 10}, err
 		}
 	}
 
-	// 3. 删除 NetworkAttachmentDefinition（如果是由我们创建的）
+	// 3. 删除不再被任何 Wukong 使用的 NodeNetworkConfigurationPolicy，等待节点上的接口移除后再继续
+	removing, err := network.GarbageCollectNMState(ctx, r.Client, vmp)
+	if err != nil {
+		logger.Error(err, "failed to garbage-collect NodeNetworkConfigurationPolicies")
+		return ctrl.Result{RequeueAfter: time.Second * 10}, err
+	}
+	if removing {
+		logger.Info("Waiting for NMState to remove unused host interfaces")
+		return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+	}
+
+	// 4. 释放从 IPPool 分配的地址
+	if err := network.ReleaseIPs(ctx, r.Client, vmp); err != nil {
+		logger.Error(err, "failed to release IP addresses")
+		return ctrl.Result{RequeueAfter: time.Second * 10}, err
+	}
+
+	// 5. 删除 NetworkAttachmentDefinition（如果是由我们创建的）
 	// 注意：如果 NAD 是用户手动创建的，不应该删除
+	// 共享 NAD 按引用计数删除：namespace 中最后一个引用 WukongNetwork 的 Wukong 删除时清理
+	if err := network.GarbageCollectSharedNADs(ctx, r.Client, vmp.Namespace, vmp); err != nil {
+		logger.Error(err, "failed to garbage-collect shared NetworkAttachmentDefinitions")
+		return ctrl.Result{RequeueAfter: time.Second * 10}, err
+	}
 	for _, net := range vmp.Status.Networks {
 		if net.NADName != "" {
 			logger.V(1).Info("NAD will be cleaned up", "name", net.NADName)
@@ -314,7 +523,7 @@ func (r *WukongReconciler) reconcileDelete(ctx context.Context, vmp *vmv1alpha1.
 		}
 	}
 
-	// 4. 移除 finalizer
+	// 6. 移除 finalizer
 	vmp.Finalizers = removeString(vmp.Finalizers, finalizerName)
 	if err := r.Update(ctx, vmp); err != nil {
 		logger.Error(err, "unable to remove finalizer")
@@ -325,6 +534,26 @@ func (r *WukongReconciler) reconcileDelete(ctx context.Context, vmp *vmv1alpha1.
 	return ctrl.Result{}, nil
 }
 
+// reclaimVolume 在 Wukong 删除时按 reclaimPolicy 删除或保留卷
+func (r *WukongReconciler) reclaimVolume(ctx context.Context, vmp *vmv1alpha1.Wukong, name, pvcName, policy string) error {
+	logger := log.FromContext(ctx)
+	if pvcName == "" {
+		return nil
+	}
+	if policy != vmv1alpha1.ReclaimPolicyDelete {
+		// 未设置策略的卷（如旧版本创建的卷）默认保留
+		logger.Info("Retaining volume", "disk", name, "pvc", pvcName)
+		return nil
+	}
+
+	logger.Info("Deleting volume", "disk", name, "pvc", pvcName)
+	if err := storage.DeleteVolume(ctx, r.Client, vmp.Namespace, pvcName); err != nil {
+		logger.Error(err, "failed to delete volume", "disk", name, "pvc", pvcName)
+		return err
+	}
+	return nil
+}
+
 // validateSpec 验证 Wukong spec 的有效性
 func (r *WukongReconciler) validateSpec(vmp *vmv1alpha1.Wukong) error {
 	// 验证 CPU
@@ -343,18 +572,141 @@ func (r *WukongReconciler) validateSpec(vmp *vmv1alpha1.Wukong) error {
 	}
 
 	// 验证磁盘配置
+	pvcNames := make(map[string]bool, len(vmp.Spec.Disks))
 	for i, disk := range vmp.Spec.Disks {
 		if disk.Name == "" {
 			return fmt.Errorf("disk[%d].name is required", i)
 		}
-		if disk.Size == "" {
+		if disk.PVCName != "" {
+			if pvcNames[disk.PVCName] {
+				return fmt.Errorf("disk[%d]: pvcName %s is used by another disk", i, disk.PVCName)
+			}
+			pvcNames[disk.PVCName] = true
+		}
+		// 引用 catalog 镜像的磁盘可以省略 size，使用镜像默认大小
+		if disk.Encryption != nil && (disk.ContainerDisk != nil || disk.EmptyDisk != nil || disk.ExistingClaim != "") {
+			return fmt.Errorf("disk[%d]: encryption only applies to PVC-backed disks created by the Wukong", i)
+		}
+		// 共享盘会被多个 VM 同时挂载，只能引用不属于任何 Wukong 的已有 PVC
+		if disk.Shareable && disk.ExistingClaim == "" {
+			return fmt.Errorf("disk[%d]: shareable only applies to existingClaim disks", i)
+		}
+		if disk.ContainerDisk != nil {
+			// containerDisk 直接从 OCI 镜像启动，不需要 size 和 storageClassName
+			if disk.Image != "" || disk.Source != nil || disk.ExistingClaim != "" || disk.EmptyDisk != nil {
+				return fmt.Errorf("disk[%d]: containerDisk is mutually exclusive with image, source, existingClaim and emptyDisk", i)
+			}
+			continue
+		}
+		if disk.EmptyDisk != nil {
+			if disk.Size == "" {
+				return fmt.Errorf("disk[%d].size is required for emptyDisk", i)
+			}
+			if disk.Image != "" || disk.Source != nil || disk.ExistingClaim != "" {
+				return fmt.Errorf("disk[%d]: emptyDisk is mutually exclusive with image, source and existingClaim", i)
+			}
+			continue
+		}
+		if disk.ExistingClaim != "" {
+			// 已有 PVC 不需要 size 和 storageClassName
+			if disk.Image != "" || disk.Source != nil {
+				return fmt.Errorf("disk[%d]: existingClaim is mutually exclusive with image and source", i)
+			}
+			continue
+		}
+		if disk.Size == "" && disk.Image == "" && !(disk.Boot && disk.Source == nil && vmp.Spec.OSImage != "") {
 			return fmt.Errorf("disk[%d].size is required", i)
 		}
-		if disk.StorageClassName == "" {
-			return fmt.Errorf("disk[%d].storageClassName is required", i)
+		if disk.Image != "" && disk.Source != nil {
+			return fmt.Errorf("disk[%d]: image and source are mutually exclusive", i)
+		}
+		if disk.Encryption != nil && disk.Encryption.Mode == vmv1alpha1.EncryptionModeGuest &&
+			(disk.Boot || disk.Image != "" || disk.Source != nil) {
+			return fmt.Errorf("disk[%d]: guest encryption requires a blank data disk", i)
 		}
 	}
 
+	// 验证网络接口配置：PCI 地址、启动顺序和引用的 WukongNetwork 不能重复，最多一个主网络且需要关闭 Pod 网络
+	podNetworkEnabled := network.PodNetworkEnabled(vmp.Spec.PodNetwork)
+	pciAddresses := make(map[string]bool, len(vmp.Spec.Networks))
+	bootOrders := make(map[uint]bool, len(vmp.Spec.Networks))
+	networkRefs := make(map[string]bool, len(vmp.Spec.Networks))
+	primary := ""
+	for i, net := range vmp.Spec.Networks {
+		if net.Primary {
+			if primary != "" {
+				return fmt.Errorf("network[%d]: network %s is already primary", i, primary)
+			}
+			if podNetworkEnabled {
+				return fmt.Errorf("network[%d]: a primary network requires podNetwork.enabled to be false", i)
+			}
+			primary = net.Name
+		}
+		if net.Binding == vmv1alpha1.InterfaceBindingPlugin && net.BindingPlugin == "" {
+			return fmt.Errorf("network[%d]: bindingPlugin is required for the plugin binding", i)
+		}
+		if net.PCIAddress != "" {
+			if pciAddresses[strings.ToLower(net.PCIAddress)] {
+				return fmt.Errorf("network[%d]: pciAddress %s is used by another network", i, net.PCIAddress)
+			}
+			pciAddresses[strings.ToLower(net.PCIAddress)] = true
+		}
+		if net.NetworkRef != "" {
+			// 重复引用会在同一个共享 NAD 上创建两个接口，并重复计入引用计数
+			if networkRefs[net.NetworkRef] {
+				return fmt.Errorf("network[%d]: networkRef %s is used by another network", i, net.NetworkRef)
+			}
+			networkRefs[net.NetworkRef] = true
+		}
+		if net.BootOrder != nil {
+			if bootOrders[*net.BootOrder] {
+				return fmt.Errorf("network[%d]: bootOrder %d is used by another network", i, *net.BootOrder)
+			}
+			bootOrders[*net.BootOrder] = true
+		}
+		if err := validateIPConfig(net.IPConfig); err != nil {
+			return fmt.Errorf("network[%d].ipConfig: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// validateIPConfig 校验写入 cloud-init 网络配置的地址、网关和路由
+func validateIPConfig(ipConfig *vmv1alpha1.IPConfigSpec) error {
+	if ipConfig == nil {
+		return nil
+	}
+	if ipConfig.Address != nil {
+		if _, err := netip.ParsePrefix(*ipConfig.Address); err != nil {
+			return fmt.Errorf("invalid address %q: must be in CIDR notation", *ipConfig.Address)
+		}
+	}
+	if ipConfig.Gateway != nil {
+		if _, err := netip.ParseAddr(*ipConfig.Gateway); err != nil {
+			return fmt.Errorf("invalid gateway %q", *ipConfig.Gateway)
+		}
+	}
+	if ipv6 := ipConfig.IPv6; ipv6 != nil {
+		if ipv6.Address != nil {
+			if prefix, err := netip.ParsePrefix(*ipv6.Address); err != nil || !prefix.Addr().Is6() {
+				return fmt.Errorf("invalid ipv6.address %q: must be an IPv6 address in CIDR notation", *ipv6.Address)
+			}
+		}
+		if ipv6.Gateway != nil {
+			if addr, err := netip.ParseAddr(*ipv6.Gateway); err != nil || !addr.Is6() {
+				return fmt.Errorf("invalid ipv6.gateway %q", *ipv6.Gateway)
+			}
+		}
+	}
+	for i, route := range ipConfig.Routes {
+		if _, err := netip.ParsePrefix(route.To); err != nil {
+			return fmt.Errorf("routes[%d]: invalid destination %q: must be in CIDR notation", i, route.To)
+		}
+		if _, err := netip.ParseAddr(route.Via); err != nil {
+			return fmt.Errorf("routes[%d]: invalid next hop %q", i, route.Via)
+		}
+	}
 	return nil
 }
 
@@ -369,6 +721,39 @@ func (r *WukongReconciler) syncNetworkStatusFromVMI(ctx context.Context, vmp *vm
 	return nil
 }
 
+// setNetworksNotConfigured 在网络无法配置时将 Wukong 标记为 Error，并记录 Warning 事件
+func (r *WukongReconciler) setNetworksNotConfigured(ctx context.Context, vmp *vmv1alpha1.Wukong, reason, message string) {
+	r.recordEvent(vmp, corev1.EventTypeWarning, reason, message)
+	vmp.Status.Phase = vmv1alpha1.PhaseError
+	meta.SetStatusCondition(&vmp.Status.Conditions, metav1.Condition{
+		Type:    "NetworksConfigured",
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+	r.Status().Update(ctx, vmp)
+}
+
+// markRestartRequired 标记在 NAD 配置更新之前启动的网络：KubeVirt 只在 VM 启动时读取 NAD，
+// 运行中的 VM 需要重启才能使用新的 CNI 配置
+func (r *WukongReconciler) markRestartRequired(ctx context.Context, vmp *vmv1alpha1.Wukong, vmName string, networks []vmv1alpha1.NetworkStatus) error {
+	started, err := kubevirt.GetVMICreationTime(ctx, r.Client, vmp.Namespace, vmName)
+	if err != nil || started == nil {
+		return err
+	}
+
+	wasRequired := meta.IsStatusConditionTrue(vmp.Status.Conditions, "RestartRequired")
+	for i := range networks {
+		net := &networks[i]
+		net.RestartRequired = net.LastConfigUpdateTime != nil && started.Before(net.LastConfigUpdateTime)
+		if net.RestartRequired && !wasRequired {
+			r.recordEvent(vmp, corev1.EventTypeWarning, "RestartRequired",
+				fmt.Sprintf("Network %s: NetworkAttachmentDefinition %s was updated, restart the virtual machine to apply it", net.Name, net.NADName))
+		}
+	}
+	return nil
+}
+
 // updateConditions 更新状态条件
 func (r *WukongReconciler) updateConditions(vmp *vmv1alpha1.Wukong, networks []vmv1alpha1.NetworkStatus, volumes []vmv1alpha1.VolumeStatus, vmPhase string) {
 	now := metav1.Now()
@@ -406,6 +791,14 @@ func (r *WukongReconciler) updateConditions(vmp *vmv1alpha1.Wukong, networks []v
 		Message:            fmt.Sprintf("%d networks configured", len(networks)),
 		LastTransitionTime: now,
 	}
+	for _, net := range networks {
+		if net.Degraded {
+			networksCondition.Status = metav1.ConditionFalse
+			networksCondition.Reason = "NetworkDegraded"
+			networksCondition.Message = fmt.Sprintf("Network %s: %s", net.Name, net.Message)
+			break
+		}
+	}
 
 	// VolumesBound 条件
 	allVolumesBound := true
@@ -423,12 +816,188 @@ func (r *WukongReconciler) updateConditions(vmp *vmv1alpha1.Wukong, networks []v
 		LastTransitionTime: now,
 	}
 
+	// DiskImportFailed 条件
+	importCondition := metav1.Condition{
+		Type:               "DiskImportFailed",
+		Status:             metav1.ConditionFalse,
+		Reason:             "NoImportFailures",
+		Message:            "No disk import has failed",
+		LastTransitionTime: now,
+	}
+	for _, vol := range volumes {
+		if vol.LastImportFailureTime == nil {
+			continue
+		}
+		importCondition.Status = metav1.ConditionTrue
+		importCondition.Reason = "ImportFailed"
+		importCondition.Message = fmt.Sprintf("Import of disk %s failed (retries: %d): %s", vol.Name, vol.ImportRetries, vol.Message)
+		if len(failedImports(vmp, volumes)) > 0 {
+			importCondition.Reason = "ImportRetriesExhausted"
+		}
+		break
+	}
+
+	// LiveMigratable 条件 - 所有磁盘都是 ReadWriteMany 时才能热迁移
+	migratableCondition := metav1.Condition{
+		Type:               "LiveMigratable",
+		Status:             metav1.ConditionTrue,
+		Reason:             "AllVolumesShared",
+		Message:            "All volumes are ReadWriteMany",
+		LastTransitionTime: now,
+	}
+	for _, vol := range volumes {
+		// containerDisk 和 emptyDisk 没有 PVC，可以随 VM 迁移
+		if vol.PVCName != "" && !containsString(vol.AccessModes, string(corev1.ReadWriteMany)) {
+			migratableCondition.Status = metav1.ConditionFalse
+			migratableCondition.Reason = "VolumeNotShared"
+			migratableCondition.Message = fmt.Sprintf("Volume %s is not ReadWriteMany", vol.Name)
+			break
+		}
+	}
+	// Pod 网络使用 bridge 绑定时，KubeVirt 不允许热迁移
+	if migratableCondition.Status == metav1.ConditionTrue && vmp.Spec.PodNetwork != nil &&
+		vmp.Spec.PodNetwork.Binding == vmv1alpha1.InterfaceBindingBridge &&
+		(vmp.Spec.PodNetwork.Enabled == nil || *vmp.Spec.PodNetwork.Enabled) {
+		migratableCondition.Status = metav1.ConditionFalse
+		migratableCondition.Reason = "PodNetworkBridged"
+		migratableCondition.Message = "The pod network uses the bridge binding"
+	}
+
+	// HostNetworkReady 条件 - NMState 在节点上创建的网桥是否可用
+	hostNetworkCondition := metav1.Condition{
+		Type:               "HostNetworkReady",
+		Status:             metav1.ConditionTrue,
+		Reason:             "HostNetworksAvailable",
+		Message:            "All host networks are available",
+		LastTransitionTime: now,
+	}
+	for _, net := range networks {
+		if !net.HostNetworkReady {
+			hostNetworkCondition.Status = metav1.ConditionFalse
+			hostNetworkCondition.Reason = "HostNetworkNotAvailable"
+			hostNetworkCondition.Message = fmt.Sprintf("Network %s: %s", net.Name, net.Message)
+			break
+		}
+	}
+
+	// RestartRequired 条件 - NAD 配置在 VM 启动后发生了变化
+	restartCondition := metav1.Condition{
+		Type:               "RestartRequired",
+		Status:             metav1.ConditionFalse,
+		Reason:             "NetworksUpToDate",
+		Message:            "The virtual machine uses the current network configs",
+		LastTransitionTime: now,
+	}
+	for _, net := range networks {
+		if net.RestartRequired {
+			restartCondition.Status = metav1.ConditionTrue
+			restartCondition.Reason = "NetworkConfigChanged"
+			restartCondition.Message = fmt.Sprintf("NetworkAttachmentDefinition %s of network %s was updated after the virtual machine started", net.NADName, net.Name)
+			break
+		}
+	}
+
 	// 简化实现：直接覆盖当前 Conditions 列表，避免复杂的切片操作导致的 deep copy panic
 	vmp.Status.Conditions = []metav1.Condition{
 		readyCondition,
 		networksCondition,
+		hostNetworkCondition,
 		volumesCondition,
+		importCondition,
+		migratableCondition,
+		restartCondition,
+	}
+}
+
+// completeGuestResize 将 GuestResizePending 的卷标记为 Completed：
+// 等待运行中的 VMI 报告新的容量（guest 中的 udev 规则或启动时的 growfs 随后扩展文件系统），
+// VM 未运行时保持 GuestResizePending，直到 guest 以新的磁盘大小启动
+func (r *WukongReconciler) completeGuestResize(ctx context.Context, vmp *vmv1alpha1.Wukong, vmName, vmPhase string, volumes []vmv1alpha1.VolumeStatus) error {
+	pending := false
+	for _, vol := range volumes {
+		if vol.ExpansionState == vmv1alpha1.ExpansionStateGuestResizePending {
+			pending = true
+			break
+		}
+	}
+	if !pending || vmPhase != "Running" {
+		return nil
+	}
+
+	capacities, err := kubevirt.GetVMIVolumeCapacities(ctx, r.Client, vmp.Namespace, vmName)
+	if err != nil || capacities == nil {
+		return err
+	}
+	return storage.CompleteGuestResize(volumes, capacities)
+}
+
+// reconcileRemovedVolumes 热拔出仍挂载在 VMI 上的已移除磁盘，并对已拔出的磁盘执行 reclaimPolicy
+// 非热插入的卷无法在线拔出，会在 VM 下次重启后（VM 模板中已不包含该卷）再回收
+func (r *WukongReconciler) reconcileRemovedVolumes(ctx context.Context, vmp *vmv1alpha1.Wukong, vmName string) error {
+	logger := log.FromContext(ctx)
+	if len(vmp.Status.RemovedVolumes) == 0 {
+		return nil
+	}
+
+	removed := make(map[string]bool, len(vmp.Status.RemovedVolumes))
+	for _, vol := range vmp.Status.RemovedVolumes {
+		removed[vol.PVCName] = true
+	}
+
+	var attachedVolumes []kubevirt.AttachedVolume
+	if vmName != "" {
+		var err error
+		attachedVolumes, err = kubevirt.GetVMIAttachedVolumes(ctx, r.Client, vmp.Namespace, vmName)
+		if err != nil {
+			return err
+		}
+	}
+	attached := make(map[string]bool, len(attachedVolumes))
+	for _, vol := range attachedVolumes {
+		if removed[vol.ClaimName] && vol.Hotplugged && r.VolumeHotplug != nil {
+			logger.Info("Hot-unplugging removed disk", "volume", vol.Name, "pvc", vol.ClaimName)
+			if err := r.VolumeHotplug.RemoveVolume(ctx, vmp.Namespace, vmName, vol.Name); err != nil {
+				logger.Error(err, "failed to hot-unplug volume", "volume", vol.Name)
+			}
+		}
+		// 热拔出是异步的，直到 VMI 中不再包含该卷才回收 PVC
+		attached[vol.ClaimName] = true
+	}
+
+	return storage.ReconcileRemovedVolumes(ctx, r.Client, vmp, attached)
+}
+
+// syncGuestFileSystems 将 guest agent 报告的文件系统使用情况写入 VolumeStatus
+func (r *WukongReconciler) syncGuestFileSystems(ctx context.Context, vmp *vmv1alpha1.Wukong, vmName string, volumes []vmv1alpha1.VolumeStatus) error {
+	usage, err := kubevirt.GetGuestFileSystemUsage(ctx, r.GuestAgent, vmp.Namespace, vmName)
+	if err != nil {
+		return err
 	}
+
+	for i := range volumes {
+		vol := &volumes[i]
+		u, ok := usage[kubevirt.DiskSerial(vol.Name)]
+		if !ok {
+			continue
+		}
+		used, free := u.UsedBytes, u.FreeBytes
+		vol.FileSystemUsedBytes = &used
+		vol.FileSystemFreeBytes = &free
+	}
+	return nil
+}
+
+// failedImports 返回导入失败且没有剩余重试次数的磁盘名称
+func failedImports(vmp *vmv1alpha1.Wukong, volumes []vmv1alpha1.VolumeStatus) []string {
+	var failed []string
+	for _, disk := range vmp.Spec.Disks {
+		for _, vol := range volumes {
+			if vol.Name == disk.Name && storage.ImportFailedPermanently(disk, vol) {
+				failed = append(failed, disk.Name)
+			}
+		}
+	}
+	return failed
 }
 
 // boolToConditionStatus 将 bool 转换为 ConditionStatus
@@ -443,10 +1012,38 @@ func boolToConditionStatus(b bool) string {
 func (r *WukongReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&vmv1alpha1.Wukong{}).
+		Watches(&vmv1alpha1.WukongNetwork{}, handler.EnqueueRequestsFromMapFunc(r.networkToWukongs)).
 		Named("wukong").
 		Complete(r)
 }
 
+// networkToWukongs 将 WukongNetwork 的变化映射到引用它的 Wukong，以便更新共享 NAD
+func (r *WukongReconciler) networkToWukongs(ctx context.Context, obj client.Object) []reconcile.Request {
+	wukongs := &vmv1alpha1.WukongList{}
+	if err := r.List(ctx, wukongs); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list Wukongs for WukongNetwork", "name", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0)
+	for i := range wukongs.Items {
+		vmp := &wukongs.Items[i]
+		for _, netCfg := range vmp.Spec.Networks {
+			if netCfg.NetworkRef == obj.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(vmp)})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// recordEvent 记录 Wukong 事件（未配置 Recorder 时忽略）
+func (r *WukongReconciler) recordEvent(vmp *vmv1alpha1.Wukong, eventType, reason, message string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(vmp, eventType, reason, message)
+	}
+}
+
 // containsString 检查字符串切片是否包含指定字符串
 func containsString(slice []string, s string) bool {
 	for _, item := range slice {
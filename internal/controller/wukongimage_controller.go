@@ -0,0 +1,465 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+	"github.com/kuihuar/novasphere/pkg/catalog"
+	"github.com/kuihuar/novasphere/pkg/naming"
+	"github.com/kuihuar/novasphere/pkg/storage"
+)
+
+// WukongImageReconciler reconciles a WukongImage object
+type WukongImageReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// ImageNamespace is the namespace holding golden PVCs and DataSources
+	ImageNamespace string
+}
+
+// +kubebuilder:rbac:groups=vm.novasphere.dev,resources=wukongimages,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=vm.novasphere.dev,resources=wukongimages/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=vm.novasphere.dev,resources=wukongimages/finalizers,verbs=update
+// +kubebuilder:rbac:groups=cdi.kubevirt.io,resources=datasources,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cdi.kubevirt.io,resources=datavolumes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cdi.kubevirt.io,resources=dataimportcrons,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups=vm.novasphere.dev,resources=wukongs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch;create
+
+// Reconcile pre-imports a WukongImage into a golden PVC and DataSource per StorageClass.
+func (r *WukongImageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.Info("Reconciling WukongImage", "name", req.Name)
+
+	// 1. 获取 WukongImage
+	var img vmv1alpha1.WukongImage
+	if err := r.Get(ctx, req.NamespacedName, &img); err != nil {
+		if apierrors.IsNotFound(err) {
+			// 资源已删除，golden DataVolume/DataSource 通过 OwnerReference 级联删除
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "unable to fetch WukongImage")
+		return ctrl.Result{}, err
+	}
+	if !img.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	// 2. 确保 golden image 所在的 namespace 存在
+	if len(img.Spec.StorageClasses) > 0 {
+		if err := r.ensureImageNamespace(ctx); err != nil {
+			logger.Error(err, "failed to ensure image namespace", "namespace", r.imageNamespace())
+			return ctrl.Result{}, err
+		}
+	}
+
+	// 3. 为每个 StorageClass 导入 golden PVC 并创建 DataSource
+	goldenImages := make([]vmv1alpha1.GoldenImageStatus, 0, len(img.Spec.StorageClasses))
+	for _, storageClassName := range img.Spec.StorageClasses {
+		golden, err := r.reconcileGoldenImage(ctx, &img, storageClassName)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+			}
+			logger.Error(err, "failed to reconcile golden image", "storageClass", storageClassName)
+			img.Status.Phase = vmv1alpha1.ImagePhaseError
+			r.Status().Update(ctx, &img)
+			return ctrl.Result{RequeueAfter: time.Second * 30}, err
+		}
+		goldenImages = append(goldenImages, golden)
+	}
+
+	// 4. 删除已从 spec 中移除的 StorageClass 对应的 golden image
+	if err := r.cleanupGoldenImages(ctx, &img); err != nil {
+		logger.Error(err, "failed to clean up golden images")
+		return ctrl.Result{RequeueAfter: time.Second * 30}, err
+	}
+
+	// 5. 更新状态
+	img.Status.GoldenImages = goldenImages
+	img.Status.Phase = imagePhase(goldenImages)
+	r.updateImageConditions(&img)
+	if err := r.Status().Update(ctx, &img); err != nil {
+		logger.Error(err, "unable to update WukongImage status")
+		return ctrl.Result{}, err
+	}
+
+	if img.Status.Phase != vmv1alpha1.ImagePhaseReady {
+		return ctrl.Result{RequeueAfter: time.Second * 30}, nil
+	}
+	if img.Spec.Refresh != nil {
+		// 定期检查 DataImportCron 导入的新版本，并回收旧的 golden PVC
+		return ctrl.Result{RequeueAfter: time.Minute * 10}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// reconcileGoldenImage 导入指定 StorageClass 的 golden PVC，并创建指向它的 DataSource
+func (r *WukongImageReconciler) reconcileGoldenImage(ctx context.Context, img *vmv1alpha1.WukongImage, storageClassName string) (vmv1alpha1.GoldenImageStatus, error) {
+	if img.Spec.Refresh != nil {
+		return r.reconcileRefreshedGoldenImage(ctx, img, storageClassName)
+	}
+
+	namespace := r.imageNamespace()
+	name := catalog.GoldenName(img.Name, storageClassName)
+	golden := vmv1alpha1.GoldenImageStatus{
+		StorageClassName: storageClassName,
+		Namespace:        namespace,
+		PVCName:          name,
+		DataSourceName:   name,
+	}
+
+	disk := vmv1alpha1.DiskConfig{
+		Name:             name,
+		Size:             img.Spec.DefaultSize,
+		StorageClassName: storageClassName,
+		Source:           img.Spec.Source.DeepCopy(),
+	}
+	dv, err := storage.BuildDataVolume(ctx, r.Client, name, namespace, disk)
+	if err != nil {
+		return golden, err
+	}
+	dv.SetLabels(goldenLabels(img.Name, storageClassName))
+	if err := controllerutil.SetControllerReference(img, dv, r.Scheme); err != nil {
+		return golden, err
+	}
+
+	state, err := storage.EnsureDataVolume(ctx, r.Client, dv)
+	if err != nil {
+		return golden, err
+	}
+	golden.ImportPhase = state.Phase
+	golden.ImportProgress = state.Progress
+	golden.Message = state.Message
+	golden.Ready = state.Phase == "Succeeded"
+
+	if err := r.ensureDataSource(ctx, img, storageClassName, name, namespace); err != nil {
+		return golden, err
+	}
+
+	return golden, nil
+}
+
+// reconcileRefreshedGoldenImage 为指定 StorageClass 创建 DataImportCron，定期导入镜像的新版本，
+// DataImportCron 负责让同名 DataSource 指向最新导入的 PVC
+func (r *WukongImageReconciler) reconcileRefreshedGoldenImage(ctx context.Context, img *vmv1alpha1.WukongImage, storageClassName string) (vmv1alpha1.GoldenImageStatus, error) {
+	namespace := r.imageNamespace()
+	name := catalog.GoldenName(img.Name, storageClassName)
+	golden := vmv1alpha1.GoldenImageStatus{
+		StorageClassName:   storageClassName,
+		Namespace:          namespace,
+		DataSourceName:     name,
+		DataImportCronName: name,
+	}
+
+	disk := vmv1alpha1.DiskConfig{
+		Name:             name,
+		Size:             img.Spec.DefaultSize,
+		StorageClassName: storageClassName,
+		Source:           img.Spec.Source.DeepCopy(),
+	}
+	cron, err := storage.BuildDataImportCron(ctx, r.Client, name, namespace, disk, *img.Spec.Refresh, goldenLabels(img.Name, storageClassName))
+	if err != nil {
+		return golden, err
+	}
+	if err := controllerutil.SetControllerReference(img, cron, r.Scheme); err != nil {
+		return golden, err
+	}
+
+	cron, err = storage.EnsureDataImportCron(ctx, r.Client, cron)
+	if err != nil {
+		return golden, err
+	}
+
+	state := storage.GetDataImportCronState(cron)
+	golden.PVCName = state.LastImportedPVC
+	golden.LastImportTime = state.LastImportTime
+	golden.Message = state.Message
+	golden.Ready = state.LastImportedPVC != ""
+	switch {
+	case state.Importing:
+		golden.ImportPhase = "ImportInProgress"
+	case golden.Ready:
+		golden.ImportPhase = "Succeeded"
+	default:
+		golden.ImportPhase = "Pending"
+	}
+
+	if golden.Ready {
+		if err := r.garbageCollectImports(ctx, img, storageClassName, state.LastImportedPVC); err != nil {
+			return golden, err
+		}
+	}
+	return golden, nil
+}
+
+// garbageCollectImports 删除超出 ImportsToKeep 的旧 golden PVC，
+// 最新导入的 PVC 以及仍被 Wukong 引用的 PVC 不会被删除
+func (r *WukongImageReconciler) garbageCollectImports(ctx context.Context, img *vmv1alpha1.WukongImage, storageClassName, lastImportedPVC string) error {
+	logger := log.FromContext(ctx)
+	namespace := r.imageNamespace()
+
+	var pvcs corev1.PersistentVolumeClaimList
+	if err := r.List(ctx, &pvcs, client.InNamespace(namespace), client.MatchingLabels(goldenLabels(img.Name, storageClassName))); err != nil {
+		return err
+	}
+
+	importsToKeep := 3
+	if img.Spec.Refresh.ImportsToKeep != nil {
+		importsToKeep = int(*img.Spec.Refresh.ImportsToKeep)
+	}
+	if len(pvcs.Items) <= importsToKeep {
+		return nil
+	}
+
+	referenced, err := r.referencedSourcePVCs(ctx)
+	if err != nil {
+		return err
+	}
+
+	// 按创建时间从新到旧排序
+	sort.Slice(pvcs.Items, func(i, j int) bool {
+		return pvcs.Items[j].CreationTimestamp.Before(&pvcs.Items[i].CreationTimestamp)
+	})
+	for i := importsToKeep; i < len(pvcs.Items); i++ {
+		pvc := &pvcs.Items[i]
+		if pvc.Name == lastImportedPVC || referenced[namespace+"/"+pvc.Name] {
+			continue
+		}
+
+		logger.Info("Deleting outdated golden PVC", "name", pvc.Name, "namespace", namespace, "image", img.Name)
+		if err := storage.DeleteDataVolume(ctx, r.Client, namespace, pvc.Name); err != nil {
+			return err
+		}
+		if err := storage.DeletePVC(ctx, r.Client, namespace, pvc.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// referencedSourcePVCs 返回所有 Wukong 磁盘克隆来源的 golden PVC（namespace/name），
+// 包括尚未完成的 DataVolume 正在克隆的 PVC（Wukong 状态中还没有记录 SourcePVC）
+func (r *WukongImageReconciler) referencedSourcePVCs(ctx context.Context) (map[string]bool, error) {
+	var wukongs vmv1alpha1.WukongList
+	if err := r.List(ctx, &wukongs); err != nil {
+		return nil, err
+	}
+
+	referenced, err := storage.InFlightCloneSources(ctx, r.Client)
+	if err != nil {
+		return nil, err
+	}
+	for _, vmp := range wukongs.Items {
+		for _, vol := range vmp.Status.Volumes {
+			if vol.SourcePVC != "" {
+				referenced[vol.SourcePVC] = true
+			}
+		}
+	}
+	return referenced, nil
+}
+
+// ensureDataSource 创建指向 golden PVC 的 DataSource，如果已存在但指向其他 PVC 则更新
+func (r *WukongImageReconciler) ensureDataSource(ctx context.Context, img *vmv1alpha1.WukongImage, storageClassName, name, namespace string) error {
+	logger := log.FromContext(ctx)
+
+	existing := newDataSource()
+	err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, existing)
+	if err == nil {
+		// 关闭 refresh 后，DataSource 可能仍指向 DataImportCron 导入的 PVC
+		pvcName, _, _ := unstructured.NestedString(existing.Object, "spec", "source", "pvc", "name")
+		if pvcName == name {
+			return nil
+		}
+		logger.Info("Updating DataSource to point to golden PVC", "name", name, "namespace", namespace)
+		if err := unstructured.SetNestedField(existing.Object, name, "spec", "source", "pvc", "name"); err != nil {
+			return err
+		}
+		if err := unstructured.SetNestedField(existing.Object, namespace, "spec", "source", "pvc", "namespace"); err != nil {
+			return err
+		}
+		return r.Update(ctx, existing)
+	}
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	ds := newDataSource()
+	ds.SetName(name)
+	ds.SetNamespace(namespace)
+	ds.SetLabels(goldenLabels(img.Name, storageClassName))
+	if err := unstructured.SetNestedField(ds.Object, map[string]interface{}{
+		"source": map[string]interface{}{
+			"pvc": map[string]interface{}{
+				"namespace": namespace,
+				"name":      name,
+			},
+		},
+	}, "spec"); err != nil {
+		return err
+	}
+	if err := controllerutil.SetControllerReference(img, ds, r.Scheme); err != nil {
+		return err
+	}
+
+	logger.Info("Creating DataSource for golden image", "name", name, "namespace", namespace)
+	return r.Create(ctx, ds)
+}
+
+// cleanupGoldenImages 删除已从 spec 中移除的 StorageClass 对应的 golden DataVolume、DataSource 和 DataImportCron，
+// 关闭 refresh 时同时删除 DataImportCron
+func (r *WukongImageReconciler) cleanupGoldenImages(ctx context.Context, img *vmv1alpha1.WukongImage) error {
+	logger := log.FromContext(ctx)
+
+	// 从未导入过 golden image 时没有需要清理的对象
+	if len(img.Spec.StorageClasses) == 0 && len(img.Status.GoldenImages) == 0 {
+		return nil
+	}
+
+	desired := make(map[string]bool, len(img.Spec.StorageClasses))
+	for _, storageClassName := range img.Spec.StorageClasses {
+		desired[naming.LabelValue(storageClassName)] = true
+	}
+
+	for _, kind := range []string{"DataVolume", "DataSource", "DataImportCron"} {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(schema.GroupVersionKind{
+			Group:   "cdi.kubevirt.io",
+			Version: "v1beta1",
+			Kind:    kind + "List",
+		})
+		if err := r.List(ctx, list, client.InNamespace(r.imageNamespace()), client.MatchingLabels{catalog.LabelImage: naming.LabelValue(img.Name)}); err != nil {
+			if meta.IsNoMatchError(err) {
+				// 未安装 CDI 时不存在需要清理的对象
+				continue
+			}
+			return err
+		}
+		for i := range list.Items {
+			obj := &list.Items[i]
+			if desired[obj.GetLabels()[catalog.LabelStorageClass]] && (kind != "DataImportCron" || img.Spec.Refresh != nil) {
+				continue
+			}
+			logger.Info("Deleting golden image object no longer in spec", "kind", kind, "name", obj.GetName())
+			if err := r.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ensureImageNamespace 创建 golden image 所在的 namespace（如果不存在）
+func (r *WukongImageReconciler) ensureImageNamespace(ctx context.Context) error {
+	ns := &corev1.Namespace{}
+	err := r.Get(ctx, client.ObjectKey{Name: r.imageNamespace()}, ns)
+	if err == nil || !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	ns.Name = r.imageNamespace()
+	if err := r.Create(ctx, ns); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// imageNamespace 返回 golden image 所在的 namespace
+func (r *WukongImageReconciler) imageNamespace() string {
+	if r.ImageNamespace != "" {
+		return r.ImageNamespace
+	}
+	return catalog.DefaultImageNamespace
+}
+
+// updateImageConditions 更新 WukongImage 的状态条件
+func (r *WukongImageReconciler) updateImageConditions(img *vmv1alpha1.WukongImage) {
+	ready := 0
+	for _, golden := range img.Status.GoldenImages {
+		if golden.Ready {
+			ready++
+		}
+	}
+
+	meta.SetStatusCondition(&img.Status.Conditions, metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionStatus(boolToConditionStatus(img.Status.Phase == vmv1alpha1.ImagePhaseReady)),
+		Reason:  "GoldenImages" + img.Status.Phase,
+		Message: fmt.Sprintf("%d/%d golden images ready", ready, len(img.Status.GoldenImages)),
+	})
+}
+
+// imagePhase 根据所有 golden image 的状态计算 WukongImage phase
+func imagePhase(goldenImages []vmv1alpha1.GoldenImageStatus) string {
+	phase := vmv1alpha1.ImagePhaseReady
+	for _, golden := range goldenImages {
+		if golden.ImportPhase == "Failed" {
+			return vmv1alpha1.ImagePhaseError
+		}
+		if !golden.Ready {
+			phase = vmv1alpha1.ImagePhaseImporting
+		}
+	}
+	return phase
+}
+
+// goldenLabels 返回 golden DataVolume 和 DataSource 的标签，过长的名称截断并追加哈希
+func goldenLabels(imageName, storageClassName string) map[string]string {
+	return map[string]string{
+		catalog.LabelImage:        naming.LabelValue(imageName),
+		catalog.LabelStorageClass: naming.LabelValue(storageClassName),
+	}
+}
+
+// newDataSource 返回一个 CDI DataSource Unstructured 对象
+func newDataSource() *unstructured.Unstructured {
+	ds := &unstructured.Unstructured{}
+	ds.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "cdi.kubevirt.io",
+		Version: "v1beta1",
+		Kind:    "DataSource",
+	})
+	return ds
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *WukongImageReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&vmv1alpha1.WukongImage{}).
+		Named("wukongimage").
+		Complete(r)
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+	"github.com/kuihuar/novasphere/pkg/catalog"
+)
+
+var _ = Describe("WukongImage Controller", func() {
+	Context("When reconciling a resource", func() {
+		const (
+			resourceName   = "test-image"
+			imageNamespace = "test-images"
+			imageURL       = "https://cloud-images.ubuntu.com/noble/current/noble-server-cloudimg-amd64.img"
+		)
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name: resourceName,
+		}
+
+		BeforeEach(func() {
+			By("creating the custom resource for the Kind WukongImage")
+			wukongimage := &vmv1alpha1.WukongImage{}
+			err := k8sClient.Get(ctx, typeNamespacedName, wukongimage)
+			if err != nil && errors.IsNotFound(err) {
+				resource := &vmv1alpha1.WukongImage{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: resourceName,
+					},
+					Spec: vmv1alpha1.WukongImageSpec{
+						Source: vmv1alpha1.DiskSourceSpec{
+							HTTP: &vmv1alpha1.HTTPDiskSource{
+								URL: imageURL,
+							},
+						},
+						DefaultSize: "20Gi",
+						DefaultUser: "ubuntu",
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			resource := &vmv1alpha1.WukongImage{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Cleanup the specific resource instance WukongImage")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+
+			// envtest 不运行垃圾回收，手动删除 golden DataVolume 和 DataSource
+			for _, kind := range []string{"DataVolume", "DataSource"} {
+				obj := newCDIObject(kind)
+				Expect(k8sClient.DeleteAllOf(ctx, obj, client.InNamespace(imageNamespace))).To(Succeed())
+			}
+		})
+
+		reconcileImage := func() *vmv1alpha1.WukongImage {
+			controllerReconciler := &WukongImageReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				ImageNamespace: imageNamespace,
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			img := &vmv1alpha1.WukongImage{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, img)).To(Succeed())
+			return img
+		}
+
+		setStorageClasses := func(storageClasses ...string) {
+			img := &vmv1alpha1.WukongImage{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, img)).To(Succeed())
+			img.Spec.StorageClasses = storageClasses
+			Expect(k8sClient.Update(ctx, img)).To(Succeed())
+		}
+
+		It("should mark an image without StorageClasses Ready", func() {
+			img := reconcileImage()
+			Expect(img.Status.Phase).To(Equal(vmv1alpha1.ImagePhaseReady))
+			Expect(img.Status.GoldenImages).To(BeEmpty())
+		})
+
+		It("should import a golden DataVolume and DataSource per StorageClass", func() {
+			setStorageClasses("fast")
+			goldenName := catalog.GoldenName(resourceName, "fast")
+			goldenKey := types.NamespacedName{Namespace: imageNamespace, Name: goldenName}
+
+			By("Reconciling the image")
+			img := reconcileImage()
+			Expect(img.Status.Phase).To(Equal(vmv1alpha1.ImagePhaseImporting))
+			Expect(img.Status.GoldenImages).To(HaveLen(1))
+			golden := img.Status.GoldenImages[0]
+			Expect(golden.StorageClassName).To(Equal("fast"))
+			Expect(golden.Namespace).To(Equal(imageNamespace))
+			Expect(golden.PVCName).To(Equal(goldenName))
+			Expect(golden.DataSourceName).To(Equal(goldenName))
+			Expect(golden.Ready).To(BeFalse())
+
+			By("Checking the golden DataVolume")
+			dv := newCDIObject("DataVolume")
+			Expect(k8sClient.Get(ctx, goldenKey, dv)).To(Succeed())
+			Expect(dv.GetLabels()).To(HaveKeyWithValue(catalog.LabelImage, resourceName))
+			Expect(dv.GetLabels()).To(HaveKeyWithValue(catalog.LabelStorageClass, "fast"))
+			Expect(dv.GetOwnerReferences()).To(ConsistOf(HaveField("Name", resourceName)))
+			url, _, _ := unstructured.NestedString(dv.Object, "spec", "source", "http", "url")
+			Expect(url).To(Equal(imageURL))
+			storageClassName, _, _ := unstructured.NestedString(dv.Object, "spec", "pvc", "storageClassName")
+			Expect(storageClassName).To(Equal("fast"))
+			size, _, _ := unstructured.NestedString(dv.Object, "spec", "pvc", "resources", "requests", "storage")
+			Expect(size).To(Equal("20Gi"))
+
+			By("Checking the DataSource pointing to the golden PVC")
+			ds := newCDIObject("DataSource")
+			Expect(k8sClient.Get(ctx, goldenKey, ds)).To(Succeed())
+			Expect(ds.GetOwnerReferences()).To(ConsistOf(HaveField("Name", resourceName)))
+			pvc, _, _ := unstructured.NestedStringMap(ds.Object, "spec", "source", "pvc")
+			Expect(pvc).To(Equal(map[string]string{"namespace": imageNamespace, "name": goldenName}))
+
+			By("Completing the import")
+			Expect(unstructured.SetNestedField(dv.Object, "Succeeded", "status", "phase")).To(Succeed())
+			Expect(k8sClient.Update(ctx, dv)).To(Succeed())
+			img = reconcileImage()
+			Expect(img.Status.Phase).To(Equal(vmv1alpha1.ImagePhaseReady))
+			Expect(img.Status.GoldenImages[0].Ready).To(BeTrue())
+			ready := meta.FindStatusCondition(img.Status.Conditions, "Ready")
+			Expect(ready).NotTo(BeNil())
+			Expect(ready.Status).To(Equal(metav1.ConditionTrue))
+
+			By("Removing the StorageClass from the image")
+			setStorageClasses()
+			img = reconcileImage()
+			Expect(img.Status.GoldenImages).To(BeEmpty())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, goldenKey, newCDIObject("DataVolume")))).To(BeTrue())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, goldenKey, newCDIObject("DataSource")))).To(BeTrue())
+		})
+	})
+})
+
+// newCDIObject 返回指定 kind 的 CDI Unstructured 对象
+func newCDIObject(kind string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "cdi.kubevirt.io",
+		Version: "v1beta1",
+		Kind:    kind,
+	})
+	return obj
+}
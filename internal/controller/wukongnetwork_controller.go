@@ -0,0 +1,189 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+	"github.com/kuihuar/novasphere/pkg/network"
+)
+
+const (
+	// wukongNetworkFinalizer 防止仍被 Wukong 引用的 WukongNetwork 被删除
+	wukongNetworkFinalizer = "wukong.novasphere.dev/network-in-use"
+)
+
+// WukongNetworkReconciler reconciles a WukongNetwork object
+type WukongNetworkReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=vm.novasphere.dev,resources=wukongnetworks,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=vm.novasphere.dev,resources=wukongnetworks/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=vm.novasphere.dev,resources=wukongnetworks/finalizers,verbs=update
+// +kubebuilder:rbac:groups=vm.novasphere.dev,resources=wukongs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=k8s.cni.cncf.io,resources=networkattachmentdefinitions,verbs=get;list;watch;delete
+
+// Reconcile counts the Wukongs referencing a WukongNetwork and records them in its status.
+// The shared NetworkAttachmentDefinitions of namespaces without references are deleted.
+// A WukongNetwork cannot be deleted while Wukongs still reference it; once it is deleted,
+// the shared NetworkAttachmentDefinitions are removed through their OwnerReference.
+func (r *WukongNetworkReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.V(1).Info("Reconciling WukongNetwork", "name", req.Name)
+
+	// 1. 获取 WukongNetwork
+	var wn vmv1alpha1.WukongNetwork
+	if err := r.Get(ctx, req.NamespacedName, &wn); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "unable to fetch WukongNetwork")
+		return ctrl.Result{}, err
+	}
+
+	// 2. 统计引用该网络的 Wukong
+	references, namespaces, err := network.CountNetworkReferences(ctx, r.Client, wn.Name)
+	if err != nil {
+		logger.Error(err, "failed to count WukongNetwork references")
+		return ctrl.Result{}, err
+	}
+
+	// 3. 处理删除：仍被引用时保留 finalizer
+	if !wn.DeletionTimestamp.IsZero() {
+		if references > 0 {
+			logger.Info("WukongNetwork still in use, delaying deletion", "name", wn.Name, "references", references)
+			wn.Status.References = references
+			wn.Status.Namespaces = namespaces
+			meta.SetStatusCondition(&wn.Status.Conditions, metav1.Condition{
+				Type:    "Ready",
+				Status:  metav1.ConditionFalse,
+				Reason:  "InUse",
+				Message: fmt.Sprintf("Network is deleted but still referenced by %d Wukongs", references),
+			})
+			r.Status().Update(ctx, &wn)
+			return ctrl.Result{RequeueAfter: time.Minute}, nil
+		}
+		if containsString(wn.Finalizers, wukongNetworkFinalizer) {
+			wn.Finalizers = removeString(wn.Finalizers, wukongNetworkFinalizer)
+			if err := r.Update(ctx, &wn); err != nil {
+				logger.Error(err, "unable to remove finalizer")
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// 4. 添加 finalizer
+	if !containsString(wn.Finalizers, wukongNetworkFinalizer) {
+		wn.Finalizers = append(wn.Finalizers, wukongNetworkFinalizer)
+		if err := r.Update(ctx, &wn); err != nil {
+			logger.Error(err, "unable to add finalizer")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// 5. 删除不再被引用的 namespace 中的共享 NAD（Wukong 从 spec 中移除引用时）
+	if err := network.GarbageCollectNetworkNADs(ctx, r.Client, wn.Name, namespaces); err != nil {
+		logger.Error(err, "failed to garbage-collect shared NetworkAttachmentDefinitions")
+		return ctrl.Result{}, err
+	}
+
+	// 6. 更新状态
+	wn.Status.References = references
+	wn.Status.Namespaces = namespaces
+	meta.SetStatusCondition(&wn.Status.Conditions, metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionTrue,
+		Reason:  "NetworkDefined",
+		Message: fmt.Sprintf("Network is referenced by %d Wukongs", references),
+	})
+	if err := r.Status().Update(ctx, &wn); err != nil {
+		logger.Error(err, "unable to update WukongNetwork status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// wukongToNetworks 将 Wukong 的变化映射到其引用的 WukongNetwork
+func (r *WukongNetworkReconciler) wukongToNetworks(ctx context.Context, obj client.Object) []reconcile.Request {
+	vmp, ok := obj.(*vmv1alpha1.Wukong)
+	if !ok {
+		return nil
+	}
+	requests := make([]reconcile.Request, 0)
+	for _, netCfg := range vmp.Spec.Networks {
+		if netCfg.NetworkRef != "" {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: client.ObjectKey{Name: netCfg.NetworkRef},
+			})
+		}
+	}
+	return requests
+}
+
+// wukongEventHandler 将 Wukong 事件映射到 WukongNetwork；更新时同时映射旧对象的引用，
+// 使被移除引用的 WukongNetwork 也能更新引用计数并清理共享 NAD
+func (r *WukongNetworkReconciler) wukongEventHandler() handler.EventHandler {
+	enqueue := func(ctx context.Context, q workqueue.TypedRateLimitingInterface[reconcile.Request], objs ...client.Object) {
+		for _, obj := range objs {
+			for _, req := range r.wukongToNetworks(ctx, obj) {
+				q.Add(req)
+			}
+		}
+	}
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, q, e.Object)
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, q, e.ObjectOld, e.ObjectNew)
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, q, e.Object)
+		},
+		GenericFunc: func(ctx context.Context, e event.GenericEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, q, e.Object)
+		},
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *WukongNetworkReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&vmv1alpha1.WukongNetwork{}).
+		Watches(&vmv1alpha1.Wukong{}, r.wukongEventHandler()).
+		Named("wukongnetwork").
+		Complete(r)
+}
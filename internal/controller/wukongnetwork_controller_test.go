@@ -0,0 +1,150 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+var _ = Describe("WukongNetwork Controller", func() {
+	Context("When reconciling a resource", func() {
+		const resourceName = "test-vlan100"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name: resourceName,
+		}
+
+		BeforeEach(func() {
+			By("creating the custom resource for the Kind WukongNetwork")
+			wn := &vmv1alpha1.WukongNetwork{}
+			err := k8sClient.Get(ctx, typeNamespacedName, wn)
+			if err != nil && errors.IsNotFound(err) {
+				vlanID := 100
+				resource := &vmv1alpha1.WukongNetwork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: resourceName,
+					},
+					Spec: vmv1alpha1.WukongNetworkSpec{
+						Type:   "bridge",
+						VLANID: &vlanID,
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			By("Cleanup the specific resource instance WukongNetwork")
+			resource := &vmv1alpha1.WukongNetwork{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			if errors.IsNotFound(err) {
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			resource.Finalizers = nil
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, resource))).To(Succeed())
+		})
+
+		reconcileNetwork := func() {
+			controllerReconciler := &WukongNetworkReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		// createReferencingWukong 创建引用该网络的 Wukong
+		createReferencingWukong := func(name string) *vmv1alpha1.Wukong {
+			vmp := &vmv1alpha1.Wukong{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "default",
+				},
+				Spec: vmv1alpha1.WukongSpec{
+					Networks: []vmv1alpha1.NetworkConfig{{Name: "tenant", NetworkRef: resourceName}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, vmp)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, vmp))).To(Succeed())
+			})
+			return vmp
+		}
+
+		It("should add the finalizer and count the referencing Wukongs", func() {
+			By("Reconciling the network without references")
+			reconcileNetwork()
+			wn := &vmv1alpha1.WukongNetwork{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, wn)).To(Succeed())
+			Expect(wn.Finalizers).To(ContainElement(wukongNetworkFinalizer))
+			Expect(wn.Status.References).To(BeZero())
+
+			By("Reconciling the network referenced by a Wukong")
+			createReferencingWukong("test-network-ref")
+			reconcileNetwork()
+			Expect(k8sClient.Get(ctx, typeNamespacedName, wn)).To(Succeed())
+			Expect(wn.Status.References).To(Equal(int32(1)))
+			Expect(wn.Status.Namespaces).To(Equal([]string{"default"}))
+			ready := meta.FindStatusCondition(wn.Status.Conditions, "Ready")
+			Expect(ready).NotTo(BeNil())
+			Expect(ready.Status).To(Equal(metav1.ConditionTrue))
+			Expect(ready.Reason).To(Equal("NetworkDefined"))
+		})
+
+		It("should block deletion while a Wukong references the network", func() {
+			vmp := createReferencingWukong("test-network-in-use")
+			reconcileNetwork()
+
+			By("Deleting the referenced network")
+			wn := &vmv1alpha1.WukongNetwork{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, wn)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, wn)).To(Succeed())
+			reconcileNetwork()
+			Expect(k8sClient.Get(ctx, typeNamespacedName, wn)).To(Succeed())
+			Expect(wn.DeletionTimestamp.IsZero()).To(BeFalse())
+			Expect(wn.Finalizers).To(ContainElement(wukongNetworkFinalizer))
+			ready := meta.FindStatusCondition(wn.Status.Conditions, "Ready")
+			Expect(ready).NotTo(BeNil())
+			Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+			Expect(ready.Reason).To(Equal("InUse"))
+
+			By("Deleting the referencing Wukong")
+			Expect(k8sClient.Delete(ctx, vmp)).To(Succeed())
+			reconcileNetwork()
+			err := k8sClient.Get(ctx, typeNamespacedName, wn)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,137 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+	"github.com/kuihuar/novasphere/pkg/storage"
+)
+
+// WukongStorageProfileReconciler reconciles a WukongStorageProfile object per StorageClass
+type WukongStorageProfileReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=vm.novasphere.dev,resources=wukongstorageprofiles,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=vm.novasphere.dev,resources=wukongstorageprofiles/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=vm.novasphere.dev,resources=wukongstorageprofiles/finalizers,verbs=update
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses;csidrivers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=snapshot.storage.k8s.io,resources=volumesnapshotclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cdi.kubevirt.io,resources=storageprofiles,verbs=get;list;watch
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=volumeattributesclasses,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch
+
+// Reconcile discovers the capabilities of a StorageClass and records them in the
+// WukongStorageProfile with the same name.
+func (r *WukongStorageProfileReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.V(1).Info("Reconciling WukongStorageProfile", "storageClass", req.Name)
+
+	// 1. 获取 StorageClass
+	var sc storagev1.StorageClass
+	if err := r.Get(ctx, req.NamespacedName, &sc); err != nil {
+		if apierrors.IsNotFound(err) {
+			// StorageClass 已删除，WukongStorageProfile 通过 OwnerReference 级联删除
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "unable to fetch StorageClass")
+		return ctrl.Result{}, err
+	}
+	if !sc.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	// 2. 确保同名的 WukongStorageProfile 存在
+	var profile vmv1alpha1.WukongStorageProfile
+	if err := r.Get(ctx, req.NamespacedName, &profile); err != nil {
+		if !apierrors.IsNotFound(err) {
+			logger.Error(err, "unable to fetch WukongStorageProfile")
+			return ctrl.Result{}, err
+		}
+		profile = vmv1alpha1.WukongStorageProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: sc.Name},
+			Spec:       vmv1alpha1.WukongStorageProfileSpec{StorageClassName: sc.Name},
+		}
+		if err := controllerutil.SetControllerReference(&sc, &profile, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+		logger.Info("Creating WukongStorageProfile", "storageClass", sc.Name)
+		if err := r.Create(ctx, &profile); err != nil {
+			logger.Error(err, "failed to create WukongStorageProfile")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// 3. 探测 StorageClass 的能力
+	status, err := storage.DiscoverStorageProfile(ctx, r.Client, &sc, profile.Spec)
+	if err != nil {
+		logger.Error(err, "failed to discover storage capabilities", "storageClass", sc.Name)
+		meta.SetStatusCondition(&profile.Status.Conditions, metav1.Condition{
+			Type:    "Ready",
+			Status:  metav1.ConditionFalse,
+			Reason:  "DiscoveryFailed",
+			Message: err.Error(),
+		})
+		r.Status().Update(ctx, &profile)
+		return ctrl.Result{RequeueAfter: time.Second * 30}, err
+	}
+
+	// 4. 更新状态
+	status.Conditions = profile.Status.Conditions
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionTrue,
+		Reason:  "CapabilitiesDiscovered",
+		Message: "Storage capabilities discovered for backend " + status.Backend,
+	})
+	profile.Status = status
+	if err := r.Status().Update(ctx, &profile); err != nil {
+		logger.Error(err, "unable to update WukongStorageProfile status")
+		return ctrl.Result{}, err
+	}
+
+	// 5. 删除不再被任何 PVC 使用的 Storage QoS VolumeAttributesClass
+	if err := storage.GarbageCollectVolumeAttributesClasses(ctx, r.Client, sc.Provisioner); err != nil {
+		logger.Error(err, "failed to garbage-collect VolumeAttributesClasses", "driver", sc.Provisioner)
+	}
+
+	// CSIDriver、VolumeSnapshotClass 和 CDI StorageProfile 不在 watch 范围内，定期重新探测
+	return ctrl.Result{RequeueAfter: time.Minute * 10}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *WukongStorageProfileReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&storagev1.StorageClass{}).
+		Owns(&vmv1alpha1.WukongStorageProfile{}).
+		Named("wukongstorageprofile").
+		Complete(r)
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+var _ = Describe("WukongStorageProfile Controller", func() {
+	Context("When reconciling a StorageClass", func() {
+		ctx := context.Background()
+
+		// createStorageClass 创建 StorageClass，并在测试结束时删除它及其 WukongStorageProfile
+		createStorageClass := func(sc *storagev1.StorageClass) {
+			Expect(k8sClient.Create(ctx, sc)).To(Succeed())
+			DeferCleanup(func() {
+				profile := &vmv1alpha1.WukongStorageProfile{}
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: sc.Name}, profile); err == nil {
+					Expect(k8sClient.Delete(ctx, profile)).To(Succeed())
+				}
+				Expect(k8sClient.Delete(ctx, sc)).To(Succeed())
+			})
+		}
+
+		reconcileProfile := func(name string) *vmv1alpha1.WukongStorageProfile {
+			controllerReconciler := &WukongStorageProfileReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			profile := &vmv1alpha1.WukongStorageProfile{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name}, profile)).To(Succeed())
+			return profile
+		}
+
+		It("should create a profile with the local-path capabilities", func() {
+			createStorageClass(&storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: "test-local-path"},
+				Provisioner: "rancher.io/local-path",
+			})
+
+			profile := reconcileProfile("test-local-path")
+			Expect(profile.Spec.StorageClassName).To(Equal("test-local-path"))
+			Expect(profile.OwnerReferences).To(ConsistOf(HaveField("Name", "test-local-path")))
+			Expect(profile.Status.Provisioner).To(Equal("rancher.io/local-path"))
+			Expect(profile.Status.Backend).To(Equal(vmv1alpha1.StorageBackendLocalPath))
+			Expect(profile.Status.CSI).To(BeFalse())
+			Expect(profile.Status.Capabilities.Expansion).To(BeFalse())
+			Expect(profile.Status.Capabilities.RWXBlock).To(BeFalse())
+			Expect(profile.Status.Capabilities.RWXFilesystem).To(BeFalse())
+			Expect(profile.Status.Capabilities.CloneStrategy).To(Equal("copy"))
+			Expect(profile.Status.Capabilities.Clone).To(BeFalse())
+			Expect(profile.Status.DefaultAccessModes).To(Equal([]string{"ReadWriteOnce"}))
+			Expect(profile.Status.DefaultVolumeMode).To(Equal("Filesystem"))
+		})
+
+		It("should discover a Ceph RBD CSI driver with expansion", func() {
+			driver := &storagev1.CSIDriver{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph.rbd.csi.ceph.com"}}
+			Expect(k8sClient.Create(ctx, driver)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, driver)).To(Succeed())
+			})
+			allowExpansion := true
+			waitForFirstConsumer := storagev1.VolumeBindingWaitForFirstConsumer
+			createStorageClass(&storagev1.StorageClass{
+				ObjectMeta:           metav1.ObjectMeta{Name: "test-ceph-block"},
+				Provisioner:          "rook-ceph.rbd.csi.ceph.com",
+				AllowVolumeExpansion: &allowExpansion,
+				VolumeBindingMode:    &waitForFirstConsumer,
+			})
+
+			profile := reconcileProfile("test-ceph-block")
+			Expect(profile.Status.Backend).To(Equal(vmv1alpha1.StorageBackendCephRBD))
+			Expect(profile.Status.CSI).To(BeTrue())
+			Expect(profile.Status.VolumeBindingMode).To(Equal("WaitForFirstConsumer"))
+			Expect(profile.Status.Capabilities.Expansion).To(BeTrue())
+			Expect(profile.Status.Capabilities.RWXBlock).To(BeTrue())
+			Expect(profile.Status.Capabilities.CloneStrategy).To(Equal("csi-clone"))
+			Expect(profile.Status.Capabilities.Clone).To(BeTrue())
+			Expect(profile.Status.DefaultAccessModes).To(Equal([]string{"ReadWriteMany"}))
+			Expect(profile.Status.DefaultVolumeMode).To(Equal("Block"))
+
+			By("Checking the Ready condition")
+			ready := meta.FindStatusCondition(profile.Status.Conditions, "Ready")
+			Expect(ready).NotTo(BeNil())
+			Expect(ready.Status).To(Equal(metav1.ConditionTrue))
+			Expect(ready.Reason).To(Equal("CapabilitiesDiscovered"))
+		})
+
+		It("should apply the defaults overridden in spec", func() {
+			createStorageClass(&storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: "test-ceph-override"},
+				Provisioner: "rook-ceph.rbd.csi.ceph.com",
+			})
+			Expect(k8sClient.Create(ctx, &vmv1alpha1.WukongStorageProfile{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-ceph-override"},
+				Spec: vmv1alpha1.WukongStorageProfileSpec{
+					StorageClassName:   "test-ceph-override",
+					DefaultAccessModes: []string{"ReadWriteOnce"},
+					DefaultVolumeMode:  "Filesystem",
+				},
+			})).To(Succeed())
+
+			profile := reconcileProfile("test-ceph-override")
+			Expect(profile.Status.Capabilities.RWXBlock).To(BeTrue())
+			Expect(profile.Status.DefaultAccessModes).To(Equal([]string{"ReadWriteOnce"}))
+			Expect(profile.Status.DefaultVolumeMode).To(Equal("Filesystem"))
+		})
+	})
+})
@@ -0,0 +1,161 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+	"github.com/kuihuar/novasphere/pkg/naming"
+)
+
+const (
+	// DefaultImageNamespace is the default namespace holding golden PVCs and DataSources
+	DefaultImageNamespace = "novasphere-images"
+
+	// LabelImage is the label holding the WukongImage name on golden DataVolumes and DataSources
+	// Names longer than 63 characters are truncated and hashed
+	LabelImage = "wukong.novasphere.dev/image"
+	// LabelStorageClass is the label holding the StorageClass name on golden DataVolumes and DataSources
+	// Names longer than 63 characters are truncated and hashed
+	LabelStorageClass = "wukong.novasphere.dev/storage-class"
+)
+
+var (
+	// ErrGoldenImageNotReady is returned when a disk should be cloned from a golden image that is still importing.
+	ErrGoldenImageNotReady = errors.New("golden image is not ready")
+	// ErrImageNotFound is returned when a catalog name does not reference an existing WukongImage.
+	ErrImageNotFound = errors.New("WukongImage not found")
+)
+
+// GetImage returns the WukongImage with the given name.
+// It returns nil if name cannot be a catalog name (e.g., an image URL) or if the WukongImage CRD
+// is not installed, and ErrImageNotFound if no such WukongImage exists: a name without "/" or ":"
+// is never imported from a registry, so a misspelled catalog name is reported instead.
+func GetImage(ctx context.Context, c client.Client, name string) (*vmv1alpha1.WukongImage, error) {
+	// URL 或者 registry 地址不可能是 catalog 名称，直接跳过，避免无意义的 API 请求
+	if name == "" || strings.ContainsAny(name, "/:") {
+		return nil, nil
+	}
+
+	img := &vmv1alpha1.WukongImage{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, img); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s (images outside the catalog need a URL, e.g. docker://registry.example.com/%s)", ErrImageNotFound, name, name)
+		}
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return img, nil
+}
+
+// BootImage returns the WukongImage used by the boot disk of the Wukong, or nil if it does not use one.
+// The boot disk image takes precedence over WukongSpec.OSImage.
+func BootImage(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong) (*vmv1alpha1.WukongImage, error) {
+	for _, disk := range vmp.Spec.Disks {
+		if disk.Boot && disk.Image != "" {
+			img, err := GetImage(ctx, c, disk.Image)
+			if err != nil || img != nil {
+				return img, err
+			}
+		}
+	}
+	return GetImage(ctx, c, vmp.Spec.OSImage)
+}
+
+// GoldenName returns the name of the golden DataVolume, PVC and DataSource of an image in a StorageClass.
+// A hash suffix keeps names of different images and StorageClasses apart when either name
+// contains a dash (e.g., "ubuntu" in "ceph-rbd" and "ubuntu-ceph" in "rbd").
+func GoldenName(imageName, storageClassName string) string {
+	return naming.Join(imageName, storageClassName)
+}
+
+// FindGolden returns the golden image status of the image in the given StorageClass, or nil if none.
+func FindGolden(img *vmv1alpha1.WukongImage, storageClassName string) *vmv1alpha1.GoldenImageStatus {
+	for i := range img.Status.GoldenImages {
+		if img.Status.GoldenImages[i].StorageClassName == storageClassName {
+			return &img.Status.GoldenImages[i]
+		}
+	}
+	return nil
+}
+
+// ResolveDisk applies the catalog image referenced by the disk, if any.
+// The returned disk has Image cleared and Source set to either a clone of the golden image
+// (when the image is pre-imported into the disk's StorageClass) or the image source itself.
+// It returns ErrGoldenImageNotReady if the golden image of the StorageClass is still importing.
+func ResolveDisk(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, disk vmv1alpha1.DiskConfig) (vmv1alpha1.DiskConfig, error) {
+	imageName := disk.Image
+	if imageName == "" && disk.Source == nil && disk.ExistingClaim == "" && disk.ContainerDisk == nil && disk.EmptyDisk == nil && disk.Boot {
+		// 启动盘未指定镜像时，使用 WukongSpec.OSImage
+		imageName = vmp.Spec.OSImage
+	}
+
+	img, err := GetImage(ctx, c, imageName)
+	if err != nil {
+		return disk, err
+	}
+	if img == nil {
+		return disk, nil
+	}
+
+	resolved := *disk.DeepCopy()
+	resolved.Image = ""
+	if resolved.Size == "" {
+		resolved.Size = img.Spec.DefaultSize
+	}
+
+	golden := FindGolden(img, disk.StorageClassName)
+	if golden != nil {
+		canClone, err := cloneSupported(ctx, c, disk.StorageClassName)
+		if err != nil {
+			return resolved, err
+		}
+		if !canClone {
+			// 克隆需要整盘拷贝（copy 策略），不比从镜像源导入更快，且会长时间占用 golden PVC
+			golden = nil
+		}
+	}
+	if golden == nil {
+		// 没有对应 StorageClass 的 golden image，直接从镜像源导入
+		resolved.Source = img.Spec.Source.DeepCopy()
+		return resolved, nil
+	}
+	if !golden.Ready {
+		return resolved, fmt.Errorf("%w: %s in StorageClass %s", ErrGoldenImageNotReady, img.Name, disk.StorageClassName)
+	}
+
+	resolved.Source = &vmv1alpha1.DiskSourceSpec{
+		DataSource: &vmv1alpha1.DataSourceReference{
+			Namespace: golden.Namespace,
+			Name:      golden.DataSourceName,
+		},
+	}
+	return resolved, nil
+}
+
+// cloneSupported 根据 WukongStorageProfile 判断 StorageClass 是否支持高效克隆（CSI clone 或快照）
+// 没有已发现的 profile 时假定支持克隆
+func cloneSupported(ctx context.Context, c client.Client, storageClassName string) (bool, error) {
+	if storageClassName == "" {
+		return true, nil
+	}
+	profile := &vmv1alpha1.WukongStorageProfile{}
+	if err := c.Get(ctx, client.ObjectKey{Name: storageClassName}, profile); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	if profile.Status.Provisioner == "" {
+		return true, nil
+	}
+	return profile.Status.Capabilities.Clone, nil
+}
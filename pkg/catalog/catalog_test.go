@@ -0,0 +1,87 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+func TestGoldenName(t *testing.T) {
+	tests := []struct {
+		imageName        string
+		storageClassName string
+	}{
+		{imageName: "ubuntu", storageClassName: "local"},
+		{imageName: "ubuntu", storageClassName: "ceph-rbd"},
+		{imageName: "ubuntu-ceph", storageClassName: "rbd"},
+		{imageName: "ubuntu", storageClassName: "fast.ssd"},
+		{imageName: "ubuntu", storageClassName: "fast-ssd"},
+		{imageName: strings.Repeat("u", 60), storageClassName: "ceph-rbd"},
+		{imageName: "ubuntu", storageClassName: strings.Repeat("s", 253)},
+	}
+
+	names := make(map[string]string, len(tests))
+	for _, tt := range tests {
+		key := tt.imageName + "/" + tt.storageClassName
+		got := GoldenName(tt.imageName, tt.storageClassName)
+		if errs := validation.IsDNS1123Subdomain(got); len(errs) > 0 {
+			t.Errorf("GoldenName(%q, %q) = %q is not a valid name: %v", tt.imageName, tt.storageClassName, got, errs)
+		}
+		if errs := validation.IsValidLabelValue(got); len(errs) > 0 {
+			t.Errorf("GoldenName(%q, %q) = %q is not a valid label value: %v", tt.imageName, tt.storageClassName, got, errs)
+		}
+		if other, ok := names[got]; ok {
+			t.Errorf("GoldenName() = %q for both %s and %s", got, other, key)
+		}
+		names[got] = key
+	}
+
+	if got := GoldenName("ubuntu", "local"); got != "ubuntu-local" {
+		t.Errorf("GoldenName(ubuntu, local) = %q, want ubuntu-local", got)
+	}
+}
+
+func TestGetImage(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := vmv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	img := &vmv1alpha1.WukongImage{ObjectMeta: metav1.ObjectMeta{Name: "ubuntu-22.04"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(img).Build()
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		{name: "ubuntu-22.04", want: "ubuntu-22.04"},
+		{name: ""},
+		{name: "docker://registry.example.com/ubuntu:22.04"},
+		{name: "registry.example.com/ubuntu"},
+		{name: "ubuntu:22.04"},
+		{name: "ubunut-22.04", wantErr: ErrImageNotFound},
+	}
+	for _, tt := range tests {
+		got, err := GetImage(ctx, c, tt.name)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("GetImage(%q) error = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		gotName := ""
+		if got != nil {
+			gotName = got.Name
+		}
+		if gotName != tt.want {
+			t.Errorf("GetImage(%q) = %q, want %q", tt.name, gotName, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package kubevirt
+
+import (
+	"fmt"
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+	"github.com/kuihuar/novasphere/pkg/storage"
+)
+
+// guestEncryptionScript 是在启动早期（bootcmd）解锁 LUKS 磁盘的脚本模板：
+// 从只读的密钥盘读取 passphrase；空白磁盘先格式化为 LUKS，已有其他数据的磁盘不做处理
+const guestEncryptionScript = `  - |
+    (
+      dev=/dev/disk/by-id/virtio-%[1]s
+      keydev=/dev/disk/by-id/virtio-%[2]s
+      name=%[3]s
+      [ -e "/dev/mapper/$name" ] && exit 0
+      mnt=$(mktemp -d)
+      mount -o ro "$keydev" "$mnt" || exit 1
+      if ! cryptsetup isLuks "$dev"; then
+        if [ -n "$(blkid -p "$dev")" ]; then
+          echo "novasphere: $dev is not blank, refusing to format it with LUKS" >&2
+        else
+          cryptsetup luksFormat --batch-mode --key-file "$mnt/%[4]s" "$dev"
+        fi
+      fi
+      cryptsetup isLuks "$dev" && cryptsetup open --key-file "$mnt/%[4]s" "$dev" "$name"
+      umount "$mnt"
+      rmdir "$mnt"
+    )
+`
+
+// guestEncryptedVolumes 返回需要在 guest 内用 LUKS 加密的卷
+func guestEncryptedVolumes(volumes []vmv1alpha1.VolumeStatus) []vmv1alpha1.VolumeStatus {
+	encrypted := make([]vmv1alpha1.VolumeStatus, 0)
+	for _, vol := range volumes {
+		if vol.EncryptionMode == vmv1alpha1.EncryptionModeGuest && vol.EncryptionKeySecret != "" {
+			encrypted = append(encrypted, vol)
+		}
+	}
+	return encrypted
+}
+
+// encryptionKeyVolumeName 返回 Guest 加密磁盘的密钥盘名称
+func encryptionKeyVolumeName(volumeName string) string {
+	return "luks-" + volumeName
+}
+
+// buildEncryptionKeyDisks 为 Guest 加密磁盘构建只读的密钥盘（由 Secret 提供）
+func buildEncryptionKeyDisks(volumes []vmv1alpha1.VolumeStatus) ([]kubevirtv1.Disk, []kubevirtv1.Volume) {
+	encrypted := guestEncryptedVolumes(volumes)
+	disks := make([]kubevirtv1.Disk, 0, len(encrypted))
+	volList := make([]kubevirtv1.Volume, 0, len(encrypted))
+	for _, vol := range encrypted {
+		name := encryptionKeyVolumeName(vol.Name)
+		disks = append(disks, kubevirtv1.Disk{
+			Name:   name,
+			Serial: DiskSerial(name),
+			DiskDevice: kubevirtv1.DiskDevice{
+				Disk: &kubevirtv1.DiskTarget{
+					Bus:      "virtio",
+					ReadOnly: true,
+				},
+			},
+		})
+		volList = append(volList, kubevirtv1.Volume{
+			Name: name,
+			VolumeSource: kubevirtv1.VolumeSource{
+				Secret: &kubevirtv1.SecretVolumeSource{
+					SecretName: vol.EncryptionKeySecret,
+				},
+			},
+		})
+	}
+	return disks, volList
+}
+
+// guestEncryptionBootCmds 返回在每次启动时格式化并解锁 Guest 加密磁盘的 bootcmd 条目，
+// 解锁后的磁盘为 /dev/mapper/<磁盘名称>
+func guestEncryptionBootCmds(volumes []vmv1alpha1.VolumeStatus) string {
+	encrypted := guestEncryptedVolumes(volumes)
+	if len(encrypted) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, vol := range encrypted {
+		fmt.Fprintf(&b, guestEncryptionScript, DiskSerial(vol.Name), DiskSerial(encryptionKeyVolumeName(vol.Name)), vol.Name, storage.EncryptionKeyPassphrase)
+	}
+	return b.String()
+}
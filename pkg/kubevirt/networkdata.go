@@ -0,0 +1,280 @@
+package kubevirt
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+	"github.com/kuihuar/novasphere/pkg/network"
+)
+
+// secondaryRouteMetric 是非主网络默认路由的起始 metric，按网络顺序递增
+// 高于 DHCP 默认路由的 metric（systemd-networkd 为 1024），默认路由始终经过 Pod 网络或主网络
+const secondaryRouteMetric = 2000
+
+// netplanConfig 是 cloud-init 网络配置 version 2（netplan 格式）
+type netplanConfig struct {
+	Version   int                        `json:"version"`
+	Ethernets map[string]netplanEthernet `json:"ethernets"`
+}
+
+// netplanEthernet 是 netplan 中的一个以太网设备，按 MAC 地址匹配 guest 网卡
+type netplanEthernet struct {
+	Match          netplanMatch          `json:"match"`
+	DHCP4          bool                  `json:"dhcp4,omitempty"`
+	DHCP4Overrides *netplanDHCPOverrides `json:"dhcp4-overrides,omitempty"`
+	DHCP6          bool                  `json:"dhcp6,omitempty"`
+	DHCP6Overrides *netplanDHCPOverrides `json:"dhcp6-overrides,omitempty"`
+	AcceptRA       *bool                 `json:"accept-ra,omitempty"`
+	Addresses      []string              `json:"addresses,omitempty"`
+	Routes         []netplanRoute        `json:"routes,omitempty"`
+	Nameservers    *netplanNameservers   `json:"nameservers,omitempty"`
+	MTU            int                   `json:"mtu,omitempty"`
+}
+
+// netplanDHCPOverrides 覆盖 DHCP 获取的路由的 metric
+type netplanDHCPOverrides struct {
+	RouteMetric int32 `json:"route-metric"`
+}
+
+// netplanMatch 按 MAC 地址匹配网卡，guest 中的网卡名称与 KubeVirt 接口名称无关
+type netplanMatch struct {
+	MACAddress string `json:"macaddress"`
+}
+
+// netplanRoute 是 netplan 路由，默认网关也以路由表示（gateway4/gateway6 已废弃）
+type netplanRoute struct {
+	To     string `json:"to"`
+	Via    string `json:"via"`
+	Metric *int32 `json:"metric,omitempty"`
+}
+
+// netplanNameservers 是 netplan DNS 配置
+type netplanNameservers struct {
+	Addresses []string `json:"addresses,omitempty"`
+	Search    []string `json:"search,omitempty"`
+}
+
+// buildNetworkData 构建 cloud-init NoCloud 的网络配置（networkData）
+// 没有网络需要在 guest 中配置时返回空字符串，guest 使用 cloud-init 默认的 DHCP 配置
+func buildNetworkData(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, netConfigs []vmv1alpha1.NetworkConfig, networks []vmv1alpha1.NetworkStatus) (string, error) {
+	// 加载 pool 模式网络使用的 IPPool
+	pools := make(map[string]*vmv1alpha1.IPPool)
+	for _, netCfg := range netConfigs {
+		if netCfg.IPConfig == nil || netCfg.IPConfig.Mode != vmv1alpha1.IPModePool {
+			continue
+		}
+		pool := &vmv1alpha1.IPPool{}
+		if err := c.Get(ctx, client.ObjectKey{Name: netCfg.IPConfig.Pool}, pool); err != nil {
+			return "", fmt.Errorf("network %s: failed to get IPPool %s: %w", netCfg.Name, netCfg.IPConfig.Pool, err)
+		}
+		pools[pool.Name] = pool
+	}
+
+	podMAC := ""
+	if network.PodNetworkEnabled(vmp.Spec.PodNetwork) {
+		podMAC = vmp.Status.PodNetworkMACAddress
+	}
+	return renderNetworkData(podMAC, netConfigs, networks, pools)
+}
+
+// renderNetworkData 根据网络配置生成 netplan version 2 网络配置
+// 每个网卡按 operator 分配的 MAC 地址匹配；Pod 网络使用 DHCP
+// 只有主网络（或 DHCP 配置的 Pod 网络）提供默认路由，其他网络的默认路由使用更高的 metric
+func renderNetworkData(podMAC string, netConfigs []vmv1alpha1.NetworkConfig, networks []vmv1alpha1.NetworkStatus, pools map[string]*vmv1alpha1.IPPool) (string, error) {
+	ethernets := make(map[string]netplanEthernet)
+	for _, net := range networks {
+		if net.NADName == "" || net.MACAddress == "" {
+			continue
+		}
+		netCfg := findNetworkConfig(netConfigs, net.Name)
+		if netCfg == nil || (netCfg.IPConfig == nil && netCfg.MTU == nil) {
+			// 未配置 IP 和 MTU 的网卡保持未配置状态
+			continue
+		}
+		ethernet, err := guestEthernet(netCfg, net, pools, defaultRouteMetric(netConfigs, netCfg))
+		if err != nil {
+			return "", fmt.Errorf("network %s: %w", net.Name, err)
+		}
+		// 设备 ID 使用 KubeVirt 接口名称，在 VM 内唯一
+		ethernets[net.NADName] = ethernet
+	}
+	if len(ethernets) == 0 {
+		return "", nil
+	}
+
+	// 提供 networkData 后 cloud-init 不再对第一个网卡使用 DHCP，需要显式配置 Pod 网络
+	if podMAC != "" {
+		ethernets[network.PodNetworkName] = netplanEthernet{
+			Match: netplanMatch{MACAddress: podMAC},
+			DHCP4: true,
+		}
+	}
+
+	data, err := yaml.Marshal(netplanConfig{Version: 2, Ethernets: ethernets})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// guestEthernet 生成一个网卡的 netplan 配置：IPv4/IPv6 地址、路由、DNS 和 MTU
+// metric 不为 nil 时，网关和 DHCP 提供的默认路由使用该 metric
+func guestEthernet(netCfg *vmv1alpha1.NetworkConfig, net vmv1alpha1.NetworkStatus, pools map[string]*vmv1alpha1.IPPool, metric *int32) (netplanEthernet, error) {
+	ethernet := netplanEthernet{
+		Match: netplanMatch{MACAddress: net.MACAddress},
+	}
+	if netCfg.MTU != nil {
+		ethernet.MTU = *netCfg.MTU
+	}
+
+	ipConfig := netCfg.IPConfig
+	if ipConfig == nil {
+		return ethernet, nil
+	}
+
+	if ipConfig.Mode == vmv1alpha1.IPModeDHCP {
+		ethernet.DHCP4 = true
+		ethernet.DHCP4Overrides = dhcpOverrides(metric)
+	} else {
+		address, gateway, dnsServers := guestIPConfig(netCfg, net, pools[ipConfig.Pool])
+		if address != "" {
+			if _, err := netip.ParsePrefix(address); err != nil {
+				return ethernet, fmt.Errorf("invalid address %q: %w", address, err)
+			}
+			ethernet.Addresses = append(ethernet.Addresses, address)
+		}
+		if gateway != "" {
+			route, err := defaultRoute(gateway, metric)
+			if err != nil {
+				return ethernet, err
+			}
+			ethernet.Routes = append(ethernet.Routes, route)
+		}
+		if len(dnsServers) > 0 {
+			ethernet.Nameservers = &netplanNameservers{Addresses: dnsServers}
+		}
+	}
+
+	if ipv6 := ipConfig.IPv6; ipv6 != nil {
+		switch ipv6.Mode {
+		case vmv1alpha1.IPv6ModeDHCP:
+			ethernet.DHCP6 = true
+			ethernet.DHCP6Overrides = dhcpOverrides(metric)
+		case vmv1alpha1.IPv6ModeSLAAC:
+			acceptRA := true
+			ethernet.AcceptRA = &acceptRA
+		case vmv1alpha1.IPv6ModeStatic:
+			if ipv6.Address != nil {
+				prefix, err := netip.ParsePrefix(*ipv6.Address)
+				if err != nil || !prefix.Addr().Is6() {
+					return ethernet, fmt.Errorf("invalid IPv6 address %q", *ipv6.Address)
+				}
+				ethernet.Addresses = append(ethernet.Addresses, *ipv6.Address)
+			}
+			if ipv6.Gateway != nil {
+				route, err := defaultRoute(*ipv6.Gateway, metric)
+				if err != nil {
+					return ethernet, err
+				}
+				ethernet.Routes = append(ethernet.Routes, route)
+			}
+		}
+	}
+
+	for _, r := range ipConfig.Routes {
+		if _, err := netip.ParsePrefix(r.To); err != nil {
+			return ethernet, fmt.Errorf("invalid route destination %q: %w", r.To, err)
+		}
+		if _, err := netip.ParseAddr(r.Via); err != nil {
+			return ethernet, fmt.Errorf("invalid route next hop %q: %w", r.Via, err)
+		}
+		ethernet.Routes = append(ethernet.Routes, netplanRoute{To: r.To, Via: r.Via, Metric: r.Metric})
+	}
+
+	if len(ipConfig.SearchDomains) > 0 {
+		if ethernet.Nameservers == nil {
+			ethernet.Nameservers = &netplanNameservers{}
+		}
+		ethernet.Nameservers.Search = ipConfig.SearchDomains
+	}
+	return ethernet, nil
+}
+
+// defaultRoute 返回经过网关的默认路由，根据网关地址族选择 0.0.0.0/0 或 ::/0
+func defaultRoute(gateway string, metric *int32) (netplanRoute, error) {
+	addr, err := netip.ParseAddr(gateway)
+	if err != nil {
+		return netplanRoute{}, fmt.Errorf("invalid gateway %q: %w", gateway, err)
+	}
+	if addr.Is4() {
+		return netplanRoute{To: "0.0.0.0/0", Via: gateway, Metric: metric}, nil
+	}
+	return netplanRoute{To: "::/0", Via: gateway, Metric: metric}, nil
+}
+
+// defaultRouteMetric 返回网络默认路由的 metric：主网络为 nil（使用默认值），
+// 其他网络为 secondaryRouteMetric 加上网络的序号，避免与 Pod 网络或主网络的默认路由竞争
+func defaultRouteMetric(netConfigs []vmv1alpha1.NetworkConfig, netCfg *vmv1alpha1.NetworkConfig) *int32 {
+	if netCfg.Primary {
+		return nil
+	}
+	metric := int32(secondaryRouteMetric)
+	for i := range netConfigs {
+		if netConfigs[i].Name == netCfg.Name {
+			metric += int32(i)
+			break
+		}
+	}
+	return &metric
+}
+
+// dhcpOverrides 返回设置 DHCP 路由 metric 的配置，metric 为 nil 时不覆盖
+func dhcpOverrides(metric *int32) *netplanDHCPOverrides {
+	if metric == nil {
+		return nil
+	}
+	return &netplanDHCPOverrides{RouteMetric: *metric}
+}
+
+// guestIPConfig 返回网络在 guest 中配置的地址（带前缀长度）、网关和 DNS
+// static 模式使用 IPConfig；pool 模式使用分配的地址，网关和 DNS 默认取自 IPPool
+func guestIPConfig(netCfg *vmv1alpha1.NetworkConfig, net vmv1alpha1.NetworkStatus, pool *vmv1alpha1.IPPool) (string, string, []string) {
+	ipConfig := netCfg.IPConfig
+	if ipConfig == nil {
+		return "", "", nil
+	}
+
+	var address, gateway string
+	if ipConfig.Gateway != nil {
+		gateway = *ipConfig.Gateway
+	}
+	dnsServers := ipConfig.DNSServers
+
+	switch ipConfig.Mode {
+	case vmv1alpha1.IPModeStatic:
+		if ipConfig.Address != nil {
+			address = *ipConfig.Address
+		}
+	case vmv1alpha1.IPModePool:
+		if net.IPAddress == "" || pool == nil {
+			return "", "", nil
+		}
+		prefix, err := netip.ParsePrefix(pool.Spec.CIDR)
+		if err != nil {
+			return "", "", nil
+		}
+		address = fmt.Sprintf("%s/%d", net.IPAddress, prefix.Bits())
+		if gateway == "" {
+			gateway = pool.Spec.Gateway
+		}
+		if len(dnsServers) == 0 {
+			dnsServers = pool.Spec.DNSServers
+		}
+	}
+	return address, gateway, dnsServers
+}
@@ -0,0 +1,373 @@
+package kubevirt
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+func intPtr(i int) *int       { return &i }
+func int32Ptr(i int32) *int32 { return &i }
+func strPtr(s string) *string { return &s }
+
+// netplanSchema 是 netplan version 2 中 operator 使用的 ethernets 子集，
+// 严格解析时出现未知或已废弃的字段（如 gateway4）即报错
+// 字段名按 netplan 文档独立定义，不复用生成配置的类型，生成代码中的 JSON 标签错误会被发现
+type netplanSchema struct {
+	Version   int `json:"version"`
+	Ethernets map[string]struct {
+		Match struct {
+			MACAddress string `json:"macaddress"`
+		} `json:"match"`
+		DHCP4          *bool                       `json:"dhcp4"`
+		DHCP4Overrides *netplanSchemaDHCPOverrides `json:"dhcp4-overrides"`
+		DHCP6          *bool                       `json:"dhcp6"`
+		DHCP6Overrides *netplanSchemaDHCPOverrides `json:"dhcp6-overrides"`
+		AcceptRA       *bool                       `json:"accept-ra"`
+		Addresses      []string                    `json:"addresses"`
+		Routes         []struct {
+			To     string `json:"to"`
+			Via    string `json:"via"`
+			Metric *int   `json:"metric"`
+		} `json:"routes"`
+		Nameservers *struct {
+			Addresses []string `json:"addresses"`
+			Search    []string `json:"search"`
+		} `json:"nameservers"`
+		MTU *int `json:"mtu"`
+	} `json:"ethernets"`
+}
+
+// netplanSchemaDHCPOverrides 是 netplan 中 dhcp4-overrides/dhcp6-overrides 的子集
+type netplanSchemaDHCPOverrides struct {
+	RouteMetric *int `json:"route-metric"`
+}
+
+// metricValue 返回路由的 metric，未设置时为 0
+func metricValue(metric *int) int {
+	if metric == nil {
+		return 0
+	}
+	return *metric
+}
+
+// validateNetplan 按 netplan version 2 的约束校验网络配置
+func validateNetplan(data string) error {
+	var config netplanSchema
+	if err := yaml.UnmarshalStrict([]byte(data), &config); err != nil {
+		return err
+	}
+	if config.Version != 2 {
+		return fmt.Errorf("version = %d, want 2", config.Version)
+	}
+	if len(config.Ethernets) == 0 {
+		return fmt.Errorf("no ethernets")
+	}
+	macs := make(map[string]string)
+	defaultRoutes := make(map[string]string)
+	for id, eth := range config.Ethernets {
+		if _, err := net.ParseMAC(eth.Match.MACAddress); err != nil {
+			return fmt.Errorf("%s: invalid match.macaddress %q", id, eth.Match.MACAddress)
+		}
+		if other, ok := macs[eth.Match.MACAddress]; ok {
+			return fmt.Errorf("%s: match.macaddress is also used by %s", id, other)
+		}
+		macs[eth.Match.MACAddress] = id
+		if eth.DHCP4Overrides != nil && (eth.DHCP4 == nil || !*eth.DHCP4) {
+			return fmt.Errorf("%s: dhcp4-overrides without dhcp4", id)
+		}
+		if eth.DHCP6Overrides != nil && (eth.DHCP6 == nil || !*eth.DHCP6) {
+			return fmt.Errorf("%s: dhcp6-overrides without dhcp6", id)
+		}
+		for _, overrides := range []*netplanSchemaDHCPOverrides{eth.DHCP4Overrides, eth.DHCP6Overrides} {
+			if overrides != nil && (overrides.RouteMetric == nil || *overrides.RouteMetric < 0) {
+				return fmt.Errorf("%s: dhcp overrides need a route-metric", id)
+			}
+		}
+		for _, address := range eth.Addresses {
+			if _, err := netip.ParsePrefix(address); err != nil {
+				return fmt.Errorf("%s: address %q is not in CIDR notation", id, address)
+			}
+		}
+		for _, route := range eth.Routes {
+			to, err := netip.ParsePrefix(route.To)
+			if err != nil {
+				return fmt.Errorf("%s: route to %q is not in CIDR notation", id, route.To)
+			}
+			via, err := netip.ParseAddr(route.Via)
+			if err != nil {
+				return fmt.Errorf("%s: route via %q is not an address", id, route.Via)
+			}
+			if to.Addr().Is4() != via.Is4() {
+				return fmt.Errorf("%s: route %s via %s mixes address families", id, route.To, route.Via)
+			}
+			// 相同 metric 的多个默认路由无法确定 guest 使用哪个网关
+			if to.Bits() == 0 {
+				key := fmt.Sprintf("%s/%v", route.To, metricValue(route.Metric))
+				if other, ok := defaultRoutes[key]; ok {
+					return fmt.Errorf("%s: default route %s has the same metric as the one of %s", id, route.To, other)
+				}
+				defaultRoutes[key] = id
+			}
+		}
+		if eth.Nameservers != nil {
+			for _, address := range eth.Nameservers.Addresses {
+				if _, err := netip.ParseAddr(address); err != nil {
+					return fmt.Errorf("%s: nameserver %q is not an address", id, address)
+				}
+			}
+		}
+		if eth.MTU != nil && (*eth.MTU < 68 || *eth.MTU > 65535) {
+			return fmt.Errorf("%s: mtu %d out of range", id, *eth.MTU)
+		}
+	}
+	return nil
+}
+
+func TestRenderNetworkData(t *testing.T) {
+	pool := &vmv1alpha1.IPPool{
+		Spec: vmv1alpha1.IPPoolSpec{
+			CIDR:       "10.30.0.0/24",
+			Gateway:    "10.30.0.1",
+			DNSServers: []string{"10.30.0.53"},
+		},
+	}
+	pool.Name = "tenant-pool"
+
+	tests := []struct {
+		golden     string
+		podMAC     string
+		netConfigs []vmv1alpha1.NetworkConfig
+		networks   []vmv1alpha1.NetworkStatus
+	}{
+		{
+			golden: "static-ipv4",
+			podMAC: "02:4e:53:00:00:01",
+			netConfigs: []vmv1alpha1.NetworkConfig{{
+				Name: "tenant",
+				Type: "bridge",
+				MTU:  intPtr(9000),
+				IPConfig: &vmv1alpha1.IPConfigSpec{
+					Mode:          vmv1alpha1.IPModeStatic,
+					Address:       strPtr("192.168.100.10/24"),
+					Gateway:       strPtr("192.168.100.1"),
+					DNSServers:    []string{"192.168.100.53"},
+					SearchDomains: []string{"tenant.example.com"},
+					Routes: []vmv1alpha1.RouteSpec{
+						{To: "10.20.0.0/16", Via: "192.168.100.254", Metric: int32Ptr(100)},
+					},
+				},
+			}},
+			networks: []vmv1alpha1.NetworkStatus{
+				{Name: "tenant", NADName: "vm-tenant-nad", MACAddress: "02:4e:53:00:00:02"},
+			},
+		},
+		{
+			golden: "dhcp-dual-stack",
+			netConfigs: []vmv1alpha1.NetworkConfig{
+				{
+					Name:     "dhcp",
+					Type:     "bridge",
+					IPConfig: &vmv1alpha1.IPConfigSpec{Mode: vmv1alpha1.IPModeDHCP, IPv6: &vmv1alpha1.IPv6ConfigSpec{Mode: vmv1alpha1.IPv6ModeDHCP}},
+				},
+				{
+					Name:     "slaac",
+					Type:     "macvlan",
+					IPConfig: &vmv1alpha1.IPConfigSpec{Mode: vmv1alpha1.IPModeDHCP, IPv6: &vmv1alpha1.IPv6ConfigSpec{Mode: vmv1alpha1.IPv6ModeSLAAC}},
+				},
+			},
+			networks: []vmv1alpha1.NetworkStatus{
+				{Name: "dhcp", NADName: "vm-dhcp-nad", MACAddress: "02:4e:53:00:00:03"},
+				{Name: "slaac", NADName: "vm-slaac-nad", MACAddress: "02:4e:53:00:00:04"},
+			},
+		},
+		{
+			golden: "static-dual-stack",
+			netConfigs: []vmv1alpha1.NetworkConfig{{
+				Name: "tenant",
+				Type: "bridge",
+				IPConfig: &vmv1alpha1.IPConfigSpec{
+					Mode:    vmv1alpha1.IPModeStatic,
+					Address: strPtr("192.168.100.10/24"),
+					Gateway: strPtr("192.168.100.1"),
+					IPv6: &vmv1alpha1.IPv6ConfigSpec{
+						Mode:    vmv1alpha1.IPv6ModeStatic,
+						Address: strPtr("fd00:100::10/64"),
+						Gateway: strPtr("fd00:100::1"),
+					},
+				},
+			}},
+			networks: []vmv1alpha1.NetworkStatus{
+				{Name: "tenant", NADName: "vm-tenant-nad", MACAddress: "02:4e:53:00:00:05"},
+			},
+		},
+		{
+			golden: "primary",
+			netConfigs: []vmv1alpha1.NetworkConfig{
+				{
+					Name:    "tenant",
+					Type:    "bridge",
+					Primary: true,
+					IPConfig: &vmv1alpha1.IPConfigSpec{
+						Mode:    vmv1alpha1.IPModeStatic,
+						Address: strPtr("192.168.100.10/24"),
+						Gateway: strPtr("192.168.100.1"),
+					},
+				},
+				{
+					Name: "storage",
+					Type: "bridge",
+					IPConfig: &vmv1alpha1.IPConfigSpec{
+						Mode:    vmv1alpha1.IPModeStatic,
+						Address: strPtr("10.50.0.10/24"),
+						Gateway: strPtr("10.50.0.1"),
+					},
+				},
+				{
+					Name:     "backup",
+					Type:     "bridge",
+					IPConfig: &vmv1alpha1.IPConfigSpec{Mode: vmv1alpha1.IPModeDHCP},
+				},
+			},
+			networks: []vmv1alpha1.NetworkStatus{
+				{Name: "tenant", NADName: "vm-tenant-nad", MACAddress: "02:4e:53:00:00:0d"},
+				{Name: "storage", NADName: "vm-storage-nad", MACAddress: "02:4e:53:00:00:0e"},
+				{Name: "backup", NADName: "vm-backup-nad", MACAddress: "02:4e:53:00:00:0f"},
+			},
+		},
+		{
+			golden: "pool",
+			podMAC: "02:4e:53:00:00:06",
+			netConfigs: []vmv1alpha1.NetworkConfig{
+				{
+					Name:     "tenant",
+					Type:     "bridge",
+					IPConfig: &vmv1alpha1.IPConfigSpec{Mode: vmv1alpha1.IPModePool, Pool: "tenant-pool"},
+				},
+				{
+					// 未配置 IP 的网卡不写入网络配置
+					Name: "storage",
+					Type: "bridge",
+				},
+			},
+			networks: []vmv1alpha1.NetworkStatus{
+				{Name: "tenant", NADName: "vm-tenant-nad", MACAddress: "02:4e:53:00:00:07", IPAddress: "10.30.0.20"},
+				{Name: "storage", NADName: "vm-storage-nad", MACAddress: "02:4e:53:00:00:08"},
+			},
+		},
+	}
+
+	pools := map[string]*vmv1alpha1.IPPool{pool.Name: pool}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			got, err := renderNetworkData(tt.podMAC, tt.netConfigs, tt.networks, pools)
+			if err != nil {
+				t.Fatalf("renderNetworkData() error = %v", err)
+			}
+			if err := validateNetplan(got); err != nil {
+				t.Fatalf("renderNetworkData() is not valid netplan v2: %v\n%s", err, got)
+			}
+
+			path := filepath.Join("testdata", "networkdata-"+tt.golden+".yaml")
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal([]byte(got), want) {
+				t.Errorf("renderNetworkData() mismatch with %s:\ngot:\n%s\nwant:\n%s", path, got, want)
+			}
+		})
+	}
+}
+
+func TestRenderNetworkDataWithoutGuestConfig(t *testing.T) {
+	netConfigs := []vmv1alpha1.NetworkConfig{{Name: "storage", Type: "bridge"}}
+	networks := []vmv1alpha1.NetworkStatus{{Name: "storage", NADName: "vm-storage-nad", MACAddress: "02:4e:53:00:00:09"}}
+
+	// 没有网卡需要配置时不生成网络配置，guest 使用 cloud-init 默认的 DHCP
+	got, err := renderNetworkData("02:4e:53:00:00:0a", netConfigs, networks, nil)
+	if err != nil {
+		t.Fatalf("renderNetworkData() error = %v", err)
+	}
+	if got != "" {
+		t.Errorf("renderNetworkData() = %q, want empty", got)
+	}
+}
+
+func TestRenderNetworkDataInvalidRoute(t *testing.T) {
+	netConfigs := []vmv1alpha1.NetworkConfig{{
+		Name: "tenant",
+		Type: "bridge",
+		IPConfig: &vmv1alpha1.IPConfigSpec{
+			Mode:   vmv1alpha1.IPModeDHCP,
+			Routes: []vmv1alpha1.RouteSpec{{To: "10.20.0.0", Via: "192.168.100.254"}},
+		},
+	}}
+	networks := []vmv1alpha1.NetworkStatus{{Name: "tenant", NADName: "vm-tenant-nad", MACAddress: "02:4e:53:00:00:0b"}}
+
+	if _, err := renderNetworkData("", netConfigs, networks, nil); err == nil {
+		t.Error("renderNetworkData() expected error for a route destination without prefix length")
+	}
+}
+
+func TestBuildNetworkDataMissingPool(t *testing.T) {
+	vmp := &vmv1alpha1.Wukong{ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "vm"}}
+	netConfigs := []vmv1alpha1.NetworkConfig{{
+		Name:     "tenant",
+		Type:     "bridge",
+		IPConfig: &vmv1alpha1.IPConfigSpec{Mode: vmv1alpha1.IPModePool, Pool: "missing"},
+	}}
+	networks := []vmv1alpha1.NetworkStatus{{Name: "tenant", NADName: "vm-tenant-nad", MACAddress: "02:4e:53:00:00:10", IPAddress: "10.30.0.53/24"}}
+
+	// 读取 IPPool 失败时不能生成缺少地址配置的网络数据
+	if _, err := buildNetworkData(context.Background(), newFakeClient(t), vmp, netConfigs, networks); err == nil {
+		t.Error("buildNetworkData() expected error for a missing IPPool")
+	}
+}
+
+func TestValidateNetplanRejectsUnknownOverrides(t *testing.T) {
+	data := `version: 2
+ethernets:
+  tenant:
+    match:
+      macaddress: "02:4e:53:00:00:0c"
+    dhcp4: true
+    dhcp4-overrides:
+      route_metric: 2000
+`
+	if err := validateNetplan(data); err == nil {
+		t.Error("validateNetplan() expected error for a misspelled dhcp4-overrides key")
+	}
+}
+
+func TestValidateNetplanRejectsGateway4(t *testing.T) {
+	data := `version: 2
+ethernets:
+  tenant:
+    match:
+      macaddress: "02:4e:53:00:00:0c"
+    addresses: [192.168.100.10/24]
+    gateway4: 192.168.100.1
+`
+	if err := validateNetplan(data); err == nil {
+		t.Error("validateNetplan() expected error for deprecated gateway4")
+	}
+}
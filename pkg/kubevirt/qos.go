@@ -0,0 +1,254 @@
+package kubevirt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+const (
+	// DefaultSidecarShimImage is the default KubeVirt hook sidecar image running the IO tuning script
+	// It should match the KubeVirt version of the cluster
+	DefaultSidecarShimImage = "quay.io/kubevirt/sidecar-shim:v1.2.0"
+
+	// SidecarFeatureGate is the KubeVirt feature gate required by hook sidecars
+	SidecarFeatureGate = "Sidecar"
+
+	// hookSidecarsAnnotation is the VMI annotation declaring KubeVirt hook sidecars
+	hookSidecarsAnnotation = "hooks.kubevirt.io/hookSidecars"
+	// iotuneScriptKey is the ConfigMap key holding the IO tuning script
+	iotuneScriptKey = "onDefineDomain"
+)
+
+// iotuneHookScript 是 onDefineDomain hook 脚本模板：
+// 按 KubeVirt 磁盘别名（ua-<磁盘名称>）为磁盘添加 libvirt <iotune> 限速配置
+const iotuneHookScript = `#!/usr/bin/env python3
+import json
+import sys
+import xml.etree.ElementTree as ET
+
+LIMITS = json.loads(%q)
+
+ET.register_namespace("qemu", "http://libvirt.org/schemas/domain/qemu/1.0")
+root = ET.fromstring(sys.argv[sys.argv.index("--domain") + 1])
+for disk in root.findall("./devices/disk"):
+    alias = disk.find("alias")
+    if alias is None:
+        continue
+    name = alias.get("name", "")
+    if name.startswith("ua-"):
+        name = name[3:]
+    if name not in LIMITS:
+        continue
+    iotune = disk.find("iotune")
+    if iotune is not None:
+        disk.remove(iotune)
+    iotune = ET.SubElement(disk, "iotune")
+    for key, value in LIMITS[name]:
+        ET.SubElement(iotune, key).text = value
+sys.stdout.write(ET.tostring(root, encoding="unicode"))
+`
+
+// hookSidecar 是 hooks.kubevirt.io/hookSidecars 注解中的一个 sidecar
+type hookSidecar struct {
+	Args      []string              `json:"args"`
+	Image     string                `json:"image"`
+	ConfigMap *hookSidecarConfigMap `json:"configMap"`
+}
+
+// hookSidecarConfigMap 描述 sidecar-shim 从 ConfigMap 加载的 hook 脚本
+type hookSidecarConfigMap struct {
+	Name     string `json:"name"`
+	Key      string `json:"key"`
+	HookPath string `json:"hookPath"`
+}
+
+// VMOptions configures how VirtualMachines are built.
+type VMOptions struct {
+	// SidecarShimImage is the hook sidecar image running the IO tuning script; DefaultSidecarShimImage if empty
+	SidecarShimImage string
+
+	// HookSidecars indicates whether the KubeVirt Sidecar feature gate is enabled
+	// Hypervisor QoS is not applied without it, since KubeVirt rejects VMs declaring hook sidecars
+	HookSidecars bool
+}
+
+// SidecarFeatureGateEnabled reports whether the Sidecar feature gate is enabled in the KubeVirt configuration.
+func SidecarFeatureGateEnabled(ctx context.Context, c client.Client) (bool, error) {
+	list := &kubevirtv1.KubeVirtList{}
+	if err := c.List(ctx, list); err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, kv := range list.Items {
+		devConfig := kv.Spec.Configuration.DeveloperConfiguration
+		if devConfig == nil {
+			continue
+		}
+		for _, gate := range devConfig.FeatureGates {
+			if gate == SidecarFeatureGate {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// HypervisorQoSRequested reports whether any volume has Hypervisor QoS limits.
+func HypervisorQoSRequested(volumes []vmv1alpha1.VolumeStatus) bool {
+	return len(buildIOTuneLimits(volumes)) > 0
+}
+
+// IOTuneConfigMapName returns the name of the ConfigMap holding the IO tuning hook of a Wukong.
+func IOTuneConfigMapName(vmp *vmv1alpha1.Wukong) string {
+	return vmp.Name + "-iotune"
+}
+
+// ReconcileIOTuneHook creates or updates the ConfigMap holding the IO tuning hook script of the disks
+// with Hypervisor QoS, or deletes it if no disk has Hypervisor QoS.
+func ReconcileIOTuneHook(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, volumes []vmv1alpha1.VolumeStatus) error {
+	logger := log.FromContext(ctx)
+
+	cm := &corev1.ConfigMap{}
+	key := client.ObjectKey{Namespace: vmp.Namespace, Name: IOTuneConfigMapName(vmp)}
+	err := c.Get(ctx, key, cm)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	limits := buildIOTuneLimits(volumes)
+	if len(limits) == 0 {
+		if exists {
+			logger.Info("Deleting IO tuning hook ConfigMap", "name", key.Name)
+			return client.IgnoreNotFound(c.Delete(ctx, cm))
+		}
+		return nil
+	}
+
+	limitsJSON, err := json.Marshal(limits)
+	if err != nil {
+		return err
+	}
+	data := map[string]string{
+		iotuneScriptKey: fmt.Sprintf(iotuneHookScript, string(limitsJSON)),
+	}
+
+	if !exists {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            key.Name,
+				Namespace:       key.Namespace,
+				OwnerReferences: ownerReferences(vmp),
+			},
+			Data: data,
+		}
+		logger.Info("Creating IO tuning hook ConfigMap", "name", key.Name)
+		return c.Create(ctx, cm)
+	}
+	if equality.Semantic.DeepEqual(cm.Data, data) {
+		return nil
+	}
+	logger.Info("Updating IO tuning hook ConfigMap", "name", key.Name)
+	cm.Data = data
+	return c.Update(ctx, cm)
+}
+
+// applyIOTuneHook 为有 Hypervisor QoS 的磁盘添加 hook sidecar 注解（修改在 VM 重启后生效）
+// Sidecar feature gate 未开启时不添加，否则 KubeVirt 会拒绝整个 VM
+func applyIOTuneHook(template *kubevirtv1.VirtualMachineInstanceTemplateSpec, vmp *vmv1alpha1.Wukong, volumes []vmv1alpha1.VolumeStatus, opts VMOptions) {
+	if !opts.HookSidecars || !HypervisorQoSRequested(volumes) {
+		return
+	}
+	image := opts.SidecarShimImage
+	if image == "" {
+		image = DefaultSidecarShimImage
+	}
+	sidecars := []hookSidecar{{
+		Args:  []string{"--version", "v1alpha2"},
+		Image: image,
+		ConfigMap: &hookSidecarConfigMap{
+			Name:     IOTuneConfigMapName(vmp),
+			Key:      iotuneScriptKey,
+			HookPath: "/usr/bin/onDefineDomain",
+		},
+	}}
+	value, err := json.Marshal(sidecars)
+	if err != nil {
+		return
+	}
+	if template.ObjectMeta.Annotations == nil {
+		template.ObjectMeta.Annotations = make(map[string]string)
+	}
+	template.ObjectMeta.Annotations[hookSidecarsAnnotation] = string(value)
+}
+
+// buildIOTuneLimits 返回每个 Hypervisor QoS 磁盘的 libvirt iotune 元素（按 libvirt schema 顺序）
+func buildIOTuneLimits(volumes []vmv1alpha1.VolumeStatus) map[string][][2]string {
+	limits := map[string][][2]string{}
+	for _, vol := range volumes {
+		if vol.QoS == nil || vol.QoS.Mode == vmv1alpha1.QoSModeStorage {
+			continue
+		}
+		qos := vol.QoS
+		var elems [][2]string
+		add := func(name string, value *int64) {
+			if value != nil {
+				elems = append(elems, [2]string{name, strconv.FormatInt(*value, 10)})
+			}
+		}
+		add("read_bytes_sec", quantityValue(qos.ReadBytesPerSecond))
+		add("write_bytes_sec", quantityValue(qos.WriteBytesPerSecond))
+		add("read_iops_sec", qos.ReadIOPS)
+		add("write_iops_sec", qos.WriteIOPS)
+		if burst := qos.Burst; burst != nil {
+			add("read_bytes_sec_max", quantityValue(burst.ReadBytesPerSecond))
+			add("write_bytes_sec_max", quantityValue(burst.WriteBytesPerSecond))
+			add("read_iops_sec_max", burst.ReadIOPS)
+			add("write_iops_sec_max", burst.WriteIOPS)
+			if burst.DurationSeconds != nil {
+				if burst.ReadBytesPerSecond != nil {
+					add("read_bytes_sec_max_length", burst.DurationSeconds)
+				}
+				if burst.WriteBytesPerSecond != nil {
+					add("write_bytes_sec_max_length", burst.DurationSeconds)
+				}
+				if burst.ReadIOPS != nil {
+					add("read_iops_sec_max_length", burst.DurationSeconds)
+				}
+				if burst.WriteIOPS != nil {
+					add("write_iops_sec_max_length", burst.DurationSeconds)
+				}
+			}
+		}
+		if len(elems) > 0 {
+			limits[vol.Name] = elems
+		}
+	}
+	return limits
+}
+
+// quantityValue 将 Quantity 转换为 int64 指针
+func quantityValue(q *resource.Quantity) *int64 {
+	if q == nil {
+		return nil
+	}
+	v := q.Value()
+	return &v
+}
@@ -0,0 +1,140 @@
+package kubevirt
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+func int64Ptr(i int64) *int64 { return &i }
+
+func quantityPtr(s string) *resource.Quantity {
+	q := resource.MustParse(s)
+	return &q
+}
+
+func TestBuildIOTuneLimits(t *testing.T) {
+	volumes := []vmv1alpha1.VolumeStatus{
+		{
+			Name: "system",
+			QoS: &vmv1alpha1.DiskQoSSpec{
+				Mode:               vmv1alpha1.QoSModeHypervisor,
+				ReadBytesPerSecond: quantityPtr("100Mi"),
+				WriteIOPS:          int64Ptr(500),
+				Burst: &vmv1alpha1.DiskQoSBurst{
+					WriteIOPS:       int64Ptr(2000),
+					DurationSeconds: int64Ptr(30),
+				},
+			},
+		},
+		{
+			Name: "data",
+			QoS:  &vmv1alpha1.DiskQoSSpec{ReadIOPS: int64Ptr(100), WriteIOPS: int64Ptr(50)},
+		},
+		// Storage 模式由 CSI 驱动限速
+		{Name: "fast", QoS: &vmv1alpha1.DiskQoSSpec{Mode: vmv1alpha1.QoSModeStorage, ReadIOPS: int64Ptr(100)}},
+		{Name: "scratch"},
+		// 没有任何限制
+		{Name: "empty", QoS: &vmv1alpha1.DiskQoSSpec{Mode: vmv1alpha1.QoSModeHypervisor}},
+	}
+
+	got := buildIOTuneLimits(volumes)
+	want := map[string][][2]string{
+		"system": {
+			{"read_bytes_sec", "104857600"},
+			{"write_iops_sec", "500"},
+			{"write_iops_sec_max", "2000"},
+			{"write_iops_sec_max_length", "30"},
+		},
+		"data": {
+			{"read_iops_sec", "100"},
+			{"write_iops_sec", "50"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildIOTuneLimits() = %v, want %v", got, want)
+	}
+}
+
+func TestApplyIOTuneHook(t *testing.T) {
+	vmp := &vmv1alpha1.Wukong{ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "vm"}}
+	volumes := []vmv1alpha1.VolumeStatus{{Name: "data", QoS: &vmv1alpha1.DiskQoSSpec{ReadIOPS: int64Ptr(100)}}}
+
+	// Sidecar feature gate 未开启时不添加 hook
+	template := &kubevirtv1.VirtualMachineInstanceTemplateSpec{}
+	applyIOTuneHook(template, vmp, volumes, VMOptions{})
+	if _, ok := template.ObjectMeta.Annotations[hookSidecarsAnnotation]; ok {
+		t.Error("hook sidecar added without the Sidecar feature gate")
+	}
+
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "", want: DefaultSidecarShimImage},
+		{image: "registry.example.com/kubevirt/sidecar-shim:v1.3.0", want: "registry.example.com/kubevirt/sidecar-shim:v1.3.0"},
+	}
+	for _, tt := range tests {
+		template := &kubevirtv1.VirtualMachineInstanceTemplateSpec{}
+		applyIOTuneHook(template, vmp, volumes, VMOptions{SidecarShimImage: tt.image, HookSidecars: true})
+
+		var sidecars []hookSidecar
+		if err := json.Unmarshal([]byte(template.ObjectMeta.Annotations[hookSidecarsAnnotation]), &sidecars); err != nil {
+			t.Fatalf("invalid %s annotation: %v", hookSidecarsAnnotation, err)
+		}
+		if len(sidecars) != 1 || sidecars[0].Image != tt.want {
+			t.Errorf("sidecars = %+v, want image %s", sidecars, tt.want)
+		}
+		if sidecars[0].ConfigMap == nil || sidecars[0].ConfigMap.Name != IOTuneConfigMapName(vmp) {
+			t.Errorf("sidecar ConfigMap = %+v, want %s", sidecars[0].ConfigMap, IOTuneConfigMapName(vmp))
+		}
+	}
+}
+
+func TestSidecarFeatureGateEnabled(t *testing.T) {
+	ctx := context.Background()
+	newKubeVirt := func(gates ...string) *kubevirtv1.KubeVirt {
+		return &kubevirtv1.KubeVirt{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kubevirt", Name: "kubevirt"},
+			Spec: kubevirtv1.KubeVirtSpec{
+				Configuration: kubevirtv1.KubeVirtConfiguration{
+					DeveloperConfiguration: &kubevirtv1.DeveloperConfiguration{FeatureGates: gates},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		kubevirt *kubevirtv1.KubeVirt
+		want     bool
+	}{
+		{name: "no KubeVirt", want: false},
+		{name: "no developer configuration", kubevirt: &kubevirtv1.KubeVirt{ObjectMeta: metav1.ObjectMeta{Namespace: "kubevirt", Name: "kubevirt"}}, want: false},
+		{name: "other gates", kubevirt: newKubeVirt("HotplugVolumes"), want: false},
+		{name: "sidecar gate", kubevirt: newKubeVirt("HotplugVolumes", SidecarFeatureGate), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeClient(t)
+			if tt.kubevirt != nil {
+				c = newFakeClient(t, tt.kubevirt)
+			}
+			got, err := SidecarFeatureGateEnabled(ctx, c)
+			if err != nil {
+				t.Fatalf("SidecarFeatureGateEnabled() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("SidecarFeatureGateEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
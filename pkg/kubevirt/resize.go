@@ -0,0 +1,76 @@
+package kubevirt
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+	"github.com/kuihuar/novasphere/pkg/storage"
+)
+
+// guestResizeCloudInit 是扩展 guest 分区和文件系统的 Cloud-Init 配置：
+// growpart/resize_rootfs 在每次启动时扩展根分区；
+// udev 规则在磁盘在线扩容（KubeVirt 扩展磁盘后 virtio 触发 change 事件）时扩展该磁盘上已挂载的文件系统；
+// guestResizeBootCmd 在每次启动时扩展离线扩容的数据盘
+const guestResizeCloudInit = `
+growpart:
+  mode: auto
+  devices: ["/"]
+resize_rootfs: true
+
+write_files:
+  - path: /usr/local/sbin/novasphere-growfs
+    permissions: "0755"
+    content: |
+      #!/bin/sh
+      # 磁盘容量变化时扩展其上已挂载的分区和文件系统
+      dev="/dev/$1"
+      findmnt -rn -o SOURCE,TARGET,FSTYPE | while read -r source target fstype; do
+        case "$source" in
+          "$dev") ;;
+          "$dev"[0-9]*|"$dev"p[0-9]*) part="${source#$dev}"; growpart "$dev" "${part#p}" ;;
+          *) continue ;;
+        esac
+        case "$fstype" in
+          ext2|ext3|ext4) resize2fs "$source" ;;
+          xfs) xfs_growfs "$target" ;;
+          btrfs) btrfs filesystem resize max "$target" ;;
+        esac
+      done
+  - path: /etc/udev/rules.d/99-novasphere-growfs.rules
+    content: |
+      ACTION=="change", SUBSYSTEM=="block", ENV{DEVTYPE}=="disk", KERNEL=="vd*", RUN+="/usr/local/sbin/novasphere-growfs %k"
+
+runcmd:
+  - udevadm control --reload-rules
+`
+
+// guestResizeBootCmd 是在每次启动时扩展离线扩容的数据盘的 bootcmd 条目
+const guestResizeBootCmd = `  - [sh, -c, 'if [ -x /usr/local/sbin/novasphere-growfs ]; then for d in /sys/block/vd*; do /usr/local/sbin/novasphere-growfs "${d##*/}"; done; fi']
+`
+
+// guestResizeEnabled 判断是否需要在 guest 中配置磁盘扩容：
+// 已发生过扩容，或有 Wukong 创建的 PVC 磁盘使用允许扩容的 StorageClass
+func guestResizeEnabled(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, volumes []vmv1alpha1.VolumeStatus) bool {
+	for _, vol := range volumes {
+		if vol.PVCName == "" {
+			continue
+		}
+		disk := findDiskConfig(vmp.Spec.Disks, vol.Name)
+		if disk == nil || disk.ExistingClaim != "" || disk.Ephemeral {
+			// 已有 PVC 和 ephemeral 磁盘不由 Wukong 扩容
+			continue
+		}
+		if vol.ExpansionState != "" {
+			return true
+		}
+		if vol.StorageClassName == "" {
+			continue
+		}
+		if supported, err := storage.ExpansionSupported(ctx, c, vol.StorageClassName); err == nil && supported {
+			return true
+		}
+	}
+	return false
+}
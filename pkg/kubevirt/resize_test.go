@@ -0,0 +1,122 @@
+package kubevirt
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+// newFakeClient 返回注册了 Wukong、KubeVirt 和 core/storage 资源的 fake client
+func newFakeClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{corev1.AddToScheme, storagev1.AddToScheme, kubevirtv1.AddToScheme, vmv1alpha1.AddToScheme} {
+		if err := add(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+func newStorageClass(name string, allowExpansion bool) *storagev1.StorageClass {
+	return &storagev1.StorageClass{
+		ObjectMeta:           metav1.ObjectMeta{Name: name},
+		Provisioner:          "example.com/csi",
+		AllowVolumeExpansion: &allowExpansion,
+	}
+}
+
+func TestGuestResizeEnabled(t *testing.T) {
+	c := newFakeClient(t, newStorageClass("expandable", true), newStorageClass("fixed", false))
+	vmp := &vmv1alpha1.Wukong{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "vm"},
+		Spec: vmv1alpha1.WukongSpec{
+			Disks: []vmv1alpha1.DiskConfig{
+				{Name: "system", Boot: true, Size: "20Gi"},
+				{Name: "shared", ExistingClaim: "shared-data"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		volumes []vmv1alpha1.VolumeStatus
+		want    bool
+	}{
+		{
+			name:    "expandable storage class",
+			volumes: []vmv1alpha1.VolumeStatus{{Name: "system", PVCName: "vm-system", StorageClassName: "expandable"}},
+			want:    true,
+		},
+		{
+			name:    "storage class without expansion",
+			volumes: []vmv1alpha1.VolumeStatus{{Name: "system", PVCName: "vm-system", StorageClassName: "fixed"}},
+			want:    false,
+		},
+		{
+			name: "expansion already recorded",
+			volumes: []vmv1alpha1.VolumeStatus{
+				{Name: "system", PVCName: "vm-system", StorageClassName: "fixed", ExpansionState: vmv1alpha1.ExpansionStateCompleted},
+			},
+			want: true,
+		},
+		{
+			name:    "existing claims are not expanded by the Wukong",
+			volumes: []vmv1alpha1.VolumeStatus{{Name: "shared", PVCName: "shared-data", StorageClassName: "expandable"}},
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := guestResizeEnabled(context.Background(), c, vmp, tt.volumes); got != tt.want {
+				t.Errorf("guestResizeEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildCloudInitDataMergesBootCmds(t *testing.T) {
+	c := newFakeClient(t, newStorageClass("expandable", true))
+	vmp := &vmv1alpha1.Wukong{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "vm"},
+		Spec: vmv1alpha1.WukongSpec{
+			Disks: []vmv1alpha1.DiskConfig{
+				{Name: "system", Boot: true, Size: "20Gi"},
+				{Name: "secret", Size: "10Gi", Encryption: &vmv1alpha1.DiskEncryptionSpec{Mode: vmv1alpha1.EncryptionModeGuest}},
+			},
+		},
+	}
+	volumes := []vmv1alpha1.VolumeStatus{
+		{Name: "system", PVCName: "vm-system", StorageClassName: "expandable"},
+		{Name: "secret", PVCName: "vm-secret", StorageClassName: "expandable", EncryptionMode: vmv1alpha1.EncryptionModeGuest, EncryptionKeySecret: "vm-secret-key"},
+	}
+
+	data := buildCloudInitData(context.Background(), c, vmp, nil, volumes)
+	if !strings.HasPrefix(data, "#cloud-config\n") {
+		t.Fatalf("buildCloudInitData() is not a cloud-config:\n%s", data)
+	}
+
+	var config map[string]interface{}
+	if err := yaml.UnmarshalStrict([]byte(data), &config); err != nil {
+		t.Fatalf("buildCloudInitData() is not valid YAML: %v\n%s", err, data)
+	}
+	bootCmds, ok := config["bootcmd"].([]interface{})
+	if !ok || len(bootCmds) != 2 {
+		t.Fatalf("bootcmd = %v, want the unlock and growfs commands", config["bootcmd"])
+	}
+	if _, ok := config["growpart"]; !ok {
+		t.Error("growpart missing for an expandable disk")
+	}
+}
@@ -0,0 +1,155 @@
+package kubevirt
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/rest"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+// maxDiskSerialLength 是 virtio 磁盘序列号的最大长度（QEMU 会截断更长的序列号）
+const maxDiskSerialLength = 20
+
+// diskSerialHashLength 是长磁盘名称的序列号中哈希后缀的长度
+const diskSerialHashLength = 8
+
+// GuestAgent queries information reported by the QEMU guest agent of a running VMI.
+type GuestAgent interface {
+	// ListFileSystems returns the file systems mounted in the guest.
+	ListFileSystems(ctx context.Context, namespace, name string) ([]kubevirtv1.VirtualMachineInstanceFileSystem, error)
+}
+
+// FileSystemUsage is the guest file system usage of a disk.
+type FileSystemUsage struct {
+	UsedBytes int64
+	FreeBytes int64
+}
+
+// VolumeHotplug hot-unplugs volumes from a running VM.
+type VolumeHotplug interface {
+	// RemoveVolume hot-unplugs a hotplugged volume from the VM.
+	RemoveVolume(ctx context.Context, namespace, vmName, volumeName string) error
+}
+
+// SubresourceClient accesses the KubeVirt subresources API (guest agent, volume hotplug).
+// It implements GuestAgent and VolumeHotplug.
+type SubresourceClient struct {
+	restClient rest.Interface
+}
+
+// NewSubresourceClient creates a client for the KubeVirt subresources API.
+func NewSubresourceClient(cfg *rest.Config) (*SubresourceClient, error) {
+	config := rest.CopyConfig(cfg)
+	config.GroupVersion = &schema.GroupVersion{Group: "subresources.kubevirt.io", Version: "v1"}
+	config.APIPath = "/apis"
+	config.NegotiatedSerializer = serializer.NewCodecFactory(runtime.NewScheme()).WithoutConversion()
+
+	restClient, err := rest.RESTClientFor(config)
+	if err != nil {
+		return nil, err
+	}
+	return &SubresourceClient{restClient: restClient}, nil
+}
+
+// ListFileSystems returns the file systems mounted in the guest.
+func (s *SubresourceClient) ListFileSystems(ctx context.Context, namespace, name string) ([]kubevirtv1.VirtualMachineInstanceFileSystem, error) {
+	raw, err := s.restClient.Get().
+		Namespace(namespace).
+		Resource("virtualmachineinstances").
+		Name(name).
+		SubResource("filesystemlist").
+		DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list guest file systems of %s/%s: %w", namespace, name, err)
+	}
+
+	list := &kubevirtv1.VirtualMachineInstanceFileSystemList{}
+	if err := json.Unmarshal(raw, list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// RemoveVolume hot-unplugs a hotplugged volume from the VM.
+func (s *SubresourceClient) RemoveVolume(ctx context.Context, namespace, vmName, volumeName string) error {
+	body, err := json.Marshal(&kubevirtv1.RemoveVolumeOptions{Name: volumeName})
+	if err != nil {
+		return err
+	}
+
+	err = s.restClient.Put().
+		Namespace(namespace).
+		Resource("virtualmachines").
+		Name(vmName).
+		SubResource("removevolume").
+		Body(body).
+		Do(ctx).
+		Error()
+	if err != nil {
+		return fmt.Errorf("failed to remove volume %s from %s/%s: %w", volumeName, namespace, vmName, err)
+	}
+	return nil
+}
+
+// GetGuestFileSystemUsage returns the guest file system usage per disk serial.
+// File systems are matched to disks by the disk serial number set in buildDisks.
+// File systems spanning several disks (e.g., LVM, RAID) cannot be attributed to one disk and are skipped.
+func GetGuestFileSystemUsage(ctx context.Context, agent GuestAgent, namespace, vmName string) (map[string]FileSystemUsage, error) {
+	fileSystems, err := agent.ListFileSystems(ctx, namespace, vmName)
+	if err != nil {
+		return nil, err
+	}
+	return fileSystemUsageBySerial(fileSystems), nil
+}
+
+// fileSystemUsageBySerial 按磁盘序列号汇总文件系统使用情况
+// 同一设备的多个挂载点（bind mount）只计算一次；跨多个磁盘的文件系统不计入任何磁盘
+func fileSystemUsageBySerial(fileSystems []kubevirtv1.VirtualMachineInstanceFileSystem) map[string]FileSystemUsage {
+	usage := make(map[string]FileSystemUsage)
+	counted := make(map[string]bool)
+	for _, fs := range fileSystems {
+		serial := ""
+		for _, disk := range fs.Disk {
+			if disk.Serial == "" {
+				continue
+			}
+			if serial != "" && serial != disk.Serial {
+				serial = ""
+				break
+			}
+			serial = disk.Serial
+		}
+		if serial == "" {
+			continue
+		}
+		if fs.DiskName != "" {
+			if counted[fs.DiskName] {
+				continue
+			}
+			counted[fs.DiskName] = true
+		}
+		u := usage[serial]
+		u.UsedBytes += int64(fs.UsedBytes)
+		u.FreeBytes += int64(fs.TotalBytes - fs.UsedBytes)
+		usage[serial] = u
+	}
+	return usage
+}
+
+// DiskSerial returns the serial number of the disk, used by the guest agent to report the disk of a file system.
+// Names longer than the virtio limit are truncated and suffixed with a hash so that they stay unique.
+func DiskSerial(diskName string) string {
+	if len(diskName) <= maxDiskSerialLength {
+		return diskName
+	}
+	sum := sha256.Sum256([]byte(diskName))
+	return diskName[:maxDiskSerialLength-diskSerialHashLength-1] + "-" + hex.EncodeToString(sum[:])[:diskSerialHashLength]
+}
@@ -0,0 +1,82 @@
+package kubevirt
+
+import (
+	"strings"
+	"testing"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+func TestDiskSerial(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "data", want: "data"},
+		{name: "exactly-twenty-chars", want: "exactly-twenty-chars"},
+	}
+	for _, tt := range tests {
+		if got := DiskSerial(tt.name); got != tt.want {
+			t.Errorf("DiskSerial(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// 长名称共享前 20 个字符时序列号仍然不同
+	names := []string{
+		"database-volume-primary",
+		"database-volume-secondary",
+		"luks-database-volume-primary",
+		"luks-database-volume-secondary",
+	}
+	seen := make(map[string]string)
+	for _, name := range names {
+		serial := DiskSerial(name)
+		if len(serial) > maxDiskSerialLength {
+			t.Errorf("DiskSerial(%q) = %q, longer than %d", name, serial, maxDiskSerialLength)
+		}
+		if !strings.HasPrefix(serial, name[:maxDiskSerialLength-diskSerialHashLength-1]) {
+			t.Errorf("DiskSerial(%q) = %q, want the name as prefix", name, serial)
+		}
+		if other, ok := seen[serial]; ok {
+			t.Errorf("DiskSerial(%q) = DiskSerial(%q) = %q", name, other, serial)
+		}
+		seen[serial] = name
+		if DiskSerial(name) != serial {
+			t.Errorf("DiskSerial(%q) is not stable", name)
+		}
+	}
+}
+
+func TestFileSystemUsageBySerial(t *testing.T) {
+	disks := func(serials ...string) []kubevirtv1.VirtualMachineInstanceFileSystemDisk {
+		var d []kubevirtv1.VirtualMachineInstanceFileSystemDisk
+		for _, serial := range serials {
+			d = append(d, kubevirtv1.VirtualMachineInstanceFileSystemDisk{Serial: serial, BusType: "virtio"})
+		}
+		return d
+	}
+	fileSystems := []kubevirtv1.VirtualMachineInstanceFileSystem{
+		{DiskName: "vda1", MountPoint: "/", TotalBytes: 100, UsedBytes: 40, Disk: disks("system")},
+		{DiskName: "vda2", MountPoint: "/boot", TotalBytes: 10, UsedBytes: 5, Disk: disks("system")},
+		// /var/lib/data 是 /data 的 bind mount
+		{DiskName: "vdb", MountPoint: "/data", TotalBytes: 200, UsedBytes: 50, Disk: disks("data")},
+		{DiskName: "vdb", MountPoint: "/var/lib/data", TotalBytes: 200, UsedBytes: 50, Disk: disks("data")},
+		// 跨两个磁盘的 LVM 卷无法归属到单个磁盘
+		{DiskName: "dm-0", MountPoint: "/srv", TotalBytes: 400, UsedBytes: 100, Disk: disks("logs", "cache")},
+		{DiskName: "sr0", MountPoint: "/mnt", TotalBytes: 1, UsedBytes: 1},
+	}
+
+	got := fileSystemUsageBySerial(fileSystems)
+	want := map[string]FileSystemUsage{
+		"system": {UsedBytes: 45, FreeBytes: 65},
+		"data":   {UsedBytes: 50, FreeBytes: 150},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("fileSystemUsageBySerial() = %v, want %v", got, want)
+	}
+	for serial, u := range want {
+		if got[serial] != u {
+			t.Errorf("fileSystemUsageBySerial()[%q] = %+v, want %+v", serial, got[serial], u)
+		}
+	}
+}
@@ -16,20 +16,28 @@ import (
 	kubevirtv1 "kubevirt.io/api/core/v1"
 
 	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+	"github.com/kuihuar/novasphere/pkg/catalog"
+	"github.com/kuihuar/novasphere/pkg/network"
 )
 
 // ReconcileVirtualMachine creates or updates a KubeVirt VirtualMachine
 // based on the Wukong specification.
-func ReconcileVirtualMachine(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, networks []vmv1alpha1.NetworkStatus, volumes []vmv1alpha1.VolumeStatus) (string, error) {
+func ReconcileVirtualMachine(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, networks []vmv1alpha1.NetworkStatus, volumes []vmv1alpha1.VolumeStatus, opts VMOptions) (string, error) {
 	logger := log.FromContext(ctx)
 	vmName := fmt.Sprintf("%s-vm", vmp.Name)
 
 	logger.Info("Reconciling VirtualMachine", "name", vmName, "namespace", vmp.Namespace)
 
+	// Hypervisor QoS 的 hook 脚本需要在 VM 启动前准备好
+	if err := ReconcileIOTuneHook(ctx, c, vmp, volumes); err != nil {
+		logger.Error(err, "failed to reconcile IO tuning hook")
+		return "", err
+	}
+
 	// 构建 VirtualMachine 对象
-	vm := buildVirtualMachine(ctx, c, vmp, networks, volumes)
-	if vm == nil {
-		return "", fmt.Errorf("failed to build VirtualMachine object")
+	vm, err := buildVirtualMachine(ctx, c, vmp, networks, volumes, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to build VirtualMachine object: %w", err)
 	}
 
 	// 尝试获取现有的 VirtualMachine
@@ -63,30 +71,23 @@ func ReconcileVirtualMachine(ctx context.Context, c client.Client, vmp *vmv1alph
 }
 
 // buildVirtualMachine 构建 VirtualMachine 对象
-func buildVirtualMachine(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, networks []vmv1alpha1.NetworkStatus, volumes []vmv1alpha1.VolumeStatus) *kubevirtv1.VirtualMachine {
+func buildVirtualMachine(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, networks []vmv1alpha1.NetworkStatus, volumes []vmv1alpha1.VolumeStatus, opts VMOptions) (*kubevirtv1.VirtualMachine, error) {
 	vmName := fmt.Sprintf("%s-vm", vmp.Name)
 
+	spec, err := buildVMSpec(ctx, c, vmp, networks, volumes, opts)
+	if err != nil {
+		return nil, err
+	}
 	vm := &kubevirtv1.VirtualMachine{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      vmName,
 			Namespace: vmp.Namespace,
 		},
-		Spec: buildVMSpec(ctx, c, vmp, networks, volumes),
+		Spec: spec,
 	}
 
 	// 设置 OwnerReference，使 VM 成为 Wukong 的子资源
-	if vmp.UID != "" {
-		controller := true
-		vm.OwnerReferences = []metav1.OwnerReference{
-			{
-				APIVersion: vmp.APIVersion,
-				Kind:       vmp.Kind,
-				Name:       vmp.Name,
-				UID:        vmp.UID,
-				Controller: &controller,
-			},
-		}
-	}
+	vm.OwnerReferences = ownerReferences(vmp)
 
 	// 构建 annotations（用于 Multus 网络）
 	annotations := buildNetworkAnnotations(networks)
@@ -94,11 +95,28 @@ func buildVirtualMachine(ctx context.Context, c client.Client, vmp *vmv1alpha1.W
 		vm.Annotations = annotations
 	}
 
-	return vm
+	return vm, nil
+}
+
+// ownerReferences 返回指向 Wukong 的 OwnerReference，使对象成为 Wukong 的子资源
+func ownerReferences(vmp *vmv1alpha1.Wukong) []metav1.OwnerReference {
+	if vmp.UID == "" {
+		return nil
+	}
+	controller := true
+	return []metav1.OwnerReference{
+		{
+			APIVersion: vmp.APIVersion,
+			Kind:       vmp.Kind,
+			Name:       vmp.Name,
+			UID:        vmp.UID,
+			Controller: &controller,
+		},
+	}
 }
 
 // buildVMSpec 构建 VirtualMachine spec
-func buildVMSpec(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, networks []vmv1alpha1.NetworkStatus, volumes []vmv1alpha1.VolumeStatus) kubevirtv1.VirtualMachineSpec {
+func buildVMSpec(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, networks []vmv1alpha1.NetworkStatus, volumes []vmv1alpha1.VolumeStatus, opts VMOptions) (kubevirtv1.VirtualMachineSpec, error) {
 	// 确定是否运行
 	autoStart := true
 	if vmp.Spec.StartStrategy != nil {
@@ -112,6 +130,14 @@ func buildVMSpec(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, n
 		memoryQuantity = resource.MustParse("2Gi")
 	}
 
+	// 引用 WukongNetwork 的网络使用其中的定义
+	netConfigs := resolveNetworkConfigs(ctx, c, vmp)
+
+	vmVolumes, err := buildVolumes(vmp.Spec.Disks, volumes)
+	if err != nil {
+		return kubevirtv1.VirtualMachineSpec{}, err
+	}
+
 	// 构建 template
 	template := &kubevirtv1.VirtualMachineInstanceTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
@@ -126,25 +152,52 @@ func buildVMSpec(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, n
 					Guest: &memoryQuantity,
 				},
 				Devices: kubevirtv1.Devices{
-					Disks:      buildDisks(volumes),
-					Interfaces: buildInterfaces(networks),
+					Disks:      buildDisks(vmp.Spec.Disks, volumes),
+					Interfaces: buildInterfaces(vmp.Spec.PodNetwork, vmp.Status.PodNetworkMACAddress, netConfigs, networks),
 				},
 			},
-			Networks: buildNetworks(networks),
-			Volumes:  buildVolumes(volumes),
+			Networks: buildNetworks(vmp.Spec.PodNetwork, netConfigs, networks),
+			Volumes:  vmVolumes,
 		},
 	}
 
+	// 从网卡网络启动时，启动盘排在网卡之后
+	applyBootDiskOrder(template.Spec.Domain.Devices.Disks, template.Spec.Domain.Devices.Interfaces, vmp.Spec.Disks)
+
+	// 应用 catalog 镜像（WukongImage）要求的固件
+	bootImage, err := catalog.BootImage(ctx, c, vmp)
+	if err != nil {
+		log.FromContext(ctx).V(1).Info("Failed to get catalog image of boot disk", "error", err)
+	}
+	if bootImage != nil && bootImage.Spec.Firmware != nil {
+		applyFirmware(&template.Spec.Domain, bootImage.Spec.Firmware)
+	}
+
+	// Hypervisor QoS：通过 hook sidecar 设置 libvirt 块设备限速
+	applyIOTuneHook(template, vmp, volumes, opts)
+
+	// Guest 加密磁盘的密钥盘
+	keyDisks, keyVolumes := buildEncryptionKeyDisks(volumes)
+	template.Spec.Domain.Devices.Disks = append(template.Spec.Domain.Devices.Disks, keyDisks...)
+	template.Spec.Volumes = append(template.Spec.Volumes, keyVolumes...)
+
+	// 网卡的 guest 网络配置（netplan version 2），与用户数据分开传递
+	networkData, err := buildNetworkData(ctx, c, vmp, netConfigs, networks)
+	if err != nil {
+		return kubevirtv1.VirtualMachineSpec{}, fmt.Errorf("failed to build cloud-init network data: %w", err)
+	}
+
 	// 添加 Cloud-Init 配置（如果有）
-	if vmp.Spec.OSImage != "" || vmp.Spec.SSHKeySecret != "" || vmp.Spec.CloudInitUser != nil {
-		cloudInitData := buildCloudInitData(ctx, c, vmp)
+	if vmp.Spec.OSImage != "" || vmp.Spec.SSHKeySecret != "" || vmp.Spec.CloudInitUser != nil || len(keyVolumes) > 0 || networkData != "" {
+		cloudInitData := buildCloudInitData(ctx, c, vmp, bootImage, volumes)
 		if cloudInitData != "" {
 			// 添加 cloudInitNoCloud volume
 			cloudInitVolume := kubevirtv1.Volume{
 				Name: "cloudinitdisk",
 				VolumeSource: kubevirtv1.VolumeSource{
 					CloudInitNoCloud: &kubevirtv1.CloudInitNoCloudSource{
-						UserData: cloudInitData,
+						UserData:    cloudInitData,
+						NetworkData: networkData,
 					},
 				},
 			}
@@ -192,7 +245,32 @@ func buildVMSpec(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, n
 		Template: template,
 	}
 
-	return spec
+	return spec, nil
+}
+
+// applyFirmware 根据镜像的固件要求设置 bootloader
+func applyFirmware(domain *kubevirtv1.DomainSpec, firmware *vmv1alpha1.FirmwareSpec) {
+	if firmware.Bootloader != "EFI" {
+		return
+	}
+
+	secureBoot := firmware.SecureBoot
+	domain.Firmware = &kubevirtv1.Firmware{
+		Bootloader: &kubevirtv1.Bootloader{
+			EFI: &kubevirtv1.EFI{
+				SecureBoot: &secureBoot,
+			},
+		},
+	}
+
+	// SecureBoot 需要启用 SMM
+	if secureBoot {
+		smmEnabled := true
+		if domain.Features == nil {
+			domain.Features = &kubevirtv1.Features{}
+		}
+		domain.Features.SMM = &kubevirtv1.FeatureState{Enabled: &smmEnabled}
+	}
 }
 
 // buildNetworkAnnotations 构建 Multus 网络注解
@@ -224,43 +302,68 @@ func buildNetworkAnnotations(networks []vmv1alpha1.NetworkStatus) map[string]str
 }
 
 // buildDisks 构建磁盘设备列表
-func buildDisks(volumes []vmv1alpha1.VolumeStatus) []kubevirtv1.Disk {
+func buildDisks(diskConfigs []vmv1alpha1.DiskConfig, volumes []vmv1alpha1.VolumeStatus) []kubevirtv1.Disk {
 	disks := make([]kubevirtv1.Disk, 0, len(volumes))
 	for _, vol := range volumes {
 		disk := kubevirtv1.Disk{
 			Name: vol.Name,
+			// 序列号用于将 guest agent 报告的文件系统对应到磁盘
+			Serial: DiskSerial(vol.Name),
 			DiskDevice: kubevirtv1.DiskDevice{
 				Disk: &kubevirtv1.DiskTarget{
 					Bus: "virtio",
 				},
 			},
 		}
+		// 共享磁盘：允许多个 VM 同时挂载，必须关闭 host 侧缓存
+		if diskConfig := findDiskConfig(diskConfigs, vol.Name); diskConfig != nil && diskConfig.Shareable {
+			shareable := true
+			disk.Shareable = &shareable
+			disk.Cache = kubevirtv1.CacheNone
+		}
 		disks = append(disks, disk)
 	}
 	return disks
 }
 
+// findDiskConfig 根据名称查找磁盘配置
+func findDiskConfig(disks []vmv1alpha1.DiskConfig, name string) *vmv1alpha1.DiskConfig {
+	for i := range disks {
+		if disks[i].Name == name {
+			return &disks[i]
+		}
+	}
+	return nil
+}
+
 // buildNetworks 构建网络列表
-func buildNetworks(networks []vmv1alpha1.NetworkStatus) []kubevirtv1.Network {
+func buildNetworks(podNetwork *vmv1alpha1.PodNetworkSpec, netConfigs []vmv1alpha1.NetworkConfig, networks []vmv1alpha1.NetworkStatus) []kubevirtv1.Network {
 	netList := make([]kubevirtv1.Network, 0, len(networks)+1)
 
 	// 默认网络（Pod 网络）
-	netList = append(netList, kubevirtv1.Network{
-		Name: "default",
-		NetworkSource: kubevirtv1.NetworkSource{
-			Pod: &kubevirtv1.PodNetwork{},
-		},
-	})
+	if network.PodNetworkEnabled(podNetwork) {
+		netList = append(netList, kubevirtv1.Network{
+			Name: network.PodNetworkName,
+			NetworkSource: kubevirtv1.NetworkSource{
+				Pod: &kubevirtv1.PodNetwork{},
+			},
+		})
+	}
 
 	// Multus 网络
 	for _, net := range networks {
 		if net.NADName != "" {
+			multus := &kubevirtv1.MultusNetwork{
+				NetworkName: net.NADName,
+			}
+			// 主网络作为 Multus 默认网络，替代集群 CNI
+			if netCfg := findNetworkConfig(netConfigs, net.Name); netCfg != nil && netCfg.Primary {
+				multus.Default = true
+			}
 			netList = append(netList, kubevirtv1.Network{
 				Name: net.NADName,
 				NetworkSource: kubevirtv1.NetworkSource{
-					Multus: &kubevirtv1.MultusNetwork{
-						NetworkName: net.NADName,
-					},
+					Multus: multus,
 				},
 			})
 		}
@@ -271,58 +374,186 @@ func buildNetworks(networks []vmv1alpha1.NetworkStatus) []kubevirtv1.Network {
 
 // buildInterfaces 构建网络接口列表
 // 每个接口必须引用一个 network 名称
-func buildInterfaces(networks []vmv1alpha1.NetworkStatus) []kubevirtv1.Interface {
+// podMAC 是 operator 为 Pod 网络接口分配的 MAC 地址（可以为空）
+func buildInterfaces(podNetwork *vmv1alpha1.PodNetworkSpec, podMAC string, netConfigs []vmv1alpha1.NetworkConfig, networks []vmv1alpha1.NetworkStatus) []kubevirtv1.Interface {
 	interfaceList := make([]kubevirtv1.Interface, 0, len(networks)+1)
 
 	// 默认网络接口（Pod 网络）
-	interfaceList = append(interfaceList, kubevirtv1.Interface{
-		Name: "default",
-		InterfaceBindingMethod: kubevirtv1.InterfaceBindingMethod{
-			Masquerade: &kubevirtv1.InterfaceMasquerade{},
-		},
-	})
+	if network.PodNetworkEnabled(podNetwork) {
+		iface := kubevirtv1.Interface{
+			Name:       network.PodNetworkName,
+			MacAddress: podMAC,
+		}
+		binding := vmv1alpha1.InterfaceBindingMasquerade
+		if podNetwork != nil && podNetwork.Binding != "" {
+			binding = podNetwork.Binding
+		}
+		switch binding {
+		case vmv1alpha1.InterfaceBindingBridge:
+			iface.Bridge = &kubevirtv1.InterfaceBridge{}
+		case vmv1alpha1.InterfaceBindingPasst:
+			iface.Binding = &kubevirtv1.PluginBinding{Name: vmv1alpha1.InterfaceBindingPasst}
+		default:
+			iface.Masquerade = &kubevirtv1.InterfaceMasquerade{}
+		}
+		interfaceList = append(interfaceList, iface)
+	}
 
 	// Multus 网络接口
-	for i, net := range networks {
+	for _, net := range networks {
 		if net.NADName != "" {
-			interfaceList = append(interfaceList, kubevirtv1.Interface{
+			iface := kubevirtv1.Interface{
 				Name: net.NADName,
-				InterfaceBindingMethod: kubevirtv1.InterfaceBindingMethod{
-					Bridge: &kubevirtv1.InterfaceBridge{},
-				},
-			})
-			// 如果这是第一个 Multus 网络，也可以使用 SR-IOV 或其他类型
-			_ = i // 占位，后续可以根据配置选择不同的接口类型
+			}
+			applyInterfaceConfig(&iface, findNetworkConfig(netConfigs, net.Name))
+			// 使用 operator 分配的稳定 MAC 地址，避免 VM 重启后 MAC 变化
+			if net.MACAddress != "" {
+				iface.MacAddress = net.MACAddress
+			}
+			interfaceList = append(interfaceList, iface)
 		}
 	}
 
 	return interfaceList
 }
 
+// applyInterfaceConfig 根据网络配置设置接口的绑定方式、网卡型号、MAC/PCI 地址和启动顺序
+func applyInterfaceConfig(iface *kubevirtv1.Interface, netCfg *vmv1alpha1.NetworkConfig) {
+	switch interfaceBinding(netCfg) {
+	case vmv1alpha1.InterfaceBindingSRIOV:
+		iface.SRIOV = &kubevirtv1.InterfaceSRIOV{}
+	case vmv1alpha1.InterfaceBindingPlugin:
+		iface.Binding = &kubevirtv1.PluginBinding{Name: netCfg.BindingPlugin}
+	default:
+		iface.Bridge = &kubevirtv1.InterfaceBridge{}
+	}
+	if netCfg == nil {
+		return
+	}
+	iface.Model = netCfg.Model
+	iface.MacAddress = netCfg.MACAddress
+	iface.PciAddress = netCfg.PCIAddress
+	iface.BootOrder = netCfg.BootOrder
+}
+
+// interfaceBinding 返回网络的绑定方式，SR-IOV 网络默认使用 SR-IOV 绑定
+func interfaceBinding(netCfg *vmv1alpha1.NetworkConfig) string {
+	if netCfg == nil {
+		return vmv1alpha1.InterfaceBindingBridge
+	}
+	if netCfg.Binding != "" {
+		return netCfg.Binding
+	}
+	if netCfg.Type == "sriov" {
+		return vmv1alpha1.InterfaceBindingSRIOV
+	}
+	return vmv1alpha1.InterfaceBindingBridge
+}
+
+// resolveNetworkConfigs 返回 Wukong 的有效网络配置，引用 WukongNetwork 的网络使用其中的定义
+func resolveNetworkConfigs(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong) []vmv1alpha1.NetworkConfig {
+	netConfigs := make([]vmv1alpha1.NetworkConfig, 0, len(vmp.Spec.Networks))
+	for i := range vmp.Spec.Networks {
+		netCfg, err := network.ResolveNetworkConfig(ctx, c, &vmp.Spec.Networks[i])
+		if err != nil {
+			// 网络已由 ReconcileNetworks 解析过，这里失败时使用原始配置
+			log.FromContext(ctx).V(1).Info("Failed to resolve network config", "network", vmp.Spec.Networks[i].Name, "error", err)
+			netCfg = &vmp.Spec.Networks[i]
+		}
+		netConfigs = append(netConfigs, *netCfg)
+	}
+	return netConfigs
+}
+
+// findNetworkConfig 根据名称查找网络配置
+func findNetworkConfig(netConfigs []vmv1alpha1.NetworkConfig, name string) *vmv1alpha1.NetworkConfig {
+	for i := range netConfigs {
+		if netConfigs[i].Name == name {
+			return &netConfigs[i]
+		}
+	}
+	return nil
+}
+
+// applyBootDiskOrder 在有网卡设置启动顺序时，为启动盘设置排在所有网卡之后的启动顺序
+// KubeVirt 中只要有设备设置了启动顺序，未设置的设备就不会用于启动
+func applyBootDiskOrder(disks []kubevirtv1.Disk, interfaces []kubevirtv1.Interface, diskConfigs []vmv1alpha1.DiskConfig) {
+	var maxOrder uint
+	for _, iface := range interfaces {
+		if iface.BootOrder != nil && *iface.BootOrder > maxOrder {
+			maxOrder = *iface.BootOrder
+		}
+	}
+	if maxOrder == 0 {
+		return
+	}
+	for i := range disks {
+		if diskConfig := findDiskConfig(diskConfigs, disks[i].Name); diskConfig != nil && diskConfig.Boot && disks[i].BootOrder == nil {
+			order := maxOrder + 1
+			disks[i].BootOrder = &order
+		}
+	}
+}
+
 // buildVolumes 构建卷列表
-func buildVolumes(volumes []vmv1alpha1.VolumeStatus) []kubevirtv1.Volume {
+// 根据磁盘配置映射为 containerDisk、emptyDisk、ephemeral 或 PVC 卷
+func buildVolumes(diskConfigs []vmv1alpha1.DiskConfig, volumes []vmv1alpha1.VolumeStatus) ([]kubevirtv1.Volume, error) {
 	volList := make([]kubevirtv1.Volume, 0, len(volumes))
 	for _, vol := range volumes {
 		volume := kubevirtv1.Volume{
 			Name: vol.Name,
-			VolumeSource: kubevirtv1.VolumeSource{
-				PersistentVolumeClaim: &kubevirtv1.PersistentVolumeClaimVolumeSource{
-					PersistentVolumeClaimVolumeSource: corev1.PersistentVolumeClaimVolumeSource{
-						ClaimName: vol.PVCName,
-					},
+		}
+
+		diskConfig := findDiskConfig(diskConfigs, vol.Name)
+		switch {
+		case diskConfig != nil && diskConfig.ContainerDisk != nil:
+			volume.ContainerDisk = &kubevirtv1.ContainerDiskSource{
+				Image:           diskConfig.ContainerDisk.Image,
+				ImagePullPolicy: corev1.PullPolicy(diskConfig.ContainerDisk.ImagePullPolicy),
+				ImagePullSecret: diskConfig.ContainerDisk.ImagePullSecret,
+			}
+		case diskConfig != nil && diskConfig.EmptyDisk != nil:
+			capacity, err := resource.ParseQuantity(diskConfig.Size)
+			if err != nil {
+				// 跳过该卷会留下没有 Volume 的 Disk，KubeVirt 会拒绝整个 VM
+				return nil, fmt.Errorf("disk %s: invalid emptyDisk size %q: %w", vol.Name, diskConfig.Size, err)
+			}
+			volume.EmptyDisk = &kubevirtv1.EmptyDiskSource{
+				Capacity: capacity,
+			}
+		case diskConfig != nil && diskConfig.Ephemeral:
+			// 写入 copy-on-write overlay，PVC 只读
+			volume.Ephemeral = &kubevirtv1.EphemeralVolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: vol.PVCName,
+					ReadOnly:  true,
 				},
-			},
+			}
+		default:
+			volume.PersistentVolumeClaim = &kubevirtv1.PersistentVolumeClaimVolumeSource{
+				PersistentVolumeClaimVolumeSource: corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: vol.PVCName,
+				},
+			}
 		}
 		volList = append(volList, volume)
 	}
-	return volList
+	return volList, nil
 }
 
 // buildCloudInitData 构建 Cloud-Init 用户数据
-func buildCloudInitData(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong) string {
+// bootImage 是启动盘使用的 catalog 镜像（可以为 nil）
+// volumes 用于生成 Guest 加密磁盘的解锁配置
+// 网络配置不在用户数据中，见 buildNetworkData
+func buildCloudInitData(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, bootImage *vmv1alpha1.WukongImage, volumes []vmv1alpha1.VolumeStatus) string {
 	logger := log.FromContext(ctx)
 	cloudInit := "#cloud-config\n"
 
+	// 未配置用户时，使用镜像的默认用户（SSH Key 会注入到该用户）
+	if vmp.Spec.CloudInitUser == nil && bootImage != nil && bootImage.Spec.DefaultUser != "" {
+		cloudInit += fmt.Sprintf("user: %s\n", bootImage.Spec.DefaultUser)
+	}
+
 	// 配置用户（如果有）
 	if vmp.Spec.CloudInitUser != nil {
 		user := vmp.Spec.CloudInitUser
@@ -419,30 +650,17 @@ func buildCloudInitData(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wu
 		}
 	}
 
-	// 配置网络（如果有静态 IP）
-	hasNetworkConfig := false
-	for _, net := range vmp.Spec.Networks {
-		if net.IPConfig != nil && net.IPConfig.Mode == "static" && net.IPConfig.Address != nil {
-			if !hasNetworkConfig {
-				cloudInit += "\nnetwork:\n"
-				cloudInit += "  version: 2\n"
-				cloudInit += "  ethernets:\n"
-				hasNetworkConfig = true
-			}
-			cloudInit += fmt.Sprintf("    %s:\n", net.Name)
-			cloudInit += "      addresses:\n"
-			cloudInit += fmt.Sprintf("        - %s\n", *net.IPConfig.Address)
-			if net.IPConfig.Gateway != nil {
-				cloudInit += fmt.Sprintf("      gateway4: %s\n", *net.IPConfig.Gateway)
-			}
-			if len(net.IPConfig.DNSServers) > 0 {
-				cloudInit += "      nameservers:\n"
-				cloudInit += "        addresses:\n"
-				for _, dns := range net.IPConfig.DNSServers {
-					cloudInit += fmt.Sprintf("          - %s\n", dns)
-				}
-			}
-		}
+	// 格式化并解锁 Guest 加密磁盘（先于扩容执行）
+	bootCmds := guestEncryptionBootCmds(volumes)
+
+	// 磁盘可以扩容时，在 guest 内扩展分区和文件系统
+	if guestResizeEnabled(ctx, c, vmp, volumes) {
+		cloudInit += guestResizeCloudInit
+		bootCmds += guestResizeBootCmd
+	}
+
+	if bootCmds != "" {
+		cloudInit += "\nbootcmd:\n" + bootCmds
 	}
 
 	return cloudInit
@@ -512,3 +730,90 @@ func GetVMStatus(ctx context.Context, c client.Client, namespace, vmName string)
 
 	return phase, nodeName, nil
 }
+
+// GetVMIVolumeCapacities 获取运行中 VMI 的卷容量（KubeVirt 将 PVC 扩展同步给 guest 后更新）
+// VMI 不存在时返回 nil
+func GetVMIVolumeCapacities(ctx context.Context, c client.Client, namespace, vmName string) (map[string]resource.Quantity, error) {
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: vmName}, vmi); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	capacities := make(map[string]resource.Quantity, len(vmi.Status.VolumeStatus))
+	for _, vol := range vmi.Status.VolumeStatus {
+		if vol.PersistentVolumeClaimInfo == nil {
+			continue
+		}
+		if capacity, ok := vol.PersistentVolumeClaimInfo.Capacity[corev1.ResourceStorage]; ok {
+			capacities[vol.Name] = capacity
+		}
+	}
+	return capacities, nil
+}
+
+// GetVMICreationTime 获取运行中 VMI 的创建时间，即 VM 最近一次启动的时间
+// VMI 不存在时返回 nil
+func GetVMICreationTime(ctx context.Context, c client.Client, namespace, vmName string) (*metav1.Time, error) {
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: vmName}, vmi); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	created := vmi.CreationTimestamp
+	return &created, nil
+}
+
+// AttachedVolume 是运行中 VMI 使用的 PVC 卷
+type AttachedVolume struct {
+	// Name 是 VMI 中的卷名称
+	Name string
+	// ClaimName 是卷使用的 PVC 名称
+	ClaimName string
+	// Hotplugged 表示该卷是热插入的，可以热拔出
+	Hotplugged bool
+}
+
+// GetVMIAttachedVolumes 获取运行中 VMI 使用的 PVC 卷
+// VMI 不存在时返回 nil
+func GetVMIAttachedVolumes(ctx context.Context, c client.Client, namespace, vmName string) ([]AttachedVolume, error) {
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: vmName}, vmi); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	hotplugged := make(map[string]bool, len(vmi.Status.VolumeStatus))
+	for _, vol := range vmi.Status.VolumeStatus {
+		if vol.HotplugVolume != nil {
+			hotplugged[vol.Name] = true
+		}
+	}
+
+	attached := make([]AttachedVolume, 0, len(vmi.Spec.Volumes))
+	for _, vol := range vmi.Spec.Volumes {
+		claimName := ""
+		switch {
+		case vol.PersistentVolumeClaim != nil:
+			claimName = vol.PersistentVolumeClaim.ClaimName
+		case vol.DataVolume != nil:
+			claimName = vol.DataVolume.Name
+		case vol.Ephemeral != nil && vol.Ephemeral.PersistentVolumeClaim != nil:
+			claimName = vol.Ephemeral.PersistentVolumeClaim.ClaimName
+		default:
+			continue
+		}
+		attached = append(attached, AttachedVolume{
+			Name:       vol.Name,
+			ClaimName:  claimName,
+			Hotplugged: hotplugged[vol.Name],
+		})
+	}
+	return attached, nil
+}
@@ -0,0 +1,240 @@
+package kubevirt
+
+import (
+	"reflect"
+	"testing"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+	"github.com/kuihuar/novasphere/pkg/network"
+)
+
+func uintPtr(i uint) *uint { return &i }
+func boolPtr(b bool) *bool { return &b }
+
+func TestBuildVolumes(t *testing.T) {
+	diskConfigs := []vmv1alpha1.DiskConfig{
+		{Name: "system", Boot: true, Size: "20Gi"},
+		{Name: "scratch", Size: "5Gi", EmptyDisk: &vmv1alpha1.EmptyDiskSource{}},
+		{Name: "base", Ephemeral: true},
+	}
+	volumes := []vmv1alpha1.VolumeStatus{
+		{Name: "system", PVCName: "vm-system"},
+		{Name: "scratch"},
+		{Name: "base", PVCName: "base-image"},
+	}
+
+	got, err := buildVolumes(diskConfigs, volumes)
+	if err != nil {
+		t.Fatalf("buildVolumes() error = %v", err)
+	}
+	if len(got) != len(volumes) {
+		t.Fatalf("buildVolumes() returned %d volumes, want %d", len(got), len(volumes))
+	}
+	if got[0].PersistentVolumeClaim == nil || got[0].PersistentVolumeClaim.ClaimName != "vm-system" {
+		t.Errorf("system volume = %+v, want PVC vm-system", got[0].VolumeSource)
+	}
+	if got[1].EmptyDisk == nil || got[1].EmptyDisk.Capacity.String() != "5Gi" {
+		t.Errorf("scratch volume = %+v, want 5Gi emptyDisk", got[1].VolumeSource)
+	}
+	if got[2].Ephemeral == nil || !got[2].Ephemeral.PersistentVolumeClaim.ReadOnly {
+		t.Errorf("base volume = %+v, want read-only ephemeral PVC", got[2].VolumeSource)
+	}
+}
+
+func TestBuildVolumesInvalidEmptyDiskSize(t *testing.T) {
+	diskConfigs := []vmv1alpha1.DiskConfig{{Name: "scratch", Size: "lots", EmptyDisk: &vmv1alpha1.EmptyDiskSource{}}}
+	volumes := []vmv1alpha1.VolumeStatus{{Name: "scratch"}}
+
+	// 不能只跳过该卷，否则 Disk 没有对应的 Volume
+	if _, err := buildVolumes(diskConfigs, volumes); err == nil {
+		t.Error("buildVolumes() expected error for an invalid emptyDisk size")
+	}
+}
+
+func TestBuildInterfacesMultus(t *testing.T) {
+	disabled := &vmv1alpha1.PodNetworkSpec{Enabled: boolPtr(false)}
+
+	tests := []struct {
+		name   string
+		netCfg vmv1alpha1.NetworkConfig
+		mac    string
+		want   kubevirtv1.Interface
+	}{
+		{
+			name:   "bridge by default",
+			netCfg: vmv1alpha1.NetworkConfig{Name: "tenant", Type: "bridge"},
+			want:   kubevirtv1.Interface{Name: "vm-tenant-nad", InterfaceBindingMethod: kubevirtv1.InterfaceBindingMethod{Bridge: &kubevirtv1.InterfaceBridge{}}},
+		},
+		{
+			name:   "SR-IOV binding for sriov networks",
+			netCfg: vmv1alpha1.NetworkConfig{Name: "tenant", Type: "sriov"},
+			want:   kubevirtv1.Interface{Name: "vm-tenant-nad", InterfaceBindingMethod: kubevirtv1.InterfaceBindingMethod{SRIOV: &kubevirtv1.InterfaceSRIOV{}}},
+		},
+		{
+			name:   "binding plugin",
+			netCfg: vmv1alpha1.NetworkConfig{Name: "tenant", Type: "bridge", Binding: vmv1alpha1.InterfaceBindingPlugin, BindingPlugin: "managedtap"},
+			want:   kubevirtv1.Interface{Name: "vm-tenant-nad", Binding: &kubevirtv1.PluginBinding{Name: "managedtap"}},
+		},
+		{
+			name: "model, PCI address and boot order",
+			netCfg: vmv1alpha1.NetworkConfig{
+				Name: "tenant", Type: "bridge", Model: "e1000e", PCIAddress: "0000:81:01.0", BootOrder: uintPtr(1),
+			},
+			want: kubevirtv1.Interface{
+				Name:                   "vm-tenant-nad",
+				InterfaceBindingMethod: kubevirtv1.InterfaceBindingMethod{Bridge: &kubevirtv1.InterfaceBridge{}},
+				Model:                  "e1000e",
+				PciAddress:             "0000:81:01.0",
+				BootOrder:              uintPtr(1),
+			},
+		},
+		{
+			name:   "configured MAC address",
+			netCfg: vmv1alpha1.NetworkConfig{Name: "tenant", Type: "bridge", MACAddress: "02:00:00:00:00:01"},
+			want:   kubevirtv1.Interface{Name: "vm-tenant-nad", InterfaceBindingMethod: kubevirtv1.InterfaceBindingMethod{Bridge: &kubevirtv1.InterfaceBridge{}}, MacAddress: "02:00:00:00:00:01"},
+		},
+		{
+			name:   "allocated MAC address",
+			netCfg: vmv1alpha1.NetworkConfig{Name: "tenant", Type: "bridge"},
+			mac:    "02:4e:53:00:00:01",
+			want:   kubevirtv1.Interface{Name: "vm-tenant-nad", InterfaceBindingMethod: kubevirtv1.InterfaceBindingMethod{Bridge: &kubevirtv1.InterfaceBridge{}}, MacAddress: "02:4e:53:00:00:01"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			networks := []vmv1alpha1.NetworkStatus{{Name: "tenant", NADName: "vm-tenant-nad", MACAddress: tt.mac}}
+			got := buildInterfaces(disabled, "", []vmv1alpha1.NetworkConfig{tt.netCfg}, networks)
+			if len(got) != 1 || !reflect.DeepEqual(got[0], tt.want) {
+				t.Errorf("buildInterfaces() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// 未连接的网络（没有 NAD）不生成接口
+	networks := []vmv1alpha1.NetworkStatus{{Name: "tenant", Degraded: true}}
+	if got := buildInterfaces(disabled, "", []vmv1alpha1.NetworkConfig{{Name: "tenant", Type: "bridge"}}, networks); len(got) != 0 {
+		t.Errorf("buildInterfaces() = %+v for a network without NAD, want none", got)
+	}
+}
+
+func TestApplyBootDiskOrder(t *testing.T) {
+	diskConfigs := []vmv1alpha1.DiskConfig{{Name: "system", Boot: true}, {Name: "data"}}
+	newDisks := func() []kubevirtv1.Disk {
+		return []kubevirtv1.Disk{{Name: "system"}, {Name: "data"}}
+	}
+
+	// 没有网卡设置启动顺序时不修改磁盘
+	disks := newDisks()
+	applyBootDiskOrder(disks, []kubevirtv1.Interface{{Name: "default"}}, diskConfigs)
+	if disks[0].BootOrder != nil || disks[1].BootOrder != nil {
+		t.Errorf("boot orders = %v, %v, want none", disks[0].BootOrder, disks[1].BootOrder)
+	}
+
+	// 启动盘排在所有网卡之后，其他磁盘不参与启动
+	disks = newDisks()
+	interfaces := []kubevirtv1.Interface{{Name: "pxe", BootOrder: uintPtr(2)}, {Name: "backup", BootOrder: uintPtr(1)}}
+	applyBootDiskOrder(disks, interfaces, diskConfigs)
+	if disks[0].BootOrder == nil || *disks[0].BootOrder != 3 {
+		t.Errorf("boot disk order = %v, want 3", disks[0].BootOrder)
+	}
+	if disks[1].BootOrder != nil {
+		t.Errorf("data disk order = %d, want none", *disks[1].BootOrder)
+	}
+}
+
+func TestBuildPodInterface(t *testing.T) {
+	const podMAC = "02:4e:53:00:00:10"
+
+	tests := []struct {
+		name       string
+		podNetwork *vmv1alpha1.PodNetworkSpec
+		want       []kubevirtv1.Interface
+	}{
+		{
+			name: "masquerade by default",
+			want: []kubevirtv1.Interface{{
+				Name:                   network.PodNetworkName,
+				MacAddress:             podMAC,
+				InterfaceBindingMethod: kubevirtv1.InterfaceBindingMethod{Masquerade: &kubevirtv1.InterfaceMasquerade{}},
+			}},
+		},
+		{
+			name:       "bridge binding",
+			podNetwork: &vmv1alpha1.PodNetworkSpec{Binding: vmv1alpha1.InterfaceBindingBridge},
+			want: []kubevirtv1.Interface{{
+				Name:                   network.PodNetworkName,
+				MacAddress:             podMAC,
+				InterfaceBindingMethod: kubevirtv1.InterfaceBindingMethod{Bridge: &kubevirtv1.InterfaceBridge{}},
+			}},
+		},
+		{
+			name:       "passt binding plugin",
+			podNetwork: &vmv1alpha1.PodNetworkSpec{Enabled: boolPtr(true), Binding: vmv1alpha1.InterfaceBindingPasst},
+			want: []kubevirtv1.Interface{{
+				Name:       network.PodNetworkName,
+				MacAddress: podMAC,
+				Binding:    &kubevirtv1.PluginBinding{Name: "passt"},
+			}},
+		},
+		{
+			name:       "disabled",
+			podNetwork: &vmv1alpha1.PodNetworkSpec{Enabled: boolPtr(false), Binding: vmv1alpha1.InterfaceBindingBridge},
+			want:       []kubevirtv1.Interface{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildInterfaces(tt.podNetwork, podMAC, nil, nil)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildInterfaces() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildNetworks(t *testing.T) {
+	podNet := kubevirtv1.Network{
+		Name:          network.PodNetworkName,
+		NetworkSource: kubevirtv1.NetworkSource{Pod: &kubevirtv1.PodNetwork{}},
+	}
+	multusNet := func(nad string, def bool) kubevirtv1.Network {
+		return kubevirtv1.Network{
+			Name:          nad,
+			NetworkSource: kubevirtv1.NetworkSource{Multus: &kubevirtv1.MultusNetwork{NetworkName: nad, Default: def}},
+		}
+	}
+	networks := []vmv1alpha1.NetworkStatus{
+		{Name: "tenant", NADName: "vm-tenant-nad"},
+		{Name: "storage", NADName: "vm-storage-nad"},
+		{Name: "pending"},
+	}
+
+	tests := []struct {
+		name       string
+		podNetwork *vmv1alpha1.PodNetworkSpec
+		netConfigs []vmv1alpha1.NetworkConfig
+		want       []kubevirtv1.Network
+	}{
+		{
+			name:       "pod network and secondary networks",
+			netConfigs: []vmv1alpha1.NetworkConfig{{Name: "tenant"}, {Name: "storage"}, {Name: "pending"}},
+			want:       []kubevirtv1.Network{podNet, multusNet("vm-tenant-nad", false), multusNet("vm-storage-nad", false)},
+		},
+		{
+			name:       "primary network replaces the pod network",
+			podNetwork: &vmv1alpha1.PodNetworkSpec{Enabled: boolPtr(false)},
+			netConfigs: []vmv1alpha1.NetworkConfig{{Name: "tenant", Primary: true}, {Name: "storage"}, {Name: "pending"}},
+			want:       []kubevirtv1.Network{multusNet("vm-tenant-nad", true), multusNet("vm-storage-nad", false)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildNetworks(tt.podNetwork, tt.netConfigs, networks)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildNetworks() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
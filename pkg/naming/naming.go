@@ -0,0 +1,58 @@
+// Package naming generates deterministic object names and label values from user-provided names.
+package naming
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// MaxLength keeps generated names usable as label values and in the names of derived objects
+	MaxLength = 63
+	// hashLength is the length of the hash suffix of generated names
+	hashLength = 8
+)
+
+// Join returns the parts joined with dashes.
+// The plain join is used when no part contains a dash, since such names cannot collide
+// (e.g., "a-b" + "c" and "a" + "b-c" would both give "a-b-c"). Otherwise, or when the name
+// would be longer than MaxLength, the name is truncated and a hash of the parts is appended.
+func Join(parts ...string) string {
+	name := strings.Join(parts, "-")
+	ambiguous := false
+	for _, part := range parts {
+		if strings.Contains(part, "-") {
+			ambiguous = true
+			break
+		}
+	}
+	if !ambiguous && len(name) <= MaxLength {
+		return name
+	}
+
+	// "/" 不能出现在名称中，保证哈希输入没有歧义
+	return truncate(name, MaxLength-hashLength-1) + "-" + shortHash(strings.Join(parts, "/"))
+}
+
+// LabelValue returns a valid label value for a name; names longer than MaxLength are truncated and hashed.
+func LabelValue(name string) string {
+	if len(name) <= MaxLength {
+		return name
+	}
+	return truncate(name, MaxLength-hashLength-1) + "-" + shortHash(name)
+}
+
+// truncate 将名称截断到 maxLen，并去掉末尾的 "-" 和 "."，保证名称以字母或数字结尾
+func truncate(name string, maxLen int) string {
+	if len(name) > maxLen {
+		name = name[:maxLen]
+	}
+	return strings.TrimRight(name, "-.")
+}
+
+// shortHash 返回 s 的短十六进制哈希
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:hashLength]
+}
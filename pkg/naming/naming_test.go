@@ -0,0 +1,68 @@
+package naming
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestJoin(t *testing.T) {
+	long := strings.Repeat("a", 60)
+
+	tests := []struct {
+		name  string
+		parts []string
+		want  string
+	}{
+		{name: "parts without dashes", parts: []string{"vm", "system"}, want: "vm-system"},
+		{name: "parts with dots", parts: []string{"ubuntu", "fast.ssd"}, want: "ubuntu-fast.ssd"},
+		{name: "first part with dash", parts: []string{"web-1", "system"}, want: "web-1-system-" + shortHash("web-1/system")},
+		{name: "second part with dash", parts: []string{"vm", "data-1"}, want: "vm-data-1-" + shortHash("vm/data-1")},
+		{name: "too long", parts: []string{long, "system"}, want: long[:54] + "-" + shortHash(long+"/system")},
+		{name: "truncated at a dash", parts: []string{strings.Repeat("a", 53), "database01"}, want: strings.Repeat("a", 53) + "-" + shortHash(strings.Repeat("a", 53)+"/database01")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Join(tt.parts...)
+			if got != tt.want {
+				t.Errorf("Join(%q) = %q, want %q", tt.parts, got, tt.want)
+			}
+			if len(got) > MaxLength {
+				t.Errorf("Join(%q) is %d characters long", tt.parts, len(got))
+			}
+			if errs := validation.IsDNS1123Subdomain(got); len(errs) > 0 {
+				t.Errorf("Join(%q) = %q is not a valid name: %v", tt.parts, got, errs)
+			}
+		})
+	}
+
+	// 拼接结果相同的不同名称必须生成不同的名称
+	collisions := [][2][]string{
+		{{"a-b", "c"}, {"a", "b-c"}},
+		{{"ubuntu", "ceph-rbd"}, {"ubuntu-ceph", "rbd"}},
+	}
+	for _, pair := range collisions {
+		if Join(pair[0]...) == Join(pair[1]...) {
+			t.Errorf("Join(%q) and Join(%q) collide: %q", pair[0], pair[1], Join(pair[0]...))
+		}
+	}
+}
+
+func TestLabelValue(t *testing.T) {
+	if got := LabelValue("vm"); got != "vm" {
+		t.Errorf("LabelValue(vm) = %q, want vm", got)
+	}
+
+	long := strings.Repeat("a", 70)
+	got := LabelValue(long)
+	if len(got) > MaxLength {
+		t.Errorf("LabelValue() is %d characters long", len(got))
+	}
+	if errs := validation.IsValidLabelValue(got); len(errs) > 0 {
+		t.Errorf("LabelValue() = %q is not a valid label value: %v", got, errs)
+	}
+	if other := LabelValue(strings.Repeat("a", 71)); other == got {
+		t.Errorf("LabelValue() = %q for two names", got)
+	}
+}
@@ -0,0 +1,189 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+const (
+	// CNIVersion is the CNI spec version of the generated NetworkAttachmentDefinitions
+	CNIVersion = "1.0.0"
+
+	// AnnotationResourceName is the NetworkAttachmentDefinition annotation requesting a device plugin
+	// resource (e.g., an SR-IOV VF) for the pods attached to the network
+	AnnotationResourceName = "k8s.v1.cni.cncf.io/resourceName"
+
+	// defaultMacvlanMode 是 macvlan 网络的默认模式
+	defaultMacvlanMode = "bridge"
+)
+
+// buildCNIConfig 根据网络类型构造 CNI 配置 JSON 字符串，name 为 CNI 网络名称（NAD 名称）。
+// ipam 为 nil 时不配置 CNI IPAM。
+func buildCNIConfig(netCfg *vmv1alpha1.NetworkConfig, name string, ipam map[string]interface{}) (string, error) {
+	var cfg map[string]interface{}
+	switch netCfg.Type {
+	case "bridge":
+		cfg = bridgeCNIConfig(netCfg)
+	case "macvlan":
+		cfg = macvlanCNIConfig(netCfg)
+	case "sriov":
+		cfg = sriovCNIConfig(netCfg)
+	case "ovs":
+		cfg = ovsCNIConfig(netCfg)
+	default:
+		return "", fmt.Errorf("unsupported network type %q for network %s", netCfg.Type, netCfg.Name)
+	}
+
+	cfg["cniVersion"] = CNIVersion
+	cfg["name"] = name
+	if ipam != nil {
+		cfg["ipam"] = ipam
+	}
+
+	// map 序列化时按 key 排序，保证生成的配置稳定
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// bridgeCNIConfig 构造 Linux bridge 插件配置
+func bridgeCNIConfig(netCfg *vmv1alpha1.NetworkConfig) map[string]interface{} {
+	cfg := map[string]interface{}{
+		"type":   "bridge",
+		"bridge": bridgeName(netCfg),
+	}
+	// NMState 为 bridge 网络创建了 VLAN 子接口时，网桥本身已在该 VLAN 上，不再打标签
+	if netCfg.VLANID != nil && netCfg.HostInterface == "" {
+		cfg["vlan"] = *netCfg.VLANID
+	}
+	if netCfg.MTU != nil {
+		cfg["mtu"] = *netCfg.MTU
+	}
+	return cfg
+}
+
+// macvlanCNIConfig 构造 macvlan 插件配置，VLAN 通过主机上的 VLAN 子接口作为 master 实现，子接口由 NNCP 创建
+func macvlanCNIConfig(netCfg *vmv1alpha1.NetworkConfig) map[string]interface{} {
+	master := netCfg.HostInterface
+	if netCfg.VLANID != nil {
+		master = vlanInterfaceName(netCfg.HostInterface, *netCfg.VLANID)
+	}
+	mode := netCfg.MacvlanMode
+	if mode == "" {
+		mode = defaultMacvlanMode
+	}
+	cfg := map[string]interface{}{
+		"type":   "macvlan",
+		"master": master,
+		"mode":   mode,
+	}
+	if netCfg.MTU != nil {
+		cfg["mtu"] = *netCfg.MTU
+	}
+	return cfg
+}
+
+// sriovCNIConfig 构造 SR-IOV 插件配置，VF 由 NAD 的 resourceName 注解向 device plugin 申请
+func sriovCNIConfig(netCfg *vmv1alpha1.NetworkConfig) map[string]interface{} {
+	cfg := map[string]interface{}{
+		"type": "sriov",
+	}
+	if netCfg.VLANID != nil {
+		cfg["vlan"] = *netCfg.VLANID
+	}
+	if netCfg.SRIOV != nil {
+		if netCfg.SRIOV.SpoofChk != nil {
+			cfg["spoofchk"] = onOff(*netCfg.SRIOV.SpoofChk)
+		}
+		if netCfg.SRIOV.Trust != nil {
+			cfg["trust"] = onOff(*netCfg.SRIOV.Trust)
+		}
+	}
+	return cfg
+}
+
+// ovsCNIConfig 构造 OVS 插件配置，支持 access VLAN 和 trunk VLAN
+func ovsCNIConfig(netCfg *vmv1alpha1.NetworkConfig) map[string]interface{} {
+	cfg := map[string]interface{}{
+		"type":   "ovs",
+		"bridge": bridgeName(netCfg),
+	}
+	if netCfg.VLANID != nil {
+		cfg["vlan"] = *netCfg.VLANID
+	}
+	if len(netCfg.Trunk) > 0 {
+		trunk := make([]map[string]int, 0, len(netCfg.Trunk))
+		for _, r := range netCfg.Trunk {
+			if r.ID != nil {
+				trunk = append(trunk, map[string]int{"id": *r.ID})
+			} else if r.MinID != nil && r.MaxID != nil {
+				trunk = append(trunk, map[string]int{"minID": *r.MinID, "maxID": *r.MaxID})
+			}
+		}
+		cfg["trunk"] = trunk
+	}
+	if netCfg.MTU != nil {
+		cfg["mtu"] = *netCfg.MTU
+	}
+	return cfg
+}
+
+// nadAnnotations 返回 NAD 的注解，SR-IOV 网络需要声明 device plugin 资源
+func nadAnnotations(netCfg *vmv1alpha1.NetworkConfig) map[string]string {
+	if netCfg.Type != "sriov" || netCfg.SRIOV == nil {
+		return nil
+	}
+	return map[string]string{AnnotationResourceName: netCfg.SRIOV.ResourceName}
+}
+
+// ipamForIPConfig 简单根据 IPConfig 选择 ipam 类型
+func ipamForIPConfig(ipConfig *vmv1alpha1.IPConfigSpec) map[string]interface{} {
+	if ipConfig == nil {
+		return nil
+	}
+	if ipConfig.Mode == "dhcp" {
+		return map[string]interface{}{
+			"type": "dhcp",
+		}
+	}
+	if ipConfig.Mode == "static" && ipConfig.Address != nil {
+		ipam := map[string]interface{}{
+			"type": "static",
+			"addresses": []map[string]string{
+				{
+					"address": *ipConfig.Address,
+				},
+			},
+		}
+		if ipConfig.Gateway != nil {
+			ipam["routes"] = []map[string]string{
+				{
+					"dst": "0.0.0.0/0",
+					"gw":  *ipConfig.Gateway,
+				},
+			}
+		}
+		return ipam
+	}
+	return nil
+}
+
+// bridgeName 返回 bridge/ovs 网络使用的网桥名称
+func bridgeName(netCfg *vmv1alpha1.NetworkConfig) string {
+	if netCfg.BridgeName != "" {
+		return netCfg.BridgeName
+	}
+	return fmt.Sprintf("br-%s", netCfg.Name)
+}
+
+// onOff 将布尔值转换为 SR-IOV CNI 使用的 on/off
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,153 @@
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+func intPtr(i int) *int       { return &i }
+func boolPtr(b bool) *bool    { return &b }
+func strPtr(s string) *string { return &s }
+
+func TestBuildCNIConfig(t *testing.T) {
+	tests := []struct {
+		golden string
+		netCfg vmv1alpha1.NetworkConfig
+		ipam   map[string]interface{}
+	}{
+		{
+			golden: "bridge-vlan",
+			netCfg: vmv1alpha1.NetworkConfig{
+				Name:   "tenant",
+				Type:   "bridge",
+				VLANID: intPtr(100),
+				MTU:    intPtr(9000),
+			},
+			ipam: ipamForIPConfig(&vmv1alpha1.IPConfigSpec{Mode: "dhcp"}),
+		},
+		{
+			golden: "bridge-host-interface",
+			netCfg: vmv1alpha1.NetworkConfig{
+				Name:          "tenant",
+				Type:          "bridge",
+				BridgeName:    "br-tenant",
+				HostInterface: "eth1",
+				VLANID:        intPtr(100),
+			},
+			ipam: ipamForIPConfig(&vmv1alpha1.IPConfigSpec{
+				Mode:    "static",
+				Address: strPtr("192.168.100.10/24"),
+				Gateway: strPtr("192.168.100.1"),
+			}),
+		},
+		{
+			golden: "macvlan",
+			netCfg: vmv1alpha1.NetworkConfig{
+				Name:          "external",
+				Type:          "macvlan",
+				HostInterface: "eth2",
+				VLANID:        intPtr(200),
+				MacvlanMode:   "vepa",
+				MTU:           intPtr(1500),
+			},
+		},
+		{
+			golden: "sriov",
+			netCfg: vmv1alpha1.NetworkConfig{
+				Name:   "fast",
+				Type:   "sriov",
+				VLANID: intPtr(300),
+				SRIOV: &vmv1alpha1.SRIOVConfig{
+					ResourceName: "intel.com/sriov_netdevice",
+					SpoofChk:     boolPtr(false),
+					Trust:        boolPtr(true),
+				},
+			},
+		},
+		{
+			golden: "ovs-trunk",
+			netCfg: vmv1alpha1.NetworkConfig{
+				Name:       "trunk",
+				Type:       "ovs",
+				BridgeName: "br-ovs",
+				Trunk: []vmv1alpha1.VLANRange{
+					{ID: intPtr(10)},
+					{MinID: intPtr(100), MaxID: intPtr(199)},
+				},
+				MTU: intPtr(9000),
+			},
+		},
+		{
+			golden: "shared-whereabouts",
+			netCfg: *sharedNetworkConfig(&vmv1alpha1.NetworkConfig{Name: "vlan400", NetworkRef: "vlan400"}, &vmv1alpha1.WukongNetwork{
+				ObjectMeta: metav1.ObjectMeta{Name: "vlan400"},
+				Spec:       vmv1alpha1.WukongNetworkSpec{Type: "bridge", VLANID: intPtr(400)},
+			}),
+			ipam: ipamForNetwork(&vmv1alpha1.NetworkIPAMSpec{
+				Type:    vmv1alpha1.NetworkIPAMWhereabouts,
+				Range:   "10.40.0.0/24",
+				Gateway: "10.40.0.1",
+				Exclude: []string{"10.40.0.0/28"},
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			config, err := buildCNIConfig(&tt.netCfg, tt.golden+"-nad", tt.ipam)
+			if err != nil {
+				t.Fatalf("buildCNIConfig() error = %v", err)
+			}
+
+			var got bytes.Buffer
+			if err := json.Indent(&got, []byte(config), "", "  "); err != nil {
+				t.Fatalf("buildCNIConfig() returned invalid JSON: %v", err)
+			}
+			got.WriteByte('\n')
+
+			path := filepath.Join("testdata", tt.golden+".json")
+			if *update {
+				if err := os.WriteFile(path, got.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("buildCNIConfig() mismatch with %s:\ngot:\n%s\nwant:\n%s", path, got.String(), want)
+			}
+		})
+	}
+}
+
+func TestBuildCNIConfigUnsupportedType(t *testing.T) {
+	if _, err := buildCNIConfig(&vmv1alpha1.NetworkConfig{Name: "x", Type: "ipvlan"}, "x-nad", nil); err == nil {
+		t.Error("buildCNIConfig() expected error for unsupported type")
+	}
+}
+
+func TestNADAnnotations(t *testing.T) {
+	netCfg := &vmv1alpha1.NetworkConfig{
+		Name:  "fast",
+		Type:  "sriov",
+		SRIOV: &vmv1alpha1.SRIOVConfig{ResourceName: "intel.com/sriov_netdevice"},
+	}
+	if got := nadAnnotations(netCfg)[AnnotationResourceName]; got != "intel.com/sriov_netdevice" {
+		t.Errorf("nadAnnotations() resourceName = %q", got)
+	}
+	if got := nadAnnotations(&vmv1alpha1.NetworkConfig{Name: "tenant", Type: "bridge"}); got != nil {
+		t.Errorf("nadAnnotations() = %v, want nil for bridge", got)
+	}
+}
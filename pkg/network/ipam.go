@@ -0,0 +1,398 @@
+package network
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+const (
+	// LabelPool is the label set on IPLeases with the name of their IPPool
+	LabelPool = "wukong.novasphere.dev/pool"
+	// LabelLeaseOwner is the label set on IPLeases with the UID of the Wukong holding them
+	LabelLeaseOwner = "wukong.novasphere.dev/owner"
+)
+
+// ErrIPPoolExhausted is returned when an IPPool has no free address left
+var ErrIPPoolExhausted = stderrors.New("IP pool exhausted")
+
+// ReconcileIPAM allocates an address from the IPPool of every network with ipConfig.mode pool,
+// records it in the network statuses and releases the leases the Wukong does not need anymore.
+func ReconcileIPAM(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, statuses []vmv1alpha1.NetworkStatus) error {
+	logger := log.FromContext(ctx)
+
+	desired := make(map[string]bool)
+	for i := range statuses {
+		netCfg := findNetworkConfig(vmp, statuses[i].Name)
+		if netCfg == nil || netCfg.IPConfig == nil || netCfg.IPConfig.Mode != vmv1alpha1.IPModePool {
+			continue
+		}
+
+		lease, err := AllocateIP(ctx, c, vmp, netCfg.Name, netCfg.IPConfig.Pool)
+		if err != nil {
+			logger.Error(err, "failed to allocate IP address", "network", netCfg.Name, "pool", netCfg.IPConfig.Pool)
+			return fmt.Errorf("failed to allocate IP address for network %s from pool %s: %w", netCfg.Name, netCfg.IPConfig.Pool, err)
+		}
+		desired[lease.Name] = true
+		statuses[i].IPAddress = lease.Spec.Address
+	}
+
+	return releaseIPs(ctx, c, vmp, desired)
+}
+
+// ReleaseIPs deletes all IPLeases held by the Wukong.
+func ReleaseIPs(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong) error {
+	return releaseIPs(ctx, c, vmp, nil)
+}
+
+// releaseIPs 删除 Wukong 持有的、不在 keep 中的 IPLease
+func releaseIPs(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, keep map[string]bool) error {
+	logger := log.FromContext(ctx)
+
+	if vmp.UID == "" {
+		return nil
+	}
+	leases := &vmv1alpha1.IPLeaseList{}
+	if err := c.List(ctx, leases, client.MatchingLabels{LabelLeaseOwner: string(vmp.UID)}); err != nil {
+		return err
+	}
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		if keep[lease.Name] {
+			continue
+		}
+		logger.Info("Releasing IP address", "pool", lease.Spec.Pool, "address", lease.Spec.Address, "network", lease.Spec.Network)
+		if err := c.Delete(ctx, lease); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// AllocateIP returns the IPLease of a Wukong network in the given IPPool, allocating a free
+// address if the network does not hold one yet.
+// Addresses reserved for the network are allocated first; the network and broadcast addresses,
+// the gateway, excluded addresses and addresses reserved for other networks are never allocated.
+// Allocation relies on IPLeases being named after the address: creating an existing lease fails,
+// so concurrent allocations can never hand out the same address.
+func AllocateIP(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, networkName, poolName string) (*vmv1alpha1.IPLease, error) {
+	logger := log.FromContext(ctx)
+
+	pool := &vmv1alpha1.IPPool{}
+	if err := c.Get(ctx, client.ObjectKey{Name: poolName}, pool); err != nil {
+		return nil, err
+	}
+	prefix, err := netip.ParsePrefix(pool.Spec.CIDR)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q of IPPool %s: %w", pool.Spec.CIDR, poolName, err)
+	}
+	prefix = prefix.Masked()
+
+	leases := &vmv1alpha1.IPLeaseList{}
+	if err := c.List(ctx, leases, client.MatchingLabels{LabelPool: poolName}); err != nil {
+		return nil, err
+	}
+
+	// 1. 已经持有地址则直接返回
+	leased := make(map[netip.Addr]bool, len(leases.Items))
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		if lease.Labels[LabelLeaseOwner] == string(vmp.UID) && lease.Spec.Network == networkName {
+			return lease, nil
+		}
+		if addr, err := netip.ParseAddr(lease.Spec.Address); err == nil {
+			leased[addr] = true
+		}
+	}
+
+	// 2. 优先使用为该网络预留的地址
+	excluded, err := excludedAddresses(pool)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range pool.Spec.Reservations {
+		if r.Namespace != vmp.Namespace || r.Wukong != vmp.Name || r.Network != networkName {
+			continue
+		}
+		addr, err := netip.ParseAddr(r.Address)
+		if err != nil || !prefix.Contains(addr) {
+			return nil, fmt.Errorf("invalid reserved address %q in IPPool %s", r.Address, poolName)
+		}
+		if leased[addr] {
+			return nil, fmt.Errorf("reserved address %s of IPPool %s is leased to another network", addr, poolName)
+		}
+		return createLease(ctx, c, vmp, networkName, poolName, addr)
+	}
+
+	// 3. 按顺序查找空闲地址
+	for addr := firstHost(prefix); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+		if isBroadcast(prefix, addr) {
+			break
+		}
+		if leased[addr] || excluded.contains(addr) {
+			continue
+		}
+		lease, err := createLease(ctx, c, vmp, networkName, poolName, addr)
+		if errors.IsAlreadyExists(err) {
+			// 并发分配时地址已被占用，继续尝试下一个
+			logger.V(1).Info("IP address allocated concurrently, trying next", "pool", poolName, "address", addr)
+			continue
+		}
+		return lease, err
+	}
+	return nil, ErrIPPoolExhausted
+}
+
+// PoolCapacity returns the number of allocatable addresses of an IPPool: the addresses of the CIDR
+// without the network and broadcast addresses, the gateway and excluded addresses. Reserved addresses
+// are included, since they are allocated to their networks. It saturates at math.MaxInt64.
+func PoolCapacity(pool *vmv1alpha1.IPPool) int64 {
+	prefix, err := netip.ParsePrefix(pool.Spec.CIDR)
+	if err != nil {
+		return 0
+	}
+	prefix = prefix.Masked()
+	unavailable, err := unavailableAddresses(pool, false)
+	if err != nil {
+		return 0
+	}
+	return hostCount(prefix) - countInPool(prefix, unavailable)
+}
+
+// PoolExhausted returns true if no address of an IPPool is left for networks without a reservation,
+// given the leases of the pool.
+func PoolExhausted(pool *vmv1alpha1.IPPool, leases []vmv1alpha1.IPLease) bool {
+	prefix, err := netip.ParsePrefix(pool.Spec.CIDR)
+	if err != nil {
+		return true
+	}
+	prefix = prefix.Masked()
+	excluded, err := excludedAddresses(pool)
+	if err != nil {
+		return true
+	}
+
+	free := hostCount(prefix) - countInPool(prefix, excluded)
+	for i := range leases {
+		addr, err := netip.ParseAddr(leases[i].Spec.Address)
+		if err != nil || !prefix.Contains(addr) || excluded.contains(addr) {
+			continue
+		}
+		free--
+	}
+	return free <= 0
+}
+
+// hostCount 返回网段中可分配的地址数（不含网络地址和 IPv4 广播地址），超过 math.MaxInt64 时取 math.MaxInt64
+func hostCount(prefix netip.Prefix) int64 {
+	size := prefixSize(prefix)
+	if size == math.MaxInt64 || pointToPoint(prefix) {
+		return size
+	}
+	if prefix.Addr().Is4() {
+		return size - 2
+	}
+	return size - 1
+}
+
+// prefixSize 返回网段的地址数，超过 math.MaxInt64 时取 math.MaxInt64
+func prefixSize(prefix netip.Prefix) int64 {
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits >= 63 {
+		return math.MaxInt64
+	}
+	return int64(1) << hostBits
+}
+
+// countInPool 返回集合中属于网段可分配地址的数量
+// CIDR 网段之间要么互不相交要么相互包含，去掉被包含的网段后即可直接求和
+func countInPool(pool netip.Prefix, set *addressSet) int64 {
+	prefixes := make([]netip.Prefix, 0, len(set.addrs)+len(set.prefixes))
+	for addr := range set.addrs {
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	for _, p := range set.prefixes {
+		if p.Overlaps(pool) {
+			// 包含整个地址池的网段只计地址池本身
+			if p.Bits() < pool.Bits() {
+				p = pool
+			}
+			prefixes = append(prefixes, p)
+		}
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return prefixes[i].Bits() < prefixes[j].Bits() })
+
+	var count int64
+	kept := make([]netip.Prefix, 0, len(prefixes))
+	for _, p := range prefixes {
+		if !pool.Contains(p.Addr()) {
+			continue
+		}
+		covered := false
+		for _, k := range kept {
+			if k.Contains(p.Addr()) {
+				covered = true
+				break
+			}
+		}
+		if covered {
+			continue
+		}
+		kept = append(kept, p)
+		if size := prefixSize(p); count > math.MaxInt64-size {
+			count = math.MaxInt64
+		} else {
+			count += size
+		}
+	}
+
+	// 网络地址和广播地址不在 hostCount 中，不能重复扣除
+	if !pointToPoint(pool) && count < math.MaxInt64 {
+		if set.contains(pool.Addr()) {
+			count--
+		}
+		if last := lastAddr(pool); pool.Addr().Is4() && set.contains(last) {
+			count--
+		}
+	}
+	return count
+}
+
+// lastAddr 返回网段的最后一个地址
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// createLease 创建 IPLease，名称由地址池和地址生成，保证地址唯一
+func createLease(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, networkName, poolName string, addr netip.Addr) (*vmv1alpha1.IPLease, error) {
+	logger := log.FromContext(ctx)
+
+	lease := &vmv1alpha1.IPLease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: leaseName(poolName, addr),
+			Labels: map[string]string{
+				LabelPool:       poolName,
+				LabelLeaseOwner: string(vmp.UID),
+			},
+		},
+		Spec: vmv1alpha1.IPLeaseSpec{
+			Pool:      poolName,
+			Address:   addr.String(),
+			Namespace: vmp.Namespace,
+			Wukong:    vmp.Name,
+			Network:   networkName,
+		},
+	}
+	if err := c.Create(ctx, lease); err != nil {
+		return nil, err
+	}
+	logger.Info("Allocated IP address", "pool", poolName, "address", lease.Spec.Address, "network", networkName)
+	return lease, nil
+}
+
+// leaseName 根据地址池和地址生成 IPLease 名称
+func leaseName(poolName string, addr netip.Addr) string {
+	return fmt.Sprintf("%s-%s", poolName, strings.NewReplacer(".", "-", ":", "-").Replace(addr.String()))
+}
+
+// firstHost 返回网段中第一个可分配的地址：点对点网段从网络地址开始，其他网段跳过网络地址
+func firstHost(prefix netip.Prefix) netip.Addr {
+	if pointToPoint(prefix) {
+		return prefix.Addr()
+	}
+	return prefix.Addr().Next()
+}
+
+// pointToPoint 判断是否为没有网络地址和广播地址的点对点网段（IPv4 /31、/32，IPv6 /127、/128）
+func pointToPoint(prefix netip.Prefix) bool {
+	return prefix.Addr().BitLen()-prefix.Bits() <= 1
+}
+
+// isBroadcast 判断是否为 IPv4 广播地址（点对点网段没有广播地址）
+func isBroadcast(prefix netip.Prefix, addr netip.Addr) bool {
+	if !addr.Is4() || pointToPoint(prefix) {
+		return false
+	}
+	next := addr.Next()
+	return !next.IsValid() || !prefix.Contains(next)
+}
+
+// addressSet 是不可分配的地址和网段集合
+type addressSet struct {
+	addrs    map[netip.Addr]bool
+	prefixes []netip.Prefix
+}
+
+// contains 判断地址是否在集合中
+func (s *addressSet) contains(addr netip.Addr) bool {
+	if s.addrs[addr] {
+		return true
+	}
+	for _, p := range s.prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// excludedAddresses 返回地址池中不能自动分配的地址：网关、排除的地址和所有预留地址
+func excludedAddresses(pool *vmv1alpha1.IPPool) (*addressSet, error) {
+	return unavailableAddresses(pool, true)
+}
+
+// unavailableAddresses 返回网关和排除的地址，withReservations 为 true 时包含所有预留地址
+func unavailableAddresses(pool *vmv1alpha1.IPPool, withReservations bool) (*addressSet, error) {
+	set := &addressSet{addrs: make(map[netip.Addr]bool)}
+	add := func(s string) error {
+		if strings.Contains(s, "/") {
+			p, err := netip.ParsePrefix(s)
+			if err != nil {
+				return err
+			}
+			set.prefixes = append(set.prefixes, p.Masked())
+			return nil
+		}
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return err
+		}
+		set.addrs[addr] = true
+		return nil
+	}
+
+	if pool.Spec.Gateway != "" {
+		if err := add(pool.Spec.Gateway); err != nil {
+			return nil, fmt.Errorf("invalid gateway %q in IPPool %s: %w", pool.Spec.Gateway, pool.Name, err)
+		}
+	}
+	for _, e := range pool.Spec.Exclude {
+		if err := add(e); err != nil {
+			return nil, fmt.Errorf("invalid exclusion %q in IPPool %s: %w", e, pool.Name, err)
+		}
+	}
+	if withReservations {
+		for _, r := range pool.Spec.Reservations {
+			if err := add(r.Address); err != nil {
+				return nil, fmt.Errorf("invalid reserved address %q in IPPool %s: %w", r.Address, pool.Name, err)
+			}
+		}
+	}
+	return set, nil
+}
@@ -0,0 +1,176 @@
+package network
+
+import (
+	"context"
+	stderrors "errors"
+	"math"
+	"net/netip"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+// newPool 构造测试用的 IPPool
+func newPool(cidr, gateway string, exclude ...string) *vmv1alpha1.IPPool {
+	return &vmv1alpha1.IPPool{
+		ObjectMeta: metav1.ObjectMeta{Name: "pool"},
+		Spec:       vmv1alpha1.IPPoolSpec{CIDR: cidr, Gateway: gateway, Exclude: exclude},
+	}
+}
+
+// newLease 构造其他 Wukong 持有的 IPLease
+func newLease(poolName, address string) *vmv1alpha1.IPLease {
+	addr := netip.MustParseAddr(address)
+	return &vmv1alpha1.IPLease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   leaseName(poolName, addr),
+			Labels: map[string]string{LabelPool: poolName, LabelLeaseOwner: "other"},
+		},
+		Spec: vmv1alpha1.IPLeaseSpec{Pool: poolName, Address: address, Namespace: "vms", Wukong: "other", Network: "tenant"},
+	}
+}
+
+func TestAllocateIP(t *testing.T) {
+	vmp := &vmv1alpha1.Wukong{ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "vm", UID: types.UID("uid-vm")}}
+
+	reserved := newPool("10.0.0.0/29", "10.0.0.1")
+	reserved.Spec.Reservations = []vmv1alpha1.IPReservation{
+		{Address: "10.0.0.2", Namespace: "vms", Wukong: "db", Network: "tenant"},
+		{Address: "10.0.0.5", Namespace: "vms", Wukong: "vm", Network: "tenant"},
+	}
+	otherReservation := reserved.DeepCopy()
+	otherReservation.Spec.Reservations = otherReservation.Spec.Reservations[:1]
+
+	tests := []struct {
+		name    string
+		pool    *vmv1alpha1.IPPool
+		objs    []client.Object
+		want    string
+		wantErr error
+	}{
+		{name: "first host", pool: newPool("10.0.0.0/24", ""), want: "10.0.0.1"},
+		{name: "gateway skipped", pool: newPool("10.0.0.0/24", "10.0.0.1"), want: "10.0.0.2"},
+		{name: "leased addresses skipped", pool: newPool("10.0.0.0/24", "10.0.0.1"), objs: []client.Object{newLease("pool", "10.0.0.2")}, want: "10.0.0.3"},
+		{name: "excluded address and range skipped", pool: newPool("10.0.0.0/24", "10.0.0.1", "10.0.0.2", "10.0.0.0/29"), want: "10.0.0.8"},
+		{name: "reservation of the network", pool: reserved, want: "10.0.0.5"},
+		{name: "reservations of other networks skipped", pool: otherReservation, want: "10.0.0.3"},
+		{
+			name:    "broadcast never allocated",
+			pool:    newPool("10.0.0.0/30", "10.0.0.1"),
+			objs:    []client.Object{newLease("pool", "10.0.0.2")},
+			wantErr: ErrIPPoolExhausted,
+		},
+		{name: "/31 uses both addresses", pool: newPool("10.0.0.0/31", ""), objs: []client.Object{newLease("pool", "10.0.0.0")}, want: "10.0.0.1"},
+		{name: "/31 first address", pool: newPool("10.0.0.0/31", ""), want: "10.0.0.0"},
+		{name: "IPv6", pool: newPool("fd00::/64", "fd00::1"), want: "fd00::2"},
+		{name: "IPv6 last address", pool: newPool("fd00::/126", ""), objs: []client.Object{newLease("pool", "fd00::1"), newLease("pool", "fd00::2")}, want: "fd00::3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeClient(t, append(tt.objs, tt.pool)...)
+			lease, err := AllocateIP(context.Background(), c, vmp, "tenant", "pool")
+			if tt.wantErr != nil {
+				if !stderrors.Is(err, tt.wantErr) {
+					t.Fatalf("AllocateIP() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AllocateIP() error = %v", err)
+			}
+			if lease.Spec.Address != tt.want {
+				t.Errorf("AllocateIP() = %s, want %s", lease.Spec.Address, tt.want)
+			}
+
+			// 再次分配返回同一个租约
+			again, err := AllocateIP(context.Background(), c, vmp, "tenant", "pool")
+			if err != nil || again.Name != lease.Name {
+				t.Errorf("AllocateIP() again = %v, %v, want lease %s", again, err, lease.Name)
+			}
+		})
+	}
+}
+
+func TestAllocateIPReservedAddressLeased(t *testing.T) {
+	vmp := &vmv1alpha1.Wukong{ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "vm", UID: types.UID("uid-vm")}}
+	pool := newPool("10.0.0.0/24", "")
+	pool.Spec.Reservations = []vmv1alpha1.IPReservation{{Address: "10.0.0.5", Namespace: "vms", Wukong: "vm", Network: "tenant"}}
+	c := newFakeClient(t, pool, newLease("pool", "10.0.0.5"))
+
+	if _, err := AllocateIP(context.Background(), c, vmp, "tenant", "pool"); err == nil {
+		t.Error("AllocateIP() expected an error when the reserved address is leased to another network")
+	}
+}
+
+func TestAllocateIPConcurrentAllocation(t *testing.T) {
+	vmp := &vmv1alpha1.Wukong{ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "vm", UID: types.UID("uid-vm")}}
+	// 并发分配的租约尚未出现在列表中（没有地址池标签），创建时返回 AlreadyExists
+	concurrent := newLease("pool", "10.0.0.1")
+	concurrent.Labels = nil
+	c := newFakeClient(t, newPool("10.0.0.0/24", ""), concurrent)
+
+	lease, err := AllocateIP(context.Background(), c, vmp, "tenant", "pool")
+	if err != nil {
+		t.Fatalf("AllocateIP() error = %v", err)
+	}
+	if lease.Spec.Address != "10.0.0.2" {
+		t.Errorf("AllocateIP() = %s, want the next address 10.0.0.2", lease.Spec.Address)
+	}
+}
+
+func TestPoolCapacity(t *testing.T) {
+	tests := []struct {
+		name string
+		pool *vmv1alpha1.IPPool
+		want int64
+	}{
+		{name: "IPv4 /24", pool: newPool("10.0.0.0/24", ""), want: 254},
+		{name: "gateway", pool: newPool("10.0.0.0/24", "10.0.0.1"), want: 253},
+		{name: "gateway outside the CIDR", pool: newPool("10.0.0.0/24", "10.0.1.1"), want: 254},
+		{name: "exclusions", pool: newPool("10.0.0.0/24", "10.0.0.1", "10.0.0.1", "10.0.0.0/28", "10.0.0.20"), want: 238},
+		{name: "exclusion containing the CIDR", pool: newPool("10.0.0.0/24", "", "10.0.0.0/16"), want: 0},
+		{name: "/31", pool: newPool("10.0.0.0/31", ""), want: 2},
+		{name: "/32", pool: newPool("10.0.0.1/32", ""), want: 1},
+		{name: "IPv6 /120", pool: newPool("fd00::/120", "fd00::1"), want: 254},
+		{name: "IPv6 /64", pool: newPool("fd00::/64", ""), want: math.MaxInt64},
+		{name: "invalid CIDR", pool: newPool("10.0.0.0", ""), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PoolCapacity(tt.pool); got != tt.want {
+				t.Errorf("PoolCapacity() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// 预留地址分配给对应的网络，计入容量
+	reserved := newPool("10.0.0.0/29", "10.0.0.1")
+	reserved.Spec.Reservations = []vmv1alpha1.IPReservation{{Address: "10.0.0.2", Namespace: "vms", Wukong: "vm", Network: "tenant"}}
+	if got := PoolCapacity(reserved); got != 5 {
+		t.Errorf("PoolCapacity() with a reservation = %d, want 5", got)
+	}
+}
+
+func TestPoolExhausted(t *testing.T) {
+	pool := newPool("10.0.0.0/29", "10.0.0.1", "10.0.0.6")
+	pool.Spec.Reservations = []vmv1alpha1.IPReservation{{Address: "10.0.0.5", Namespace: "vms", Wukong: "db", Network: "tenant"}}
+
+	// 可动态分配的地址：10.0.0.2、10.0.0.3、10.0.0.4
+	leases := []vmv1alpha1.IPLease{*newLease("pool", "10.0.0.2"), *newLease("pool", "10.0.0.3"), *newLease("pool", "10.0.0.5")}
+	if PoolExhausted(pool, leases) {
+		t.Error("PoolExhausted() = true with 10.0.0.4 free")
+	}
+	leases = append(leases, *newLease("pool", "10.0.0.4"))
+	if !PoolExhausted(pool, leases) {
+		t.Error("PoolExhausted() = false with every address leased")
+	}
+	// 未使用的预留地址不能被其他网络分配
+	leases = []vmv1alpha1.IPLease{*newLease("pool", "10.0.0.2"), *newLease("pool", "10.0.0.3"), *newLease("pool", "10.0.0.4")}
+	if !PoolExhausted(pool, leases) {
+		t.Error("PoolExhausted() counts the unused reserved address as free")
+	}
+}
@@ -0,0 +1,182 @@
+package network
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+// DefaultMACPrefix is the default prefix of the MAC addresses allocated by the operator.
+// It is a locally administered unicast OUI, so it never conflicts with vendor-assigned addresses.
+const DefaultMACPrefix = "02:4e:53"
+
+// PodNetworkName is the name of the pod network interface of the virtual machine
+const PodNetworkName = "default"
+
+// ErrDuplicateMAC is returned when a MAC address set in a Wukong is already used by another Wukong
+var ErrDuplicateMAC = stderrors.New("duplicate MAC address")
+
+// ParseMACPrefix parses a MAC prefix of 1 to 5 bytes (e.g., "02:4e:53").
+// The prefix must be unicast, since multicast MAC addresses cannot be assigned to interfaces.
+func ParseMACPrefix(prefix string) ([]byte, error) {
+	parts := strings.Split(prefix, ":")
+	if len(parts) < 1 || len(parts) > 5 {
+		return nil, fmt.Errorf("invalid MAC prefix %q: must have 1 to 5 bytes", prefix)
+	}
+	bytes := make([]byte, 0, len(parts))
+	for _, p := range parts {
+		b, err := hex.DecodeString(p)
+		if err != nil || len(b) != 1 {
+			return nil, fmt.Errorf("invalid MAC prefix %q", prefix)
+		}
+		bytes = append(bytes, b[0])
+	}
+	if bytes[0]&0x01 != 0 {
+		return nil, fmt.Errorf("invalid MAC prefix %q: must be unicast", prefix)
+	}
+	return bytes, nil
+}
+
+// ReconcileMACAddresses assigns a stable MAC address to every Multus network of the Wukong and
+// records it in the network statuses. A MAC address set in the network config is used as is;
+// otherwise the address recorded in the Wukong status is kept, and new networks get an address
+// derived from the Wukong UID and the network name under the given prefix, avoiding the addresses
+// of all Wukongs in the cluster. A configured MAC address used by another Wukong is an error.
+// The pod network interface gets a stable address too, recorded in status.podNetworkMACAddress.
+func ReconcileMACAddresses(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, statuses []vmv1alpha1.NetworkStatus, prefix string) error {
+	logger := log.FromContext(ctx)
+
+	prefixBytes, err := ParseMACPrefix(prefix)
+	if err != nil {
+		return err
+	}
+
+	// 收集集群中其他 Wukong 使用的 MAC 地址
+	used, err := usedMACAddresses(ctx, c, vmp)
+	if err != nil {
+		return err
+	}
+
+	for i := range statuses {
+		status := &statuses[i]
+		if status.NADName == "" {
+			// 没有 Multus 网络的接口不需要分配 MAC
+			continue
+		}
+
+		netCfg := findNetworkConfig(vmp, status.Name)
+		mac := ""
+		switch {
+		case netCfg != nil && netCfg.MACAddress != "":
+			mac = normalizeMAC(netCfg.MACAddress)
+			if owner, ok := used[mac]; ok {
+				return fmt.Errorf("%w: %s of network %s is used by %s", ErrDuplicateMAC, mac, status.Name, owner)
+			}
+		case previousMACAddress(vmp, status.Name) != "":
+			mac = previousMACAddress(vmp, status.Name)
+		default:
+			mac = generateMACAddress(prefixBytes, vmp, status.Name, used)
+			if mac == "" {
+				return fmt.Errorf("no free MAC address under prefix %s for network %s", prefix, status.Name)
+			}
+			logger.Info("Allocated MAC address", "network", status.Name, "mac", mac)
+		}
+
+		status.MACAddress = mac
+		used[mac] = fmt.Sprintf("%s/%s", vmp.Namespace, vmp.Name)
+	}
+
+	// Pod 网络接口同样使用稳定的 MAC 地址，cloud-init 网络配置按 MAC 匹配网卡
+	if !PodNetworkEnabled(vmp.Spec.PodNetwork) {
+		vmp.Status.PodNetworkMACAddress = ""
+		return nil
+	}
+	if vmp.Status.PodNetworkMACAddress == "" {
+		mac := generateMACAddress(prefixBytes, vmp, PodNetworkName, used)
+		if mac == "" {
+			return fmt.Errorf("no free MAC address under prefix %s for the pod network", prefix)
+		}
+		logger.Info("Allocated MAC address", "network", PodNetworkName, "mac", mac)
+		vmp.Status.PodNetworkMACAddress = mac
+	}
+	return nil
+}
+
+// PodNetworkEnabled returns whether the virtual machine is connected to the pod network,
+// which is the default.
+func PodNetworkEnabled(podNetwork *vmv1alpha1.PodNetworkSpec) bool {
+	return podNetwork == nil || podNetwork.Enabled == nil || *podNetwork.Enabled
+}
+
+// usedMACAddresses 返回集群中其他 Wukong 配置或分配的 MAC 地址及其所属 Wukong
+func usedMACAddresses(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong) (map[string]string, error) {
+	wukongs := &vmv1alpha1.WukongList{}
+	if err := c.List(ctx, wukongs); err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]string)
+	for i := range wukongs.Items {
+		other := &wukongs.Items[i]
+		if other.UID == vmp.UID {
+			continue
+		}
+		owner := fmt.Sprintf("%s/%s", other.Namespace, other.Name)
+		for _, netCfg := range other.Spec.Networks {
+			if netCfg.MACAddress != "" {
+				used[normalizeMAC(netCfg.MACAddress)] = owner
+			}
+		}
+		if other.Status.PodNetworkMACAddress != "" {
+			used[normalizeMAC(other.Status.PodNetworkMACAddress)] = owner
+		}
+		for _, net := range other.Status.Networks {
+			if net.MACAddress != "" {
+				used[normalizeMAC(net.MACAddress)] = owner
+			}
+		}
+	}
+	return used, nil
+}
+
+// previousMACAddress 返回状态中已记录的网络 MAC 地址
+func previousMACAddress(vmp *vmv1alpha1.Wukong, name string) string {
+	for _, net := range vmp.Status.Networks {
+		if net.Name == name {
+			return normalizeMAC(net.MACAddress)
+		}
+	}
+	return ""
+}
+
+// generateMACAddress 根据 Wukong UID 和网络名称在前缀下生成 MAC 地址，冲突时使用下一个候选
+func generateMACAddress(prefix []byte, vmp *vmv1alpha1.Wukong, name string, used map[string]string) string {
+	for attempt := 0; attempt < 1024; attempt++ {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%d", vmp.UID, name, attempt)))
+		mac := make(net.HardwareAddr, 6)
+		copy(mac, prefix)
+		copy(mac[len(prefix):], sum[:6-len(prefix)])
+		if _, ok := used[mac.String()]; !ok {
+			return mac.String()
+		}
+	}
+	return ""
+}
+
+// normalizeMAC 将 MAC 地址统一为小写冒号格式
+func normalizeMAC(mac string) string {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return strings.ToLower(mac)
+	}
+	return hw.String()
+}
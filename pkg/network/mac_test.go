@@ -0,0 +1,188 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"net"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+func TestParseMACPrefix(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		want    []byte
+		wantErr bool
+	}{
+		{prefix: "02:4e:53", want: []byte{0x02, 0x4e, 0x53}},
+		{prefix: "02", want: []byte{0x02}},
+		{prefix: "0A:BC:de:f0:12", want: []byte{0x0a, 0xbc, 0xde, 0xf0, 0x12}},
+		{prefix: "", wantErr: true},
+		{prefix: "02:4e:53:00:00:01", wantErr: true},
+		{prefix: "2:4e:53", wantErr: true},
+		{prefix: "02:4g:53", wantErr: true},
+		{prefix: "02-4e-53", wantErr: true},
+		{prefix: "01:00:5e", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			got, err := ParseMACPrefix(tt.prefix)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMACPrefix(%q) error = %v, wantErr %v", tt.prefix, err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("ParseMACPrefix(%q) = %x, want %x", tt.prefix, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateMACAddress(t *testing.T) {
+	prefix := []byte{0x02, 0x4e, 0x53}
+	vmp := &vmv1alpha1.Wukong{ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "vm", UID: types.UID("uid-vm")}}
+
+	mac := generateMACAddress(prefix, vmp, "tenant", map[string]string{})
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		t.Fatalf("generateMACAddress() = %q is not a MAC address: %v", mac, err)
+	}
+	if !bytes.HasPrefix(hw, prefix) {
+		t.Errorf("generateMACAddress() = %s, want prefix %x", mac, prefix)
+	}
+	if again := generateMACAddress(prefix, vmp, "tenant", map[string]string{}); again != mac {
+		t.Errorf("generateMACAddress() = %s, then %s, want a stable address", mac, again)
+	}
+	if other := generateMACAddress(prefix, vmp, "storage", map[string]string{}); other == mac {
+		t.Errorf("generateMACAddress() = %s for two networks", mac)
+	}
+
+	// 地址已被使用时生成下一个候选
+	used := map[string]string{mac: "vms/other"}
+	next := generateMACAddress(prefix, vmp, "tenant", used)
+	if next == "" || next == mac {
+		t.Errorf("generateMACAddress() = %q with %s used, want another address", next, mac)
+	}
+
+	// 前缀占满 5 字节时只有 256 个地址，全部被使用时返回空字符串
+	fullPrefix := []byte{0x02, 0x4e, 0x53, 0x00, 0x00}
+	used = make(map[string]string, 256)
+	for i := 0; i < 256; i++ {
+		used[net.HardwareAddr(append(append([]byte{}, fullPrefix...), byte(i))).String()] = "vms/other"
+	}
+	if got := generateMACAddress(fullPrefix, vmp, "tenant", used); got != "" {
+		t.Errorf("generateMACAddress() = %s with every address used, want none", got)
+	}
+}
+
+func TestReconcileMACAddresses(t *testing.T) {
+	other := &vmv1alpha1.Wukong{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "other", UID: types.UID("uid-other")},
+		Spec: vmv1alpha1.WukongSpec{
+			Networks: []vmv1alpha1.NetworkConfig{{Name: "tenant", Type: "bridge", MACAddress: "02:00:00:00:00:01"}},
+		},
+		Status: vmv1alpha1.WukongStatus{
+			Networks: []vmv1alpha1.NetworkStatus{{Name: "storage", MACAddress: "02:00:00:00:00:02"}},
+		},
+	}
+	newWukong := func(mac string) *vmv1alpha1.Wukong {
+		return &vmv1alpha1.Wukong{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "vm", UID: types.UID("uid-vm")},
+			Spec: vmv1alpha1.WukongSpec{
+				Networks: []vmv1alpha1.NetworkConfig{{Name: "tenant", Type: "bridge", MACAddress: mac}},
+			},
+		}
+	}
+	ctx := context.Background()
+
+	t.Run("configured addresses", func(t *testing.T) {
+		tests := []struct {
+			mac     string
+			want    string
+			wantErr bool
+		}{
+			{mac: "02:00:00:00:00:01", wantErr: true},
+			{mac: "02-00-00-00-00-02", wantErr: true},
+			{mac: "02:00:00:00:00:0A", want: "02:00:00:00:00:0a"},
+		}
+		for _, tt := range tests {
+			vmp := newWukong(tt.mac)
+			c := newFakeClient(t, other, vmp)
+			statuses := []vmv1alpha1.NetworkStatus{{Name: "tenant", NADName: "vm-tenant"}}
+			err := ReconcileMACAddresses(ctx, c, vmp, statuses, DefaultMACPrefix)
+			if tt.wantErr {
+				if !stderrors.Is(err, ErrDuplicateMAC) {
+					t.Errorf("ReconcileMACAddresses(%s) error = %v, want %v", tt.mac, err, ErrDuplicateMAC)
+				}
+				continue
+			}
+			if err != nil || statuses[0].MACAddress != tt.want {
+				t.Errorf("ReconcileMACAddresses(%s) = %v, %s, want %s", tt.mac, err, statuses[0].MACAddress, tt.want)
+			}
+		}
+	})
+
+	t.Run("generated addresses are stable", func(t *testing.T) {
+		vmp := newWukong("")
+		c := newFakeClient(t, other, vmp)
+		statuses := []vmv1alpha1.NetworkStatus{{Name: "tenant", NADName: "vm-tenant"}, {Name: "pod-only"}}
+		if err := ReconcileMACAddresses(ctx, c, vmp, statuses, DefaultMACPrefix); err != nil {
+			t.Fatalf("ReconcileMACAddresses() error = %v", err)
+		}
+		mac := statuses[0].MACAddress
+		if mac == "" || statuses[1].MACAddress != "" {
+			t.Fatalf("MAC addresses = %q, %q, want one for the Multus network only", mac, statuses[1].MACAddress)
+		}
+		if vmp.Status.PodNetworkMACAddress == "" || vmp.Status.PodNetworkMACAddress == mac {
+			t.Errorf("pod network MAC address = %q, want a distinct address", vmp.Status.PodNetworkMACAddress)
+		}
+
+		// 状态中已记录的地址保持不变
+		vmp.Status.Networks = []vmv1alpha1.NetworkStatus{{Name: "tenant", MACAddress: "02:4e:53:aa:bb:cc"}}
+		statuses = []vmv1alpha1.NetworkStatus{{Name: "tenant", NADName: "vm-tenant"}}
+		if err := ReconcileMACAddresses(ctx, c, vmp, statuses, DefaultMACPrefix); err != nil {
+			t.Fatalf("ReconcileMACAddresses() error = %v", err)
+		}
+		if statuses[0].MACAddress != "02:4e:53:aa:bb:cc" {
+			t.Errorf("MAC address = %s, want the recorded address", statuses[0].MACAddress)
+		}
+	})
+
+	t.Run("pod network disabled", func(t *testing.T) {
+		vmp := newWukong("")
+		enabled := false
+		vmp.Spec.PodNetwork = &vmv1alpha1.PodNetworkSpec{Enabled: &enabled}
+		vmp.Status.PodNetworkMACAddress = "02:4e:53:00:00:01"
+		c := newFakeClient(t, vmp)
+		if err := ReconcileMACAddresses(ctx, c, vmp, nil, DefaultMACPrefix); err != nil {
+			t.Fatalf("ReconcileMACAddresses() error = %v", err)
+		}
+		if vmp.Status.PodNetworkMACAddress != "" {
+			t.Errorf("pod network MAC address = %s, want none", vmp.Status.PodNetworkMACAddress)
+		}
+	})
+}
+
+func TestPodNetworkEnabled(t *testing.T) {
+	tests := []struct {
+		name       string
+		podNetwork *vmv1alpha1.PodNetworkSpec
+		want       bool
+	}{
+		{name: "default", want: true},
+		{name: "enabled unset", podNetwork: &vmv1alpha1.PodNetworkSpec{}, want: true},
+		{name: "enabled", podNetwork: &vmv1alpha1.PodNetworkSpec{Enabled: boolPtr(true)}, want: true},
+		{name: "disabled", podNetwork: &vmv1alpha1.PodNetworkSpec{Enabled: boolPtr(false)}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PodNetworkEnabled(tt.podNetwork); got != tt.want {
+				t.Errorf("PodNetworkEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
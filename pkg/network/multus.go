@@ -2,11 +2,15 @@ package network
 
 import (
 	"context"
-	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"sync"
+	"time"
 
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -15,13 +19,39 @@ import (
 	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
 )
 
+// ErrMultusUnavailable is returned when a network requires a NetworkAttachmentDefinition but Multus is not installed
+var ErrMultusUnavailable = stderrors.New("Multus CNI is not installed")
+
+// ErrNADNotFound is returned when the NetworkAttachmentDefinition named by a network's NADName does not exist
+var ErrNADNotFound = stderrors.New("NetworkAttachmentDefinition not found")
+
+// AnnotationConfigUpdatedAt records when the operator last updated the CNI config of a managed NetworkAttachmentDefinition
+const AnnotationConfigUpdatedAt = "wukong.novasphere.dev/config-updated-at"
+
+// LabelWukong is the label set on per-Wukong NetworkAttachmentDefinitions with the name of their Wukong
+const LabelWukong = "wukong.novasphere.dev/wukong"
+
+// multusCRDRecheckInterval 是 Multus CRD 不存在时重新检查的间隔
+const multusCRDRecheckInterval = time.Minute
+
+// nadGVK is the GroupVersionKind of Multus NetworkAttachmentDefinitions
+var nadGVK = schema.GroupVersionKind{
+	Group:   "k8s.cni.cncf.io",
+	Version: "v1",
+	Kind:    "NetworkAttachmentDefinition",
+}
+
 // ReconcileNetworks creates/updates NetworkAttachmentDefinitions for the given Wukong
 // and returns the resulting NetworkStatus list.
+// multus caches whether Multus is installed across reconciles; the CRD is looked up on every call if nil.
 //
 // 目标（原型阶段）：
 // - 为每个 NetworkConfig 准备一个 NetworkAttachmentDefinition（如果未显式指定 NADName）
+// - operator 创建的 NAD 带有 managed 标签，CNI 配置与 spec 不一致时更新，并记录更新时间
+// - 用户指定的 NADName 只校验是否存在，从不修改
+// - 引用 WukongNetwork 的网络在同一 namespace 中共享一个 NAD，不再被引用时删除
 // - 目前使用 Unstructured 避免额外依赖，后续可以替换为强类型客户端
-func ReconcileNetworks(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong) ([]vmv1alpha1.NetworkStatus, error) {
+func ReconcileNetworks(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, multus *MultusCRDCache) ([]vmv1alpha1.NetworkStatus, error) {
 	logger := log.FromContext(ctx)
 	logger.Info("Reconciling Multus networks", "vmprofile", client.ObjectKeyFromObject(vmp), "networkCount", len(vmp.Spec.Networks))
 
@@ -30,152 +60,267 @@ func ReconcileNetworks(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wuk
 	for _, netCfg := range vmp.Spec.Networks {
 		// 如果用户已经指定了 NADName，则只记录状态，不自动创建
 		nadName := netCfg.NADName
-		if nadName == "" {
+		var shared *vmv1alpha1.WukongNetwork
+		if netCfg.NetworkRef != "" {
+			shared = &vmv1alpha1.WukongNetwork{}
+			if err := c.Get(ctx, client.ObjectKey{Name: netCfg.NetworkRef}, shared); err != nil {
+				logger.Error(err, "failed to get WukongNetwork", "network", netCfg.Name, "networkRef", netCfg.NetworkRef)
+				return nil, fmt.Errorf("failed to get WukongNetwork %s for network %s: %w", netCfg.NetworkRef, netCfg.Name, err)
+			}
+			nadName = SharedNADName(netCfg.NetworkRef)
+		} else if nadName == "" {
 			nadName = fmt.Sprintf("%s-%s-nad", vmp.Name, netCfg.Name)
 		}
 
 		nad := &unstructured.Unstructured{}
 		// 手动设置 GVK，因为 NetworkAttachmentDefinition 是 CRD
-		nad.SetGroupVersionKind(schema.GroupVersionKind{
-			Group:   "k8s.cni.cncf.io",
-			Version: "v1",
-			Kind:    "NetworkAttachmentDefinition",
-		})
+		nad.SetGroupVersionKind(nadGVK)
 
 		key := client.ObjectKey{Namespace: vmp.Namespace, Name: nadName}
 
 		err := c.Get(ctx, key, nad)
+
+		// 检查 Multus CRD 是否存在：NAD 类型未注册，或需要创建 NAD 时检查（结果会缓存）
+		multusInstalled := !meta.IsNoMatchError(err)
+		if multusInstalled && errors.IsNotFound(err) && netCfg.NADName == "" {
+			crdExists, crdErr := multus.Installed(ctx, c)
+			if crdErr != nil {
+				logger.Error(crdErr, "failed to check Multus CRD", "network", netCfg.Name)
+				return nil, crdErr
+			}
+			multusInstalled = crdExists
+		}
+		if !multusInstalled {
+			if vmp.Spec.NetworkPolicy != vmv1alpha1.NetworkPolicyBestEffort {
+				return nil, fmt.Errorf("%w: network %s cannot be attached", ErrMultusUnavailable, netCfg.Name)
+			}
+			// bestEffort：Multus 未安装时不连接该网络，并标记为 degraded
+			logger.Info("Multus CNI not installed, network is not attached", "network", netCfg.Name)
+			statuses = append(statuses, vmv1alpha1.NetworkStatus{
+				Name: netCfg.Name,
+				// 不设置 NADName，表示该网络未连接
+				Degraded:         true,
+				HostNetworkReady: true,
+				Message:          "Multus CNI is not installed, the network is not attached",
+			})
+			continue
+		}
+
+		if err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "failed to get NetworkAttachmentDefinition", "name", nadName)
+			return nil, err
+		}
+
+		if netCfg.NADName != "" {
+			// 用户指定的 NAD 只校验是否存在，从不修改
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s of network %s", ErrNADNotFound, nadName, netCfg.Name)
+			}
+			logger.V(1).Info("Found user-supplied NetworkAttachmentDefinition", "name", nadName)
+			statuses = append(statuses, vmv1alpha1.NetworkStatus{
+				Name:    netCfg.Name,
+				NADName: nadName,
+			})
+			continue
+		}
+
+		var desired *unstructured.Unstructured
+		var buildErr error
+		if shared != nil {
+			desired, buildErr = buildSharedNAD(shared, vmp.Namespace)
+		} else {
+			desired, buildErr = buildNAD(vmp, &netCfg, nadName)
+		}
+		if buildErr != nil {
+			logger.Error(buildErr, "failed to build NetworkAttachmentDefinition", "network", netCfg.Name)
+			return nil, buildErr
+		}
+
 		if err != nil {
-			if errors.IsNotFound(err) && netCfg.NADName == "" {
-				// 检查 Multus CRD 是否存在
-				crdExists, crdErr := checkMultusCRDExists(ctx, c)
-				if crdErr != nil {
-					logger.Error(crdErr, "failed to check Multus CRD", "network", netCfg.Name)
-					return nil, crdErr
-				}
-				if !crdExists {
-					// Multus 未安装，使用默认 Pod 网络
-					logger.Info("Multus CNI not installed, using default Pod network", "network", netCfg.Name)
-					statuses = append(statuses, vmv1alpha1.NetworkStatus{
-						Name: netCfg.Name,
-						// 不设置 NADName，表示使用默认网络
-					})
-					continue
-				}
-
-				// 未找到且未显式指定 NADName，则自动创建一个简单的 NAD
-				logger.Info("Creating NetworkAttachmentDefinition", "name", nadName, "namespace", vmp.Namespace)
-				nad.SetName(nadName)
-				nad.SetNamespace(vmp.Namespace)
-
-				configStr, cfgErr := buildCNIConfig(&netCfg)
-				if cfgErr != nil {
-					logger.Error(cfgErr, "failed to build CNI config", "network", netCfg.Name)
-					return nil, cfgErr
-				}
-
-				if err := unstructured.SetNestedField(nad.Object, map[string]interface{}{
-					"config": configStr,
-				}, "spec"); err != nil {
-					logger.Error(err, "failed to set NAD spec.config", "name", nadName)
-					return nil, err
-				}
-
-				if err := c.Create(ctx, nad); err != nil {
-					logger.Error(err, "failed to create NetworkAttachmentDefinition", "name", nadName)
-					return nil, err
-				}
-			} else if !errors.IsNotFound(err) {
-				// 如果不是 NotFound 错误，说明是其他错误
-				logger.Error(err, "failed to get NetworkAttachmentDefinition", "name", nadName)
+			// 未找到：由 operator 创建；共享 NAD 由同一 namespace 中第一个引用 WukongNetwork 的 Wukong 创建
+			logger.Info("Creating NetworkAttachmentDefinition", "name", nadName, "namespace", vmp.Namespace, "networkRef", netCfg.NetworkRef)
+			if err := c.Create(ctx, desired); err != nil && !(shared != nil && errors.IsAlreadyExists(err)) {
+				logger.Error(err, "failed to create NetworkAttachmentDefinition", "name", nadName)
 				return nil, err
 			}
-		} else {
-			logger.V(1).Info("Found existing NetworkAttachmentDefinition", "name", nadName)
+			nad = desired
+		} else if err := reconcileNADDrift(ctx, c, nad, desired); err != nil {
+			logger.Error(err, "failed to update NetworkAttachmentDefinition", "name", nadName)
+			return nil, err
 		}
 
 		statuses = append(statuses, vmv1alpha1.NetworkStatus{
 			Name: netCfg.Name,
 			// NADName 记录实际使用的 NAD 名称
-			NADName: nadName,
+			NADName:              nadName,
+			LastConfigUpdateTime: configUpdateTime(nad),
 			// Interface/IP/MAC 需要在 VM 运行后由 KubeVirt / guest-agent 填充，这里先留空
 		})
 	}
 
+	// 删除 namespace 中不再被引用的共享 NAD（例如 Wukong 不再引用某个 WukongNetwork）
+	if err := GarbageCollectSharedNADs(ctx, c, vmp.Namespace, nil); err != nil {
+		logger.Error(err, "failed to garbage-collect shared NetworkAttachmentDefinitions")
+		return nil, err
+	}
+
 	return statuses, nil
 }
 
-// checkMultusCRDExists 检查 Multus NetworkAttachmentDefinition CRD 是否存在
-func checkMultusCRDExists(ctx context.Context, c client.Client) (bool, error) {
-	crd := &apiextensionsv1.CustomResourceDefinition{}
-	key := client.ObjectKey{Name: "networkattachmentdefinitions.k8s.cni.cncf.io"}
-	err := c.Get(ctx, key, crd)
+// buildNAD 构造 Wukong 独占的 NetworkAttachmentDefinition，Wukong 删除时级联删除
+func buildNAD(vmp *vmv1alpha1.Wukong, netCfg *vmv1alpha1.NetworkConfig, nadName string) (*unstructured.Unstructured, error) {
+	configStr, err := buildCNIConfig(netCfg, nadName, ipamForIPConfig(netCfg.IPConfig))
 	if err != nil {
-		if errors.IsNotFound(err) {
-			return false, nil
-		}
-		return false, err
+		return nil, err
 	}
-	return true, nil
+
+	nad := &unstructured.Unstructured{}
+	nad.SetGroupVersionKind(nadGVK)
+	nad.SetName(nadName)
+	nad.SetNamespace(vmp.Namespace)
+	nad.SetAnnotations(nadAnnotations(netCfg))
+	nad.SetLabels(map[string]string{
+		LabelManaged: "true",
+		LabelWukong:  vmp.Name,
+	})
+	nad.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: vmv1alpha1.GroupVersion.String(),
+		Kind:       "Wukong",
+		Name:       vmp.Name,
+		UID:        vmp.UID,
+	}})
+	if err := unstructured.SetNestedField(nad.Object, map[string]interface{}{
+		"config": configStr,
+	}, "spec"); err != nil {
+		return nil, err
+	}
+	return nad, nil
 }
 
-// buildCNIConfig 构造一个简单的 CNI 配置 JSON 字符串。
-// 这里仅作为原型示例，实际生产环境需要根据具体网络规划进行调整。
-func buildCNIConfig(netCfg *vmv1alpha1.NetworkConfig) (string, error) {
-	type baseConfig struct {
-		CNIVersion string                 `json:"cniVersion"`
-		Type       string                 `json:"type"`
-		Bridge     string                 `json:"bridge,omitempty"`
-		VLAN       *int                   `json:"vlan,omitempty"`
-		IPAM       map[string]interface{} `json:"ipam,omitempty"`
+// reconcileNADDrift 将 operator 管理的 NAD 更新为期望的状态。
+// 早期版本创建的 NAD 没有 managed 标签，按名称认领后同样纳入管理。
+// CNI 配置变化时记录更新时间，已运行的 VM 需要重启才能使用新配置。
+func reconcileNADDrift(ctx context.Context, c client.Client, existing, desired *unstructured.Unstructured) error {
+	logger := log.FromContext(ctx)
+
+	updated := existing.DeepCopy()
+	changed := false
+
+	labels := updated.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	if labels[LabelManaged] != "true" {
+		logger.Info("Adopting NetworkAttachmentDefinition", "name", existing.GetName())
 	}
+	for k, v := range desired.GetLabels() {
+		if labels[k] != v {
+			labels[k] = v
+			changed = true
+		}
+	}
+	updated.SetLabels(labels)
 
-	cfg := baseConfig{
-		CNIVersion: "0.3.1",
-		Type:       netCfg.Type,
+	if len(updated.GetOwnerReferences()) == 0 && len(desired.GetOwnerReferences()) > 0 {
+		updated.SetOwnerReferences(desired.GetOwnerReferences())
+		changed = true
 	}
 
-	// 对 bridge/ovs 类型，使用 bridge 字段和可选 VLAN
-	if netCfg.Type == "bridge" || netCfg.Type == "ovs" {
-		if netCfg.BridgeName != "" {
-			cfg.Bridge = netCfg.BridgeName
+	annotations := updated.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	desiredResource, ok := desired.GetAnnotations()[AnnotationResourceName]
+	if annotations[AnnotationResourceName] != desiredResource {
+		if ok {
+			annotations[AnnotationResourceName] = desiredResource
 		} else {
-			cfg.Bridge = fmt.Sprintf("br-%s", netCfg.Name)
-		}
-		if netCfg.VLANID != nil {
-			cfg.VLAN = netCfg.VLANID
+			delete(annotations, AnnotationResourceName)
 		}
+		changed = true
 	}
 
-	// 简单根据 IPConfig 选择 ipam 类型
-	if netCfg.IPConfig != nil {
-		if netCfg.IPConfig.Mode == "dhcp" {
-			cfg.IPAM = map[string]interface{}{
-				"type": "dhcp",
-			}
-		} else if netCfg.IPConfig.Mode == "static" && netCfg.IPConfig.Address != nil {
-			cfg.IPAM = map[string]interface{}{
-				"type": "static",
-				"addresses": []map[string]string{
-					{
-						"address": *netCfg.IPConfig.Address,
-					},
-				},
-			}
-			if netCfg.IPConfig.Gateway != nil {
-				cfg.IPAM["routes"] = []map[string]string{
-					{
-						"dst": "0.0.0.0/0",
-						"gw":  *netCfg.IPConfig.Gateway,
-					},
-				}
-			}
+	currentConfig, _, _ := unstructured.NestedString(existing.Object, "spec", "config")
+	desiredConfig, _, _ := unstructured.NestedString(desired.Object, "spec", "config")
+	if currentConfig != desiredConfig {
+		logger.Info("NetworkAttachmentDefinition config drifted, updating", "name", existing.GetName(), "namespace", existing.GetNamespace())
+		if err := unstructured.SetNestedField(updated.Object, desiredConfig, "spec", "config"); err != nil {
+			return err
 		}
+		annotations[AnnotationConfigUpdatedAt] = time.Now().UTC().Format(time.RFC3339)
+		changed = true
 	}
+	updated.SetAnnotations(annotations)
 
-	data, err := json.Marshal(cfg)
+	if !changed {
+		logger.V(1).Info("NetworkAttachmentDefinition is up to date", "name", existing.GetName())
+		return nil
+	}
+	if err := c.Update(ctx, updated); err != nil {
+		return err
+	}
+	existing.Object = updated.Object
+	return nil
+}
+
+// configUpdateTime 返回 operator 最后一次更新 NAD CNI 配置的时间，未更新过时返回 nil
+func configUpdateTime(nad *unstructured.Unstructured) *metav1.Time {
+	value, ok := nad.GetAnnotations()[AnnotationConfigUpdatedAt]
+	if !ok {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
 	if err != nil {
-		return "", err
+		return nil
+	}
+	return &metav1.Time{Time: t}
+}
+
+// MultusCRDCache caches whether the Multus NetworkAttachmentDefinition CRD is installed.
+// A present CRD is cached for good; an absent one is checked again after a minute, so that
+// installing Multus does not require restarting the operator. The zero value is ready to use.
+type MultusCRDCache struct {
+	mu        sync.Mutex
+	present   bool
+	checkedAt time.Time
+
+	// now 返回当前时间，测试中替换
+	now func() time.Time
+}
+
+// Installed reports whether the Multus CRD exists. A nil cache checks the CRD every time.
+func (m *MultusCRDCache) Installed(ctx context.Context, c client.Client) (bool, error) {
+	if m == nil {
+		return checkMultusCRDExists(ctx, c)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now
+	if m.now != nil {
+		now = m.now
+	}
+	if m.present || (!m.checkedAt.IsZero() && now().Sub(m.checkedAt) < multusCRDRecheckInterval) {
+		return m.present, nil
+	}
+
+	present, err := checkMultusCRDExists(ctx, c)
+	if err != nil {
+		return false, err
+	}
+	m.present = present
+	m.checkedAt = now()
+	return present, nil
+}
+
+// checkMultusCRDExists 检查 Multus NetworkAttachmentDefinition CRD 是否存在
+func checkMultusCRDExists(ctx context.Context, c client.Client) (bool, error) {
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	key := client.ObjectKey{Name: "networkattachmentdefinitions.k8s.cni.cncf.io"}
+	err := c.Get(ctx, key, crd)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
 	}
-	return string(data), nil
+	return err == nil, nil
 }
@@ -0,0 +1,231 @@
+package network
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+// multusCRD 是 Multus NetworkAttachmentDefinition 的 CRD
+var multusCRD = &apiextensionsv1.CustomResourceDefinition{
+	ObjectMeta: metav1.ObjectMeta{Name: "networkattachmentdefinitions.k8s.cni.cncf.io"},
+}
+
+// newMultusClient 返回可以查询 CRD 的 fake client，withNAD 为 false 时 NAD 类型未注册（未安装 Multus）
+// crdGets 不为 nil 时统计 CRD 的查询次数
+func newMultusClient(t *testing.T, withNAD bool, crdGets *int, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{corev1.AddToScheme, apiextensionsv1.AddToScheme, vmv1alpha1.AddToScheme} {
+		if err := add(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if withNAD {
+		scheme.AddKnownTypeWithName(nadGVK, &unstructured.Unstructured{})
+		scheme.AddKnownTypeWithName(nadGVK.GroupVersion().WithKind(nadGVK.Kind+"List"), &unstructured.UnstructuredList{})
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			if _, ok := obj.(*apiextensionsv1.CustomResourceDefinition); ok && crdGets != nil {
+				*crdGets++
+			}
+			return c.Get(ctx, key, obj, opts...)
+		},
+	}).Build()
+}
+
+func TestMultusCRDCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	cache := &MultusCRDCache{now: func() time.Time { return now }}
+
+	gets := 0
+	c := newMultusClient(t, true, &gets)
+	if installed, err := cache.Installed(ctx, c); err != nil || installed {
+		t.Fatalf("Installed() = %t, %v, want false", installed, err)
+	}
+
+	// 不存在的结果在重新检查间隔内有效
+	c = newMultusClient(t, true, &gets, multusCRD.DeepCopy())
+	now = now.Add(multusCRDRecheckInterval / 2)
+	if installed, _ := cache.Installed(ctx, c); installed || gets != 1 {
+		t.Errorf("Installed() = %t after %d CRD lookups, want the cached absence", installed, gets)
+	}
+
+	// 超过间隔后重新检查，安装 Multus 后无需重启 operator
+	now = now.Add(multusCRDRecheckInterval)
+	if installed, _ := cache.Installed(ctx, c); !installed || gets != 2 {
+		t.Errorf("Installed() = %t after %d CRD lookups, want the CRD checked again", installed, gets)
+	}
+
+	// 存在的结果一直有效
+	now = now.Add(time.Hour)
+	if installed, _ := cache.Installed(ctx, newMultusClient(t, true, &gets)); !installed || gets != 2 {
+		t.Errorf("Installed() = %t after %d CRD lookups, want the cached presence", installed, gets)
+	}
+
+	// 没有缓存时每次都检查
+	var nilCache *MultusCRDCache
+	for i := 0; i < 2; i++ {
+		if installed, _ := nilCache.Installed(ctx, c); !installed {
+			t.Error("Installed() = false without a cache, want true")
+		}
+	}
+	if gets != 4 {
+		t.Errorf("CRD looked up %d times, want 4", gets)
+	}
+}
+
+func TestReconcileNetworksWithoutMultus(t *testing.T) {
+	tests := []struct {
+		name    string
+		withNAD bool
+		policy  string
+		wantErr bool
+	}{
+		// NAD 类型未注册：RESTMapper 返回 NoMatch
+		{name: "strict without NAD kind", wantErr: true},
+		{name: "bestEffort without NAD kind", policy: vmv1alpha1.NetworkPolicyBestEffort},
+		// NAD 类型已注册但 CRD 不存在
+		{name: "strict without CRD", withNAD: true, wantErr: true},
+		{name: "bestEffort without CRD", withNAD: true, policy: vmv1alpha1.NetworkPolicyBestEffort},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vmp := newBridgeWukong("vm", "br-tenant", "", nil)
+			vmp.Spec.NetworkPolicy = tt.policy
+			c := newMultusClient(t, tt.withNAD, nil, vmp)
+
+			statuses, err := ReconcileNetworks(context.Background(), c, vmp, &MultusCRDCache{})
+			if tt.wantErr {
+				if !stderrors.Is(err, ErrMultusUnavailable) {
+					t.Fatalf("ReconcileNetworks() error = %v, want %v", err, ErrMultusUnavailable)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReconcileNetworks() error = %v", err)
+			}
+			if len(statuses) != 1 || !statuses[0].Degraded || statuses[0].NADName != "" {
+				t.Errorf("ReconcileNetworks() = %+v, want one degraded network without NAD", statuses)
+			}
+		})
+	}
+}
+
+// newNAD 构造 namespace vms 中的 NAD
+func newNAD(name, config string, labels map[string]string) *unstructured.Unstructured {
+	nad := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"config": config},
+	}}
+	nad.SetGroupVersionKind(nadGVK)
+	nad.SetNamespace("vms")
+	nad.SetName(name)
+	nad.SetLabels(labels)
+	return nad
+}
+
+func TestReconcileNetworksNADDrift(t *testing.T) {
+	ctx := context.Background()
+	vmp := newBridgeWukong("vm", "br-tenant", "", nil)
+	desired, err := buildNAD(vmp, &vmp.Spec.Networks[0], "vm-tenant-nad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	desiredConfig, _, _ := unstructured.NestedString(desired.Object, "spec", "config")
+	managed := map[string]string{LabelManaged: "true", LabelWukong: "vm"}
+
+	tests := []struct {
+		name        string
+		nad         *unstructured.Unstructured
+		wantUpdated bool
+	}{
+		{name: "created", wantUpdated: false},
+		// 早期版本创建的 NAD 没有标签，按名称认领并更新配置
+		{name: "unlabeled NAD adopted", nad: newNAD("vm-tenant-nad", `{"cniVersion":"0.3.1","type":"bridge","bridge":"br-old"}`, nil), wantUpdated: true},
+		{name: "drifted config updated", nad: newNAD("vm-tenant-nad", `{"cniVersion":"0.3.1","type":"bridge","bridge":"br-old"}`, managed), wantUpdated: true},
+		{name: "up to date", nad: newNAD("vm-tenant-nad", desiredConfig, managed)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := []client.Object{multusCRD.DeepCopy(), vmp.DeepCopy()}
+			if tt.nad != nil {
+				objs = append(objs, tt.nad.DeepCopy())
+			}
+			c := newMultusClient(t, true, nil, objs...)
+
+			statuses, err := ReconcileNetworks(ctx, c, vmp, nil)
+			if err != nil {
+				t.Fatalf("ReconcileNetworks() error = %v", err)
+			}
+			if len(statuses) != 1 || statuses[0].NADName != "vm-tenant-nad" {
+				t.Fatalf("ReconcileNetworks() = %+v, want network on vm-tenant-nad", statuses)
+			}
+			if got := statuses[0].LastConfigUpdateTime != nil; got != tt.wantUpdated {
+				t.Errorf("LastConfigUpdateTime = %v, want set: %t", statuses[0].LastConfigUpdateTime, tt.wantUpdated)
+			}
+
+			nad := newNAD("", "", nil)
+			if err := c.Get(ctx, client.ObjectKey{Namespace: "vms", Name: "vm-tenant-nad"}, nad); err != nil {
+				t.Fatal(err)
+			}
+			if config, _, _ := unstructured.NestedString(nad.Object, "spec", "config"); config != desiredConfig {
+				t.Errorf("NAD config = %s, want %s", config, desiredConfig)
+			}
+			labels := nad.GetLabels()
+			if labels[LabelManaged] != "true" || labels[LabelWukong] != "vm" {
+				t.Errorf("NAD labels = %v, want managed by vm", labels)
+			}
+			if owners := nad.GetOwnerReferences(); len(owners) != 1 || owners[0].Name != "vm" {
+				t.Errorf("NAD owners = %+v, want Wukong vm", owners)
+			}
+			if _, ok := nad.GetAnnotations()[AnnotationConfigUpdatedAt]; ok != tt.wantUpdated {
+				t.Errorf("NAD annotations = %v, want %s: %t", nad.GetAnnotations(), AnnotationConfigUpdatedAt, tt.wantUpdated)
+			}
+		})
+	}
+}
+
+func TestReconcileNetworksUserNAD(t *testing.T) {
+	ctx := context.Background()
+	config := `{"cniVersion":"0.3.1","type":"macvlan","master":"eth1"}`
+	vmp := newBridgeWukong("vm", "br-tenant", "", nil)
+	vmp.Spec.Networks[0].NADName = "custom"
+
+	// 用户指定的 NAD 与 spec 不一致时也不修改
+	c := newMultusClient(t, true, nil, multusCRD.DeepCopy(), vmp, newNAD("custom", config, nil))
+	statuses, err := ReconcileNetworks(ctx, c, vmp, nil)
+	if err != nil {
+		t.Fatalf("ReconcileNetworks() error = %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].NADName != "custom" || statuses[0].LastConfigUpdateTime != nil {
+		t.Errorf("ReconcileNetworks() = %+v, want network on custom", statuses)
+	}
+	nad := newNAD("", "", nil)
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "vms", Name: "custom"}, nad); err != nil {
+		t.Fatal(err)
+	}
+	got, _, _ := unstructured.NestedString(nad.Object, "spec", "config")
+	if got != config || len(nad.GetLabels()) != 0 || len(nad.GetOwnerReferences()) != 0 || len(nad.GetAnnotations()) != 0 {
+		t.Errorf("user-supplied NAD modified: %v", nad.Object)
+	}
+
+	// 不存在时报错，不自动创建
+	c = newMultusClient(t, true, nil, multusCRD.DeepCopy(), vmp)
+	if _, err := ReconcileNetworks(ctx, c, vmp, nil); !stderrors.Is(err, ErrNADNotFound) {
+		t.Errorf("ReconcileNetworks() error = %v, want %v", err, ErrNADNotFound)
+	}
+}
@@ -2,36 +2,509 @@ package network
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
 
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
 )
 
-// ReconcileNMState is a placeholder for NMState integration.
-//
-// 原型阶段仅预留接口：
-// - 未来可以在这里根据 NetworkConfig 生成 NodeNetworkConfigurationPolicy（NNCP）
-// - 目前默认不创建任何 NNCP，仅为后续扩展预留入口
-func ReconcileNMState(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong) error {
+// LabelManaged marks network objects created and garbage-collected by the operator
+const LabelManaged = "wukong.novasphere.dev/managed"
+
+const (
+	// labelBridge 记录 NNCP 提供的网桥名称，用于检测冲突的网桥定义
+	labelBridge = "wukong.novasphere.dev/bridge"
+	// labelBridgeConfig 记录网桥所连接的主机网卡和 VLAN 的哈希
+	labelBridgeConfig = "wukong.novasphere.dev/bridge-config"
+	// labelNMStateAbsent 标记正在从节点上移除接口、随后删除的 NNCP
+	labelNMStateAbsent = "wukong.novasphere.dev/nmstate-absent"
+	// annotationAbsentSince 记录 NNCP 被设置为 absent 的时间
+	annotationAbsentSince = "wukong.novasphere.dev/absent-since"
+
+	// nncpAbsentTimeout 是等待 absent 状态生效的最长时间，超时后直接删除 NNCP
+	nncpAbsentTimeout = 5 * time.Minute
+)
+
+// nncpGVK is the GroupVersionKind of NMState NodeNetworkConfigurationPolicies
+var nncpGVK = schema.GroupVersionKind{
+	Group:   "nmstate.io",
+	Version: "v1",
+	Kind:    "NodeNetworkConfigurationPolicy",
+}
+
+// ReconcileNMState provisions the host bridges and VLAN sub-interfaces required by the networks
+// of a Wukong with NMState NodeNetworkConfigurationPolicies (NNCPs), and records in the network
+// statuses whether they are Available. Networks without HostInterface need no policy and are ready.
+// Macvlan networks with a VLANID get a policy provisioning their VLAN sub-interface master.
+// A bridge already attached to a different host interface or VLAN by another policy is rejected.
+// Policies no longer required by any Wukong are removed when the Wukong stops using a policy.
+func ReconcileNMState(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, statuses []vmv1alpha1.NetworkStatus) error {
 	logger := log.FromContext(ctx)
-	logger.V(1).Info("ReconcileNMState (no-op prototype)", "vmprofile", client.ObjectKeyFromObject(vmp))
-
-	// 示例：当未来需要按网络生成 NNCP 时，可按如下方式构造 Unstructured：
-	_ = &unstructured.Unstructured{
-		Object: map[string]interface{}{
-			"apiVersion": "nmstate.io/v1",
-			"kind":       "NodeNetworkConfigurationPolicy",
-			// "metadata": map[string]interface{}{
-			// 	"name": "example-nncp",
-			// },
-			// "spec": map[string]interface{}{
-			// 	"desiredState": map[string]interface{}{...},
-			// },
+
+	// 本 Wukong 内每个网桥的配置，用于检测同一 Wukong 中冲突的网桥定义
+	bridges := make(map[string]string)
+	for i := range statuses {
+		status := &statuses[i]
+		status.HostNetworkReady = true
+
+		netCfg := findNetworkConfig(vmp, status.Name)
+		if netCfg == nil {
+			continue
+		}
+		// 引用 WukongNetwork 的网络使用其中定义的网桥和主机网卡
+		netCfg, err := ResolveNetworkConfig(ctx, c, netCfg)
+		if err != nil {
+			return err
+		}
+		desired := BuildNodeNetworkConfigurationPolicy(vmp, netCfg)
+		if desired == nil {
+			continue
+		}
+		status.HostNetworkPolicy = desired.GetName()
+
+		device := hostNetworkDevice(netCfg)
+		if bridge := desired.GetLabels()[labelBridge]; bridge != "" {
+			if config, ok := bridges[bridge]; ok && config != bridgeConfig(netCfg) {
+				status.HostNetworkReady = false
+				status.Message = fmt.Sprintf("Bridge %s is defined by another network of this Wukong with a different host interface or VLAN", bridge)
+				continue
+			}
+			bridges[bridge] = bridgeConfig(netCfg)
+		}
+
+		policy := &unstructured.Unstructured{}
+		policy.SetGroupVersionKind(nncpGVK)
+		err = c.Get(ctx, client.ObjectKeyFromObject(desired), policy)
+		if meta.IsNoMatchError(err) {
+			status.HostNetworkReady = false
+			status.Message = "NMState is not installed, cannot provision host interface " + device
+			continue
+		}
+		if errors.IsNotFound(err) {
+			conflict, err := conflictingPolicy(ctx, c, desired)
+			if err != nil {
+				return err
+			}
+			if conflict != "" {
+				status.HostNetworkReady = false
+				status.Message = fmt.Sprintf("Bridge %s is already attached to a different host interface or VLAN by NodeNetworkConfigurationPolicy %s", device, conflict)
+				continue
+			}
+			// 名称由配置哈希生成，配置相同的 Wukong 共用同一个 NNCP，配置变化时使用新的 NNCP
+			logger.Info("Creating NodeNetworkConfigurationPolicy", "name", desired.GetName(), "network", netCfg.Name)
+			if err := c.Create(ctx, desired); err != nil && !errors.IsAlreadyExists(err) {
+				return err
+			}
+			status.HostNetworkReady = false
+			status.Message = fmt.Sprintf("Waiting for NodeNetworkConfigurationPolicy %s to be Available", desired.GetName())
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, absent := policy.GetLabels()[labelNMStateAbsent]; absent {
+			// 正在移除的 NNCP 重新被使用，恢复期望的接口状态
+			logger.Info("Restoring NodeNetworkConfigurationPolicy", "name", desired.GetName(), "network", netCfg.Name)
+			policy.SetLabels(desired.GetLabels())
+			policy.SetAnnotations(nil)
+			policy.Object["spec"] = desired.Object["spec"]
+			if err := c.Update(ctx, policy); err != nil {
+				return err
+			}
+			status.HostNetworkReady = false
+			status.Message = fmt.Sprintf("Waiting for NodeNetworkConfigurationPolicy %s to be Available", desired.GetName())
+			continue
+		}
+
+		status.HostNetworkReady, status.Message = nncpAvailable(policy)
+	}
+
+	// 全量回收需要遍历所有 Wukong，只在本 Wukong 不再使用之前的某个 NNCP 时执行
+	if droppedPolicy(vmp.Status.Networks, statuses) {
+		_, err := GarbageCollectNMState(ctx, c, nil)
+		return err
+	}
+	_, err := removeAbsentPolicies(ctx, c)
+	return err
+}
+
+// BuildNodeNetworkConfigurationPolicy builds the NNCP provisioning the host bridge of a network,
+// or returns nil if the network does not require one.
+// Linux bridges are attached to HostInterface, or to the VLAN sub-interface "<hostInterface>.<vlanId>"
+// when VLANID is set; OVS bridges are attached to HostInterface and tag VLANs on their ports.
+// Macvlan networks with a VLANID only require the VLAN sub-interface used as their master.
+// The policy applies to the nodes matching the Wukong's node selector.
+func BuildNodeNetworkConfigurationPolicy(vmp *vmv1alpha1.Wukong, netCfg *vmv1alpha1.NetworkConfig) *unstructured.Unstructured {
+	if netCfg.HostInterface == "" {
+		return nil
+	}
+
+	var interfaces []interface{}
+	labels := map[string]string{LabelManaged: "true"}
+	switch netCfg.Type {
+	case "bridge", "ovs":
+		interfaces = bridgeInterfaces(netCfg)
+		labels[labelBridge] = bridgeName(netCfg)
+		labels[labelBridgeConfig] = bridgeConfig(netCfg)
+	case "macvlan":
+		if netCfg.VLANID == nil {
+			return nil
+		}
+		interfaces = []interface{}{vlanInterface(netCfg.HostInterface, *netCfg.VLANID)}
+	default:
+		return nil
+	}
+
+	spec := map[string]interface{}{
+		"desiredState": map[string]interface{}{
+			"interfaces": interfaces,
 		},
 	}
+	nodeSelector := wukongNodeSelector(vmp)
+	if len(nodeSelector) > 0 {
+		selector := make(map[string]interface{}, len(nodeSelector))
+		for k, v := range nodeSelector {
+			selector[k] = v
+		}
+		spec["nodeSelector"] = selector
+	}
+
+	policy := &unstructured.Unstructured{}
+	policy.SetGroupVersionKind(nncpGVK)
+	policy.SetName(nncpName(netCfg, nodeSelector))
+	policy.SetLabels(labels)
+	policy.Object["spec"] = spec
+	return policy
+}
+
+// bridgeInterfaces 返回网桥及其 VLAN 子接口的 NMState 接口定义
+func bridgeInterfaces(netCfg *vmv1alpha1.NetworkConfig) []interface{} {
+	bridge := bridgeName(netCfg)
+	port := netCfg.HostInterface
+	interfaces := make([]interface{}, 0, 2)
+
+	if netCfg.Type == "bridge" && netCfg.VLANID != nil {
+		port = vlanInterfaceName(netCfg.HostInterface, *netCfg.VLANID)
+		interfaces = append(interfaces, vlanInterface(netCfg.HostInterface, *netCfg.VLANID))
+	}
+
+	bridgeIface := map[string]interface{}{
+		"name":  bridge,
+		"state": "up",
+	}
+	if netCfg.Type == "ovs" {
+		bridgeIface["type"] = "ovs-bridge"
+		bridgeIface["bridge"] = map[string]interface{}{
+			"options": map[string]interface{}{"stp": false},
+			"port":    []interface{}{map[string]interface{}{"name": port}},
+		}
+	} else {
+		bridgeIface["type"] = "linux-bridge"
+		bridgeIface["bridge"] = map[string]interface{}{
+			"options": map[string]interface{}{
+				"stp": map[string]interface{}{"enabled": false},
+			},
+			"port": []interface{}{map[string]interface{}{"name": port}},
+		}
+	}
+	return append(interfaces, bridgeIface)
+}
+
+// vlanInterface 返回 VLAN 子接口的 NMState 接口定义
+func vlanInterface(hostInterface string, vlanID int) map[string]interface{} {
+	return map[string]interface{}{
+		"name":  vlanInterfaceName(hostInterface, vlanID),
+		"type":  "vlan",
+		"state": "up",
+		"vlan": map[string]interface{}{
+			"base-iface": hostInterface,
+			"id":         int64(vlanID),
+		},
+	}
+}
+
+// GarbageCollectNMState removes the NNCPs created by the operator that no Wukong requires anymore.
+// The given Wukong (e.g., one being deleted) is not considered, and may be nil.
+// Deleting a policy leaves its interfaces on the nodes, so an unused policy is first updated to
+// set its interfaces absent, and deleted once that state is applied.
+// It returns true while policies are still being removed.
+func GarbageCollectNMState(ctx context.Context, c client.Client, exclude *vmv1alpha1.Wukong) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	policies := &unstructured.UnstructuredList{}
+	policies.SetGroupVersionKind(nncpGVK)
+	if err := c.List(ctx, policies, client.MatchingLabels{LabelManaged: "true"}); err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if len(policies.Items) == 0 {
+		return false, nil
+	}
+
+	wukongs := &vmv1alpha1.WukongList{}
+	if err := c.List(ctx, wukongs); err != nil {
+		return false, err
+	}
+	required := make(map[string]bool)
+	// 仍在使用的 NNCP 中的接口（例如同一 VLAN 子接口上的网桥和 macvlan 网络）不能设置为 absent
+	inUse := make(map[string]bool)
+	for i := range wukongs.Items {
+		vmp := &wukongs.Items[i]
+		if !vmp.DeletionTimestamp.IsZero() || (exclude != nil && vmp.UID == exclude.UID) {
+			continue
+		}
+		for j := range vmp.Spec.Networks {
+			netCfg, err := ResolveNetworkConfig(ctx, c, &vmp.Spec.Networks[j])
+			if errors.IsNotFound(err) {
+				// WukongNetwork 已删除，不再需要对应的 NNCP
+				continue
+			}
+			if err != nil {
+				return false, err
+			}
+			if policy := BuildNodeNetworkConfigurationPolicy(vmp, netCfg); policy != nil {
+				required[policy.GetName()] = true
+				for _, name := range policyInterfaces(policy) {
+					inUse[name] = true
+				}
+			}
+		}
+	}
+
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		if _, absent := policy.GetLabels()[labelNMStateAbsent]; absent || required[policy.GetName()] {
+			continue
+		}
+		logger.Info("Removing the interfaces of unused NodeNetworkConfigurationPolicy", "name", policy.GetName())
+		setPolicyAbsent(policy, inUse, time.Now())
+		if err := c.Update(ctx, policy); err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+	}
+	return removeAbsentPolicies(ctx, c)
+}
+
+// removeAbsentPolicies 删除 absent 状态已生效（或超时）的 NNCP，返回是否仍有 NNCP 在移除中
+func removeAbsentPolicies(ctx context.Context, c client.Client) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	policies := &unstructured.UnstructuredList{}
+	policies.SetGroupVersionKind(nncpGVK)
+	if err := c.List(ctx, policies, client.MatchingLabels{LabelManaged: "true", labelNMStateAbsent: "true"}); err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	pending := false
+	now := time.Now()
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		if !absentApplied(policy, now) {
+			pending = true
+			continue
+		}
+		logger.Info("Deleting unused NodeNetworkConfigurationPolicy", "name", policy.GetName())
+		if err := c.Delete(ctx, policy); err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+	}
+	return pending, nil
+}
+
+// setPolicyAbsent 将 NNCP 中不在 inUse 中的接口设置为 absent，NMState 据此从节点上移除网桥和 VLAN 子接口
+func setPolicyAbsent(policy *unstructured.Unstructured, inUse map[string]bool, now time.Time) {
+	interfaces, _, _ := unstructured.NestedSlice(policy.Object, "spec", "desiredState", "interfaces")
+	absent := make([]interface{}, 0, len(interfaces))
+	for _, iface := range interfaces {
+		ifaceMap, ok := iface.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, _ := ifaceMap["name"].(string); inUse[name] {
+			continue
+		}
+		absent = append(absent, map[string]interface{}{
+			"name":  ifaceMap["name"],
+			"type":  ifaceMap["type"],
+			"state": "absent",
+		})
+	}
+	_ = unstructured.SetNestedSlice(policy.Object, absent, "spec", "desiredState", "interfaces")
+
+	labels := policy.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string, 1)
+	}
+	labels[labelNMStateAbsent] = "true"
+	policy.SetLabels(labels)
+	annotations := policy.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string, 1)
+	}
+	annotations[annotationAbsentSince] = now.UTC().Format(time.RFC3339)
+	policy.SetAnnotations(annotations)
+}
+
+// policyInterfaces 返回 NNCP 中定义的接口名称
+func policyInterfaces(policy *unstructured.Unstructured) []string {
+	interfaces, _, _ := unstructured.NestedSlice(policy.Object, "spec", "desiredState", "interfaces")
+	names := make([]string, 0, len(interfaces))
+	for _, iface := range interfaces {
+		if ifaceMap, ok := iface.(map[string]interface{}); ok {
+			if name, ok := ifaceMap["name"].(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// absentApplied 判断 absent 状态是否已生效：设置 absent 之后 NNCP 变为 Available 或 Degraded，
+// 或者等待超过 nncpAbsentTimeout
+func absentApplied(policy *unstructured.Unstructured, now time.Time) bool {
+	since, err := time.Parse(time.RFC3339, policy.GetAnnotations()[annotationAbsentSince])
+	if err != nil || now.Sub(since) > nncpAbsentTimeout {
+		return true
+	}
+	conditions, _, _ := unstructured.NestedSlice(policy.Object, "status", "conditions")
+	for _, cond := range conditions {
+		condMap, ok := cond.(map[string]interface{})
+		if !ok || condMap["status"] != "True" || (condMap["type"] != "Available" && condMap["type"] != "Degraded") {
+			continue
+		}
+		// 设置 absent 之前的条件不代表新的状态
+		transition, _, _ := unstructured.NestedString(condMap, "lastTransitionTime")
+		if t, err := time.Parse(time.RFC3339, transition); err == nil && !t.Before(since) {
+			return true
+		}
+	}
+	return false
+}
+
+// conflictingPolicy 返回已将同名网桥连接到其他主机网卡或 VLAN 的 NNCP 名称，没有冲突时返回空字符串
+func conflictingPolicy(ctx context.Context, c client.Client, desired *unstructured.Unstructured) (string, error) {
+	policies := &unstructured.UnstructuredList{}
+	policies.SetGroupVersionKind(nncpGVK)
+	if err := c.List(ctx, policies, client.MatchingLabels{LabelManaged: "true", labelBridge: desired.GetLabels()[labelBridge]}); err != nil {
+		return "", err
+	}
+	for i := range policies.Items {
+		labels := policies.Items[i].GetLabels()
+		if _, absent := labels[labelNMStateAbsent]; absent {
+			continue
+		}
+		if labels[labelBridgeConfig] != desired.GetLabels()[labelBridgeConfig] {
+			return policies.Items[i].GetName(), nil
+		}
+	}
+	return "", nil
+}
 
+// droppedPolicy 判断 Wukong 之前使用的 NNCP 是否有不再使用的
+func droppedPolicy(previous, current []vmv1alpha1.NetworkStatus) bool {
+	inUse := make(map[string]bool, len(current))
+	for _, status := range current {
+		inUse[status.HostNetworkPolicy] = true
+	}
+	for _, status := range previous {
+		if status.HostNetworkPolicy != "" && !inUse[status.HostNetworkPolicy] {
+			return true
+		}
+	}
+	return false
+}
+
+// nncpAvailable 根据 NNCP 的 Available/Degraded 条件判断主机网络是否就绪
+func nncpAvailable(policy *unstructured.Unstructured) (bool, string) {
+	conditions, _, _ := unstructured.NestedSlice(policy.Object, "status", "conditions")
+	message := fmt.Sprintf("Waiting for NodeNetworkConfigurationPolicy %s to be Available", policy.GetName())
+	for _, cond := range conditions {
+		condMap, ok := cond.(map[string]interface{})
+		if !ok || condMap["status"] != "True" {
+			continue
+		}
+		condMessage, _, _ := unstructured.NestedString(condMap, "message")
+		switch condMap["type"] {
+		case "Available":
+			return true, ""
+		case "Degraded":
+			message = fmt.Sprintf("NodeNetworkConfigurationPolicy %s is Degraded: %s", policy.GetName(), condMessage)
+		}
+	}
+	return false, message
+}
+
+// nncpName 根据网络的主机配置和节点选择器生成 NNCP 名称，配置相同的网络共用同一个 NNCP
+func nncpName(netCfg *vmv1alpha1.NetworkConfig, nodeSelector map[string]string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s", netCfg.Type, hostNetworkDevice(netCfg), netCfg.HostInterface)
+	if netCfg.Type != "ovs" && netCfg.VLANID != nil {
+		fmt.Fprintf(h, "\x00%d", *netCfg.VLANID)
+	}
+	keys := make([]string, 0, len(nodeSelector))
+	for k := range nodeSelector {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(h, "\x00%s=%s", k, nodeSelector[k])
+	}
+	return fmt.Sprintf("wukong-%s-%s", hostNetworkDevice(netCfg), hex.EncodeToString(h.Sum(nil))[:8])
+}
+
+// hostNetworkDevice 返回 NNCP 为网络提供的主机设备：macvlan 网络的 VLAN 子接口，其他网络的网桥
+func hostNetworkDevice(netCfg *vmv1alpha1.NetworkConfig) string {
+	if netCfg.Type == "macvlan" && netCfg.VLANID != nil {
+		return vlanInterfaceName(netCfg.HostInterface, *netCfg.VLANID)
+	}
+	return bridgeName(netCfg)
+}
+
+// bridgeConfig 返回网桥所连接的主机网卡和 VLAN 的哈希，同名网桥的配置必须相同
+func bridgeConfig(netCfg *vmv1alpha1.NetworkConfig) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s", netCfg.Type, netCfg.HostInterface)
+	if netCfg.Type == "bridge" && netCfg.VLANID != nil {
+		fmt.Fprintf(h, "\x00%d", *netCfg.VLANID)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:8]
+}
+
+// vlanInterfaceName 返回 VLAN 子接口名称
+func vlanInterfaceName(hostInterface string, vlanID int) string {
+	return fmt.Sprintf("%s.%d", hostInterface, vlanID)
+}
+
+// wukongNodeSelector 返回 Wukong 的节点选择器
+func wukongNodeSelector(vmp *vmv1alpha1.Wukong) map[string]string {
+	if vmp.Spec.HighAvailability == nil {
+		return nil
+	}
+	return vmp.Spec.HighAvailability.NodeSelector
+}
+
+// findNetworkConfig 根据名称查找网络配置
+func findNetworkConfig(vmp *vmv1alpha1.Wukong, name string) *vmv1alpha1.NetworkConfig {
+	for i := range vmp.Spec.Networks {
+		if vmp.Spec.Networks[i].Name == name {
+			return &vmp.Spec.Networks[i]
+		}
+	}
 	return nil
 }
@@ -0,0 +1,345 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+// newFakeClient 返回注册了 Wukong、core 资源以及 unstructured 形式的 NAD 和 NNCP 的 fake client
+func newFakeClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := vmv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	for _, gvk := range []schema.GroupVersionKind{nadGVK, nncpGVK} {
+		scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+		scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithStatusSubresource(&vmv1alpha1.Wukong{}).Build()
+}
+
+// newBridgeWukong 构造一个网络连接到主机网桥的 Wukong
+func newBridgeWukong(name, bridge, hostInterface string, vlanID *int) *vmv1alpha1.Wukong {
+	return &vmv1alpha1.Wukong{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: name, UID: types.UID("uid-" + name)},
+		Spec: vmv1alpha1.WukongSpec{
+			Networks: []vmv1alpha1.NetworkConfig{
+				{Name: "tenant", Type: "bridge", BridgeName: bridge, HostInterface: hostInterface, VLANID: vlanID},
+			},
+		},
+	}
+}
+
+// getPolicy 读取 NNCP，不存在时返回 nil
+func getPolicy(t *testing.T, c client.Client, name string) *unstructured.Unstructured {
+	t.Helper()
+	policy := &unstructured.Unstructured{}
+	policy.SetGroupVersionKind(nncpGVK)
+	if err := c.Get(context.Background(), client.ObjectKey{Name: name}, policy); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return nil
+		}
+		t.Fatal(err)
+	}
+	return policy
+}
+
+func TestBuildNodeNetworkConfigurationPolicy(t *testing.T) {
+	tests := []struct {
+		golden       string
+		netCfg       vmv1alpha1.NetworkConfig
+		nodeSelector map[string]string
+	}{
+		{
+			golden: "nncp-linux-bridge",
+			netCfg: vmv1alpha1.NetworkConfig{Name: "tenant", Type: "bridge", HostInterface: "eth1"},
+		},
+		{
+			golden:       "nncp-linux-bridge-vlan",
+			netCfg:       vmv1alpha1.NetworkConfig{Name: "tenant", Type: "bridge", BridgeName: "br-vlan100", HostInterface: "eth1", VLANID: intPtr(100)},
+			nodeSelector: map[string]string{"network.example.com/tenant": "true"},
+		},
+		{
+			golden: "nncp-macvlan-vlan",
+			netCfg: vmv1alpha1.NetworkConfig{Name: "external", Type: "macvlan", HostInterface: "eth2", VLANID: intPtr(200)},
+		},
+		{
+			golden: "nncp-ovs-bridge",
+			netCfg: vmv1alpha1.NetworkConfig{Name: "trunk", Type: "ovs", BridgeName: "br-ovs", HostInterface: "bond0", VLANID: intPtr(100)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			vmp := &vmv1alpha1.Wukong{ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "vm"}}
+			if tt.nodeSelector != nil {
+				vmp.Spec.HighAvailability = &vmv1alpha1.HighAvailabilitySpec{NodeSelector: tt.nodeSelector}
+			}
+			policy := BuildNodeNetworkConfigurationPolicy(vmp, &tt.netCfg)
+			if policy == nil {
+				t.Fatal("BuildNodeNetworkConfigurationPolicy() = nil")
+			}
+
+			got, err := json.MarshalIndent(policy.Object, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			path := filepath.Join("testdata", tt.golden+".json")
+			if *update {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("BuildNodeNetworkConfigurationPolicy() mismatch with %s:\ngot:\n%s\nwant:\n%s", path, got, want)
+			}
+		})
+	}
+}
+
+func TestBuildNodeNetworkConfigurationPolicyNotRequired(t *testing.T) {
+	vmp := &vmv1alpha1.Wukong{ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "vm"}}
+	for _, netCfg := range []vmv1alpha1.NetworkConfig{
+		{Name: "tenant", Type: "bridge"},
+		{Name: "external", Type: "macvlan", HostInterface: "eth2"},
+		{Name: "external", Type: "macvlan", VLANID: intPtr(200)},
+		{Name: "fast", Type: "sriov", HostInterface: "ens1f0"},
+	} {
+		if policy := BuildNodeNetworkConfigurationPolicy(vmp, &netCfg); policy != nil {
+			t.Errorf("BuildNodeNetworkConfigurationPolicy(%s) = %s, want nil", netCfg.Type, policy.GetName())
+		}
+	}
+}
+
+func TestNNCPName(t *testing.T) {
+	base := vmv1alpha1.NetworkConfig{Name: "tenant", Type: "bridge", HostInterface: "eth1", VLANID: intPtr(100)}
+	name := nncpName(&base, nil)
+
+	// 配置相同的网络共用同一个 NNCP
+	same := base
+	same.Name = "other"
+	same.BridgeName = "br-tenant"
+	if got := nncpName(&same, nil); got != name {
+		t.Errorf("nncpName() = %q for the same host configuration, want %q", got, name)
+	}
+
+	for _, tt := range []struct {
+		name         string
+		netCfg       vmv1alpha1.NetworkConfig
+		nodeSelector map[string]string
+	}{
+		{name: "vlan", netCfg: vmv1alpha1.NetworkConfig{Name: "tenant", Type: "bridge", HostInterface: "eth1", VLANID: intPtr(200)}},
+		{name: "host interface", netCfg: vmv1alpha1.NetworkConfig{Name: "tenant", Type: "bridge", HostInterface: "eth2", VLANID: intPtr(100)}},
+		{name: "node selector", netCfg: base, nodeSelector: map[string]string{"zone": "a"}},
+	} {
+		if got := nncpName(&tt.netCfg, tt.nodeSelector); got == name {
+			t.Errorf("nncpName() with a different %s = %q, want a different name", tt.name, got)
+		}
+	}
+}
+
+func TestReconcileNMStateRejectsConflictingBridge(t *testing.T) {
+	first := newBridgeWukong("first", "br-tenant", "eth1", intPtr(100))
+	c := newFakeClient(t, first)
+	statuses := []vmv1alpha1.NetworkStatus{{Name: "tenant"}}
+	if err := ReconcileNMState(context.Background(), c, first, statuses); err != nil {
+		t.Fatalf("ReconcileNMState() error = %v", err)
+	}
+	if getPolicy(t, c, statuses[0].HostNetworkPolicy) == nil {
+		t.Fatal("NodeNetworkConfigurationPolicy not created")
+	}
+
+	tests := []struct {
+		name          string
+		hostInterface string
+		vlanID        *int
+		wantConflict  bool
+	}{
+		{name: "same configuration", hostInterface: "eth1", vlanID: intPtr(100)},
+		{name: "different VLAN", hostInterface: "eth1", vlanID: intPtr(200), wantConflict: true},
+		{name: "different host interface", hostInterface: "eth2", vlanID: intPtr(100), wantConflict: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vmp := newBridgeWukong("second", "br-tenant", tt.hostInterface, tt.vlanID)
+			statuses := []vmv1alpha1.NetworkStatus{{Name: "tenant"}}
+			if err := ReconcileNMState(context.Background(), c, vmp, statuses); err != nil {
+				t.Fatalf("ReconcileNMState() error = %v", err)
+			}
+			created := getPolicy(t, c, statuses[0].HostNetworkPolicy) != nil
+			if created == tt.wantConflict {
+				t.Errorf("NodeNetworkConfigurationPolicy created = %v, want %v", created, !tt.wantConflict)
+			}
+			if tt.wantConflict && (statuses[0].HostNetworkReady || !strings.Contains(statuses[0].Message, "br-tenant")) {
+				t.Errorf("status = %+v, want the conflicting bridge reported", statuses[0])
+			}
+		})
+	}
+
+	// 同一 Wukong 中的冲突定义
+	vmp := newBridgeWukong("third", "br-other", "eth1", nil)
+	vmp.Spec.Networks = append(vmp.Spec.Networks, vmv1alpha1.NetworkConfig{Name: "storage", Type: "bridge", BridgeName: "br-other", HostInterface: "eth3"})
+	statuses = []vmv1alpha1.NetworkStatus{{Name: "tenant"}, {Name: "storage"}}
+	if err := ReconcileNMState(context.Background(), c, vmp, statuses); err != nil {
+		t.Fatalf("ReconcileNMState() error = %v", err)
+	}
+	if statuses[1].HostNetworkReady || getPolicy(t, c, statuses[1].HostNetworkPolicy) != nil {
+		t.Errorf("conflicting bridge of the same Wukong was provisioned: %+v", statuses[1])
+	}
+}
+
+func TestGarbageCollectNMState(t *testing.T) {
+	vmp := newBridgeWukong("vm", "br-tenant", "eth1", intPtr(100))
+	c := newFakeClient(t, vmp)
+	ctx := context.Background()
+	statuses := []vmv1alpha1.NetworkStatus{{Name: "tenant"}}
+	if err := ReconcileNMState(ctx, c, vmp, statuses); err != nil {
+		t.Fatalf("ReconcileNMState() error = %v", err)
+	}
+	name := statuses[0].HostNetworkPolicy
+
+	// 仍被使用的 NNCP 保持不变
+	if removing, err := GarbageCollectNMState(ctx, c, nil); err != nil || removing {
+		t.Fatalf("GarbageCollectNMState() = %v, %v", removing, err)
+	}
+	if policy := getPolicy(t, c, name); policy == nil || policy.GetLabels()[labelNMStateAbsent] != "" {
+		t.Fatalf("required NodeNetworkConfigurationPolicy was removed: %v", policy)
+	}
+
+	// 删除的 Wukong 不再需要该 NNCP：先设置 absent，不立即删除
+	removing, err := GarbageCollectNMState(ctx, c, vmp)
+	if err != nil || !removing {
+		t.Fatalf("GarbageCollectNMState() = %v, %v, want removal in progress", removing, err)
+	}
+	policy := getPolicy(t, c, name)
+	if policy == nil {
+		t.Fatal("NodeNetworkConfigurationPolicy deleted before its interfaces were removed")
+	}
+	interfaces, _, _ := unstructured.NestedSlice(policy.Object, "spec", "desiredState", "interfaces")
+	if len(interfaces) != 2 {
+		t.Fatalf("interfaces = %v, want the bridge and the VLAN interface", interfaces)
+	}
+	for _, iface := range interfaces {
+		if state := iface.(map[string]interface{})["state"]; state != "absent" {
+			t.Errorf("interface %v state = %v, want absent", iface, state)
+		}
+	}
+
+	// absent 状态生效后删除
+	since, _ := time.Parse(time.RFC3339, policy.GetAnnotations()[annotationAbsentSince])
+	policy.Object["status"] = map[string]interface{}{
+		"conditions": []interface{}{map[string]interface{}{
+			"type":               "Available",
+			"status":             "True",
+			"lastTransitionTime": since.Add(time.Second).Format(time.RFC3339),
+		}},
+	}
+	if err := c.Update(ctx, policy); err != nil {
+		t.Fatal(err)
+	}
+	if removing, err := GarbageCollectNMState(ctx, c, vmp); err != nil || removing {
+		t.Fatalf("GarbageCollectNMState() = %v, %v", removing, err)
+	}
+	if getPolicy(t, c, name) != nil {
+		t.Error("NodeNetworkConfigurationPolicy not deleted once its interfaces were absent")
+	}
+}
+
+func TestAbsentApplied(t *testing.T) {
+	since := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	policy := func(conditionTime time.Time) *unstructured.Unstructured {
+		p := &unstructured.Unstructured{Object: map[string]interface{}{
+			"status": map[string]interface{}{
+				"conditions": []interface{}{map[string]interface{}{
+					"type":               "Available",
+					"status":             "True",
+					"lastTransitionTime": conditionTime.Format(time.RFC3339),
+				}},
+			},
+		}}
+		p.SetAnnotations(map[string]string{annotationAbsentSince: since.Format(time.RFC3339)})
+		return p
+	}
+
+	if absentApplied(policy(since.Add(-time.Hour)), since.Add(time.Minute)) {
+		t.Error("absentApplied() = true for a condition from before the policy was set absent")
+	}
+	if !absentApplied(policy(since.Add(time.Minute)), since.Add(time.Minute)) {
+		t.Error("absentApplied() = false for a condition from after the policy was set absent")
+	}
+	if !absentApplied(policy(since.Add(-time.Hour)), since.Add(nncpAbsentTimeout+time.Minute)) {
+		t.Error("absentApplied() = false after the timeout")
+	}
+}
+
+func TestDroppedPolicy(t *testing.T) {
+	previous := []vmv1alpha1.NetworkStatus{{Name: "tenant", HostNetworkPolicy: "wukong-br-tenant-1"}, {Name: "pod"}}
+	if droppedPolicy(previous, []vmv1alpha1.NetworkStatus{{Name: "tenant", HostNetworkPolicy: "wukong-br-tenant-1"}}) {
+		t.Error("droppedPolicy() = true when the policy is still used")
+	}
+	if !droppedPolicy(previous, []vmv1alpha1.NetworkStatus{{Name: "tenant", HostNetworkPolicy: "wukong-br-tenant-2"}}) {
+		t.Error("droppedPolicy() = false when the policy changed")
+	}
+	if !droppedPolicy(previous, nil) {
+		t.Error("droppedPolicy() = false when the network was removed")
+	}
+}
+
+func TestGarbageCollectNMStateKeepsSharedInterfaces(t *testing.T) {
+	// 网桥和 macvlan 网络使用同一个 VLAN 子接口 eth1.100
+	bridged := newBridgeWukong("bridged", "br-tenant", "eth1", intPtr(100))
+	macvlan := &vmv1alpha1.Wukong{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "macvlan", UID: types.UID("uid-macvlan")},
+		Spec: vmv1alpha1.WukongSpec{
+			Networks: []vmv1alpha1.NetworkConfig{{Name: "tenant", Type: "macvlan", HostInterface: "eth1", VLANID: intPtr(100)}},
+		},
+	}
+	c := newFakeClient(t, bridged, macvlan)
+	ctx := context.Background()
+	statuses := []vmv1alpha1.NetworkStatus{{Name: "tenant"}}
+	if err := ReconcileNMState(ctx, c, macvlan, statuses); err != nil {
+		t.Fatalf("ReconcileNMState() error = %v", err)
+	}
+	name := statuses[0].HostNetworkPolicy
+	if name == "" || getPolicy(t, c, name) == nil {
+		t.Fatal("NodeNetworkConfigurationPolicy of the macvlan VLAN interface not created")
+	}
+
+	if _, err := GarbageCollectNMState(ctx, c, macvlan); err != nil {
+		t.Fatalf("GarbageCollectNMState() error = %v", err)
+	}
+	policy := getPolicy(t, c, name)
+	if policy == nil {
+		t.Fatal("NodeNetworkConfigurationPolicy deleted before it was set absent")
+	}
+	if interfaces := policyInterfaces(policy); len(interfaces) != 0 {
+		t.Errorf("interfaces set absent = %v, want eth1.100 kept for the bridge", interfaces)
+	}
+}
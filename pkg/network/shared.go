@@ -0,0 +1,230 @@
+package network
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+// LabelNetwork is the label set on shared NetworkAttachmentDefinitions with the name of their WukongNetwork
+const LabelNetwork = "wukong.novasphere.dev/network"
+
+// SharedNADName returns the name of the NetworkAttachmentDefinition shared by all Wukongs
+// of a namespace referencing the given WukongNetwork
+func SharedNADName(networkRef string) string {
+	return fmt.Sprintf("wukong-net-%s", networkRef)
+}
+
+// ResolveNetworkConfig returns the effective configuration of a Wukong network.
+// For networks referencing a WukongNetwork, the type, bridge, VLAN, host interface, MTU and
+// type-specific settings come from the WukongNetwork; the guest IPConfig stays per Wukong.
+// A missing WukongNetwork is returned as a NotFound error.
+func ResolveNetworkConfig(ctx context.Context, c client.Client, netCfg *vmv1alpha1.NetworkConfig) (*vmv1alpha1.NetworkConfig, error) {
+	if netCfg.NetworkRef == "" {
+		return netCfg, nil
+	}
+
+	wn := &vmv1alpha1.WukongNetwork{}
+	if err := c.Get(ctx, client.ObjectKey{Name: netCfg.NetworkRef}, wn); err != nil {
+		return nil, err
+	}
+	return sharedNetworkConfig(netCfg, wn), nil
+}
+
+// sharedNetworkConfig 将 WukongNetwork 的定义合并到网络配置中
+func sharedNetworkConfig(netCfg *vmv1alpha1.NetworkConfig, wn *vmv1alpha1.WukongNetwork) *vmv1alpha1.NetworkConfig {
+	resolved := netCfg.DeepCopy()
+	resolved.Type = wn.Spec.Type
+	resolved.VLANID = wn.Spec.VLANID
+	resolved.HostInterface = wn.Spec.HostInterface
+	resolved.MTU = wn.Spec.MTU
+	resolved.MacvlanMode = wn.Spec.MacvlanMode
+	resolved.SRIOV = wn.Spec.SRIOV
+	resolved.Trunk = wn.Spec.Trunk
+	resolved.BridgeName = wn.Spec.BridgeName
+	// 默认网桥名称使用 WukongNetwork 名称，保证所有引用方使用同一个网桥
+	if resolved.BridgeName == "" {
+		resolved.BridgeName = fmt.Sprintf("br-%s", wn.Name)
+	}
+	return resolved
+}
+
+// buildSharedNAD 构造 WukongNetwork 在指定 namespace 中共享的 NetworkAttachmentDefinition
+func buildSharedNAD(wn *vmv1alpha1.WukongNetwork, namespace string) (*unstructured.Unstructured, error) {
+	netCfg := sharedNetworkConfig(&vmv1alpha1.NetworkConfig{Name: wn.Name, NetworkRef: wn.Name}, wn)
+	configStr, err := buildCNIConfig(netCfg, SharedNADName(wn.Name), ipamForNetwork(wn.Spec.IPAM))
+	if err != nil {
+		return nil, err
+	}
+
+	nad := &unstructured.Unstructured{}
+	nad.SetGroupVersionKind(nadGVK)
+	nad.SetName(SharedNADName(wn.Name))
+	nad.SetNamespace(namespace)
+	nad.SetAnnotations(nadAnnotations(netCfg))
+	nad.SetLabels(map[string]string{
+		LabelManaged: "true",
+		LabelNetwork: wn.Name,
+	})
+	// 集群级 WukongNetwork 删除时级联删除各 namespace 中的 NAD
+	nad.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: vmv1alpha1.GroupVersion.String(),
+		Kind:       "WukongNetwork",
+		Name:       wn.Name,
+		UID:        wn.UID,
+	}})
+	if err := unstructured.SetNestedField(nad.Object, map[string]interface{}{
+		"config": configStr,
+	}, "spec"); err != nil {
+		return nil, err
+	}
+	return nad, nil
+}
+
+// ipamForNetwork 根据 WukongNetwork 的 IPAM 配置构造 CNI ipam
+func ipamForNetwork(ipam *vmv1alpha1.NetworkIPAMSpec) map[string]interface{} {
+	if ipam == nil {
+		return nil
+	}
+	switch ipam.Type {
+	case vmv1alpha1.NetworkIPAMDHCP:
+		return map[string]interface{}{"type": "dhcp"}
+	case vmv1alpha1.NetworkIPAMWhereabouts:
+		cfg := map[string]interface{}{
+			"type":  "whereabouts",
+			"range": ipam.Range,
+		}
+		if ipam.Gateway != "" {
+			cfg["gateway"] = ipam.Gateway
+		}
+		if len(ipam.Exclude) > 0 {
+			cfg["exclude"] = ipam.Exclude
+		}
+		return cfg
+	}
+	return nil
+}
+
+// CountNetworkReferences returns the number of Wukongs referencing the given WukongNetwork and
+// the namespaces they live in. Wukongs being deleted are not counted.
+func CountNetworkReferences(ctx context.Context, c client.Client, networkRef string) (int32, []string, error) {
+	wukongs := &vmv1alpha1.WukongList{}
+	if err := c.List(ctx, wukongs); err != nil {
+		return 0, nil, err
+	}
+
+	var count int32
+	namespaces := make([]string, 0)
+	seen := make(map[string]bool)
+	for i := range wukongs.Items {
+		vmp := &wukongs.Items[i]
+		if !vmp.DeletionTimestamp.IsZero() || !referencesNetwork(vmp, networkRef) {
+			continue
+		}
+		count++
+		if !seen[vmp.Namespace] {
+			seen[vmp.Namespace] = true
+			namespaces = append(namespaces, vmp.Namespace)
+		}
+	}
+	return count, namespaces, nil
+}
+
+// GarbageCollectSharedNADs deletes the shared NetworkAttachmentDefinitions of a namespace that no
+// Wukong references anymore. The given Wukong (e.g., one being deleted) is not counted, and may be nil.
+func GarbageCollectSharedNADs(ctx context.Context, c client.Client, namespace string, exclude *vmv1alpha1.Wukong) error {
+	logger := log.FromContext(ctx)
+
+	nads := &unstructured.UnstructuredList{}
+	nads.SetGroupVersionKind(nadGVK)
+	if err := c.List(ctx, nads, client.InNamespace(namespace), client.HasLabels{LabelNetwork}); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil
+		}
+		return err
+	}
+	if len(nads.Items) == 0 {
+		return nil
+	}
+
+	wukongs := &vmv1alpha1.WukongList{}
+	if err := c.List(ctx, wukongs, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+
+	// 引用计数：统计 namespace 中仍引用各 WukongNetwork 的 Wukong
+	references := make(map[string]int)
+	for i := range wukongs.Items {
+		vmp := &wukongs.Items[i]
+		if !vmp.DeletionTimestamp.IsZero() || (exclude != nil && vmp.UID == exclude.UID) {
+			continue
+		}
+		for _, netCfg := range vmp.Spec.Networks {
+			if netCfg.NetworkRef != "" {
+				references[netCfg.NetworkRef]++
+			}
+		}
+	}
+
+	for i := range nads.Items {
+		nad := &nads.Items[i]
+		networkRef := nad.GetLabels()[LabelNetwork]
+		if references[networkRef] > 0 {
+			continue
+		}
+		logger.Info("Deleting unused shared NetworkAttachmentDefinition", "name", nad.GetName(), "namespace", namespace, "network", networkRef)
+		if err := c.Delete(ctx, nad); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// GarbageCollectNetworkNADs deletes the shared NetworkAttachmentDefinitions of a WukongNetwork in
+// the namespaces where no Wukong references it anymore; namespaces lists the namespaces still referencing it.
+func GarbageCollectNetworkNADs(ctx context.Context, c client.Client, networkRef string, namespaces []string) error {
+	logger := log.FromContext(ctx)
+
+	nads := &unstructured.UnstructuredList{}
+	nads.SetGroupVersionKind(nadGVK)
+	if err := c.List(ctx, nads, client.MatchingLabels{LabelNetwork: networkRef}); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil
+		}
+		return err
+	}
+
+	inUse := make(map[string]bool, len(namespaces))
+	for _, ns := range namespaces {
+		inUse[ns] = true
+	}
+	for i := range nads.Items {
+		nad := &nads.Items[i]
+		if inUse[nad.GetNamespace()] {
+			continue
+		}
+		logger.Info("Deleting unused shared NetworkAttachmentDefinition", "name", nad.GetName(), "namespace", nad.GetNamespace(), "network", networkRef)
+		if err := c.Delete(ctx, nad); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// referencesNetwork 判断 Wukong 是否引用了指定的 WukongNetwork
+func referencesNetwork(vmp *vmv1alpha1.Wukong, networkRef string) bool {
+	for _, netCfg := range vmp.Spec.Networks {
+		if netCfg.NetworkRef == networkRef {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package network
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+// newSharedNAD 构造 WukongNetwork 在 namespace 中共享的 NAD
+func newSharedNAD(namespace, networkRef string) *unstructured.Unstructured {
+	nad := &unstructured.Unstructured{}
+	nad.SetGroupVersionKind(nadGVK)
+	nad.SetNamespace(namespace)
+	nad.SetName(SharedNADName(networkRef))
+	nad.SetLabels(map[string]string{LabelManaged: "true", LabelNetwork: networkRef})
+	return nad
+}
+
+// newRefWukong 构造引用 WukongNetwork 的 Wukong
+func newRefWukong(namespace, name string, networkRefs ...string) *vmv1alpha1.Wukong {
+	vmp := &vmv1alpha1.Wukong{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	for _, ref := range networkRefs {
+		vmp.Spec.Networks = append(vmp.Spec.Networks, vmv1alpha1.NetworkConfig{Name: ref, NetworkRef: ref})
+	}
+	return vmp
+}
+
+func TestCountNetworkReferences(t *testing.T) {
+	c := newFakeClient(t,
+		newRefWukong("team-a", "web", "tenant"),
+		newRefWukong("team-a", "db", "tenant", "storage"),
+		newRefWukong("team-b", "app", "storage"),
+	)
+
+	count, namespaces, err := CountNetworkReferences(context.Background(), c, "tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || len(namespaces) != 1 || namespaces[0] != "team-a" {
+		t.Errorf("CountNetworkReferences(tenant) = %d, %v, want 2 in team-a", count, namespaces)
+	}
+}
+
+func TestGarbageCollectNetworkNADs(t *testing.T) {
+	ctx := context.Background()
+	// team-b 中的 Wukong 已从 spec 中移除了对 tenant 的引用
+	c := newFakeClient(t,
+		newRefWukong("team-a", "web", "tenant"),
+		newRefWukong("team-b", "app"),
+		newSharedNAD("team-a", "tenant"),
+		newSharedNAD("team-b", "tenant"),
+		newSharedNAD("team-b", "storage"),
+	)
+
+	_, namespaces, err := CountNetworkReferences(ctx, c, "tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := GarbageCollectNetworkNADs(ctx, c, "tenant", namespaces); err != nil {
+		t.Fatalf("GarbageCollectNetworkNADs() error = %v", err)
+	}
+
+	exists := func(namespace, networkRef string) bool {
+		nad := &unstructured.Unstructured{}
+		nad.SetGroupVersionKind(nadGVK)
+		err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: SharedNADName(networkRef)}, nad)
+		if client.IgnoreNotFound(err) != nil {
+			t.Fatal(err)
+		}
+		return err == nil
+	}
+	if !exists("team-a", "tenant") {
+		t.Error("referenced shared NAD deleted")
+	}
+	if exists("team-b", "tenant") {
+		t.Error("unreferenced shared NAD not deleted")
+	}
+	if !exists("team-b", "storage") {
+		t.Error("shared NAD of another WukongNetwork deleted")
+	}
+}
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+// LabelDataImportCron is the label CDI sets on DataVolumes and PVCs imported by a DataImportCron
+const LabelDataImportCron = "cdi.kubevirt.io/dataImportCron"
+
+// BuildDataImportCron builds a DataImportCron that periodically imports the disk source
+// into new PVCs and keeps the DataSource with the same name pointing to the latest one.
+// The labels are set on the DataImportCron and on the DataVolumes it imports.
+func BuildDataImportCron(ctx context.Context, c client.Client, name, namespace string, disk vmv1alpha1.DiskConfig, refresh vmv1alpha1.ImageRefreshSpec, labels map[string]string) (*unstructured.Unstructured, error) {
+	// 复用 DataVolume 的 spec 作为 DataImportCron 的模板
+	dv, err := BuildDataVolume(ctx, c, name, namespace, disk)
+	if err != nil {
+		return nil, err
+	}
+	dvSpec, _, err := unstructured.NestedMap(dv.Object, "spec")
+	if err != nil {
+		return nil, err
+	}
+
+	cron := &unstructured.Unstructured{}
+	cron.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "cdi.kubevirt.io",
+		Version: "v1beta1",
+		Kind:    "DataImportCron",
+	})
+	cron.SetName(name)
+	cron.SetNamespace(namespace)
+	cron.SetLabels(labels)
+
+	templateLabels := make(map[string]interface{}, len(labels))
+	for k, v := range labels {
+		templateLabels[k] = v
+	}
+
+	importsToKeep := int64(3)
+	if refresh.ImportsToKeep != nil {
+		importsToKeep = int64(*refresh.ImportsToKeep)
+	}
+
+	spec := map[string]interface{}{
+		"schedule":          refresh.Schedule,
+		"managedDataSource": name,
+		// 旧的 PVC 可能仍被 Wukong 引用，由 operator 自己回收，不交给 CDI
+		"garbageCollect":  "Never",
+		"importsToKeep":   importsToKeep,
+		"retentionPolicy": "All",
+		"template": map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"labels": templateLabels,
+			},
+			"spec": dvSpec,
+		},
+	}
+	if err := unstructured.SetNestedField(cron.Object, spec, "spec"); err != nil {
+		return nil, err
+	}
+	return cron, nil
+}
+
+// EnsureDataImportCron creates the DataImportCron if it does not exist, or updates its spec and labels if they changed.
+// It returns the DataImportCron as observed in the cluster.
+func EnsureDataImportCron(ctx context.Context, c client.Client, cron *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	logger := log.FromContext(ctx)
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(cron.GroupVersionKind())
+	if err := c.Get(ctx, client.ObjectKeyFromObject(cron), existing); err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+		logger.Info("Creating DataImportCron", "name", cron.GetName(), "namespace", cron.GetNamespace())
+		if err := c.Create(ctx, cron); err != nil {
+			return nil, err
+		}
+		return cron, nil
+	}
+
+	desiredSpec, _, _ := unstructured.NestedMap(cron.Object, "spec")
+	currentSpec, _, _ := unstructured.NestedMap(existing.Object, "spec")
+	// CDI 会为 template 填充默认值，只比较我们关心的字段
+	if !cronSpecChanged(desiredSpec, currentSpec) && equality.Semantic.DeepEqual(existing.GetLabels(), cron.GetLabels()) {
+		return existing, nil
+	}
+
+	logger.Info("Updating DataImportCron", "name", cron.GetName(), "namespace", cron.GetNamespace())
+	if err := unstructured.SetNestedMap(existing.Object, desiredSpec, "spec"); err != nil {
+		return nil, err
+	}
+	existing.SetLabels(cron.GetLabels())
+	if err := c.Update(ctx, existing); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// cronSpecChanged reports whether the desired DataImportCron spec differs from the current one.
+func cronSpecChanged(desired, current map[string]interface{}) bool {
+	for _, field := range []string{"schedule", "importsToKeep", "managedDataSource"} {
+		if fmt.Sprint(desired[field]) != fmt.Sprint(current[field]) {
+			return true
+		}
+	}
+	desiredSource, _, _ := unstructured.NestedMap(desired, "template", "spec", "source")
+	currentSource, _, _ := unstructured.NestedMap(current, "template", "spec", "source")
+	return !equality.Semantic.DeepEqual(desiredSource, currentSource)
+}
+
+// DataImportCronState describes the observed state of a DataImportCron.
+type DataImportCronState struct {
+	// LastImportedPVC is the name of the most recently imported PVC
+	LastImportedPVC string
+	// LastImportTime is the time of the most recent import
+	LastImportTime *metav1.Time
+	// Importing indicates an import is in progress
+	Importing bool
+	// UpToDate indicates the latest build of the source has been imported
+	UpToDate bool
+	// Message is the message of the UpToDate condition
+	Message string
+}
+
+// GetDataImportCronState extracts the import state from a DataImportCron.
+func GetDataImportCronState(cron *unstructured.Unstructured) *DataImportCronState {
+	state := &DataImportCronState{}
+	state.LastImportedPVC, _, _ = unstructured.NestedString(cron.Object, "status", "lastImportedPVC", "name")
+	if ts, found, _ := unstructured.NestedString(cron.Object, "status", "lastImportTimestamp"); found {
+		if t, err := time.Parse(time.RFC3339, ts); err == nil {
+			state.LastImportTime = &metav1.Time{Time: t}
+		}
+	}
+
+	currentImports, _, _ := unstructured.NestedSlice(cron.Object, "status", "currentImports")
+	state.Importing = len(currentImports) > 0
+
+	conditions, _, _ := unstructured.NestedSlice(cron.Object, "status", "conditions")
+	for _, cond := range conditions {
+		condMap, ok := cond.(map[string]interface{})
+		if !ok || condMap["type"] != "UpToDate" {
+			continue
+		}
+		state.UpToDate = condMap["status"] == "True"
+		state.Message, _, _ = unstructured.NestedString(condMap, "message")
+	}
+	return state
+}
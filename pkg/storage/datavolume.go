@@ -3,7 +3,6 @@ package storage
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
@@ -17,15 +16,35 @@ import (
 )
 
 // ReconcileDataVolume creates or gets an existing DataVolume for the given disk configuration.
-// DataVolume is used when disk.image is specified to import data from a container image.
-// It returns the PVC name (created by DataVolume) and bound status.
-func ReconcileDataVolume(ctx context.Context, c client.Client, disk vmv1alpha1.DiskConfig, namespace, vmName string) (string, bool, error) {
+// DataVolume is used when disk.image or disk.source is specified to populate the disk.
+// The labels are set on the DataVolume; CDI copies them to the PVC.
+// It returns the PVC name (created by DataVolume) and the observed DataVolume state.
+func ReconcileDataVolume(ctx context.Context, c client.Client, disk vmv1alpha1.DiskConfig, namespace, dvName string, labels map[string]string) (string, *DataVolumeState, error) {
 	logger := log.FromContext(ctx)
-	dvName := fmt.Sprintf("%s-%s", vmName, disk.Name)
 	pvcName := dvName // DataVolume 创建的 PVC 名称与 DataVolume 名称相同
 
 	logger.Info("Reconciling DataVolume", "name", dvName, "namespace", namespace, "image", disk.Image, "size", disk.Size, "storageClass", disk.StorageClassName)
 
+	dv, err := BuildDataVolume(ctx, c, dvName, namespace, disk)
+	if err != nil {
+		return "", nil, err
+	}
+	dv.SetLabels(labels)
+
+	state, err := EnsureDataVolume(ctx, c, dv)
+	if err != nil {
+		if ctx.Err() != nil {
+			return pvcName, nil, err
+		}
+		return "", nil, err
+	}
+	return pvcName, state, nil
+}
+
+// BuildDataVolume builds a DataVolume object populating a PVC for the given disk configuration.
+func BuildDataVolume(ctx context.Context, c client.Client, name, namespace string, disk vmv1alpha1.DiskConfig) (*unstructured.Unstructured, error) {
+	logger := log.FromContext(ctx)
+
 	// 使用 Unstructured 创建 DataVolume（避免直接依赖 CDI API）
 	dv := &unstructured.Unstructured{}
 	dv.SetGroupVersionKind(schema.GroupVersionKind{
@@ -33,51 +52,22 @@ func ReconcileDataVolume(ctx context.Context, c client.Client, disk vmv1alpha1.D
 		Version: "v1beta1",
 		Kind:    "DataVolume",
 	})
-	dv.SetName(dvName)
+	dv.SetName(name)
 	dv.SetNamespace(namespace)
 
-	// 根据 image URL 类型选择不同的 source
-	// 支持 http://、https://（HTTP 源）和 docker://（registry 源）
-	var source map[string]interface{}
-	imageURL := disk.Image
-
-	if strings.HasPrefix(imageURL, "http://") || strings.HasPrefix(imageURL, "https://") {
-		// HTTP/HTTPS 源：直接从 URL 下载镜像文件
-		logger.Info("Using HTTP source for DataVolume", "url", imageURL)
-		source = map[string]interface{}{
-			"http": map[string]interface{}{
-				"url": imageURL,
-			},
-		}
-	} else if strings.HasPrefix(imageURL, "docker://") {
-		// Docker registry 源：从容器镜像仓库拉取
-		// 去掉 docker:// 前缀，CDI 需要的是纯 URL
-		registryURL := strings.TrimPrefix(imageURL, "docker://")
-		logger.Info("Using registry source for DataVolume", "url", registryURL)
-		source = map[string]interface{}{
-			"registry": map[string]interface{}{
-				// 使用容器内拉取镜像的方式（pod 模式），在 Docker Desktop 等环境下更通用
-				"url":        registryURL,
-				"pullMethod": "pod",
-			},
-		}
-	} else {
-		// 默认当作 registry URL（兼容旧格式）
-		logger.Info("Using registry source for DataVolume (default)", "url", imageURL)
-		source = map[string]interface{}{
-			"registry": map[string]interface{}{
-				"url":        imageURL,
-				"pullMethod": "pod",
-			},
-		}
+	// 根据 disk.source（或旧的 disk.image）构建 source / sourceRef
+	source, sourceRef, err := buildDataVolumeSource(ctx, c, disk, namespace)
+	if err != nil {
+		logger.Error(err, "failed to build DataVolume source", "name", name)
+		return nil, err
 	}
 
 	// 构建 DataVolume spec
 	spec := map[string]interface{}{
-		"source": source,
 		"pvc": map[string]interface{}{
 			// 注意：这里必须使用 []interface{}，否则在 DeepCopy 期间会因为 []string 触发 panic: cannot deep copy []string
-			"accessModes": []interface{}{"ReadWriteOnce"},
+			"accessModes": toInterfaceSlice(pvcAccessModes(disk)),
+			"volumeMode":  pvcVolumeMode(disk),
 			"resources": map[string]interface{}{
 				"requests": map[string]interface{}{
 					"storage": disk.Size,
@@ -87,55 +77,78 @@ func ReconcileDataVolume(ctx context.Context, c client.Client, disk vmv1alpha1.D
 		},
 	}
 
+	if sourceRef != nil {
+		spec["sourceRef"] = sourceRef
+	} else {
+		spec["source"] = source
+	}
+
 	if err := unstructured.SetNestedField(dv.Object, spec, "spec"); err != nil {
 		logger.Error(err, "failed to set DataVolume spec")
-		return "", false, err
+		return nil, err
 	}
 
+	return dv, nil
+}
+
+// EnsureDataVolume creates the DataVolume if it does not exist yet and returns its observed state.
+// An existing DataVolume is not updated.
+func EnsureDataVolume(ctx context.Context, c client.Client, dv *unstructured.Unstructured) (*DataVolumeState, error) {
+	logger := log.FromContext(ctx)
+	dvName := dv.GetName()
+	namespace := dv.GetNamespace()
+
 	// 检查 context 是否已取消
 	if ctx.Err() != nil {
 		logger.V(1).Info("Context canceled before checking DataVolume, will retry", "name", dvName, "error", ctx.Err())
-		return pvcName, false, ctx.Err()
+		return nil, ctx.Err()
 	}
 
 	// 尝试获取现有的 DataVolume
 	existingDV := &unstructured.Unstructured{}
-	existingDV.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   "cdi.kubevirt.io",
-		Version: "v1beta1",
-		Kind:    "DataVolume",
-	})
+	existingDV.SetGroupVersionKind(dv.GroupVersionKind())
 	key := client.ObjectKey{Namespace: namespace, Name: dvName}
-	err := c.Get(ctx, key, existingDV)
-	if err != nil {
+	if err := c.Get(ctx, key, existingDV); err != nil {
 		if errors.IsNotFound(err) {
 			// DataVolume 不存在，创建新的
-			logger.Info("Creating DataVolume", "name", dvName, "image", disk.Image)
+			logger.Info("Creating DataVolume", "name", dvName, "namespace", namespace)
 			if err := c.Create(ctx, dv); err != nil {
 				logger.Error(err, "failed to create DataVolume", "name", dvName)
-				return "", false, err
+				return nil, err
 			}
 			// 不等待，让 controller requeue 来检查状态
 			logger.Info("DataVolume created, will check status in next reconcile", "name", dvName)
-			return pvcName, false, nil
+			return &DataVolumeState{}, nil
 		}
 		// 如果是 context canceled，返回以便 controller 处理
 		if ctx.Err() != nil {
 			logger.V(1).Info("Context canceled during Get DataVolume, will retry", "name", dvName)
-			return pvcName, false, ctx.Err()
+			return nil, ctx.Err()
 		}
 		// 其他错误
 		logger.Error(err, "failed to get DataVolume", "name", dvName)
-		return "", false, err
+		return nil, err
 	}
 
 	// DataVolume 已存在，检查状态（不等待）
 	logger.V(1).Info("Found existing DataVolume", "name", dvName)
-	bound, err := CheckDataVolumeStatus(ctx, c, namespace, dvName)
-	if err != nil {
-		return pvcName, false, err
-	}
-	return pvcName, bound, nil
+	return GetDataVolumeState(ctx, c, namespace, dvName)
+}
+
+// DataVolumeState describes the observed state of a DataVolume import.
+type DataVolumeState struct {
+	// Phase is the DataVolume status.phase (e.g., ImportInProgress, Succeeded, Failed)
+	Phase string
+	// Progress is the DataVolume status.progress (e.g., "45.20%")
+	Progress string
+	// RestartCount is the number of times the populating pod has restarted
+	RestartCount int32
+	// Message is the message of the most recent DataVolume condition
+	Message string
+	// Ready indicates that the disk can be used by the VM
+	Ready bool
+	// Failed indicates that the import has failed
+	Failed bool
 }
 
 // CheckDataVolumeStatus checks the current status of a DataVolume (non-blocking).
@@ -143,6 +156,19 @@ func ReconcileDataVolume(ctx context.Context, c client.Client, disk vmv1alpha1.D
 // Returns false if DataVolume is still in progress.
 // Returns error if DataVolume is in Failed/Error state or other errors occur.
 func CheckDataVolumeStatus(ctx context.Context, c client.Client, namespace, name string) (bool, error) {
+	state, err := GetDataVolumeState(ctx, c, namespace, name)
+	if err != nil {
+		return false, err
+	}
+	if state.Failed {
+		return false, fmt.Errorf("DataVolume %s/%s is in %s state: %s", namespace, name, state.Phase, state.Message)
+	}
+	return state.Ready, nil
+}
+
+// GetDataVolumeState returns the observed state of a DataVolume (non-blocking).
+// A failed import is reported through DataVolumeState.Failed rather than as an error.
+func GetDataVolumeState(ctx context.Context, c client.Client, namespace, name string) (*DataVolumeState, error) {
 	logger := log.FromContext(ctx)
 
 	dv := &unstructured.Unstructured{}
@@ -154,7 +180,7 @@ func CheckDataVolumeStatus(ctx context.Context, c client.Client, namespace, name
 	// 检查 context 是否已取消
 	if ctx.Err() != nil {
 		logger.V(1).Info("Context canceled, will retry in next reconcile", "name", name, "error", ctx.Err())
-		return false, ctx.Err()
+		return nil, ctx.Err()
 	}
 
 	key := client.ObjectKey{Namespace: namespace, Name: name}
@@ -162,39 +188,62 @@ func CheckDataVolumeStatus(ctx context.Context, c client.Client, namespace, name
 		if errors.IsNotFound(err) {
 			// DataVolume 可能还在创建中
 			logger.V(1).Info("DataVolume not found, may still be creating", "name", name)
-			return false, nil
+			return &DataVolumeState{}, nil
 		}
 		// 如果是 context canceled，返回特殊错误以便 controller 处理
 		if ctx.Err() != nil {
 			logger.V(1).Info("Context canceled during Get, will retry", "name", name)
-			return false, ctx.Err()
+			return nil, ctx.Err()
 		}
-		return false, err
+		return nil, err
 	}
 
 	// 获取 phase 字段
 	phase, found, err := unstructured.NestedString(dv.Object, "status", "phase")
 	if err != nil {
 		logger.Error(err, "failed to get DataVolume phase", "name", name)
-		return false, err
+		return nil, err
 	}
 	if !found {
 		// phase 字段不存在，可能还在初始化
 		logger.V(1).Info("DataVolume phase not found, still initializing", "name", name)
-		return false, nil
+		return &DataVolumeState{}, nil
+	}
+
+	state := &DataVolumeState{
+		Phase:   phase,
+		Message: latestConditionMessage(dv),
+	}
+	state.Progress, _, _ = unstructured.NestedString(dv.Object, "status", "progress")
+	if restarts, found, _ := unstructured.NestedInt64(dv.Object, "status", "restartCount"); found {
+		state.RestartCount = int32(restarts)
 	}
 
-	logger.V(1).Info("DataVolume status", "name", name, "phase", phase)
+	logger.V(1).Info("DataVolume status", "name", name, "phase", phase, "progress", state.Progress, "restartCount", state.RestartCount)
 
 	if phase == "Succeeded" {
 		logger.Info("DataVolume is ready", "name", name)
 		// 检查对应的 PVC 是否已绑定（非阻塞检查）
 		pvcBound, err := CheckPVCBound(ctx, c, namespace, name)
-		return pvcBound, err
+		state.Ready = pvcBound
+		return state, err
 	}
 
 	if phase == "Failed" || phase == "Error" {
-		return false, fmt.Errorf("DataVolume %s/%s is in %s state", namespace, name, phase)
+		state.Failed = true
+		return state, nil
+	}
+
+	// populating Pod 反复崩溃时 CDI 保持 ImportInProgress 并累加 restartCount，不会进入 Failed
+	if state.RestartCount >= importerFailureRestarts {
+		if reason, message, failing := importerFailure(dv); failing {
+			logger.Info("DataVolume populating pod keeps failing", "name", name, "restartCount", state.RestartCount, "reason", reason)
+			state.Failed = true
+			if message != "" {
+				state.Message = message
+			}
+			return state, nil
+		}
 	}
 
 	// 检查是否是 WaitForFirstConsumer 模式
@@ -212,20 +261,73 @@ func CheckDataVolumeStatus(ctx context.Context, c client.Client, namespace, name
 					if sc.VolumeBindingMode != nil && *sc.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer {
 						// WaitForFirstConsumer 模式：DataVolume/PVC 会在第一个 Pod 创建时绑定
 						logger.V(1).Info("DataVolume is in WaitForFirstConsumer mode, will bind when VM Pod is created", "name", name)
-						// 返回 true，允许继续创建 VM
-						return true, nil
+						// 允许继续创建 VM
+						state.Ready = true
+						return state, nil
 					}
 				}
 			}
 		}
 		// 如果不是 WaitForFirstConsumer 模式，继续等待
 		logger.V(1).Info("DataVolume is in WaitForFirstConsumer phase but not WaitForFirstConsumer mode, waiting", "name", name)
-		return false, nil
+		return state, nil
 	}
 
 	// 其他状态（Pending, ImportScheduled, ImportInProgress 等），还在进行中
 	logger.V(1).Info("DataVolume is still in progress", "name", name, "phase", phase)
-	return false, nil
+	return state, nil
+}
+
+// importerFailure returns the reason and message of the Running condition of a DataVolume
+// whose populating pod is not running because it failed (e.g., Error or CrashLoopBackOff).
+func importerFailure(dv *unstructured.Unstructured) (string, string, bool) {
+	conditions, _, _ := unstructured.NestedSlice(dv.Object, "status", "conditions")
+	for _, item := range conditions {
+		cond, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if condType, _, _ := unstructured.NestedString(cond, "type"); condType != "Running" {
+			continue
+		}
+		status, _, _ := unstructured.NestedString(cond, "status")
+		reason, _, _ := unstructured.NestedString(cond, "reason")
+		message, _, _ := unstructured.NestedString(cond, "message")
+		// Pod 正常运行、已完成或仍在调度时不是失败
+		switch {
+		case status != "False", reason == "", reason == "Completed", reason == "Pending":
+			return reason, message, false
+		}
+		return reason, message, true
+	}
+	return "", "", false
+}
+
+// latestConditionMessage returns the message of the most recently transitioned DataVolume condition.
+func latestConditionMessage(dv *unstructured.Unstructured) string {
+	conditions, found, err := unstructured.NestedSlice(dv.Object, "status", "conditions")
+	if err != nil || !found {
+		return ""
+	}
+
+	var latestMessage, latestTime string
+	for _, item := range conditions {
+		cond, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		message, _, _ := unstructured.NestedString(cond, "message")
+		if message == "" {
+			continue
+		}
+		// lastTransitionTime 为 RFC3339 格式，可以直接按字符串比较
+		transitionTime, _, _ := unstructured.NestedString(cond, "lastTransitionTime")
+		if latestMessage == "" || transitionTime > latestTime {
+			latestMessage = message
+			latestTime = transitionTime
+		}
+	}
+	return latestMessage
 }
 
 // DeleteDataVolume deletes a DataVolume.
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+const (
+	// AnnotationDefaultStorageClass is the namespace annotation holding the default StorageClass of its Wukongs
+	AnnotationDefaultStorageClass = "wukong.novasphere.dev/default-storage-class"
+
+	// annotationIsDefaultClass marks the cluster default StorageClass
+	annotationIsDefaultClass = "storageclass.kubernetes.io/is-default-class"
+	// annotationBetaIsDefaultClass is the deprecated beta form of annotationIsDefaultClass
+	annotationBetaIsDefaultClass = "storageclass.beta.kubernetes.io/is-default-class"
+)
+
+// GetWukongDefaults returns the WukongDefaults of a namespace.
+// If several exist, the one named "default" is used, otherwise the first by name.
+// It returns nil if there is none or the WukongDefaults CRD is not installed.
+func GetWukongDefaults(ctx context.Context, c client.Client, namespace string) (*vmv1alpha1.WukongDefaults, error) {
+	list := &vmv1alpha1.WukongDefaultsList{}
+	if err := c.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(list.Items) == 0 {
+		return nil, nil
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		if list.Items[i].Name == "default" || list.Items[j].Name == "default" {
+			return list.Items[i].Name == "default"
+		}
+		return list.Items[i].Name < list.Items[j].Name
+	})
+	return &list.Items[0], nil
+}
+
+// DefaultStorageClass returns the name of the cluster default StorageClass, or an empty string if none is marked default.
+func DefaultStorageClass(ctx context.Context, c client.Client) (string, error) {
+	scList := &storagev1.StorageClassList{}
+	if err := c.List(ctx, scList); err != nil {
+		return "", err
+	}
+	for _, sc := range scList.Items {
+		if sc.Annotations[annotationIsDefaultClass] == "true" || sc.Annotations[annotationBetaIsDefaultClass] == "true" {
+			return sc.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// ResolveStorageClass returns the StorageClass of a PVC-backed disk that does not specify one.
+// The WukongDefaults of the namespace takes precedence, then the namespace annotation,
+// then the cluster default StorageClass.
+func ResolveStorageClass(ctx context.Context, c client.Client, namespace string) (string, error) {
+	defaults, err := GetWukongDefaults(ctx, c, namespace)
+	if err != nil {
+		return "", err
+	}
+	if defaults != nil && defaults.Spec.StorageClassName != "" {
+		return defaults.Spec.StorageClassName, nil
+	}
+
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return "", err
+		}
+	} else if name := ns.Annotations[AnnotationDefaultStorageClass]; name != "" {
+		return name, nil
+	}
+
+	name, err := DefaultStorageClass(ctx, c)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", fmt.Errorf("no StorageClass specified and no default StorageClass found for namespace %s", namespace)
+	}
+	return name, nil
+}
+
+// defaultDiskStorageClass fills in the StorageClass of a PVC-backed disk that does not specify one.
+// A class resolved in a previous reconcile is kept so that changing the defaults does not affect existing disks.
+func defaultDiskStorageClass(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, disk *vmv1alpha1.DiskConfig) error {
+	if disk.StorageClassName != "" || disk.ExistingClaim != "" || disk.ContainerDisk != nil || disk.EmptyDisk != nil {
+		return nil
+	}
+	if prev := findVolumeStatus(vmp.Status.Volumes, disk.Name); prev != nil && prev.StorageClassName != "" {
+		disk.StorageClassName = prev.StorageClassName
+		return nil
+	}
+	// 需要 StorageClass 加密的磁盘优先使用 namespace 默认的加密 StorageClass
+	if disk.Encryption != nil && disk.Encryption.Mode == vmv1alpha1.EncryptionModeStorageClass {
+		defaults, err := GetWukongDefaults(ctx, c, vmp.Namespace)
+		if err != nil {
+			return err
+		}
+		if defaults != nil && defaults.Spec.EncryptedStorageClassName != "" {
+			disk.StorageClassName = defaults.Spec.EncryptedStorageClassName
+			return nil
+		}
+	}
+	name, err := ResolveStorageClass(ctx, c, vmp.Namespace)
+	if err != nil {
+		return fmt.Errorf("disk %s: %w", disk.Name, err)
+	}
+	disk.StorageClassName = name
+	return nil
+}
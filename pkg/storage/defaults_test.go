@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+// newWukongDefaults 构造 vms namespace 中指定默认 StorageClass 的 WukongDefaults
+func newWukongDefaults(name, storageClassName string) *vmv1alpha1.WukongDefaults {
+	return &vmv1alpha1.WukongDefaults{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: name},
+		Spec:       vmv1alpha1.WukongDefaultsSpec{StorageClassName: storageClassName},
+	}
+}
+
+func TestGetWukongDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		objs []client.Object
+		want string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name: "default wins",
+			objs: []client.Object{newWukongDefaults("a", "slow"), newWukongDefaults("default", "fast"), newWukongDefaults("z", "slow")},
+			want: "default",
+		},
+		{
+			name: "first by name",
+			objs: []client.Object{newWukongDefaults("team-b", "slow"), newWukongDefaults("team-a", "fast")},
+			want: "team-a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 其他 namespace 的 WukongDefaults 不生效
+			other := newWukongDefaults("default", "other")
+			other.Namespace = "other"
+			c := newFakeClient(t, false, append(tt.objs, other)...)
+
+			defaults, err := GetWukongDefaults(context.Background(), c, "vms")
+			if err != nil {
+				t.Fatalf("GetWukongDefaults() error = %v", err)
+			}
+			got := ""
+			if defaults != nil {
+				got = defaults.Name
+			}
+			if got != tt.want {
+				t.Errorf("GetWukongDefaults() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveStorageClass(t *testing.T) {
+	annotatedNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "vms",
+		Annotations: map[string]string{AnnotationDefaultStorageClass: "namespace-default"},
+	}}
+	plainNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "vms"}}
+	clusterDefault := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "cluster-default", Annotations: map[string]string{annotationIsDefaultClass: "true"}},
+		Provisioner: "csi.example.com",
+	}
+	betaDefault := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "beta-default", Annotations: map[string]string{annotationBetaIsDefaultClass: "true"}},
+		Provisioner: "csi.example.com",
+	}
+	otherClass := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "other"}, Provisioner: "csi.example.com"}
+
+	tests := []struct {
+		name    string
+		objs    []client.Object
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "WukongDefaults first",
+			objs: []client.Object{newWukongDefaults("default", "wukong-default"), annotatedNamespace, clusterDefault},
+			want: "wukong-default",
+		},
+		{
+			name: "WukongDefaults without StorageClass",
+			objs: []client.Object{newWukongDefaults("default", ""), annotatedNamespace, clusterDefault},
+			want: "namespace-default",
+		},
+		{
+			name: "then the namespace annotation",
+			objs: []client.Object{annotatedNamespace, clusterDefault},
+			want: "namespace-default",
+		},
+		{
+			name: "then the cluster default",
+			objs: []client.Object{plainNamespace, otherClass, clusterDefault},
+			want: "cluster-default",
+		},
+		{
+			name: "beta default annotation",
+			objs: []client.Object{otherClass, betaDefault},
+			want: "beta-default",
+		},
+		{
+			name:    "no default",
+			objs:    []client.Object{plainNamespace, otherClass},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeClient(t, false, tt.objs...)
+			got, err := ResolveStorageClass(context.Background(), c, "vms")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveStorageClass() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveStorageClass() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultDiskStorageClass(t *testing.T) {
+	defaults := newWukongDefaults("default", "wukong-default")
+	defaults.Spec.EncryptedStorageClassName = "encrypted"
+	c := newFakeClient(t, false, defaults)
+	vmp := &vmv1alpha1.Wukong{ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "vm1"}}
+	vmp.Status.Volumes = []vmv1alpha1.VolumeStatus{{Name: "system", StorageClassName: "previous"}}
+
+	tests := []struct {
+		name string
+		disk vmv1alpha1.DiskConfig
+		want string
+	}{
+		{
+			name: "explicit StorageClass",
+			disk: vmv1alpha1.DiskConfig{Name: "data", StorageClassName: "fast"},
+			want: "fast",
+		},
+		{
+			name: "resolved in a previous reconcile",
+			disk: vmv1alpha1.DiskConfig{Name: "system"},
+			want: "previous",
+		},
+		{
+			name: "existing claim",
+			disk: vmv1alpha1.DiskConfig{Name: "data", ExistingClaim: "data"},
+		},
+		{
+			name: "encrypted disk",
+			disk: vmv1alpha1.DiskConfig{Name: "data", Encryption: &vmv1alpha1.DiskEncryptionSpec{Mode: vmv1alpha1.EncryptionModeStorageClass}},
+			want: "encrypted",
+		},
+		{
+			name: "namespace defaults",
+			disk: vmv1alpha1.DiskConfig{Name: "data"},
+			want: "wukong-default",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			disk := tt.disk
+			if err := defaultDiskStorageClass(context.Background(), c, vmp, &disk); err != nil {
+				t.Fatalf("defaultDiskStorageClass() error = %v", err)
+			}
+			if disk.StorageClassName != tt.want {
+				t.Errorf("StorageClassName = %q, want %q", disk.StorageClassName, tt.want)
+			}
+		})
+	}
+}
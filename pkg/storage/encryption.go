@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+	"github.com/kuihuar/novasphere/pkg/naming"
+)
+
+const (
+	// EncryptionKeyPassphrase is the Secret key holding the passphrase of an encrypted disk
+	EncryptionKeyPassphrase = "passphrase"
+
+	// LabelEncryptionKey marks key Secrets generated by the operator
+	LabelEncryptionKey = "wukong.novasphere.dev/encryption-key"
+
+	// encryptionKeyBytes is the length of generated passphrases before base64 encoding
+	encryptionKeyBytes = 32
+)
+
+var (
+	// ErrEncryptionKeyMissing is returned when the key Secret of an encrypted disk whose PVC already exists is missing.
+	// A new key must never be generated for such a disk, since its data could not be decrypted anymore.
+	ErrEncryptionKeyMissing = errors.New("encryption key is missing")
+	// ErrEncryptionKeyReleased is returned when the key Secret of a disk was released to the PersistentVolume
+	// of a deleted PVC with the same name. The Secret is garbage-collected with that PV, so it must not be
+	// used for a new disk; a new key is generated once the PV and its Secret are gone.
+	ErrEncryptionKeyReleased = errors.New("encryption key is released to a deleted PVC")
+)
+
+// EncryptionKeySecretName returns the name of the per-PVC key Secret of an encrypted disk.
+func EncryptionKeySecretName(pvcName string) string {
+	return pvcName + "-key"
+}
+
+// EnsureEncryptionKey makes sure the key of an encrypted disk is available and returns the name of its Secret.
+// When the disk does not reference a Secret, a unique passphrase is generated into the Secret "<pvc>-key",
+// unless the PVC already exists. In StorageClass mode, a referenced Secret is copied to "<pvc>-key"
+// so that the CSI driver can find it through the per-PVC secret templates of the StorageClass.
+// It returns ErrEncryptionKeyMissing if the key cannot be found, and ErrEncryptionKeyReleased if
+// "<pvc>-key" still belongs to the PersistentVolume of a deleted PVC with the same name.
+func EnsureEncryptionKey(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, disk vmv1alpha1.DiskConfig, pvcName string) (string, error) {
+	logger := log.FromContext(ctx)
+	keySecretName := EncryptionKeySecretName(pvcName)
+
+	// 1. 用户指定了 Secret：Guest 模式直接使用
+	var passphrase []byte
+	if disk.Encryption.SecretName != "" {
+		var err error
+		passphrase, err = getPassphrase(ctx, c, vmp.Namespace, disk.Encryption.SecretName)
+		if err != nil {
+			return "", err
+		}
+		if disk.Encryption.Mode == vmv1alpha1.EncryptionModeGuest {
+			return disk.Encryption.SecretName, nil
+		}
+	}
+
+	// 2. 同名磁盘被删除后重新添加时，旧密钥已交给旧 PV 并随 PV 回收，不能用于新磁盘
+	if err := checkKeyReleased(ctx, c, vmp.Namespace, keySecretName); err != nil {
+		return "", err
+	}
+
+	// 3. StorageClass 模式将用户指定的 Secret 复制为 per-PVC Secret
+	if passphrase != nil {
+		return keySecretName, ensureKeySecret(ctx, c, vmp, disk, keySecretName, passphrase)
+	}
+
+	// 4. 已生成过的密钥
+	_, err := getPassphrase(ctx, c, vmp.Namespace, keySecretName)
+	if err == nil {
+		return keySecretName, nil
+	}
+	if !errors.Is(err, ErrEncryptionKeyMissing) {
+		return "", err
+	}
+
+	// 5. PVC 已存在但密钥丢失：不能重新生成，否则数据无法解密
+	exists, err := pvcExists(ctx, c, vmp.Namespace, pvcName)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", fmt.Errorf("%w: Secret %s of disk %s not found", ErrEncryptionKeyMissing, keySecretName, disk.Name)
+	}
+
+	// 6. 新磁盘：生成唯一的密钥
+	buf := make([]byte, encryptionKeyBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	logger.Info("Generating encryption key", "disk", disk.Name, "secret", keySecretName)
+	return keySecretName, ensureKeySecret(ctx, c, vmp, disk, keySecretName, []byte(base64.StdEncoding.EncodeToString(buf)))
+}
+
+// CheckEncryptedStorageClass returns an error if the StorageClass of a disk with StorageClass encryption is not encrypted.
+func CheckEncryptedStorageClass(ctx context.Context, c client.Client, disk vmv1alpha1.DiskConfig) error {
+	sc := &storagev1.StorageClass{}
+	if err := c.Get(ctx, client.ObjectKey{Name: disk.StorageClassName}, sc); err != nil {
+		return err
+	}
+	if sc.Parameters["encrypted"] != "true" {
+		return fmt.Errorf("disk %s: StorageClass %s is not encrypted (parameter encrypted must be \"true\")", disk.Name, disk.StorageClassName)
+	}
+	return nil
+}
+
+// DeleteEncryptionKey deletes the key Secret generated for a PVC, if any.
+// Secrets referenced by DiskEncryptionSpec.SecretName are not deleted.
+func DeleteEncryptionKey(ctx context.Context, c client.Client, namespace, pvcName string) error {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: EncryptionKeySecretName(pvcName)}, secret); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if secret.Labels[LabelEncryptionKey] != "true" {
+		return nil
+	}
+	return client.IgnoreNotFound(c.Delete(ctx, secret))
+}
+
+// ReleaseEncryptionKey releases the key Secret generated for a deleted PVC.
+// The CSI driver may still need the key to delete the bound PersistentVolume, so the Secret is
+// made a dependent of the PV and garbage-collected once the PV is gone.
+// Without a bound PV, or once the PV is gone, the Secret is deleted immediately.
+// pvName may be empty if the PVC is already gone; the PV is then looked up by its claim reference.
+func ReleaseEncryptionKey(ctx context.Context, c client.Client, namespace, pvcName, pvName string) error {
+	pv, err := claimedPV(ctx, c, namespace, pvcName, pvName)
+	if err != nil {
+		return err
+	}
+	if pv == nil {
+		return DeleteEncryptionKey(ctx, c, namespace, pvcName)
+	}
+
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: EncryptionKeySecretName(pvcName)}, secret); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if secret.Labels[LabelEncryptionKey] != "true" {
+		return nil
+	}
+	for _, ref := range secret.OwnerReferences {
+		if ref.UID == pv.UID {
+			return nil
+		}
+	}
+	secret.OwnerReferences = append(secret.OwnerReferences, metav1.OwnerReference{
+		APIVersion: "v1",
+		Kind:       "PersistentVolume",
+		Name:       pv.Name,
+		UID:        pv.UID,
+	})
+	return c.Update(ctx, secret)
+}
+
+// claimedPV 返回绑定到 PVC 的 PV，不存在时返回 nil
+// pvName 为空时按 claimRef 查找（PVC 已删除时无法读取 spec.volumeName）
+func claimedPV(ctx context.Context, c client.Client, namespace, pvcName, pvName string) (*corev1.PersistentVolume, error) {
+	if pvName != "" {
+		pv := &corev1.PersistentVolume{}
+		if err := c.Get(ctx, client.ObjectKey{Name: pvName}, pv); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return pv, nil
+	}
+
+	pvs := &corev1.PersistentVolumeList{}
+	if err := c.List(ctx, pvs); err != nil {
+		return nil, err
+	}
+	for i := range pvs.Items {
+		ref := pvs.Items[i].Spec.ClaimRef
+		if ref != nil && ref.Namespace == namespace && ref.Name == pvcName {
+			return &pvs.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// checkKeyReleased returns ErrEncryptionKeyReleased if the key Secret is owned by a PersistentVolume,
+// i.e. it was handed over to the PV of a deleted PVC by ReleaseEncryptionKey.
+func checkKeyReleased(ctx context.Context, c client.Client, namespace, name string) error {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, secret); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	for _, ref := range secret.OwnerReferences {
+		if ref.Kind == "PersistentVolume" {
+			return fmt.Errorf("%w: Secret %s is kept until PersistentVolume %s is deleted", ErrEncryptionKeyReleased, name, ref.Name)
+		}
+	}
+	return nil
+}
+
+// getPassphrase returns the passphrase stored in a key Secret, or ErrEncryptionKeyMissing.
+func getPassphrase(ctx context.Context, c client.Client, namespace, name string) ([]byte, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: Secret %s not found", ErrEncryptionKeyMissing, name)
+		}
+		return nil, err
+	}
+	passphrase := secret.Data[EncryptionKeyPassphrase]
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("%w: Secret %s has no %q key", ErrEncryptionKeyMissing, name, EncryptionKeyPassphrase)
+	}
+	return passphrase, nil
+}
+
+// ensureKeySecret creates the per-PVC key Secret with the given passphrase if it does not exist.
+// Besides the passphrase, the keys read by the Longhorn and Ceph CSI drivers are set.
+func ensureKeySecret(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, disk vmv1alpha1.DiskConfig, name string, passphrase []byte) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: vmp.Namespace,
+			Labels: map[string]string{
+				LabelWukong:        naming.LabelValue(vmp.Name),
+				LabelDisk:          naming.LabelValue(disk.Name),
+				LabelEncryptionKey: "true",
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			EncryptionKeyPassphrase: passphrase,
+			// Longhorn
+			"CRYPTO_KEY_VALUE":    passphrase,
+			"CRYPTO_KEY_PROVIDER": []byte("secret"),
+			// Ceph CSI (metadata KMS)
+			"encryptionPassphrase": passphrase,
+		},
+	}
+	if err := c.Create(ctx, secret); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+	"github.com/kuihuar/novasphere/pkg/naming"
+)
+
+// newKeySecret 构造 operator 为 PVC 生成的密钥 Secret
+func newKeySecret(pvcName string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "vms",
+			Name:      EncryptionKeySecretName(pvcName),
+			Labels:    map[string]string{LabelEncryptionKey: "true"},
+		},
+	}
+}
+
+// newPV 构造绑定到 vms/<pvcName> 的 PV
+func newPV(name, pvcName string) *corev1.PersistentVolume {
+	return &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name + "-uid")},
+		Spec: corev1.PersistentVolumeSpec{
+			ClaimRef: &corev1.ObjectReference{Namespace: "vms", Name: pvcName},
+		},
+	}
+}
+
+func TestReleaseEncryptionKey(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClient(t, false,
+		newKeySecret("vm-unbound"),
+		newKeySecret("vm-bound"), newPV("pv-bound", "vm-bound"),
+		newKeySecret("vm-gone"), newPV("pv-gone", "vm-gone"),
+		newKeySecret("vm-deleted-pv"),
+	)
+
+	tests := []struct {
+		pvcName   string
+		pvName    string
+		wantOwner string
+	}{
+		// 未绑定 PV：直接删除
+		{pvcName: "vm-unbound"},
+		// PV 仍存在：交给 PV 回收
+		{pvcName: "vm-bound", pvName: "pv-bound", wantOwner: "pv-bound"},
+		// PVC 已删除：按 claimRef 查找 PV
+		{pvcName: "vm-gone", wantOwner: "pv-gone"},
+		// PV 已删除：直接删除
+		{pvcName: "vm-deleted-pv", pvName: "pv-deleted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pvcName, func(t *testing.T) {
+			if err := ReleaseEncryptionKey(ctx, c, "vms", tt.pvcName, tt.pvName); err != nil {
+				t.Fatalf("ReleaseEncryptionKey() error = %v", err)
+			}
+			secret := &corev1.Secret{}
+			err := c.Get(ctx, client.ObjectKey{Namespace: "vms", Name: EncryptionKeySecretName(tt.pvcName)}, secret)
+			if tt.wantOwner == "" {
+				if !errors.IsNotFound(err) {
+					t.Errorf("key Secret still exists (err = %v)", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(secret.OwnerReferences) != 1 || secret.OwnerReferences[0].Kind != "PersistentVolume" || secret.OwnerReferences[0].Name != tt.wantOwner {
+				t.Errorf("key Secret owners = %+v, want PV %s", secret.OwnerReferences, tt.wantOwner)
+			}
+		})
+	}
+
+	// 再次释放不会重复添加 OwnerReference
+	if err := ReleaseEncryptionKey(ctx, c, "vms", "vm-bound", "pv-bound"); err != nil {
+		t.Fatal(err)
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "vms", Name: EncryptionKeySecretName("vm-bound")}, secret); err != nil {
+		t.Fatal(err)
+	}
+	if len(secret.OwnerReferences) != 1 {
+		t.Errorf("key Secret owners = %+v, want one", secret.OwnerReferences)
+	}
+}
+
+func TestEnsureEncryptionKeyLabels(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClient(t, false)
+	vmp := &vmv1alpha1.Wukong{ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: strings.Repeat("w", 70)}}
+	disk := vmv1alpha1.DiskConfig{Name: "data", Encryption: &vmv1alpha1.DiskEncryptionSpec{Mode: vmv1alpha1.EncryptionModeGuest}}
+
+	name, err := EnsureEncryptionKey(ctx, c, vmp, disk, "vm-data")
+	if err != nil {
+		t.Fatalf("EnsureEncryptionKey() error = %v", err)
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "vms", Name: name}, secret); err != nil {
+		t.Fatal(err)
+	}
+	if got := secret.Labels[LabelWukong]; got != naming.LabelValue(vmp.Name) || len(got) > naming.MaxLength {
+		t.Errorf("%s label = %q, want a valid label value", LabelWukong, got)
+	}
+	if len(secret.Data[EncryptionKeyPassphrase]) == 0 {
+		t.Error("generated key Secret has no passphrase")
+	}
+}
+
+func TestEnsureEncryptionKeyReleased(t *testing.T) {
+	ctx := context.Background()
+	vmp := &vmv1alpha1.Wukong{ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "vm"}}
+
+	// 磁盘删除后密钥交给了旧 PV，PV 删除前重新添加同名磁盘
+	released := newKeySecret("vm-data")
+	released.Data = map[string][]byte{EncryptionKeyPassphrase: []byte("old")}
+	released.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "PersistentVolume", Name: "pv-old", UID: types.UID("pv-old-uid")}}
+	user := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "user-key"},
+		Data:       map[string][]byte{EncryptionKeyPassphrase: []byte("user")},
+	}
+
+	tests := []struct {
+		name    string
+		spec    vmv1alpha1.DiskEncryptionSpec
+		wantErr bool
+	}{
+		{name: "generated key", spec: vmv1alpha1.DiskEncryptionSpec{Mode: vmv1alpha1.EncryptionModeGuest}, wantErr: true},
+		{name: "copied StorageClass key", spec: vmv1alpha1.DiskEncryptionSpec{Mode: vmv1alpha1.EncryptionModeStorageClass, SecretName: "user-key"}, wantErr: true},
+		// Guest 模式直接使用用户的 Secret，与 per-PVC Secret 无关
+		{name: "user guest key", spec: vmv1alpha1.DiskEncryptionSpec{Mode: vmv1alpha1.EncryptionModeGuest, SecretName: "user-key"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeClient(t, false, released.DeepCopy(), user.DeepCopy())
+			disk := vmv1alpha1.DiskConfig{Name: "data", Encryption: tt.spec.DeepCopy()}
+			_, err := EnsureEncryptionKey(ctx, c, vmp, disk, "vm-data")
+			if tt.wantErr != stderrors.Is(err, ErrEncryptionKeyReleased) {
+				t.Fatalf("EnsureEncryptionKey() error = %v, want %v: %t", err, ErrEncryptionKeyReleased, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("EnsureEncryptionKey() error = %v", err)
+			}
+		})
+	}
+
+}
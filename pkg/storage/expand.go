@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	corev1 "k8s.io/api/core/v1"
-	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -50,24 +49,16 @@ func ExpandPVC(ctx context.Context, c client.Client, pvcName, namespace, newSize
 		storageClassName = *pvc.Spec.StorageClassName
 	} else {
 		// 使用默认的 StorageClass
-		scList := &storagev1.StorageClassList{}
-		if err := c.List(ctx, scList); err == nil {
-			for _, sc := range scList.Items {
-				if sc.Annotations["storageclass.kubernetes.io/is-default-class"] == "true" {
-					storageClassName = sc.Name
-					break
-				}
-			}
-		}
+		storageClassName, _ = DefaultStorageClass(ctx, c)
 	}
 
 	if storageClassName != "" {
-		sc := &storagev1.StorageClass{}
-		scKey := client.ObjectKey{Name: storageClassName}
-		if err := c.Get(ctx, scKey, sc); err == nil {
-			if sc.AllowVolumeExpansion != nil && !*sc.AllowVolumeExpansion {
-				return false, fmt.Errorf("StorageClass %s does not allow volume expansion", storageClassName)
-			}
+		supported, err := ExpansionSupported(ctx, c, storageClassName)
+		if err != nil {
+			return false, err
+		}
+		if !supported {
+			return false, fmt.Errorf("StorageClass %s does not allow volume expansion", storageClassName)
 		}
 	}
 
@@ -89,79 +80,182 @@ func ExpandPVC(ctx context.Context, c client.Client, pvcName, namespace, newSize
 }
 
 // CheckPVCExpansionStatus checks if a PVC expansion is in progress or completed.
-// Returns true if expansion is complete, false if in progress, and any error.
+// Returns true if the PVC capacity has reached the requested size and no file system resize is pending.
 func CheckPVCExpansionStatus(ctx context.Context, c client.Client, pvcName, namespace string) (bool, error) {
-	logger := log.FromContext(ctx)
-
 	pvc := &corev1.PersistentVolumeClaim{}
 	key := client.ObjectKey{Namespace: namespace, Name: pvcName}
 	if err := c.Get(ctx, key, pvc); err != nil {
 		return false, err
 	}
 
-	// 检查 PVC 的 conditions
-	for _, condition := range pvc.Status.Conditions {
-		if condition.Type == corev1.PersistentVolumeClaimResizing {
-			logger.V(1).Info("PVC expansion in progress", "pvc", pvcName)
-			return false, nil
+	switch pvcExpansionState(pvc, vmv1alpha1.ExpansionStateResizing) {
+	case vmv1alpha1.ExpansionStateFailed:
+		return false, fmt.Errorf("expansion of PVC %s is infeasible", pvcName)
+	case vmv1alpha1.ExpansionStateResizing, vmv1alpha1.ExpansionStateFileSystemResizePending:
+		return false, nil
+	}
+	return true, nil
+}
+
+// ObservePVC fills the volume status from its PVC: bound PV, StorageClass, access modes, volume mode,
+// requested size, capacity and expansion state.
+// prevState is the expansion state reported by the previous reconcile.
+// It does nothing if the PVC does not exist yet.
+func ObservePVC(ctx context.Context, c client.Client, namespace, prevState string, volStatus *vmv1alpha1.VolumeStatus) error {
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: volStatus.PVCName}, pvc); err != nil {
+		if errors.IsNotFound(err) {
+			// DataVolume 可能还没有创建 PVC
+			return nil
+		}
+		return err
+	}
+
+	volStatus.PVName = pvc.Spec.VolumeName
+	if pvc.Spec.StorageClassName != nil {
+		volStatus.StorageClassName = *pvc.Spec.StorageClassName
+	}
+	if pvc.Spec.VolumeMode != nil {
+		volStatus.VolumeMode = string(*pvc.Spec.VolumeMode)
+	}
+	// 已绑定的 PVC 使用 PV 实际提供的 accessModes
+	accessModes := pvc.Status.AccessModes
+	if len(accessModes) == 0 {
+		accessModes = pvc.Spec.AccessModes
+	}
+	volStatus.AccessModes = make([]string, 0, len(accessModes))
+	for _, mode := range accessModes {
+		volStatus.AccessModes = append(volStatus.AccessModes, string(mode))
+	}
+
+	if requested, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+		volStatus.RequestedSize = requested.String()
+	}
+	if capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+		volStatus.Capacity = capacity.String()
+		volStatus.Size = volStatus.Capacity
+	}
+	volStatus.ExpansionState = pvcExpansionState(pvc, prevState)
+	return nil
+}
+
+// pvcExpansionState derives the expansion state of a PVC from its conditions and capacity.
+// Once the PVC has reached the requested size, an expansion in progress moves to GuestResizePending;
+// the controller completes it after the guest has seen the new disk size.
+func pvcExpansionState(pvc *corev1.PersistentVolumeClaim, prevState string) string {
+	for _, status := range pvc.Status.AllocatedResourceStatuses {
+		if status == corev1.PersistentVolumeClaimControllerResizeInfeasible || status == corev1.PersistentVolumeClaimNodeResizeInfeasible {
+			return vmv1alpha1.ExpansionStateFailed
 		}
 	}
 
-	// 检查是否有 FileSystemResizePending condition（表示需要文件系统扩展）
 	for _, condition := range pvc.Status.Conditions {
-		if condition.Type == corev1.PersistentVolumeClaimFileSystemResizePending {
-			logger.V(1).Info("PVC expansion complete, file system resize pending", "pvc", pvcName)
-			// 扩展已完成，但需要在 VM 内部扩展文件系统
-			return true, nil
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case corev1.PersistentVolumeClaimResizing, corev1.PersistentVolumeClaimControllerResizeError, corev1.PersistentVolumeClaimNodeResizeError:
+			return vmv1alpha1.ExpansionStateResizing
+		case corev1.PersistentVolumeClaimFileSystemResizePending:
+			// 块设备已扩展，需要挂载该 PVC 的 Pod（virt-launcher）完成文件系统扩展
+			return vmv1alpha1.ExpansionStateFileSystemResizePending
 		}
 	}
 
-	// 如果没有 Resizing 或 FileSystemResizePending condition，扩展已完成
-	logger.V(1).Info("PVC expansion appears complete", "pvc", pvcName)
-	return true, nil
+	requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]
+	if !ok || capacity.IsZero() {
+		// PVC 尚未绑定
+		return prevState
+	}
+	if capacity.Cmp(requested) < 0 {
+		return vmv1alpha1.ExpansionStateResizing
+	}
+
+	switch prevState {
+	case vmv1alpha1.ExpansionStateResizing, vmv1alpha1.ExpansionStateFileSystemResizePending, vmv1alpha1.ExpansionStateFailed:
+		return vmv1alpha1.ExpansionStateGuestResizePending
+	}
+	return prevState
+}
+
+// CompleteGuestResize marks GuestResizePending volumes Completed once the running VMI reports a
+// capacity of at least the requested size, i.e. the guest has booted with or seen the new disk size.
+// capacities are the VMI volume capacities; nil means the VM is not running, and pending volumes
+// stay pending until the guest has run.
+func CompleteGuestResize(volumes []vmv1alpha1.VolumeStatus, capacities map[string]resource.Quantity) error {
+	if capacities == nil {
+		return nil
+	}
+	for i := range volumes {
+		vol := &volumes[i]
+		if vol.ExpansionState != vmv1alpha1.ExpansionStateGuestResizePending {
+			continue
+		}
+		requested, err := resource.ParseQuantity(vol.RequestedSize)
+		if err != nil {
+			return err
+		}
+		if capacity, ok := capacities[vol.Name]; ok && capacity.Cmp(requested) >= 0 {
+			vol.ExpansionState = vmv1alpha1.ExpansionStateCompleted
+		}
+	}
+	return nil
+}
+
+// ExpansionInProgress reports whether a volume expansion has not completed yet.
+func ExpansionInProgress(vol vmv1alpha1.VolumeStatus) bool {
+	switch vol.ExpansionState {
+	case vmv1alpha1.ExpansionStateResizing, vmv1alpha1.ExpansionStateFileSystemResizePending, vmv1alpha1.ExpansionStateGuestResizePending:
+		return true
+	}
+	return false
 }
 
 // ReconcileDiskExpansion reconciles disk size changes for a Wukong.
-// It checks if any disk size has changed and attempts to expand the corresponding PVC.
+// It expands the PVC of every disk whose size is larger than the size requested by its PVC.
 func ReconcileDiskExpansion(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, volumesStatus []vmv1alpha1.VolumeStatus) error {
 	logger := log.FromContext(ctx)
 
 	// 遍历所有磁盘配置
 	for _, disk := range vmp.Spec.Disks {
 		// 查找对应的 VolumeStatus
-		var currentVolumeStatus *vmv1alpha1.VolumeStatus
-		for i := range volumesStatus {
-			if volumesStatus[i].Name == disk.Name {
-				currentVolumeStatus = &volumesStatus[i]
-				break
-			}
-		}
-
-		if currentVolumeStatus == nil {
+		currentVolumeStatus := findVolumeStatus(volumesStatus, disk.Name)
+		if currentVolumeStatus == nil || currentVolumeStatus.RequestedSize == "" {
 			// 磁盘还没有创建，跳过
 			continue
 		}
+		// 未指定 size 时使用镜像默认大小，不做扩展；已有 PVC 不由 Wukong 扩容
+		if disk.Size == "" || disk.ExistingClaim != "" {
+			continue
+		}
 
-		// 比较大小
-		currentSize := currentVolumeStatus.Size
-		newSize := disk.Size
+		newQuantity, err := resource.ParseQuantity(disk.Size)
+		if err != nil {
+			return fmt.Errorf("invalid storage size %s: %w", disk.Size, err)
+		}
+		requestedQuantity, err := resource.ParseQuantity(currentVolumeStatus.RequestedSize)
+		if err != nil {
+			return err
+		}
+		if newQuantity.Cmp(requestedQuantity) <= 0 {
+			continue
+		}
 
-		if currentSize != newSize {
-			logger.Info("Disk size changed, attempting expansion",
-				"disk", disk.Name, "current", currentSize, "new", newSize)
+		logger.Info("Disk size changed, attempting expansion",
+			"disk", disk.Name, "current", currentVolumeStatus.RequestedSize, "new", disk.Size)
 
-			// 尝试扩展 PVC
-			expanded, err := ExpandPVC(ctx, c, currentVolumeStatus.PVCName, vmp.Namespace, newSize)
-			if err != nil {
-				logger.Error(err, "failed to expand PVC", "disk", disk.Name, "pvc", currentVolumeStatus.PVCName)
-				return err
-			}
+		// 尝试扩展 PVC
+		expanded, err := ExpandPVC(ctx, c, currentVolumeStatus.PVCName, vmp.Namespace, disk.Size)
+		if err != nil {
+			logger.Error(err, "failed to expand PVC", "disk", disk.Name, "pvc", currentVolumeStatus.PVCName)
+			return err
+		}
 
-			if expanded {
-				logger.Info("PVC expansion initiated", "disk", disk.Name, "pvc", currentVolumeStatus.PVCName)
-				// 更新 VolumeStatus 中的大小（会在 controller 中同步）
-				currentVolumeStatus.Size = newSize
-			}
+		if expanded {
+			logger.Info("PVC expansion initiated", "disk", disk.Name, "pvc", currentVolumeStatus.PVCName)
+			currentVolumeStatus.RequestedSize = newQuantity.String()
+			currentVolumeStatus.ExpansionState = vmv1alpha1.ExpansionStateResizing
 		}
 	}
 
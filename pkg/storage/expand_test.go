@@ -0,0 +1,241 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+// newPVC 构造请求 requested、容量为 capacity 的已绑定 PVC（capacity 为空表示未绑定）
+func newPVC(name, requested, capacity string, conditions ...corev1.PersistentVolumeClaimConditionType) *corev1.PersistentVolumeClaim {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: name},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(requested)},
+			},
+		},
+	}
+	if capacity != "" {
+		pvc.Status.Phase = corev1.ClaimBound
+		pvc.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(capacity)}
+	}
+	for _, condition := range conditions {
+		pvc.Status.Conditions = append(pvc.Status.Conditions, corev1.PersistentVolumeClaimCondition{
+			Type:   condition,
+			Status: corev1.ConditionTrue,
+		})
+	}
+	return pvc
+}
+
+// infeasiblePVC 将 PVC 标记为无法扩容
+func infeasiblePVC(pvc *corev1.PersistentVolumeClaim) *corev1.PersistentVolumeClaim {
+	pvc.Status.AllocatedResourceStatuses = map[corev1.ResourceName]corev1.ClaimResourceStatus{
+		corev1.ResourceStorage: corev1.PersistentVolumeClaimControllerResizeInfeasible,
+	}
+	return pvc
+}
+
+func TestPVCExpansionState(t *testing.T) {
+	tests := []struct {
+		name      string
+		pvc       *corev1.PersistentVolumeClaim
+		prevState string
+		want      string
+	}{
+		{
+			name: "not bound keeps previous state",
+			pvc:  newPVC("data", "20Gi", ""),
+			want: "",
+		},
+		{
+			name: "no expansion",
+			pvc:  newPVC("data", "20Gi", "20Gi"),
+			want: "",
+		},
+		{
+			name:      "capacity below request",
+			pvc:       newPVC("data", "40Gi", "20Gi"),
+			prevState: vmv1alpha1.ExpansionStateResizing,
+			want:      vmv1alpha1.ExpansionStateResizing,
+		},
+		{
+			name: "resizing condition",
+			pvc:  newPVC("data", "40Gi", "20Gi", corev1.PersistentVolumeClaimResizing),
+			want: vmv1alpha1.ExpansionStateResizing,
+		},
+		{
+			name: "file system resize pending",
+			pvc:  newPVC("data", "40Gi", "40Gi", corev1.PersistentVolumeClaimFileSystemResizePending),
+			want: vmv1alpha1.ExpansionStateFileSystemResizePending,
+		},
+		{
+			name:      "resized moves to guest resize",
+			pvc:       newPVC("data", "40Gi", "40Gi"),
+			prevState: vmv1alpha1.ExpansionStateResizing,
+			want:      vmv1alpha1.ExpansionStateGuestResizePending,
+		},
+		{
+			name:      "file system resized moves to guest resize",
+			pvc:       newPVC("data", "40Gi", "40Gi"),
+			prevState: vmv1alpha1.ExpansionStateFileSystemResizePending,
+			want:      vmv1alpha1.ExpansionStateGuestResizePending,
+		},
+		{
+			name:      "guest resize stays pending",
+			pvc:       newPVC("data", "40Gi", "40Gi"),
+			prevState: vmv1alpha1.ExpansionStateGuestResizePending,
+			want:      vmv1alpha1.ExpansionStateGuestResizePending,
+		},
+		{
+			name:      "completed stays completed",
+			pvc:       newPVC("data", "40Gi", "40Gi"),
+			prevState: vmv1alpha1.ExpansionStateCompleted,
+			want:      vmv1alpha1.ExpansionStateCompleted,
+		},
+		{
+			name:      "infeasible",
+			pvc:       infeasiblePVC(newPVC("data", "40Gi", "20Gi")),
+			prevState: vmv1alpha1.ExpansionStateResizing,
+			want:      vmv1alpha1.ExpansionStateFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pvcExpansionState(tt.pvc, tt.prevState); got != tt.want {
+				t.Errorf("pvcExpansionState() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompleteGuestResize(t *testing.T) {
+	pending := func() []vmv1alpha1.VolumeStatus {
+		return []vmv1alpha1.VolumeStatus{
+			{Name: "data", RequestedSize: "40Gi", ExpansionState: vmv1alpha1.ExpansionStateGuestResizePending},
+			{Name: "logs", RequestedSize: "10Gi", ExpansionState: vmv1alpha1.ExpansionStateResizing},
+		}
+	}
+
+	// VM 未运行：保持 GuestResizePending，直到 guest 以新的大小启动
+	volumes := pending()
+	if err := CompleteGuestResize(volumes, nil); err != nil {
+		t.Fatal(err)
+	}
+	if volumes[0].ExpansionState != vmv1alpha1.ExpansionStateGuestResizePending {
+		t.Errorf("stopped VM: state = %q, want GuestResizePending", volumes[0].ExpansionState)
+	}
+
+	// VMI 尚未报告新的容量
+	volumes = pending()
+	if err := CompleteGuestResize(volumes, map[string]resource.Quantity{"data": resource.MustParse("20Gi")}); err != nil {
+		t.Fatal(err)
+	}
+	if volumes[0].ExpansionState != vmv1alpha1.ExpansionStateGuestResizePending {
+		t.Errorf("old capacity: state = %q, want GuestResizePending", volumes[0].ExpansionState)
+	}
+
+	// VMI 报告了新的容量
+	volumes = pending()
+	if err := CompleteGuestResize(volumes, map[string]resource.Quantity{"data": resource.MustParse("40Gi")}); err != nil {
+		t.Fatal(err)
+	}
+	if volumes[0].ExpansionState != vmv1alpha1.ExpansionStateCompleted {
+		t.Errorf("new capacity: state = %q, want Completed", volumes[0].ExpansionState)
+	}
+	if volumes[1].ExpansionState != vmv1alpha1.ExpansionStateResizing {
+		t.Errorf("other volume: state = %q, want Resizing", volumes[1].ExpansionState)
+	}
+}
+
+func TestExpansionInProgress(t *testing.T) {
+	for state, want := range map[string]bool{
+		"":                                false,
+		vmv1alpha1.ExpansionStateResizing: true,
+		vmv1alpha1.ExpansionStateFileSystemResizePending: true,
+		vmv1alpha1.ExpansionStateGuestResizePending:      true,
+		vmv1alpha1.ExpansionStateCompleted:               false,
+		vmv1alpha1.ExpansionStateFailed:                  false,
+	} {
+		if got := ExpansionInProgress(vmv1alpha1.VolumeStatus{ExpansionState: state}); got != want {
+			t.Errorf("ExpansionInProgress(%q) = %v, want %v", state, got, want)
+		}
+	}
+}
+
+func TestReconcileDiskExpansion(t *testing.T) {
+	ctx := context.Background()
+	vmp := &vmv1alpha1.Wukong{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "vm"},
+		Spec: vmv1alpha1.WukongSpec{
+			Disks: []vmv1alpha1.DiskConfig{
+				{Name: "data", Size: "40Gi"},
+				{Name: "logs", Size: "10Gi"},
+			},
+		},
+	}
+	volumes := []vmv1alpha1.VolumeStatus{
+		{Name: "data", PVCName: "vm-data", RequestedSize: "20Gi"},
+		{Name: "logs", PVCName: "vm-logs", RequestedSize: "10Gi"},
+	}
+	c := newFakeClient(t, false, newPVC("vm-data", "20Gi", "20Gi"), newPVC("vm-logs", "10Gi", "10Gi"))
+
+	if err := ReconcileDiskExpansion(ctx, c, vmp, volumes); err != nil {
+		t.Fatalf("ReconcileDiskExpansion() error = %v", err)
+	}
+	if volumes[0].ExpansionState != vmv1alpha1.ExpansionStateResizing || volumes[0].RequestedSize != "40Gi" {
+		t.Errorf("data volume = %+v, want Resizing to 40Gi", volumes[0])
+	}
+	if volumes[1].ExpansionState != "" {
+		t.Errorf("logs volume state = %q, want unchanged", volumes[1].ExpansionState)
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "vms", Name: "vm-data"}, pvc); err != nil {
+		t.Fatal(err)
+	}
+	if got := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; got.Cmp(resource.MustParse("40Gi")) != 0 {
+		t.Errorf("PVC request = %s, want 40Gi", got.String())
+	}
+}
+
+func TestExpansionSupported(t *testing.T) {
+	allow, deny := true, false
+	objs := []client.Object{
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "expandable"}, AllowVolumeExpansion: &allow},
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fixed"}, AllowVolumeExpansion: &deny},
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "unset"}},
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "profiled"}, AllowVolumeExpansion: &allow},
+		// 已发现的 profile 优先于 StorageClass
+		&vmv1alpha1.WukongStorageProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "profiled"},
+			Status:     vmv1alpha1.WukongStorageProfileStatus{Provisioner: "example.com/csi"},
+		},
+	}
+	c := newFakeClient(t, false, objs...)
+
+	for name, want := range map[string]bool{
+		"expandable": true,
+		"fixed":      false,
+		"unset":      false,
+		"profiled":   false,
+		"missing":    false,
+	} {
+		got, err := ExpansionSupported(context.Background(), c, name)
+		if err != nil {
+			t.Fatalf("ExpansionSupported(%q) error = %v", name, err)
+		}
+		if got != want {
+			t.Errorf("ExpansionSupported(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+	"github.com/kuihuar/novasphere/pkg/naming"
+)
+
+const (
+	// LabelWukong is the label holding the Wukong name on objects created for its disks
+	LabelWukong = "wukong.novasphere.dev/name"
+	// LabelDisk is the label holding the disk name on objects created for a disk
+	LabelDisk = "wukong.novasphere.dev/disk"
+)
+
+// DiskLabels returns the labels identifying the PVC and DataVolume of a disk of a Wukong.
+func DiskLabels(vmName, diskName string) map[string]string {
+	return map[string]string{
+		LabelWukong: naming.LabelValue(vmName),
+		LabelDisk:   naming.LabelValue(diskName),
+	}
+}
+
+// LegacyPVCName returns the "<wukong>-<disk>" name used for PVCs created before generated names were hashed.
+func LegacyPVCName(vmName, diskName string) string {
+	return fmt.Sprintf("%s-%s", vmName, diskName)
+}
+
+// GeneratePVCName returns the deterministic name of a new PVC backing a disk of a Wukong.
+// "<wukong>-<disk>" is used when neither name contains a dash, since such names cannot collide
+// (e.g., "a-b" + "c" and "a" + "b-c" would both give "a-b-c"). Otherwise, or when the name
+// would be too long, a hash of both names is appended.
+func GeneratePVCName(vmName, diskName string) string {
+	return naming.Join(vmName, diskName)
+}
+
+// ResolvePVCName returns the name of the PVC backing a disk of a Wukong.
+// In order of precedence: the pvcName override of the disk, the PVC recorded in the status,
+// a PVC labeled with the Wukong and disk names, an unlabeled PVC with the legacy
+// "<wukong>-<disk>" name, and finally a newly generated name.
+// It returns an error if the name is taken by a PVC that does not belong to the disk, or if the
+// override differs from the PVC recorded in the status.
+func ResolvePVCName(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, disk vmv1alpha1.DiskConfig) (string, error) {
+	logger := log.FromContext(ctx)
+
+	prev := findVolumeStatus(vmp.Status.Volumes, disk.Name)
+	if disk.PVCName != "" {
+		if prev != nil && prev.PVCName == disk.PVCName {
+			return disk.PVCName, nil
+		}
+		// 覆盖名称与已创建的 PVC 不同时拒绝，否则磁盘会切换到新的空 PVC，旧 PVC 被遗留
+		if prev != nil && prev.PVCName != "" && !prev.ExistingClaim {
+			return prev.PVCName, fmt.Errorf("disk %s: pvcName %s differs from the PVC %s already backing the disk", disk.Name, disk.PVCName, prev.PVCName)
+		}
+		return disk.PVCName, checkPVCOwnership(ctx, c, vmp, disk.Name, disk.PVCName)
+	}
+
+	// 1. 已经创建过的 PVC 保持原名，Wukong 或磁盘重命名规则变化不影响已有磁盘
+	if prev != nil && prev.PVCName != "" && !prev.ExistingClaim {
+		return prev.PVCName, nil
+	}
+
+	// 2. 状态丢失时按标签查找
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	if err := c.List(ctx, pvcList, client.InNamespace(vmp.Namespace), client.MatchingLabels(DiskLabels(vmp.Name, disk.Name))); err != nil {
+		return "", err
+	}
+	if len(pvcList.Items) > 0 {
+		logger.V(1).Info("Found PVC by labels", "disk", disk.Name, "pvc", pvcList.Items[0].Name)
+		return pvcList.Items[0].Name, nil
+	}
+
+	// 3. 引入标签和哈希名称之前创建的 PVC 使用旧名称且没有标签
+	legacy := LegacyPVCName(vmp.Name, disk.Name)
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: vmp.Namespace, Name: legacy}, pvc); err == nil {
+		if _, labeled := pvc.Labels[LabelWukong]; !labeled {
+			logger.Info("Adopting unlabeled PVC with the legacy name", "disk", disk.Name, "pvc", legacy)
+			return legacy, nil
+		}
+	} else if !apierrors.IsNotFound(err) {
+		return "", err
+	}
+
+	// 4. 新磁盘
+	name := GeneratePVCName(vmp.Name, disk.Name)
+	return name, checkPVCOwnership(ctx, c, vmp, disk.Name, name)
+}
+
+// checkPVCOwnership returns an error if a PVC with the given name exists and does not belong to the disk.
+// Unlabeled PVCs are only adopted under the legacy "<wukong>-<disk>" name, since PVCs created
+// before labels were introduced have none; any other unlabeled PVC is refused.
+func checkPVCOwnership(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, diskName, pvcName string) error {
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: vmp.Namespace, Name: pvcName}, pvc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	owner, hasOwner := pvc.Labels[LabelWukong]
+	disk := pvc.Labels[LabelDisk]
+	if !hasOwner {
+		if pvcName == LegacyPVCName(vmp.Name, diskName) {
+			return nil
+		}
+		return fmt.Errorf("disk %s: PVC %s already exists and was not created for this disk; use existingClaim to attach it", diskName, pvcName)
+	}
+	if owner == naming.LabelValue(vmp.Name) && disk == naming.LabelValue(diskName) {
+		return nil
+	}
+	return fmt.Errorf("disk %s: PVC %s already belongs to disk %s of Wukong %s", diskName, pvcName, disk, owner)
+}
+
+// ensureLabels adds the missing labels to an object.
+func ensureLabels(ctx context.Context, c client.Client, obj client.Object, labels map[string]string) error {
+	current := obj.GetLabels()
+	missing := false
+	for k, v := range labels {
+		if current[k] != v {
+			missing = true
+			break
+		}
+	}
+	if !missing {
+		return nil
+	}
+	if current == nil {
+		current = make(map[string]string, len(labels))
+	}
+	for k, v := range labels {
+		current[k] = v
+	}
+	obj.SetLabels(current)
+	return c.Update(ctx, obj)
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+func TestGeneratePVCName(t *testing.T) {
+	if got := GeneratePVCName("vm", "system"); got != "vm-system" {
+		t.Errorf("GeneratePVCName(vm, system) = %q, want vm-system", got)
+	}
+
+	// "a-b" + "c" 和 "a" + "b-c" 的旧名称相同，生成的名称必须不同
+	if LegacyPVCName("a-b", "c") != LegacyPVCName("a", "b-c") {
+		t.Fatal("legacy names are expected to collide")
+	}
+	if GeneratePVCName("a-b", "c") == GeneratePVCName("a", "b-c") {
+		t.Errorf("GeneratePVCName(a-b, c) and GeneratePVCName(a, b-c) collide: %q", GeneratePVCName("a", "b-c"))
+	}
+}
+
+func TestResolvePVCName(t *testing.T) {
+	unlabeledPVC := func(name string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: name}}
+	}
+	labeledPVC := func(name, vmName, diskName string) *corev1.PersistentVolumeClaim {
+		pvc := unlabeledPVC(name)
+		pvc.Labels = DiskLabels(vmName, diskName)
+		return pvc
+	}
+
+	tests := []struct {
+		name    string
+		vmName  string
+		disk    vmv1alpha1.DiskConfig
+		volumes []vmv1alpha1.VolumeStatus
+		objs    []client.Object
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "new disk",
+			vmName: "web-1",
+			disk:   vmv1alpha1.DiskConfig{Name: "system"},
+			want:   GeneratePVCName("web-1", "system"),
+		},
+		{
+			name:   "labeled PVC",
+			vmName: "web-1",
+			disk:   vmv1alpha1.DiskConfig{Name: "system"},
+			objs:   []client.Object{labeledPVC("custom", "web-1", "system")},
+			want:   "custom",
+		},
+		{
+			name:   "unlabeled PVC with the legacy name is adopted",
+			vmName: "web-1",
+			disk:   vmv1alpha1.DiskConfig{Name: "system"},
+			objs:   []client.Object{unlabeledPVC("web-1-system")},
+			want:   "web-1-system",
+		},
+		{
+			name:   "legacy name taken by another disk",
+			vmName: "a",
+			disk:   vmv1alpha1.DiskConfig{Name: "b-c"},
+			objs:   []client.Object{labeledPVC("a-b-c", "a-b", "c")},
+			want:   GeneratePVCName("a", "b-c"),
+		},
+		{
+			name:   "override adopts the unlabeled legacy PVC",
+			vmName: "vm",
+			disk:   vmv1alpha1.DiskConfig{Name: "system", PVCName: "vm-system"},
+			objs:   []client.Object{unlabeledPVC("vm-system")},
+			want:   "vm-system",
+		},
+		{
+			name:    "override of the recorded PVC",
+			vmName:  "vm",
+			disk:    vmv1alpha1.DiskConfig{Name: "system", PVCName: "vm-system"},
+			volumes: []vmv1alpha1.VolumeStatus{{Name: "system", PVCName: "vm-system"}},
+			objs:    []client.Object{labeledPVC("vm-system", "vm", "system")},
+			want:    "vm-system",
+		},
+		{
+			name:    "override added to an existing disk",
+			vmName:  "vm",
+			disk:    vmv1alpha1.DiskConfig{Name: "system", PVCName: "database"},
+			volumes: []vmv1alpha1.VolumeStatus{{Name: "system", PVCName: "vm-system"}},
+			objs:    []client.Object{labeledPVC("vm-system", "vm", "system")},
+			want:    "vm-system",
+			wantErr: true,
+		},
+		{
+			name:    "override refuses other unlabeled PVCs",
+			vmName:  "vm",
+			disk:    vmv1alpha1.DiskConfig{Name: "system", PVCName: "database"},
+			objs:    []client.Object{unlabeledPVC("database")},
+			want:    "database",
+			wantErr: true,
+		},
+		{
+			name:    "override refuses PVCs of other disks",
+			vmName:  "vm",
+			disk:    vmv1alpha1.DiskConfig{Name: "system", PVCName: "database"},
+			objs:    []client.Object{labeledPVC("database", "db", "data")},
+			want:    "database",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeClient(t, false, tt.objs...)
+			vmp := &vmv1alpha1.Wukong{ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: tt.vmName}}
+			vmp.Status.Volumes = tt.volumes
+			got, err := ResolvePVCName(context.Background(), c, vmp, tt.disk)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolvePVCName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolvePVCName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
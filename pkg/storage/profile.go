@@ -0,0 +1,280 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+// Clone strategies reported by CDI StorageProfiles
+const (
+	CloneStrategyCopy     = "copy"
+	CloneStrategySnapshot = "snapshot"
+	CloneStrategyCSIClone = "csi-clone"
+)
+
+// backendDefaults describes the capabilities and defaults of a known storage backend.
+type backendDefaults struct {
+	rwxBlock      bool
+	rwxFilesystem bool
+	cloneStrategy string
+	accessModes   []string
+	volumeMode    string
+}
+
+// DetectBackend returns the storage backend of a StorageClass provisioner.
+func DetectBackend(provisioner string) string {
+	switch {
+	case strings.HasSuffix(provisioner, "rbd.csi.ceph.com") || provisioner == "ceph.rook.io/block":
+		return vmv1alpha1.StorageBackendCephRBD
+	case strings.HasSuffix(provisioner, "cephfs.csi.ceph.com"):
+		return vmv1alpha1.StorageBackendCephFS
+	case provisioner == "driver.longhorn.io":
+		return vmv1alpha1.StorageBackendLonghorn
+	case provisioner == "rancher.io/local-path":
+		return vmv1alpha1.StorageBackendLocalPath
+	case strings.Contains(provisioner, "nfs"):
+		return vmv1alpha1.StorageBackendNFS
+	default:
+		return vmv1alpha1.StorageBackendGeneric
+	}
+}
+
+// defaultsForBackend returns the known capabilities of a backend, or nil for generic backends.
+func defaultsForBackend(backend string, sc *storagev1.StorageClass) *backendDefaults {
+	rwx := []string{string(corev1.ReadWriteMany)}
+	rwo := []string{string(corev1.ReadWriteOnce)}
+	block := string(corev1.PersistentVolumeBlock)
+	filesystem := string(corev1.PersistentVolumeFilesystem)
+
+	switch backend {
+	case vmv1alpha1.StorageBackendCephRBD:
+		return &backendDefaults{rwxBlock: true, cloneStrategy: CloneStrategyCSIClone, accessModes: rwx, volumeMode: block}
+	case vmv1alpha1.StorageBackendCephFS:
+		return &backendDefaults{rwxFilesystem: true, cloneStrategy: CloneStrategyCSIClone, accessModes: rwx, volumeMode: filesystem}
+	case vmv1alpha1.StorageBackendLonghorn:
+		// Longhorn 只有在 migratable 参数开启时才支持 RWX Block（用于热迁移）
+		if sc.Parameters["migratable"] == "true" {
+			return &backendDefaults{rwxBlock: true, rwxFilesystem: true, cloneStrategy: CloneStrategyCSIClone, accessModes: rwx, volumeMode: block}
+		}
+		return &backendDefaults{rwxFilesystem: true, cloneStrategy: CloneStrategyCSIClone, accessModes: rwo, volumeMode: filesystem}
+	case vmv1alpha1.StorageBackendLocalPath:
+		return &backendDefaults{cloneStrategy: CloneStrategyCopy, accessModes: rwo, volumeMode: filesystem}
+	case vmv1alpha1.StorageBackendNFS:
+		return &backendDefaults{rwxFilesystem: true, cloneStrategy: CloneStrategyCopy, accessModes: rwx, volumeMode: filesystem}
+	}
+	return nil
+}
+
+// DiscoverStorageProfile inspects a StorageClass, its CSIDriver, VolumeSnapshotClasses and
+// CDI StorageProfile, and returns the capabilities and defaults of the StorageClass.
+// Defaults set in spec take precedence over the discovered ones. Conditions are not set.
+func DiscoverStorageProfile(ctx context.Context, c client.Client, sc *storagev1.StorageClass, spec vmv1alpha1.WukongStorageProfileSpec) (vmv1alpha1.WukongStorageProfileStatus, error) {
+	status := vmv1alpha1.WukongStorageProfileStatus{
+		Provisioner: sc.Provisioner,
+		Backend:     DetectBackend(sc.Provisioner),
+	}
+	if sc.VolumeBindingMode != nil {
+		status.VolumeBindingMode = string(*sc.VolumeBindingMode)
+	}
+	status.Capabilities.Expansion = sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion
+
+	// 1. CSIDriver 存在说明是 CSI 驱动
+	driver := &storagev1.CSIDriver{}
+	if err := c.Get(ctx, client.ObjectKey{Name: sc.Provisioner}, driver); err == nil {
+		status.CSI = true
+	} else if !apierrors.IsNotFound(err) {
+		return status, err
+	}
+
+	// 2. 存在同一驱动的 VolumeSnapshotClass 说明支持快照
+	if status.CSI {
+		snapshot, err := hasVolumeSnapshotClass(ctx, c, sc.Provisioner)
+		if err != nil {
+			return status, err
+		}
+		status.Capabilities.Snapshot = snapshot
+	}
+
+	// 3. 已知后端的能力和默认值
+	defaults := defaultsForBackend(status.Backend, sc)
+	if defaults != nil {
+		status.Capabilities.RWXBlock = defaults.rwxBlock
+		status.Capabilities.RWXFilesystem = defaults.rwxFilesystem
+		status.Capabilities.CloneStrategy = defaults.cloneStrategy
+		status.DefaultAccessModes = defaults.accessModes
+		status.DefaultVolumeMode = defaults.volumeMode
+	}
+
+	// 4. CDI StorageProfile 反映了 CDI 能力表和管理员的配置，优先于内置默认值
+	sets, err := getClaimPropertySets(ctx, c, sc.Name)
+	if err != nil {
+		return status, err
+	}
+	if len(sets) > 0 {
+		status.Capabilities.RWXBlock = false
+		status.Capabilities.RWXFilesystem = false
+		for _, set := range sets {
+			if !containsAll(set.accessModes, []string{string(corev1.ReadWriteMany)}) {
+				continue
+			}
+			if set.volumeMode == string(corev1.PersistentVolumeBlock) {
+				status.Capabilities.RWXBlock = true
+			} else {
+				status.Capabilities.RWXFilesystem = true
+			}
+		}
+		if defaults == nil {
+			status.DefaultAccessModes = sets[0].accessModes
+			status.DefaultVolumeMode = sets[0].volumeMode
+		}
+	}
+	cloneStrategy, err := getCloneStrategy(ctx, c, sc.Name)
+	if err != nil {
+		return status, err
+	}
+	if cloneStrategy != "" {
+		status.Capabilities.CloneStrategy = cloneStrategy
+	}
+	if status.Capabilities.CloneStrategy == "" {
+		status.Capabilities.CloneStrategy = CloneStrategyCopy
+		if status.Capabilities.Snapshot {
+			status.Capabilities.CloneStrategy = CloneStrategySnapshot
+		}
+	}
+	status.Capabilities.Clone = status.Capabilities.CloneStrategy != CloneStrategyCopy
+
+	// 5. 仍未确定默认值时回退到 ReadWriteOnce Filesystem
+	if len(status.DefaultAccessModes) == 0 {
+		status.DefaultAccessModes = []string{string(corev1.ReadWriteOnce)}
+	}
+	if status.DefaultVolumeMode == "" {
+		status.DefaultVolumeMode = string(corev1.PersistentVolumeFilesystem)
+	}
+
+	// 6. 管理员在 spec 中的覆盖
+	if len(spec.DefaultAccessModes) > 0 {
+		status.DefaultAccessModes = spec.DefaultAccessModes
+	}
+	if spec.DefaultVolumeMode != "" {
+		status.DefaultVolumeMode = spec.DefaultVolumeMode
+	}
+	return status, nil
+}
+
+// GetStorageProfile returns the WukongStorageProfile of a StorageClass.
+// It returns nil if the profile does not exist or the WukongStorageProfile CRD is not installed.
+func GetStorageProfile(ctx context.Context, c client.Client, storageClassName string) (*vmv1alpha1.WukongStorageProfile, error) {
+	if storageClassName == "" {
+		return nil, nil
+	}
+	profile := &vmv1alpha1.WukongStorageProfile{}
+	if err := c.Get(ctx, client.ObjectKey{Name: storageClassName}, profile); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return profile, nil
+}
+
+// CheckDiskCapabilities returns an error if the StorageClass of the disk cannot provide
+// the requested access modes and volume mode according to its WukongStorageProfile.
+// Disks whose StorageClass has no profile are not checked.
+func CheckDiskCapabilities(ctx context.Context, c client.Client, disk vmv1alpha1.DiskConfig) error {
+	profile, err := GetStorageProfile(ctx, c, disk.StorageClassName)
+	if err != nil || profile == nil || profile.Status.Provisioner == "" {
+		return err
+	}
+	if !containsAll(disk.AccessModes, []string{string(corev1.ReadWriteMany)}) {
+		return nil
+	}
+
+	caps := profile.Status.Capabilities
+	if disk.VolumeMode == string(corev1.PersistentVolumeBlock) && !caps.RWXBlock {
+		return fmt.Errorf("disk %s: StorageClass %s (%s) does not support ReadWriteMany Block volumes",
+			disk.Name, disk.StorageClassName, profile.Status.Backend)
+	}
+	if disk.VolumeMode != string(corev1.PersistentVolumeBlock) && !caps.RWXFilesystem {
+		return fmt.Errorf("disk %s: StorageClass %s (%s) does not support ReadWriteMany Filesystem volumes",
+			disk.Name, disk.StorageClassName, profile.Status.Backend)
+	}
+	return nil
+}
+
+// ExpansionSupported reports whether PVCs of the StorageClass can be expanded.
+// The capability discovered in the WukongStorageProfile is used when available; otherwise
+// the StorageClass must explicitly allow volume expansion.
+func ExpansionSupported(ctx context.Context, c client.Client, storageClassName string) (bool, error) {
+	profile, err := GetStorageProfile(ctx, c, storageClassName)
+	if err != nil {
+		return false, err
+	}
+	if profile != nil && profile.Status.Provisioner != "" {
+		return profile.Status.Capabilities.Expansion, nil
+	}
+
+	sc := &storagev1.StorageClass{}
+	if err := c.Get(ctx, client.ObjectKey{Name: storageClassName}, sc); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion, nil
+}
+
+// hasVolumeSnapshotClass reports whether a VolumeSnapshotClass exists for the CSI driver.
+// It returns false if the snapshot CRDs are not installed.
+func hasVolumeSnapshotClass(ctx context.Context, c client.Client, driver string) (bool, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "snapshot.storage.k8s.io",
+		Version: "v1",
+		Kind:    "VolumeSnapshotClassList",
+	})
+	if err := c.List(ctx, list); err != nil {
+		if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, item := range list.Items {
+		if d, _, _ := unstructured.NestedString(item.Object, "driver"); d == driver {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getCloneStrategy returns the clone strategy reported by the CDI StorageProfile of a StorageClass.
+// It returns an empty string if CDI is not installed or the StorageProfile does not exist.
+func getCloneStrategy(ctx context.Context, c client.Client, storageClassName string) (string, error) {
+	profile := newCDIStorageProfile()
+	if err := c.Get(ctx, client.ObjectKey{Name: storageClassName}, profile); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	strategy, _, _ := unstructured.NestedString(profile.Object, "status", "cloneStrategy")
+	return strategy, nil
+}
+
+// newCDIStorageProfile returns a CDI StorageProfile Unstructured object
+func newCDIStorageProfile() *unstructured.Unstructured {
+	profile := &unstructured.Unstructured{}
+	profile.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "cdi.kubevirt.io",
+		Version: "v1beta1",
+		Kind:    "StorageProfile",
+	})
+	return profile
+}
@@ -17,10 +17,10 @@ import (
 )
 
 // ReconcilePVC creates or gets an existing PersistentVolumeClaim for the given disk configuration.
+// The labels are set on a new PVC and added to an existing one that lacks them.
 // It returns the PVC name and bound status.
-func ReconcilePVC(ctx context.Context, c client.Client, disk vmv1alpha1.DiskConfig, namespace, vmName string) (string, bool, error) {
+func ReconcilePVC(ctx context.Context, c client.Client, disk vmv1alpha1.DiskConfig, namespace, pvcName string, labels map[string]string) (string, bool, error) {
 	logger := log.FromContext(ctx)
-	pvcName := fmt.Sprintf("%s-%s", vmName, disk.Name)
 
 	logger.Info("Reconciling PVC", "name", pvcName, "namespace", namespace, "size", disk.Size, "storageClass", disk.StorageClassName)
 
@@ -31,14 +31,22 @@ func ReconcilePVC(ctx context.Context, c client.Client, disk vmv1alpha1.DiskConf
 		return "", false, fmt.Errorf("invalid storage size %s: %w", disk.Size, err)
 	}
 
+	accessModes := make([]corev1.PersistentVolumeAccessMode, 0, len(disk.AccessModes))
+	for _, mode := range pvcAccessModes(disk) {
+		accessModes = append(accessModes, corev1.PersistentVolumeAccessMode(mode))
+	}
+	volumeMode := corev1.PersistentVolumeMode(pvcVolumeMode(disk))
+
 	// 创建 PVC 对象
 	pvc := &corev1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      pvcName,
 			Namespace: namespace,
+			Labels:    labels,
 		},
 		Spec: corev1.PersistentVolumeClaimSpec{
-			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			AccessModes: accessModes,
+			VolumeMode:  &volumeMode,
 			Resources: corev1.VolumeResourceRequirements{
 				Requests: corev1.ResourceList{
 					corev1.ResourceStorage: storageQuantity,
@@ -83,6 +91,11 @@ func ReconcilePVC(ctx context.Context, c client.Client, disk vmv1alpha1.DiskConf
 	// PVC 已存在，检查绑定状态
 	logger.V(1).Info("Found existing PersistentVolumeClaim", "name", pvcName, "phase", existingPVC.Status.Phase)
 
+	// 旧版本创建的 PVC 没有标签，补上以便按标签查找
+	if err := ensureLabels(ctx, c, existingPVC, labels); err != nil {
+		return pvcName, false, err
+	}
+
 	// 检查 StorageClass 的 volumeBindingMode
 	// 如果是 WaitForFirstConsumer，即使 PVC 未绑定也可以继续（PVC 会在 Pod 创建时绑定）
 	bound := existingPVC.Status.Phase == corev1.ClaimBound
@@ -107,6 +120,40 @@ func ReconcilePVC(ctx context.Context, c client.Client, disk vmv1alpha1.DiskConf
 	return pvcName, bound, nil
 }
 
+// ReconcileExistingClaim validates an existing PVC referenced by disk.ExistingClaim and returns its bound status.
+// Shareable disks require a ReadWriteMany PVC with Block volume mode.
+func ReconcileExistingClaim(ctx context.Context, c client.Client, disk vmv1alpha1.DiskConfig, namespace string) (bool, error) {
+	logger := log.FromContext(ctx)
+	logger.V(1).Info("Reconciling existing PVC", "name", disk.ExistingClaim, "namespace", namespace, "shareable", disk.Shareable)
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: disk.ExistingClaim}, pvc); err != nil {
+		if errors.IsNotFound(err) {
+			return false, fmt.Errorf("disk %s: existing PVC %s/%s not found", disk.Name, namespace, disk.ExistingClaim)
+		}
+		return false, err
+	}
+
+	if disk.Shareable {
+		// 多个 VM 同时挂载的磁盘必须是 RWX Block，否则会损坏数据或无法调度
+		rwx := false
+		for _, mode := range pvc.Spec.AccessModes {
+			if mode == corev1.ReadWriteMany {
+				rwx = true
+				break
+			}
+		}
+		if !rwx {
+			return false, fmt.Errorf("disk %s: shareable PVC %s must be ReadWriteMany", disk.Name, disk.ExistingClaim)
+		}
+		if pvc.Spec.VolumeMode == nil || *pvc.Spec.VolumeMode != corev1.PersistentVolumeBlock {
+			return false, fmt.Errorf("disk %s: shareable PVC %s must use Block volume mode", disk.Name, disk.ExistingClaim)
+		}
+	}
+
+	return CheckPVCBound(ctx, c, namespace, disk.ExistingClaim)
+}
+
 // CheckPVCBound checks if a PersistentVolumeClaim is bound (non-blocking).
 // Returns true if PVC is bound, false if still pending, error if in Lost state.
 func CheckPVCBound(ctx context.Context, c client.Client, namespace, name string) (bool, error) {
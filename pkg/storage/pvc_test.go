@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+func TestReconcileExistingClaim(t *testing.T) {
+	// sharedPVC 构造指定访问模式和卷模式的已绑定 PVC
+	sharedPVC := func(accessMode corev1.PersistentVolumeAccessMode, volumeMode corev1.PersistentVolumeMode) *corev1.PersistentVolumeClaim {
+		pvc := newPVC("shared", "20Gi", "20Gi")
+		pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{accessMode}
+		pvc.Spec.VolumeMode = &volumeMode
+		return pvc
+	}
+	pendingPVC := newPVC("shared", "20Gi", "")
+	pendingPVC.Status.Phase = corev1.ClaimPending
+
+	tests := []struct {
+		name      string
+		pvc       *corev1.PersistentVolumeClaim
+		shareable bool
+		wantBound bool
+		wantErr   bool
+	}{
+		{
+			name:    "missing PVC",
+			wantErr: true,
+		},
+		{
+			name:      "bound PVC",
+			pvc:       sharedPVC(corev1.ReadWriteOnce, corev1.PersistentVolumeFilesystem),
+			wantBound: true,
+		},
+		{
+			name: "pending PVC",
+			pvc:  pendingPVC,
+		},
+		{
+			name:      "shareable ReadWriteMany Block",
+			pvc:       sharedPVC(corev1.ReadWriteMany, corev1.PersistentVolumeBlock),
+			shareable: true,
+			wantBound: true,
+		},
+		{
+			name:      "shareable ReadWriteOnce",
+			pvc:       sharedPVC(corev1.ReadWriteOnce, corev1.PersistentVolumeBlock),
+			shareable: true,
+			wantErr:   true,
+		},
+		{
+			name:      "shareable Filesystem",
+			pvc:       sharedPVC(corev1.ReadWriteMany, corev1.PersistentVolumeFilesystem),
+			shareable: true,
+			wantErr:   true,
+		},
+		{
+			name:      "shareable without volume mode",
+			pvc:       newPVC("shared", "20Gi", "20Gi"),
+			shareable: true,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeClient(t, false)
+			if tt.pvc != nil {
+				c = newFakeClient(t, false, tt.pvc)
+			}
+			disk := vmv1alpha1.DiskConfig{Name: "shared", ExistingClaim: "shared", Shareable: tt.shareable}
+			bound, err := ReconcileExistingClaim(context.Background(), c, disk, "vms")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReconcileExistingClaim() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if bound != tt.wantBound {
+				t.Errorf("ReconcileExistingClaim() bound = %v, want %v", bound, tt.wantBound)
+			}
+		})
+	}
+}
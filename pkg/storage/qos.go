@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+const (
+	// volumeAttributesClassPrefix is the name prefix of VolumeAttributesClasses created for Storage QoS
+	volumeAttributesClassPrefix = "wukong-qos-"
+
+	// volumeAttributesClassGracePeriod keeps new VolumeAttributesClasses from being garbage-collected
+	// before they are assigned to their PVC
+	volumeAttributesClassGracePeriod = time.Minute
+)
+
+// resolveDiskQoS returns the QoS of a PVC-backed disk: its own QoS, or the DiskQoS of the namespace WukongDefaults.
+func resolveDiskQoS(ctx context.Context, c client.Client, namespace string, disk vmv1alpha1.DiskConfig) (*vmv1alpha1.DiskQoSSpec, error) {
+	qos := disk.QoS
+	if qos == nil {
+		defaults, err := GetWukongDefaults(ctx, c, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if defaults == nil || defaults.Spec.DiskQoS == nil {
+			return nil, nil
+		}
+		qos = defaults.Spec.DiskQoS
+	}
+	qos = qos.DeepCopy()
+	if qos.Mode == "" {
+		qos.Mode = vmv1alpha1.QoSModeHypervisor
+	}
+	return qos, nil
+}
+
+// VolumeAttributesClassParameters returns the CSI parameters enforcing the QoS on a storage backend.
+// Only Ceph RBD supports QoS through VolumeAttributesClass parameters.
+func VolumeAttributesClassParameters(backend string, qos *vmv1alpha1.DiskQoSSpec) (map[string]string, error) {
+	if backend != vmv1alpha1.StorageBackendCephRBD {
+		return nil, fmt.Errorf("storage QoS is not supported by the %s backend, use Hypervisor mode", backend)
+	}
+	params := map[string]string{}
+	if qos.ReadIOPS != nil {
+		params["BaseReadIops"] = strconv.FormatInt(*qos.ReadIOPS, 10)
+	}
+	if qos.WriteIOPS != nil {
+		params["BaseWriteIops"] = strconv.FormatInt(*qos.WriteIOPS, 10)
+	}
+	if qos.ReadBytesPerSecond != nil {
+		params["BaseReadBytesPerSecond"] = strconv.FormatInt(qos.ReadBytesPerSecond.Value(), 10)
+	}
+	if qos.WriteBytesPerSecond != nil {
+		params["BaseWriteBytesPerSecond"] = strconv.FormatInt(qos.WriteBytesPerSecond.Value(), 10)
+	}
+	if len(params) == 0 {
+		return nil, fmt.Errorf("storage QoS requires at least one limit")
+	}
+	return params, nil
+}
+
+// EnsureVolumeAttributesClass creates the VolumeAttributesClass enforcing the QoS of a disk, if it does not exist,
+// and returns its name. The name is derived from the driver and parameters, so disks with the same limits share
+// a class and changing the limits selects a new class (VolumeAttributesClass parameters are immutable).
+func EnsureVolumeAttributesClass(ctx context.Context, c client.Client, disk vmv1alpha1.DiskConfig, qos *vmv1alpha1.DiskQoSSpec) (string, error) {
+	logger := log.FromContext(ctx)
+
+	sc := &storagev1.StorageClass{}
+	if err := c.Get(ctx, client.ObjectKey{Name: disk.StorageClassName}, sc); err != nil {
+		return "", err
+	}
+	params, err := VolumeAttributesClassParameters(DetectBackend(sc.Provisioner), qos)
+	if err != nil {
+		return "", fmt.Errorf("disk %s: %w", disk.Name, err)
+	}
+
+	name := volumeAttributesClassName(sc.Provisioner, params)
+	vac := &storagev1.VolumeAttributesClass{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, vac); err == nil {
+		return name, nil
+	} else if !apierrors.IsNotFound(err) {
+		return "", err
+	}
+
+	vac = &storagev1.VolumeAttributesClass{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		DriverName: sc.Provisioner,
+		Parameters: params,
+	}
+	logger.Info("Creating VolumeAttributesClass", "name", name, "driver", sc.Provisioner)
+	if err := c.Create(ctx, vac); err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", err
+	}
+	return name, nil
+}
+
+// applyStorageQoS 为 Storage 模式 QoS 的磁盘创建并分配 VolumeAttributesClass
+func applyStorageQoS(ctx context.Context, c client.Client, namespace string, disk vmv1alpha1.DiskConfig, volStatus *vmv1alpha1.VolumeStatus) error {
+	if volStatus.QoS == nil || volStatus.QoS.Mode != vmv1alpha1.QoSModeStorage {
+		return nil
+	}
+	if disk.StorageClassName == "" {
+		return fmt.Errorf("disk %s: storage QoS requires a StorageClass", disk.Name)
+	}
+	name, err := EnsureVolumeAttributesClass(ctx, c, disk, volStatus.QoS)
+	if err != nil {
+		return err
+	}
+	volStatus.VolumeAttributesClassName = name
+	return ApplyVolumeAttributesClass(ctx, c, namespace, volStatus.PVCName, name)
+}
+
+// ApplyVolumeAttributesClass assigns a VolumeAttributesClass to a PVC; the CSI driver then modifies the volume online.
+// A PVC that does not exist yet (e.g., still importing) is left to the next reconcile.
+func ApplyVolumeAttributesClass(ctx context.Context, c client.Client, namespace, pvcName, className string) error {
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: pvcName}, pvc); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if pvc.Spec.VolumeAttributesClassName != nil && *pvc.Spec.VolumeAttributesClassName == className {
+		return nil
+	}
+	log.FromContext(ctx).Info("Assigning VolumeAttributesClass to PVC", "pvc", pvcName, "class", className)
+	pvc.Spec.VolumeAttributesClassName = &className
+	return c.Update(ctx, pvc)
+}
+
+// GarbageCollectVolumeAttributesClasses deletes the VolumeAttributesClasses of a CSI driver created for
+// Storage QoS that are no longer referenced by any PVC, either as the requested, current or target class.
+// Classes are shared by all disks with the same limits, so references are checked cluster-wide.
+func GarbageCollectVolumeAttributesClasses(ctx context.Context, c client.Client, driver string) error {
+	logger := log.FromContext(ctx)
+
+	classes := &storagev1.VolumeAttributesClassList{}
+	if err := c.List(ctx, classes); err != nil {
+		if meta.IsNoMatchError(err) {
+			// 集群未启用 VolumeAttributesClass
+			return nil
+		}
+		return err
+	}
+	candidates := make([]*storagev1.VolumeAttributesClass, 0)
+	for i := range classes.Items {
+		vac := &classes.Items[i]
+		if vac.DriverName == driver && strings.HasPrefix(vac.Name, volumeAttributesClassPrefix) && vac.DeletionTimestamp == nil &&
+			time.Since(vac.CreationTimestamp.Time) > volumeAttributesClassGracePeriod {
+			candidates = append(candidates, vac)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := c.List(ctx, pvcs); err != nil {
+		return err
+	}
+	referenced := make(map[string]bool)
+	for _, pvc := range pvcs.Items {
+		if name := pvc.Spec.VolumeAttributesClassName; name != nil {
+			referenced[*name] = true
+		}
+		if name := pvc.Status.CurrentVolumeAttributesClassName; name != nil {
+			referenced[*name] = true
+		}
+		if status := pvc.Status.ModifyVolumeStatus; status != nil && status.TargetVolumeAttributesClassName != "" {
+			referenced[status.TargetVolumeAttributesClassName] = true
+		}
+	}
+
+	for _, vac := range candidates {
+		if referenced[vac.Name] {
+			continue
+		}
+		logger.Info("Deleting unused VolumeAttributesClass", "name", vac.Name)
+		if err := c.Delete(ctx, vac); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// volumeAttributesClassName returns a deterministic VolumeAttributesClass name for a driver and its parameters.
+func volumeAttributesClassName(driver string, params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	h.Write([]byte(driver))
+	for _, k := range keys {
+		fmt.Fprintf(h, "\x00%s=%s", k, params[k])
+	}
+	return volumeAttributesClassPrefix + hex.EncodeToString(h.Sum(nil))[:10]
+}
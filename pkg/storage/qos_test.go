@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+func TestVolumeAttributesClassParameters(t *testing.T) {
+	iops := func(i int64) *int64 { return &i }
+	bandwidth := resource.MustParse("100Mi")
+
+	tests := []struct {
+		name    string
+		backend string
+		qos     *vmv1alpha1.DiskQoSSpec
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "ceph rbd",
+			backend: vmv1alpha1.StorageBackendCephRBD,
+			qos:     &vmv1alpha1.DiskQoSSpec{ReadIOPS: iops(1000), WriteIOPS: iops(500), ReadBytesPerSecond: &bandwidth},
+			want: map[string]string{
+				"BaseReadIops":           "1000",
+				"BaseWriteIops":          "500",
+				"BaseReadBytesPerSecond": "104857600",
+			},
+		},
+		{
+			name:    "unsupported backend",
+			backend: vmv1alpha1.StorageBackendLonghorn,
+			qos:     &vmv1alpha1.DiskQoSSpec{ReadIOPS: iops(1000)},
+			wantErr: true,
+		},
+		{
+			name:    "no limits",
+			backend: vmv1alpha1.StorageBackendCephRBD,
+			qos:     &vmv1alpha1.DiskQoSSpec{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VolumeAttributesClassParameters(tt.backend, tt.qos)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VolumeAttributesClassParameters() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("VolumeAttributesClassParameters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVolumeAttributesClassName(t *testing.T) {
+	params := map[string]string{"BaseReadIops": "1000", "BaseWriteIops": "500"}
+	name := volumeAttributesClassName("rbd.csi.ceph.com", params)
+	if name != volumeAttributesClassName("rbd.csi.ceph.com", map[string]string{"BaseWriteIops": "500", "BaseReadIops": "1000"}) {
+		t.Error("volumeAttributesClassName() depends on the parameter order")
+	}
+	if name == volumeAttributesClassName("rbd.csi.ceph.com", map[string]string{"BaseReadIops": "1000"}) {
+		t.Error("volumeAttributesClassName() is the same for different parameters")
+	}
+	if name == volumeAttributesClassName("other.csi.example.com", params) {
+		t.Error("volumeAttributesClassName() is the same for different drivers")
+	}
+}
+
+func TestGarbageCollectVolumeAttributesClasses(t *testing.T) {
+	ctx := context.Background()
+	old := metav1.NewTime(time.Now().Add(-time.Hour))
+	vac := func(name, driver string, created metav1.Time) *storagev1.VolumeAttributesClass {
+		return &storagev1.VolumeAttributesClass{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: created},
+			DriverName: driver,
+		}
+	}
+	className := func(s string) *string { return &s }
+
+	c := newFakeClient(t, false,
+		vac("wukong-qos-requested", "rbd.csi.ceph.com", old),
+		vac("wukong-qos-current", "rbd.csi.ceph.com", old),
+		vac("wukong-qos-target", "rbd.csi.ceph.com", old),
+		vac("wukong-qos-unused", "rbd.csi.ceph.com", old),
+		vac("wukong-qos-new", "rbd.csi.ceph.com", metav1.Now()),
+		vac("wukong-qos-other-driver", "other.csi.example.com", old),
+		vac("gold", "rbd.csi.ceph.com", old),
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "vm-data"},
+			Spec:       corev1.PersistentVolumeClaimSpec{VolumeAttributesClassName: className("wukong-qos-requested")},
+			Status: corev1.PersistentVolumeClaimStatus{
+				CurrentVolumeAttributesClassName: className("wukong-qos-current"),
+				ModifyVolumeStatus: &corev1.ModifyVolumeStatus{
+					TargetVolumeAttributesClassName: "wukong-qos-target",
+					Status:                          corev1.PersistentVolumeClaimModifyVolumeInProgress,
+				},
+			},
+		},
+	)
+
+	if err := GarbageCollectVolumeAttributesClasses(ctx, c, "rbd.csi.ceph.com"); err != nil {
+		t.Fatalf("GarbageCollectVolumeAttributesClasses() error = %v", err)
+	}
+
+	for name, wantExists := range map[string]bool{
+		"wukong-qos-requested":    true,
+		"wukong-qos-current":      true,
+		"wukong-qos-target":       true,
+		"wukong-qos-unused":       false,
+		"wukong-qos-new":          true,
+		"wukong-qos-other-driver": true,
+		"gold":                    true,
+	} {
+		err := c.Get(ctx, client.ObjectKey{Name: name}, &storagev1.VolumeAttributesClass{})
+		if exists := !errors.IsNotFound(err); exists != wantExists {
+			t.Errorf("VolumeAttributesClass %s exists = %v, want %v (err = %v)", name, exists, wantExists, err)
+		}
+	}
+}
@@ -2,15 +2,19 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+	"github.com/kuihuar/novasphere/pkg/catalog"
 )
 
 // ReconcileDisks reconciles all disks for a Wukong.
-// It creates either DataVolume (if disk.image is specified) or PVC (if not).
+// It creates either DataVolume (if disk.image or disk.source is specified) or PVC (if not).
+// Disks using an existing PVC, a container disk or an empty disk do not create any PVC.
 // Returns a list of VolumeStatus for each disk.
 func ReconcileDisks(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong) ([]vmv1alpha1.VolumeStatus, error) {
 	logger := log.FromContext(ctx)
@@ -18,18 +22,145 @@ func ReconcileDisks(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong
 
 	volumesStatus := make([]vmv1alpha1.VolumeStatus, 0, len(vmp.Spec.Disks))
 
-	for _, disk := range vmp.Spec.Disks {
-		var pvcName string
-		var bound bool
-		var err error
+	for _, specDisk := range vmp.Spec.Disks {
+		// 未指定 StorageClass 的磁盘使用 namespace 或集群的默认 StorageClass
+		if err := defaultDiskStorageClass(ctx, c, vmp, &specDisk); err != nil {
+			logger.Error(err, "failed to resolve default StorageClass", "disk", specDisk.Name)
+			return nil, err
+		}
+
+		// 解析 catalog 镜像（WukongImage），得到实际使用的 source 和 size
+		disk, err := catalog.ResolveDisk(ctx, c, vmp, specDisk)
+		if errors.Is(err, catalog.ErrGoldenImageNotReady) {
+			logger.Info("Waiting for golden image", "disk", disk.Name, "reason", err.Error())
+			volumesStatus = append(volumesStatus, vmv1alpha1.VolumeStatus{
+				Name:             disk.Name,
+				Size:             disk.Size,
+				StorageClassName: disk.StorageClassName,
+				Message:          err.Error(),
+				ReclaimPolicy:    reclaimPolicy(disk.ReclaimPolicy),
+			})
+			continue
+		}
+		if err != nil {
+			logger.Error(err, "failed to resolve catalog image", "disk", disk.Name)
+			return nil, err
+		}
+		// containerDisk 和 emptyDisk 不需要 PVC，由 KubeVirt 在 VM 启动时直接提供
+		if disk.ContainerDisk != nil || disk.EmptyDisk != nil {
+			volumesStatus = append(volumesStatus, vmv1alpha1.VolumeStatus{
+				Name:  disk.Name,
+				Size:  disk.Size,
+				Bound: true,
+			})
+			continue
+		}
+
+		// 已有 PVC：只校验并挂载，不创建、不扩容、不删除
+		if disk.ExistingClaim != "" {
+			volStatus := vmv1alpha1.VolumeStatus{
+				Name:          disk.Name,
+				PVCName:       disk.ExistingClaim,
+				ExistingClaim: true,
+				ReclaimPolicy: vmv1alpha1.ReclaimPolicyRetain,
+			}
+			volStatus.Bound, err = ReconcileExistingClaim(ctx, c, disk, vmp.Namespace)
+			if err != nil {
+				logger.Error(err, "invalid existing PVC", "disk", disk.Name, "pvc", disk.ExistingClaim)
+				return nil, err
+			}
+			if err := ObservePVC(ctx, c, vmp.Namespace, "", &volStatus); err != nil {
+				logger.V(1).Info("Failed to observe PVC", "disk", disk.Name, "pvc", volStatus.PVCName, "error", err)
+			}
+			// IO 限制同样适用于已有 PVC；Storage QoS 使用 PVC 实际的 StorageClass
+			volStatus.QoS, err = resolveDiskQoS(ctx, c, vmp.Namespace, disk)
+			if err != nil {
+				return nil, err
+			}
+			disk.StorageClassName = volStatus.StorageClassName
+			if err := applyStorageQoS(ctx, c, vmp.Namespace, disk, &volStatus); err != nil {
+				logger.Error(err, "failed to apply storage QoS", "disk", disk.Name)
+				return nil, err
+			}
+			volumesStatus = append(volumesStatus, volStatus)
+			continue
+		}
+
+		if disk.Size == "" {
+			return nil, fmt.Errorf("disk %s: size is required", disk.Name)
+		}
+
+		// 解析 accessModes 和 volumeMode（Auto 模式根据 StorageProfile 选择）
+		disk.AccessModes, disk.VolumeMode, err = ResolveVolumeMode(ctx, c, disk)
+		if err != nil {
+			logger.Error(err, "failed to resolve volume mode", "disk", disk.Name)
+			return nil, err
+		}
+		// 检查 StorageClass 是否支持请求的 RWX 模式
+		if err := CheckDiskCapabilities(ctx, c, disk); err != nil {
+			return nil, err
+		}
+
+		volStatus := vmv1alpha1.VolumeStatus{
+			Name:             disk.Name,
+			Size:             disk.Size,
+			StorageClassName: disk.StorageClassName,
+			AccessModes:      disk.AccessModes,
+			VolumeMode:       disk.VolumeMode,
+			ReclaimPolicy:    reclaimPolicy(disk.ReclaimPolicy),
+		}
+
+		// 确定 PVC 名称：pvcName 覆盖、已有状态、标签查找，最后生成无冲突的名称
+		pvcName, err := ResolvePVCName(ctx, c, vmp, disk)
+		if err != nil {
+			logger.Error(err, "failed to resolve PVC name", "disk", disk.Name)
+			return nil, err
+		}
+		labels := DiskLabels(vmp.Name, disk.Name)
 
-		// 如果指定了 image，使用 DataVolume；否则使用 PVC
-		if disk.Image != "" {
-			logger.Info("Creating DataVolume for disk with image", "disk", disk.Name, "image", disk.Image)
-			pvcName, bound, err = ReconcileDataVolume(ctx, c, disk, vmp.Namespace, vmp.Name)
+		// IO 限制：磁盘自身的 QoS 或 namespace 默认 QoS
+		volStatus.QoS, err = resolveDiskQoS(ctx, c, vmp.Namespace, disk)
+		if err != nil {
+			return nil, err
+		}
+
+		// 加密磁盘：在创建 PVC 之前准备好密钥，密钥丢失时不挂载
+		if disk.Encryption != nil {
+			volStatus.EncryptionMode = disk.Encryption.Mode
+			if disk.Encryption.Mode == vmv1alpha1.EncryptionModeStorageClass {
+				if err := CheckEncryptedStorageClass(ctx, c, disk); err != nil {
+					return nil, err
+				}
+			}
+			volStatus.EncryptionKeySecret, err = EnsureEncryptionKey(ctx, c, vmp, disk, pvcName)
+			if errors.Is(err, ErrEncryptionKeyMissing) || errors.Is(err, ErrEncryptionKeyReleased) {
+				logger.Info("Refusing to attach encrypted disk", "disk", disk.Name, "reason", err.Error())
+				volStatus.PVCName = pvcName
+				volStatus.Message = err.Error()
+				volumesStatus = append(volumesStatus, volStatus)
+				continue
+			}
+			if err != nil {
+				logger.Error(err, "failed to ensure encryption key", "disk", disk.Name)
+				return nil, err
+			}
+		}
+
+		// 如果指定了 image 或 source，使用 DataVolume；否则使用 PVC
+		if disk.Image != "" || disk.Source != nil {
+			logger.Info("Creating DataVolume for disk with source", "disk", disk.Name, "image", disk.Image)
+			var state *DataVolumeState
+			volStatus.PVCName, state, err = ReconcileDataVolume(ctx, c, disk, vmp.Namespace, pvcName, labels)
+			if err == nil {
+				// 导入进度、重试次数等信息写入 VolumeStatus
+				err = applyDataVolumeState(ctx, c, disk, vmp.Namespace, state, findVolumeStatus(vmp.Status.Volumes, disk.Name), &volStatus)
+			}
+			if err == nil {
+				volStatus.SourcePVC = resolveSourcePVC(ctx, c, disk, vmp.Namespace, findVolumeStatus(vmp.Status.Volumes, disk.Name))
+			}
 		} else {
 			logger.Info("Creating PVC for disk", "disk", disk.Name)
-			pvcName, bound, err = ReconcilePVC(ctx, c, disk, vmp.Namespace, vmp.Name)
+			volStatus.PVCName, volStatus.Bound, err = ReconcilePVC(ctx, c, disk, vmp.Namespace, pvcName, labels)
 		}
 
 		if err != nil {
@@ -42,13 +173,62 @@ func ReconcileDisks(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong
 			return nil, err
 		}
 
-		volumesStatus = append(volumesStatus, vmv1alpha1.VolumeStatus{
-			Name:    disk.Name,
-			PVCName: pvcName,
-			Bound:   bound,
-			Size:    disk.Size,
-		})
+		// Storage QoS：通过 VolumeAttributesClass 由 CSI 驱动限速
+		if err := applyStorageQoS(ctx, c, vmp.Namespace, disk, &volStatus); err != nil {
+			logger.Error(err, "failed to apply storage QoS", "disk", disk.Name)
+			return nil, err
+		}
+
+		// 从 PVC 读取实际的 PV、容量、accessModes 和扩展状态
+		prevExpansionState := ""
+		if prev := findVolumeStatus(vmp.Status.Volumes, disk.Name); prev != nil {
+			prevExpansionState = prev.ExpansionState
+		}
+		if err := ObservePVC(ctx, c, vmp.Namespace, prevExpansionState, &volStatus); err != nil {
+			logger.V(1).Info("Failed to observe PVC", "disk", disk.Name, "pvc", volStatus.PVCName, "error", err)
+		}
+
+		// upload 源需要客户端上传数据，记录 CDI upload proxy 地址
+		if disk.Source != nil && disk.Source.Upload != nil && !volStatus.Bound {
+			endpoint, err := GetUploadEndpoint(ctx, c)
+			if err != nil {
+				logger.V(1).Info("Failed to get CDI upload endpoint", "disk", disk.Name, "error", err)
+			}
+			volStatus.UploadEndpoint = endpoint
+		}
+
+		volumesStatus = append(volumesStatus, volStatus)
 	}
 
 	return volumesStatus, nil
 }
+
+// findVolumeStatus returns the VolumeStatus with the given name, or nil if not found.
+func findVolumeStatus(volumes []vmv1alpha1.VolumeStatus, name string) *vmv1alpha1.VolumeStatus {
+	for i := range volumes {
+		if volumes[i].Name == name {
+			return &volumes[i]
+		}
+	}
+	return nil
+}
+
+// resolveSourcePVC returns the golden PVC a disk cloned from a DataSource was populated from.
+// The PVC is resolved once and then kept from the previous status, since the DataSource
+// may later point to a newer import.
+func resolveSourcePVC(ctx context.Context, c client.Client, disk vmv1alpha1.DiskConfig, namespace string, prev *vmv1alpha1.VolumeStatus) string {
+	if prev != nil && prev.SourcePVC != "" {
+		return prev.SourcePVC
+	}
+	if disk.Source == nil || disk.Source.DataSource == nil {
+		return ""
+	}
+
+	ref := disk.Source.DataSource
+	sourcePVC, err := GetDataSourcePVC(ctx, c, defaultNamespace(ref.Namespace, namespace), ref.Name)
+	if err != nil {
+		log.FromContext(ctx).V(1).Info("Failed to resolve DataSource PVC", "disk", disk.Name, "dataSource", ref.Name, "error", err)
+		return ""
+	}
+	return sourcePVC
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+// TrackRemovedVolumes records the volumes whose disk was removed from the spec in Status.RemovedVolumes.
+// It must be called before Status.Volumes is replaced by the volumes of the current spec.
+// Volumes whose disk was added back to the spec are no longer tracked as removed.
+func TrackRemovedVolumes(vmp *vmv1alpha1.Wukong) {
+	inSpec := make(map[string]bool, len(vmp.Spec.Disks))
+	for _, disk := range vmp.Spec.Disks {
+		inSpec[disk.Name] = true
+	}
+
+	removed := make([]vmv1alpha1.RemovedVolumeStatus, 0, len(vmp.Status.RemovedVolumes))
+	tracked := make(map[string]bool, len(vmp.Status.RemovedVolumes))
+	for _, vol := range vmp.Status.RemovedVolumes {
+		if inSpec[vol.Name] {
+			// 磁盘重新加入 spec，继续使用原来的 PVC
+			continue
+		}
+		removed = append(removed, vol)
+		tracked[vol.Name] = true
+	}
+
+	now := metav1.Now()
+	for _, vol := range vmp.Status.Volumes {
+		// 已有 PVC 不属于 Wukong，移除后直接从 VM 拔出即可
+		if inSpec[vol.Name] || tracked[vol.Name] || vol.PVCName == "" || vol.ExistingClaim {
+			continue
+		}
+		removed = append(removed, vmv1alpha1.RemovedVolumeStatus{
+			Name:          vol.Name,
+			PVCName:       vol.PVCName,
+			ReclaimPolicy: reclaimPolicy(vol.ReclaimPolicy),
+			State:         vmv1alpha1.RemovedVolumeDetaching,
+			RemovedTime:   &now,
+		})
+	}
+
+	vmp.Status.RemovedVolumes = removed
+}
+
+// ReconcileRemovedVolumes applies the reclaim policy of removed volumes that are no longer attached to the VM.
+// attached is the set of PVC names still used by the running VMI.
+// Deleted volumes are dropped from Status.RemovedVolumes; retained volumes stay until their PVC is deleted.
+func ReconcileRemovedVolumes(ctx context.Context, c client.Client, vmp *vmv1alpha1.Wukong, attached map[string]bool) error {
+	logger := log.FromContext(ctx)
+
+	remaining := make([]vmv1alpha1.RemovedVolumeStatus, 0, len(vmp.Status.RemovedVolumes))
+	var firstErr error
+	for _, vol := range vmp.Status.RemovedVolumes {
+		if attached[vol.PVCName] {
+			// VM 仍在使用该卷，等待热拔出或下次重启后再处理
+			vol.State = vmv1alpha1.RemovedVolumeDetaching
+			vol.Message = "Volume is still attached to the running VM and will be detached on hot-unplug or next restart"
+			remaining = append(remaining, vol)
+			continue
+		}
+
+		if reclaimPolicy(vol.ReclaimPolicy) == vmv1alpha1.ReclaimPolicyRetain {
+			exists, err := pvcExists(ctx, c, vmp.Namespace, vol.PVCName)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				logger.Info("Retained volume was deleted", "disk", vol.Name, "pvc", vol.PVCName)
+				continue
+			}
+			vol.State = vmv1alpha1.RemovedVolumeRetained
+			vol.Message = "Volume is detached and retained according to its reclaim policy"
+			remaining = append(remaining, vol)
+			continue
+		}
+
+		logger.Info("Deleting volume of removed disk", "disk", vol.Name, "pvc", vol.PVCName)
+		if err := DeleteVolume(ctx, c, vmp.Namespace, vol.PVCName); err != nil {
+			vol.State = vmv1alpha1.RemovedVolumeOrphaned
+			vol.Message = fmt.Sprintf("Failed to delete volume: %v", err)
+			remaining = append(remaining, vol)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+	}
+
+	vmp.Status.RemovedVolumes = remaining
+	return firstErr
+}
+
+// DeleteVolume deletes the DataVolume (if any) and the PVC of a volume.
+// The generated encryption key of the PVC is kept until its PersistentVolume is gone.
+func DeleteVolume(ctx context.Context, c client.Client, namespace, pvcName string) error {
+	// PVC 删除前记录绑定的 PV，CSI 驱动删除 PV 时可能仍需要加密密钥
+	pvName := ""
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: pvcName}, pvc); err == nil {
+		pvName = pvc.Spec.VolumeName
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+
+	if err := DeleteDataVolume(ctx, c, namespace, pvcName); err != nil && !meta.IsNoMatchError(err) {
+		return err
+	}
+	if err := DeletePVC(ctx, c, namespace, pvcName); err != nil {
+		return err
+	}
+	return ReleaseEncryptionKey(ctx, c, namespace, pvcName, pvName)
+}
+
+// pvcExists reports whether the PVC exists.
+func pvcExists(ctx context.Context, c client.Client, namespace, name string) (bool, error) {
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, pvc); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// reclaimPolicy returns the reclaim policy, defaulting to Retain.
+// Only an explicit Delete removes the data of a volume.
+func reclaimPolicy(policy string) string {
+	if policy != vmv1alpha1.ReclaimPolicyDelete {
+		return vmv1alpha1.ReclaimPolicyRetain
+	}
+	return policy
+}
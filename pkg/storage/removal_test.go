@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+func TestTrackRemovedVolumes(t *testing.T) {
+	vmp := &vmv1alpha1.Wukong{
+		Spec: vmv1alpha1.WukongSpec{
+			Disks: []vmv1alpha1.DiskConfig{{Name: "system"}, {Name: "readded"}},
+		},
+		Status: vmv1alpha1.WukongStatus{
+			Volumes: []vmv1alpha1.VolumeStatus{
+				{Name: "system", PVCName: "vm-system"},
+				{Name: "data", PVCName: "vm-data", ReclaimPolicy: vmv1alpha1.ReclaimPolicyDelete},
+				{Name: "logs", PVCName: "vm-logs"},
+				{Name: "shared", PVCName: "shared", ExistingClaim: true},
+				{Name: "scratch"},
+				{Name: "old", PVCName: "vm-old", ReclaimPolicy: vmv1alpha1.ReclaimPolicyDelete},
+			},
+			RemovedVolumes: []vmv1alpha1.RemovedVolumeStatus{
+				{Name: "readded", PVCName: "vm-readded", State: vmv1alpha1.RemovedVolumeRetained},
+				{Name: "old", PVCName: "vm-old", ReclaimPolicy: vmv1alpha1.ReclaimPolicyRetain, State: vmv1alpha1.RemovedVolumeRetained},
+			},
+		},
+	}
+
+	TrackRemovedVolumes(vmp)
+
+	want := map[string]vmv1alpha1.RemovedVolumeStatus{
+		// 已跟踪的卷保持原来的状态和策略
+		"old":  {PVCName: "vm-old", ReclaimPolicy: vmv1alpha1.ReclaimPolicyRetain, State: vmv1alpha1.RemovedVolumeRetained},
+		"data": {PVCName: "vm-data", ReclaimPolicy: vmv1alpha1.ReclaimPolicyDelete, State: vmv1alpha1.RemovedVolumeDetaching},
+		// 未设置策略时默认保留
+		"logs": {PVCName: "vm-logs", ReclaimPolicy: vmv1alpha1.ReclaimPolicyRetain, State: vmv1alpha1.RemovedVolumeDetaching},
+	}
+	if len(vmp.Status.RemovedVolumes) != len(want) {
+		t.Fatalf("RemovedVolumes = %+v, want %d volumes", vmp.Status.RemovedVolumes, len(want))
+	}
+	for _, vol := range vmp.Status.RemovedVolumes {
+		w, ok := want[vol.Name]
+		if !ok {
+			t.Errorf("unexpected removed volume %q", vol.Name)
+			continue
+		}
+		if vol.PVCName != w.PVCName || vol.ReclaimPolicy != w.ReclaimPolicy || vol.State != w.State {
+			t.Errorf("removed volume %q = %+v, want %+v", vol.Name, vol, w)
+		}
+	}
+}
+
+func TestReconcileRemovedVolumes(t *testing.T) {
+	ctx := context.Background()
+	pvc := func(name string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: name}}
+	}
+	c := newFakeClient(t, false, pvc("vm-attached"), pvc("vm-retained"), pvc("vm-default"), pvc("vm-deleted"))
+	vmp := &vmv1alpha1.Wukong{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "vm"},
+		Status: vmv1alpha1.WukongStatus{
+			RemovedVolumes: []vmv1alpha1.RemovedVolumeStatus{
+				{Name: "attached", PVCName: "vm-attached", ReclaimPolicy: vmv1alpha1.ReclaimPolicyDelete, State: vmv1alpha1.RemovedVolumeDetaching},
+				{Name: "retained", PVCName: "vm-retained", ReclaimPolicy: vmv1alpha1.ReclaimPolicyRetain, State: vmv1alpha1.RemovedVolumeDetaching},
+				{Name: "default", PVCName: "vm-default", State: vmv1alpha1.RemovedVolumeDetaching},
+				{Name: "deleted", PVCName: "vm-deleted", ReclaimPolicy: vmv1alpha1.ReclaimPolicyDelete, State: vmv1alpha1.RemovedVolumeDetaching},
+				{Name: "gone", PVCName: "vm-gone", ReclaimPolicy: vmv1alpha1.ReclaimPolicyRetain, State: vmv1alpha1.RemovedVolumeRetained},
+			},
+		},
+	}
+
+	if err := ReconcileRemovedVolumes(ctx, c, vmp, map[string]bool{"vm-attached": true}); err != nil {
+		t.Fatalf("ReconcileRemovedVolumes() error = %v", err)
+	}
+
+	wantStates := map[string]string{
+		"attached": vmv1alpha1.RemovedVolumeDetaching,
+		"retained": vmv1alpha1.RemovedVolumeRetained,
+		"default":  vmv1alpha1.RemovedVolumeRetained,
+	}
+	if len(vmp.Status.RemovedVolumes) != len(wantStates) {
+		t.Fatalf("RemovedVolumes = %+v, want %v", vmp.Status.RemovedVolumes, wantStates)
+	}
+	for _, vol := range vmp.Status.RemovedVolumes {
+		if vol.State != wantStates[vol.Name] {
+			t.Errorf("removed volume %q state = %q, want %q", vol.Name, vol.State, wantStates[vol.Name])
+		}
+	}
+
+	for name, wantExists := range map[string]bool{
+		"vm-attached": true,
+		"vm-retained": true,
+		"vm-default":  true,
+		"vm-deleted":  false,
+	} {
+		err := c.Get(ctx, client.ObjectKey{Namespace: "vms", Name: name}, &corev1.PersistentVolumeClaim{})
+		if exists := !errors.IsNotFound(err); exists != wantExists {
+			t.Errorf("PVC %s exists = %v, want %v (err = %v)", name, exists, wantExists, err)
+		}
+	}
+}
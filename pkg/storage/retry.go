@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+const (
+	// defaultImportMaxRetries 是导入失败后的默认重试次数
+	defaultImportMaxRetries = 3
+	// defaultImportBackoffSeconds 是导入失败后第一次重试前的默认等待时间
+	defaultImportBackoffSeconds = 30
+	// maxImportBackoff 是重试等待时间的上限
+	maxImportBackoff = 10 * time.Minute
+	// importerFailureRestarts 是 populating Pod 重启多少次后视为导入失败
+	importerFailureRestarts = 3
+)
+
+// ImportFailedPermanently reports whether a volume import has failed and no retries are left.
+func ImportFailedPermanently(disk vmv1alpha1.DiskConfig, vol vmv1alpha1.VolumeStatus) bool {
+	return vol.LastImportFailureTime != nil && vol.ImportRetries >= importMaxRetries(disk.ImportRetry)
+}
+
+// applyDataVolumeState copies the observed DataVolume state into volStatus and
+// retries failed imports according to the disk's ImportRetryPolicy.
+// An import fails when the DataVolume is Failed or its populating pod keeps restarting.
+// prev is the VolumeStatus recorded in the previous reconcile (may be nil).
+func applyDataVolumeState(ctx context.Context, c client.Client, disk vmv1alpha1.DiskConfig, namespace string, state *DataVolumeState, prev *vmv1alpha1.VolumeStatus, volStatus *vmv1alpha1.VolumeStatus) error {
+	logger := log.FromContext(ctx)
+
+	volStatus.Bound = state.Ready
+	volStatus.ImportPhase = state.Phase
+	volStatus.ImportProgress = state.Progress
+	volStatus.RestartCount = state.RestartCount
+	volStatus.Message = state.Message
+	if prev != nil {
+		volStatus.ImportRetries = prev.ImportRetries
+	}
+	if state.Phase == "Succeeded" {
+		// 导入成功后重置重试次数，之后重新导入时拥有完整的重试次数
+		volStatus.ImportRetries = 0
+	}
+
+	if !state.Failed {
+		return nil
+	}
+
+	// 记录第一次观察到本次失败的时间，用于计算 backoff
+	failureTime := metav1.Now()
+	if prev != nil && prev.LastImportFailureTime != nil {
+		failureTime = *prev.LastImportFailureTime
+	}
+	volStatus.LastImportFailureTime = &failureTime
+
+	maxRetries := importMaxRetries(disk.ImportRetry)
+	if volStatus.ImportRetries >= maxRetries {
+		logger.Info("DataVolume import failed, no retries left", "disk", disk.Name, "retries", volStatus.ImportRetries, "message", state.Message)
+		return nil
+	}
+
+	backoff := importBackoff(disk.ImportRetry, volStatus.ImportRetries)
+	if time.Since(failureTime.Time) < backoff {
+		logger.V(1).Info("DataVolume import failed, waiting before retry", "disk", disk.Name, "backoff", backoff)
+		return nil
+	}
+
+	// 删除失败的 DataVolume，下次 reconcile 时会重新创建
+	logger.Info("Retrying failed DataVolume import", "disk", disk.Name, "retry", volStatus.ImportRetries+1, "maxRetries", maxRetries)
+	if err := DeleteDataVolume(ctx, c, namespace, volStatus.PVCName); err != nil {
+		return err
+	}
+	volStatus.ImportRetries++
+	volStatus.LastImportFailureTime = nil
+	return nil
+}
+
+// importMaxRetries returns the maximum number of import retries for the policy.
+func importMaxRetries(policy *vmv1alpha1.ImportRetryPolicy) int32 {
+	if policy != nil && policy.MaxRetries != nil {
+		return *policy.MaxRetries
+	}
+	return defaultImportMaxRetries
+}
+
+// importBackoff returns the delay before the given retry, doubling after each retry.
+func importBackoff(policy *vmv1alpha1.ImportRetryPolicy, retries int32) time.Duration {
+	backoff := time.Duration(defaultImportBackoffSeconds) * time.Second
+	if policy != nil && policy.BackoffSeconds != nil {
+		backoff = time.Duration(*policy.BackoffSeconds) * time.Second
+	}
+	for i := int32(0); i < retries && backoff < maxImportBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxImportBackoff {
+		backoff = maxImportBackoff
+	}
+	return backoff
+}
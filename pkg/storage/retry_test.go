@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+func int32Ptr(i int32) *int32 { return &i }
+
+func TestImportBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *vmv1alpha1.ImportRetryPolicy
+		retries int32
+		want    time.Duration
+	}{
+		{name: "default first retry", want: 30 * time.Second},
+		{name: "default doubled", retries: 2, want: 2 * time.Minute},
+		{name: "policy", policy: &vmv1alpha1.ImportRetryPolicy{BackoffSeconds: int32Ptr(10)}, retries: 1, want: 20 * time.Second},
+		{name: "capped", retries: 10, want: maxImportBackoff},
+		{name: "policy above the cap", policy: &vmv1alpha1.ImportRetryPolicy{BackoffSeconds: int32Ptr(3600)}, want: maxImportBackoff},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := importBackoff(tt.policy, tt.retries); got != tt.want {
+				t.Errorf("importBackoff() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyDataVolumeState(t *testing.T) {
+	recent := metav1.NewTime(time.Now().Add(-time.Second))
+	old := metav1.NewTime(time.Now().Add(-time.Hour))
+
+	tests := []struct {
+		name        string
+		policy      *vmv1alpha1.ImportRetryPolicy
+		state       DataVolumeState
+		prev        *vmv1alpha1.VolumeStatus
+		wantRetries int32
+		wantFailure bool
+		wantDeleted bool
+	}{
+		{
+			name:  "in progress",
+			state: DataVolumeState{Phase: "ImportInProgress", Progress: "45.00%"},
+		},
+		{
+			name:        "first failure waits for the backoff",
+			state:       DataVolumeState{Phase: "Failed", Failed: true},
+			wantFailure: true,
+		},
+		{
+			name:        "failure within the backoff",
+			state:       DataVolumeState{Phase: "Failed", Failed: true},
+			prev:        &vmv1alpha1.VolumeStatus{ImportRetries: 1, LastImportFailureTime: &recent},
+			wantRetries: 1,
+			wantFailure: true,
+		},
+		{
+			name:        "failure after the backoff is retried",
+			state:       DataVolumeState{Phase: "Failed", Failed: true},
+			prev:        &vmv1alpha1.VolumeStatus{ImportRetries: 1, LastImportFailureTime: &old},
+			wantRetries: 2,
+			wantDeleted: true,
+		},
+		{
+			name:        "no retries left",
+			policy:      &vmv1alpha1.ImportRetryPolicy{MaxRetries: int32Ptr(1)},
+			state:       DataVolumeState{Phase: "Failed", Failed: true},
+			prev:        &vmv1alpha1.VolumeStatus{ImportRetries: 1, LastImportFailureTime: &old},
+			wantRetries: 1,
+			wantFailure: true,
+		},
+		{
+			name:        "retries disabled",
+			policy:      &vmv1alpha1.ImportRetryPolicy{MaxRetries: int32Ptr(0)},
+			state:       DataVolumeState{Phase: "Failed", Failed: true},
+			prev:        &vmv1alpha1.VolumeStatus{LastImportFailureTime: &old},
+			wantFailure: true,
+		},
+		{
+			name:        "in progress after a retry",
+			state:       DataVolumeState{Phase: "ImportInProgress"},
+			prev:        &vmv1alpha1.VolumeStatus{ImportRetries: 2},
+			wantRetries: 2,
+		},
+		{
+			name:  "success resets the retries",
+			state: DataVolumeState{Phase: "Succeeded", Ready: true},
+			prev:  &vmv1alpha1.VolumeStatus{ImportRetries: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := newFakeClient(t, true, newCDIObject("DataVolume", "vms", "vm-system", nil))
+			disk := vmv1alpha1.DiskConfig{Name: "system", ImportRetry: tt.policy}
+			volStatus := &vmv1alpha1.VolumeStatus{Name: "system", PVCName: "vm-system"}
+
+			if err := applyDataVolumeState(ctx, c, disk, "vms", &tt.state, tt.prev, volStatus); err != nil {
+				t.Fatalf("applyDataVolumeState() error = %v", err)
+			}
+			if volStatus.ImportRetries != tt.wantRetries {
+				t.Errorf("ImportRetries = %d, want %d", volStatus.ImportRetries, tt.wantRetries)
+			}
+			if got := volStatus.LastImportFailureTime != nil; got != tt.wantFailure {
+				t.Errorf("LastImportFailureTime = %v, want set: %t", volStatus.LastImportFailureTime, tt.wantFailure)
+			}
+			if tt.prev != nil && tt.prev.LastImportFailureTime != nil && tt.wantFailure && !volStatus.LastImportFailureTime.Equal(tt.prev.LastImportFailureTime) {
+				t.Errorf("LastImportFailureTime = %v, want the first observed failure %v", volStatus.LastImportFailureTime, tt.prev.LastImportFailureTime)
+			}
+			if volStatus.Bound != tt.state.Ready || volStatus.ImportPhase != tt.state.Phase {
+				t.Errorf("status = %+v, want the DataVolume state %+v", volStatus, tt.state)
+			}
+
+			dv := newCDIObject("DataVolume", "", "", nil)
+			err := c.Get(ctx, client.ObjectKey{Namespace: "vms", Name: "vm-system"}, dv)
+			if deleted := client.IgnoreNotFound(err) == nil && err != nil; deleted != tt.wantDeleted {
+				t.Errorf("DataVolume deleted = %t, want %t (err = %v)", deleted, tt.wantDeleted, err)
+			}
+		})
+	}
+}
+
+func TestGetDataVolumeStateImporterFailing(t *testing.T) {
+	newDV := func(name string, restartCount int64, status, reason string) *unstructured.Unstructured {
+		return newCDIObject("DataVolume", "vms", name, map[string]interface{}{
+			"status": map[string]interface{}{
+				"phase":        "ImportInProgress",
+				"restartCount": restartCount,
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Running", "status": status, "reason": reason, "message": "Unable to connect to http data source"},
+				},
+			},
+		})
+	}
+
+	tests := []struct {
+		dv         *unstructured.Unstructured
+		wantFailed bool
+	}{
+		{dv: newDV("crash-loop", 3, "False", "Error"), wantFailed: true},
+		{dv: newDV("back-off", 5, "False", "CrashLoopBackOff"), wantFailed: true},
+		{dv: newDV("few-restarts", 2, "False", "Error")},
+		{dv: newDV("running-again", 3, "True", "Pod is running")},
+		{dv: newDV("pending", 3, "False", "Pending")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dv.GetName(), func(t *testing.T) {
+			c := newFakeClient(t, true, tt.dv)
+			state, err := GetDataVolumeState(context.Background(), c, "vms", tt.dv.GetName())
+			if err != nil {
+				t.Fatalf("GetDataVolumeState() error = %v", err)
+			}
+			if state.Failed != tt.wantFailed {
+				t.Errorf("GetDataVolumeState() Failed = %t, want %t", state.Failed, tt.wantFailed)
+			}
+			if tt.wantFailed && state.Message != "Unable to connect to http data source" {
+				t.Errorf("GetDataVolumeState() Message = %q, want the Running condition message", state.Message)
+			}
+		})
+	}
+}
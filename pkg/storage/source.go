@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+// buildDataVolumeSource builds the DataVolume "source" or "sourceRef" field for the given disk.
+// Exactly one of the returned maps is non-nil; it must be set under the matching key of the DataVolume spec.
+func buildDataVolumeSource(ctx context.Context, c client.Client, disk vmv1alpha1.DiskConfig, namespace string) (source, sourceRef map[string]interface{}, err error) {
+	if disk.Source == nil {
+		return buildLegacyImageSource(ctx, disk.Image), nil, nil
+	}
+
+	src := disk.Source
+	switch {
+	case src.HTTP != nil:
+		http := map[string]interface{}{
+			"url": src.HTTP.URL,
+		}
+		if src.HTTP.CertConfigMap != "" {
+			http["certConfigMap"] = src.HTTP.CertConfigMap
+		}
+		if src.HTTP.SecretRef != "" {
+			http["secretRef"] = src.HTTP.SecretRef
+		}
+		if len(src.HTTP.ExtraHeaders) > 0 {
+			http["extraHeaders"] = toInterfaceSlice(src.HTTP.ExtraHeaders)
+		}
+		if src.HTTP.Checksum != "" {
+			http["checksum"] = src.HTTP.Checksum
+		}
+		return map[string]interface{}{"http": http}, nil, nil
+
+	case src.Registry != nil:
+		pullMethod := src.Registry.PullMethod
+		if pullMethod == "" {
+			pullMethod = "pod"
+		}
+		registry := map[string]interface{}{
+			"url":        src.Registry.URL,
+			"pullMethod": pullMethod,
+		}
+		if src.Registry.PullSecret != "" {
+			registry["secretRef"] = src.Registry.PullSecret
+		}
+		if src.Registry.CertConfigMap != "" {
+			registry["certConfigMap"] = src.Registry.CertConfigMap
+		}
+		return map[string]interface{}{"registry": registry}, nil, nil
+
+	case src.S3 != nil:
+		s3 := map[string]interface{}{
+			"url": src.S3.URL,
+		}
+		if src.S3.SecretRef != "" {
+			s3["secretRef"] = src.S3.SecretRef
+		}
+		if src.S3.CertConfigMap != "" {
+			s3["certConfigMap"] = src.S3.CertConfigMap
+		}
+		return map[string]interface{}{"s3": s3}, nil, nil
+
+	case src.Upload != nil:
+		return map[string]interface{}{"upload": map[string]interface{}{}}, nil, nil
+
+	case src.Blank != nil:
+		return map[string]interface{}{"blank": map[string]interface{}{}}, nil, nil
+
+	case src.PVC != nil:
+		return map[string]interface{}{
+			"pvc": map[string]interface{}{
+				"namespace": defaultNamespace(src.PVC.Namespace, namespace),
+				"name":      src.PVC.Name,
+			},
+		}, nil, nil
+
+	case src.DataSource != nil:
+		return nil, dataSourceRef(defaultNamespace(src.DataSource.Namespace, namespace), src.DataSource.Name), nil
+
+	case src.DataImportCron != nil:
+		// DataImportCron 本身不能作为 DataVolume 的源，需要解析出它所管理的 DataSource
+		cronNamespace := defaultNamespace(src.DataImportCron.Namespace, namespace)
+		dataSourceName, err := getManagedDataSource(ctx, c, cronNamespace, src.DataImportCron.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, dataSourceRef(cronNamespace, dataSourceName), nil
+	}
+
+	return nil, nil, fmt.Errorf("disk %s has an empty source", disk.Name)
+}
+
+// buildLegacyImageSource guesses the DataVolume source from the disk.image URL prefix.
+// 支持 http://、https://（HTTP 源）和 docker://（registry 源），其他格式默认当作 registry URL
+func buildLegacyImageSource(ctx context.Context, imageURL string) map[string]interface{} {
+	logger := log.FromContext(ctx)
+
+	if strings.HasPrefix(imageURL, "http://") || strings.HasPrefix(imageURL, "https://") {
+		// HTTP/HTTPS 源：直接从 URL 下载镜像文件
+		logger.Info("Using HTTP source for DataVolume", "url", imageURL)
+		return map[string]interface{}{
+			"http": map[string]interface{}{
+				"url": imageURL,
+			},
+		}
+	}
+
+	if strings.HasPrefix(imageURL, "docker://") {
+		// Docker registry 源：从容器镜像仓库拉取
+		// 去掉 docker:// 前缀，CDI 需要的是纯 URL
+		registryURL := strings.TrimPrefix(imageURL, "docker://")
+		logger.Info("Using registry source for DataVolume", "url", registryURL)
+		return map[string]interface{}{
+			"registry": map[string]interface{}{
+				// 使用容器内拉取镜像的方式（pod 模式），在 Docker Desktop 等环境下更通用
+				"url":        registryURL,
+				"pullMethod": "pod",
+			},
+		}
+	}
+
+	// 默认当作 registry URL（兼容旧格式）
+	logger.Info("Using registry source for DataVolume (default)", "url", imageURL)
+	return map[string]interface{}{
+		"registry": map[string]interface{}{
+			"url":        imageURL,
+			"pullMethod": "pod",
+		},
+	}
+}
+
+// getManagedDataSource returns the name of the DataSource managed by a DataImportCron.
+func getManagedDataSource(ctx context.Context, c client.Client, namespace, name string) (string, error) {
+	cron := &unstructured.Unstructured{}
+	cron.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "cdi.kubevirt.io",
+		Version: "v1beta1",
+		Kind:    "DataImportCron",
+	})
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, cron); err != nil {
+		return "", fmt.Errorf("failed to get DataImportCron %s/%s: %w", namespace, name, err)
+	}
+
+	dataSourceName, found, err := unstructured.NestedString(cron.Object, "spec", "managedDataSource")
+	if err != nil {
+		return "", err
+	}
+	if !found || dataSourceName == "" {
+		return "", fmt.Errorf("DataImportCron %s/%s has no managedDataSource", namespace, name)
+	}
+	return dataSourceName, nil
+}
+
+// GetDataSourcePVC returns the PVC (namespace/name) a DataSource currently points to.
+// It returns an empty string if the DataSource does not reference a PVC.
+func GetDataSourcePVC(ctx context.Context, c client.Client, namespace, name string) (string, error) {
+	ds := &unstructured.Unstructured{}
+	ds.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "cdi.kubevirt.io",
+		Version: "v1beta1",
+		Kind:    "DataSource",
+	})
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, ds); err != nil {
+		return "", err
+	}
+
+	pvc, found, err := unstructured.NestedStringMap(ds.Object, "spec", "source", "pvc")
+	if err != nil || !found || pvc["name"] == "" {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s", defaultNamespace(pvc["namespace"], namespace), pvc["name"]), nil
+}
+
+// InFlightCloneSources returns the source PVCs (namespace/name) of all DataVolumes that have not
+// succeeded yet. A DataVolume cloning from a DataSource references the PVC the DataSource currently
+// points to. An empty result is returned if CDI is not installed.
+func InFlightCloneSources(ctx context.Context, c client.Client) (map[string]bool, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "cdi.kubevirt.io",
+		Version: "v1beta1",
+		Kind:    "DataVolumeList",
+	})
+	sources := make(map[string]bool)
+	if err := c.List(ctx, list); err != nil {
+		if meta.IsNoMatchError(err) {
+			return sources, nil
+		}
+		return nil, err
+	}
+
+	for i := range list.Items {
+		dv := &list.Items[i]
+		if phase, _, _ := unstructured.NestedString(dv.Object, "status", "phase"); phase == "Succeeded" {
+			continue
+		}
+		if pvc, found, _ := unstructured.NestedStringMap(dv.Object, "spec", "source", "pvc"); found && pvc["name"] != "" {
+			sources[fmt.Sprintf("%s/%s", defaultNamespace(pvc["namespace"], dv.GetNamespace()), pvc["name"])] = true
+			continue
+		}
+		ref, found, _ := unstructured.NestedStringMap(dv.Object, "spec", "sourceRef")
+		if !found || ref["kind"] != "DataSource" || ref["name"] == "" {
+			continue
+		}
+		sourcePVC, err := GetDataSourcePVC(ctx, c, defaultNamespace(ref["namespace"], dv.GetNamespace()), ref["name"])
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		if sourcePVC != "" {
+			sources[sourcePVC] = true
+		}
+	}
+	return sources, nil
+}
+
+// GetUploadEndpoint returns the upload endpoint of the CDI upload proxy.
+// It returns an empty string if CDI does not report an upload proxy URL.
+func GetUploadEndpoint(ctx context.Context, c client.Client) (string, error) {
+	cdiConfig := &unstructured.Unstructured{}
+	cdiConfig.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "cdi.kubevirt.io",
+		Version: "v1beta1",
+		Kind:    "CDIConfig",
+	})
+	if err := c.Get(ctx, client.ObjectKey{Name: "config"}, cdiConfig); err != nil {
+		return "", err
+	}
+
+	proxyURL, found, err := unstructured.NestedString(cdiConfig.Object, "status", "uploadProxyURL")
+	if err != nil || !found || proxyURL == "" {
+		return "", err
+	}
+	if !strings.Contains(proxyURL, "://") {
+		proxyURL = "https://" + proxyURL
+	}
+	return strings.TrimSuffix(proxyURL, "/") + "/v1beta1/upload", nil
+}
+
+// dataSourceRef builds a DataVolume sourceRef pointing to a DataSource.
+func dataSourceRef(namespace, name string) map[string]interface{} {
+	return map[string]interface{}{
+		"kind":      "DataSource",
+		"namespace": namespace,
+		"name":      name,
+	}
+}
+
+// defaultNamespace returns namespace if set, otherwise fallback.
+func defaultNamespace(namespace, fallback string) string {
+	if namespace != "" {
+		return namespace
+	}
+	return fallback
+}
+
+// toInterfaceSlice converts a []string for use in unstructured objects.
+// 注意：unstructured 对象中必须使用 []interface{}，否则 DeepCopy 时会 panic
+func toInterfaceSlice(values []string) []interface{} {
+	result := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		result = append(result, v)
+	}
+	return result
+}
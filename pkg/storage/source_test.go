@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// cdiKinds 是测试中以 unstructured 形式注册的 CDI 资源
+var cdiKinds = []string{"DataVolume", "DataSource", "DataImportCron", "StorageProfile"}
+
+// newFakeClient 返回注册了 Wukong、core、storage 和 CDI 资源的 fake client
+// withCDI 为 false 时模拟未安装 CDI 的集群
+func newFakeClient(t *testing.T, withCDI bool, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := storagev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := vmv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if withCDI {
+		for _, kind := range cdiKinds {
+			gv := schema.GroupVersion{Group: "cdi.kubevirt.io", Version: "v1beta1"}
+			scheme.AddKnownTypeWithName(gv.WithKind(kind), &unstructured.Unstructured{})
+			scheme.AddKnownTypeWithName(gv.WithKind(kind+"List"), &unstructured.UnstructuredList{})
+		}
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithStatusSubresource(&vmv1alpha1.Wukong{}).Build()
+}
+
+// newCDIObject 构造测试用的 CDI unstructured 对象
+func newCDIObject(kind, namespace, name string, fields map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: fields}
+	if obj.Object == nil {
+		obj.Object = map[string]interface{}{}
+	}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{Group: "cdi.kubevirt.io", Version: "v1beta1", Kind: kind})
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func TestInFlightCloneSources(t *testing.T) {
+	objs := []client.Object{
+		// 从 PVC 克隆，尚未完成
+		newCDIObject("DataVolume", "vms", "vm1-system", map[string]interface{}{
+			"spec":   map[string]interface{}{"source": map[string]interface{}{"pvc": map[string]interface{}{"namespace": "images", "name": "ubuntu-fast-old"}}},
+			"status": map[string]interface{}{"phase": "CloneInProgress"},
+		}),
+		// 从 DataSource 克隆，尚未开始
+		newCDIObject("DataVolume", "vms", "vm2-system", map[string]interface{}{
+			"spec": map[string]interface{}{"sourceRef": map[string]interface{}{"kind": "DataSource", "namespace": "images", "name": "ubuntu-fast"}},
+		}),
+		newCDIObject("DataSource", "images", "ubuntu-fast", map[string]interface{}{
+			"spec": map[string]interface{}{"source": map[string]interface{}{"pvc": map[string]interface{}{"name": "ubuntu-fast-new"}}},
+		}),
+		// 已完成的克隆不再需要来源
+		newCDIObject("DataVolume", "vms", "vm3-system", map[string]interface{}{
+			"spec":   map[string]interface{}{"source": map[string]interface{}{"pvc": map[string]interface{}{"namespace": "images", "name": "ubuntu-fast-done"}}},
+			"status": map[string]interface{}{"phase": "Succeeded"},
+		}),
+		// 引用不存在的 DataSource
+		newCDIObject("DataVolume", "vms", "vm4-system", map[string]interface{}{
+			"spec": map[string]interface{}{"sourceRef": map[string]interface{}{"kind": "DataSource", "name": "missing"}},
+		}),
+	}
+
+	got, err := InFlightCloneSources(context.Background(), newFakeClient(t, true, objs...))
+	if err != nil {
+		t.Fatalf("InFlightCloneSources() error = %v", err)
+	}
+	want := map[string]bool{
+		"images/ubuntu-fast-old": true,
+		"images/ubuntu-fast-new": true,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("InFlightCloneSources() = %v, want %v", got, want)
+	}
+	for k := range want {
+		if !got[k] {
+			t.Errorf("InFlightCloneSources() missing %s, got %v", k, got)
+		}
+	}
+}
+
+func TestInFlightCloneSourcesWithoutCDI(t *testing.T) {
+	got, err := InFlightCloneSources(context.Background(), newFakeClient(t, false))
+	if err != nil {
+		t.Fatalf("InFlightCloneSources() error = %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("InFlightCloneSources() = %v, want empty", got)
+	}
+}
+
+func TestBuildDataVolumeSource(t *testing.T) {
+	cron := newCDIObject("DataImportCron", "images", "ubuntu-nightly", map[string]interface{}{
+		"spec": map[string]interface{}{"managedDataSource": "ubuntu"},
+	})
+	c := newFakeClient(t, true, cron)
+
+	tests := []struct {
+		golden string
+		disk   vmv1alpha1.DiskConfig
+	}{
+		{
+			golden: "http",
+			disk: vmv1alpha1.DiskConfig{Name: "system", Source: &vmv1alpha1.DiskSourceSpec{HTTP: &vmv1alpha1.HTTPDiskSource{
+				URL:           "https://images.example.com/ubuntu-22.04.qcow2",
+				CertConfigMap: "images-ca",
+				SecretRef:     "images-auth",
+				ExtraHeaders:  []string{"X-Mirror: eu"},
+				Checksum:      "sha256:1f3d7c2d0b4e5a6f",
+			}}},
+		},
+		{
+			golden: "registry",
+			disk: vmv1alpha1.DiskConfig{Name: "system", Source: &vmv1alpha1.DiskSourceSpec{Registry: &vmv1alpha1.RegistryDiskSource{
+				URL:           "docker://quay.io/containerdisks/ubuntu:22.04",
+				PullMethod:    "node",
+				PullSecret:    "quay-pull",
+				CertConfigMap: "quay-ca",
+			}}},
+		},
+		{
+			golden: "registry-default-pull-method",
+			disk: vmv1alpha1.DiskConfig{Name: "system", Source: &vmv1alpha1.DiskSourceSpec{Registry: &vmv1alpha1.RegistryDiskSource{
+				URL: "docker://quay.io/containerdisks/ubuntu:22.04",
+			}}},
+		},
+		{
+			golden: "s3",
+			disk: vmv1alpha1.DiskConfig{Name: "system", Source: &vmv1alpha1.DiskSourceSpec{S3: &vmv1alpha1.S3DiskSource{
+				URL:           "https://s3.example.com/images/ubuntu.qcow2",
+				SecretRef:     "s3-credentials",
+				CertConfigMap: "s3-ca",
+			}}},
+		},
+		{
+			golden: "upload",
+			disk:   vmv1alpha1.DiskConfig{Name: "system", Source: &vmv1alpha1.DiskSourceSpec{Upload: &vmv1alpha1.UploadDiskSource{}}},
+		},
+		{
+			golden: "blank",
+			disk:   vmv1alpha1.DiskConfig{Name: "data", Source: &vmv1alpha1.DiskSourceSpec{Blank: &vmv1alpha1.BlankDiskSource{}}},
+		},
+		{
+			golden: "pvc",
+			disk: vmv1alpha1.DiskConfig{Name: "system", Source: &vmv1alpha1.DiskSourceSpec{PVC: &vmv1alpha1.PVCDiskSource{
+				Name: "ubuntu-base",
+			}}},
+		},
+		{
+			golden: "datasource",
+			disk: vmv1alpha1.DiskConfig{Name: "system", Source: &vmv1alpha1.DiskSourceSpec{DataSource: &vmv1alpha1.DataSourceReference{
+				Namespace: "images",
+				Name:      "ubuntu",
+			}}},
+		},
+		{
+			golden: "dataimportcron",
+			disk: vmv1alpha1.DiskConfig{Name: "system", Source: &vmv1alpha1.DiskSourceSpec{DataImportCron: &vmv1alpha1.DataSourceReference{
+				Namespace: "images",
+				Name:      "ubuntu-nightly",
+			}}},
+		},
+		{
+			golden: "legacy-image",
+			disk:   vmv1alpha1.DiskConfig{Name: "system", Image: "docker://quay.io/containerdisks/ubuntu:22.04"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			source, sourceRef, err := buildDataVolumeSource(context.Background(), c, tt.disk, "vms")
+			if err != nil {
+				t.Fatalf("buildDataVolumeSource() error = %v", err)
+			}
+			if (source == nil) == (sourceRef == nil) {
+				t.Fatalf("buildDataVolumeSource() = %v, %v, want exactly one of source and sourceRef", source, sourceRef)
+			}
+			spec := map[string]interface{}{"source": source}
+			if sourceRef != nil {
+				spec = map[string]interface{}{"sourceRef": sourceRef}
+			}
+
+			got, err := json.MarshalIndent(spec, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			path := filepath.Join("testdata", "source-"+tt.golden+".json")
+			if *update {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("buildDataVolumeSource() mismatch with %s:\ngot:\n%s\nwant:\n%s", path, got, want)
+			}
+		})
+	}
+}
+
+func TestBuildDataVolumeSourceErrors(t *testing.T) {
+	c := newFakeClient(t, true, newCDIObject("DataImportCron", "vms", "unmanaged", nil))
+
+	tests := []struct {
+		name   string
+		source *vmv1alpha1.DiskSourceSpec
+	}{
+		{name: "empty source", source: &vmv1alpha1.DiskSourceSpec{}},
+		{name: "missing DataImportCron", source: &vmv1alpha1.DiskSourceSpec{DataImportCron: &vmv1alpha1.DataSourceReference{Name: "missing"}}},
+		{name: "DataImportCron without managedDataSource", source: &vmv1alpha1.DiskSourceSpec{DataImportCron: &vmv1alpha1.DataSourceReference{Name: "unmanaged"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			disk := vmv1alpha1.DiskConfig{Name: "system", Source: tt.source}
+			if _, _, err := buildDataVolumeSource(context.Background(), c, disk, "vms"); err == nil {
+				t.Error("buildDataVolumeSource() expected error")
+			}
+		})
+	}
+}
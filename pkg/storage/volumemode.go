@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+// VolumeModeAuto picks the volume mode and access modes from the WukongStorageProfile or CDI StorageProfile
+const VolumeModeAuto = "Auto"
+
+// claimPropertySet is a supported combination of access modes and volume mode of a StorageClass.
+type claimPropertySet struct {
+	accessModes []string
+	volumeMode  string
+}
+
+// ResolveVolumeMode returns the access modes and volume mode of the PVC backing the disk.
+// When disk.VolumeMode is Auto, the defaults of the WukongStorageProfile of the StorageClass are used
+// if they include the requested access modes. Otherwise the CDI StorageProfile is consulted and
+// ReadWriteMany Block is preferred, then ReadWriteMany Filesystem, then the first supported set.
+func ResolveVolumeMode(ctx context.Context, c client.Client, disk vmv1alpha1.DiskConfig) ([]string, string, error) {
+	logger := log.FromContext(ctx)
+
+	if disk.VolumeMode != VolumeModeAuto {
+		return pvcAccessModes(disk), pvcVolumeMode(disk), nil
+	}
+
+	profile, err := GetStorageProfile(ctx, c, disk.StorageClassName)
+	if err != nil {
+		return nil, "", err
+	}
+	if profile != nil && profile.Status.DefaultVolumeMode != "" && containsAll(profile.Status.DefaultAccessModes, disk.AccessModes) {
+		return accessModesOrDefault(disk.AccessModes, profile.Status.DefaultAccessModes), profile.Status.DefaultVolumeMode, nil
+	}
+
+	sets, err := getClaimPropertySets(ctx, c, disk.StorageClassName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 如果用户指定了 accessModes，只在包含这些模式的组合中选择 volumeMode
+	candidates := make([]claimPropertySet, 0, len(sets))
+	for _, set := range sets {
+		if containsAll(set.accessModes, disk.AccessModes) {
+			candidates = append(candidates, set)
+		}
+	}
+
+	preferences := []claimPropertySet{
+		{accessModes: []string{string(corev1.ReadWriteMany)}, volumeMode: string(corev1.PersistentVolumeBlock)},
+		{accessModes: []string{string(corev1.ReadWriteMany)}, volumeMode: string(corev1.PersistentVolumeFilesystem)},
+	}
+	for _, pref := range preferences {
+		for _, set := range candidates {
+			if set.volumeMode == pref.volumeMode && containsAll(set.accessModes, pref.accessModes) {
+				return accessModesOrDefault(disk.AccessModes, pref.accessModes), set.volumeMode, nil
+			}
+		}
+	}
+	if len(candidates) > 0 {
+		return accessModesOrDefault(disk.AccessModes, candidates[0].accessModes), candidates[0].volumeMode, nil
+	}
+
+	// StorageProfile 不存在或没有可用组合，回退到 ReadWriteOnce Filesystem
+	logger.V(1).Info("No matching StorageProfile claim property set, falling back to Filesystem", "storageClass", disk.StorageClassName)
+	return pvcAccessModes(disk), string(corev1.PersistentVolumeFilesystem), nil
+}
+
+// getClaimPropertySets returns the claim property sets reported by the CDI StorageProfile of a StorageClass.
+// It returns nil if CDI is not installed or the StorageProfile does not exist.
+func getClaimPropertySets(ctx context.Context, c client.Client, storageClassName string) ([]claimPropertySet, error) {
+	profile := newCDIStorageProfile()
+	if err := c.Get(ctx, client.ObjectKey{Name: storageClassName}, profile); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	items, _, err := unstructured.NestedSlice(profile.Object, "status", "claimPropertySets")
+	if err != nil {
+		return nil, err
+	}
+	sets := make([]claimPropertySet, 0, len(items))
+	for _, item := range items {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		accessModes, _, _ := unstructured.NestedStringSlice(itemMap, "accessModes")
+		volumeMode, _, _ := unstructured.NestedString(itemMap, "volumeMode")
+		if volumeMode == "" {
+			volumeMode = string(corev1.PersistentVolumeFilesystem)
+		}
+		sets = append(sets, claimPropertySet{accessModes: accessModes, volumeMode: volumeMode})
+	}
+	return sets, nil
+}
+
+// pvcAccessModes returns the access modes of the disk, defaulting to ReadWriteOnce.
+func pvcAccessModes(disk vmv1alpha1.DiskConfig) []string {
+	return accessModesOrDefault(disk.AccessModes, []string{string(corev1.ReadWriteOnce)})
+}
+
+// pvcVolumeMode returns the volume mode of the disk, defaulting to Filesystem.
+func pvcVolumeMode(disk vmv1alpha1.DiskConfig) string {
+	if disk.VolumeMode == "" || disk.VolumeMode == VolumeModeAuto {
+		return string(corev1.PersistentVolumeFilesystem)
+	}
+	return disk.VolumeMode
+}
+
+// accessModesOrDefault returns accessModes if set, otherwise fallback.
+func accessModesOrDefault(accessModes, fallback []string) []string {
+	if len(accessModes) > 0 {
+		return accessModes
+	}
+	return fallback
+}
+
+// containsAll reports whether values contains every element of required.
+func containsAll(values, required []string) bool {
+	for _, r := range required {
+		found := false
+		for _, v := range values {
+			if v == r {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
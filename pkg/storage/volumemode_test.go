@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1alpha1 "github.com/kuihuar/novasphere/api/v1alpha1"
+)
+
+// newCDIStorageProfileObject 构造带有 claimPropertySets 状态的 CDI StorageProfile
+func newCDIStorageProfileObject(storageClassName string, sets ...claimPropertySet) client.Object {
+	items := make([]interface{}, 0, len(sets))
+	for _, set := range sets {
+		accessModes := make([]interface{}, 0, len(set.accessModes))
+		for _, mode := range set.accessModes {
+			accessModes = append(accessModes, mode)
+		}
+		items = append(items, map[string]interface{}{"accessModes": accessModes, "volumeMode": set.volumeMode})
+	}
+	return newCDIObject("StorageProfile", "", storageClassName, map[string]interface{}{
+		"status": map[string]interface{}{"claimPropertySets": items},
+	})
+}
+
+func TestResolveVolumeMode(t *testing.T) {
+	rwoFS := claimPropertySet{accessModes: []string{"ReadWriteOnce"}, volumeMode: "Filesystem"}
+	rwoBlock := claimPropertySet{accessModes: []string{"ReadWriteOnce"}, volumeMode: "Block"}
+	rwxFS := claimPropertySet{accessModes: []string{"ReadWriteMany"}, volumeMode: "Filesystem"}
+	rwxBlock := claimPropertySet{accessModes: []string{"ReadWriteMany"}, volumeMode: "Block"}
+	profile := &vmv1alpha1.WukongStorageProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "fast"},
+		Status: vmv1alpha1.WukongStorageProfileStatus{
+			DefaultAccessModes: []string{"ReadWriteOnce"},
+			DefaultVolumeMode:  "Block",
+		},
+	}
+
+	tests := []struct {
+		name            string
+		disk            vmv1alpha1.DiskConfig
+		objs            []client.Object
+		wantAccessModes []string
+		wantVolumeMode  string
+	}{
+		{
+			name:            "defaults without Auto",
+			disk:            vmv1alpha1.DiskConfig{Name: "data", StorageClassName: "fast"},
+			objs:            []client.Object{profile, newCDIStorageProfileObject("fast", rwxBlock)},
+			wantAccessModes: []string{"ReadWriteOnce"},
+			wantVolumeMode:  "Filesystem",
+		},
+		{
+			name:            "explicit modes",
+			disk:            vmv1alpha1.DiskConfig{Name: "data", StorageClassName: "fast", AccessModes: []string{"ReadWriteMany"}, VolumeMode: "Block"},
+			wantAccessModes: []string{"ReadWriteMany"},
+			wantVolumeMode:  "Block",
+		},
+		{
+			name:            "WukongStorageProfile defaults first",
+			disk:            vmv1alpha1.DiskConfig{Name: "data", StorageClassName: "fast", VolumeMode: VolumeModeAuto},
+			objs:            []client.Object{profile, newCDIStorageProfileObject("fast", rwxBlock)},
+			wantAccessModes: []string{"ReadWriteOnce"},
+			wantVolumeMode:  "Block",
+		},
+		{
+			name:            "WukongStorageProfile defaults without the requested access modes",
+			disk:            vmv1alpha1.DiskConfig{Name: "data", StorageClassName: "fast", VolumeMode: VolumeModeAuto, AccessModes: []string{"ReadWriteMany"}},
+			objs:            []client.Object{profile, newCDIStorageProfileObject("fast", rwxFS)},
+			wantAccessModes: []string{"ReadWriteMany"},
+			wantVolumeMode:  "Filesystem",
+		},
+		{
+			name:            "ReadWriteMany Block preferred",
+			disk:            vmv1alpha1.DiskConfig{Name: "data", StorageClassName: "fast", VolumeMode: VolumeModeAuto},
+			objs:            []client.Object{newCDIStorageProfileObject("fast", rwoFS, rwxFS, rwxBlock)},
+			wantAccessModes: []string{"ReadWriteMany"},
+			wantVolumeMode:  "Block",
+		},
+		{
+			name:            "then ReadWriteMany Filesystem",
+			disk:            vmv1alpha1.DiskConfig{Name: "data", StorageClassName: "fast", VolumeMode: VolumeModeAuto},
+			objs:            []client.Object{newCDIStorageProfileObject("fast", rwoBlock, rwxFS)},
+			wantAccessModes: []string{"ReadWriteMany"},
+			wantVolumeMode:  "Filesystem",
+		},
+		{
+			name:            "then the first supported set",
+			disk:            vmv1alpha1.DiskConfig{Name: "data", StorageClassName: "fast", VolumeMode: VolumeModeAuto},
+			objs:            []client.Object{newCDIStorageProfileObject("fast", rwoBlock, rwoFS)},
+			wantAccessModes: []string{"ReadWriteOnce"},
+			wantVolumeMode:  "Block",
+		},
+		{
+			name:            "only sets with the requested access modes",
+			disk:            vmv1alpha1.DiskConfig{Name: "data", StorageClassName: "fast", VolumeMode: VolumeModeAuto, AccessModes: []string{"ReadWriteOnce"}},
+			objs:            []client.Object{newCDIStorageProfileObject("fast", rwxBlock, rwoFS)},
+			wantAccessModes: []string{"ReadWriteOnce"},
+			wantVolumeMode:  "Filesystem",
+		},
+		{
+			name:            "no StorageProfile falls back to Filesystem",
+			disk:            vmv1alpha1.DiskConfig{Name: "data", StorageClassName: "fast", VolumeMode: VolumeModeAuto},
+			wantAccessModes: []string{"ReadWriteOnce"},
+			wantVolumeMode:  "Filesystem",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeClient(t, true, tt.objs...)
+			accessModes, volumeMode, err := ResolveVolumeMode(context.Background(), c, tt.disk)
+			if err != nil {
+				t.Fatalf("ResolveVolumeMode() error = %v", err)
+			}
+			if !reflect.DeepEqual(accessModes, tt.wantAccessModes) || volumeMode != tt.wantVolumeMode {
+				t.Errorf("ResolveVolumeMode() = %v, %s, want %v, %s", accessModes, volumeMode, tt.wantAccessModes, tt.wantVolumeMode)
+			}
+		})
+	}
+}